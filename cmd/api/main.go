@@ -1,49 +1,147 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 
 	"github.com/waizbart/aletheia-api/internal/config"
 	"github.com/waizbart/aletheia-api/internal/handler"
+	_ "github.com/waizbart/aletheia-api/internal/imaging"
 	"github.com/waizbart/aletheia-api/internal/repository"
 	"github.com/waizbart/aletheia-api/internal/usecase"
+	"github.com/waizbart/aletheia-api/internal/video"
 )
 
+// certificateStore is everything the API and its workers need from storage.
+type certificateStore interface {
+	usecase.BatchRepository
+	usecase.ReorgRepository
+	usecase.ReconciliationRepository
+	usecase.CheckpointStore
+	usecase.AnchorRepository
+	usecase.ProofRepository
+}
+
 func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("no .env file found, reading environment directly")
 	}
 
-	db, err := sql.Open("postgres", config.MustEnv("DATABASE_URL"))
+	db, certRepo, err := openStore(config.MustEnv("DATABASE_URL"))
 	if err != nil {
-		log.Fatalf("opening database: %v", err)
+		log.Fatal(err)
 	}
-	defer db.Close()
-
-	if err := db.Ping(); err != nil {
-		log.Fatalf("connecting to database: %v", err)
+	if db != nil {
+		defer db.Close()
 	}
-	log.Println("connected to PostgreSQL")
 
-	certRepo := repository.NewPostgresCertificateRepo(db)
-	chainSvc, err := repository.NewBlockchainServiceFromEnv()
+	chainSvc, err := repository.NewBlockchainServiceFromEnv(db)
 	if err != nil {
 		log.Fatalf("initializing blockchain service: %v", err)
 	}
 
-	certifyUC := usecase.NewCertifyUseCase(certRepo, chainSvc)
-	verifyUC := usecase.NewVerifyUseCase(certRepo)
+	if config.EnvBool("INDEXER_ENABLED", false) {
+		source, ok := chainSvc.(usecase.RegistrationEventSource)
+		if !ok {
+			log.Fatalf("indexer: blockchain service %T cannot read contract events", chainSvc)
+		}
+		indexer := usecase.NewIndexerUseCase(source, certRepo, certRepo, usecase.IndexerConfig{
+			StartBlock:    config.EnvUint("INDEXER_START_BLOCK", 0),
+			BatchSize:     config.EnvUint("INDEXER_BATCH_SIZE", 1000),
+			Confirmations: config.EnvUint("CONFIRMATIONS", 1),
+			PollInterval:  config.EnvDuration("INDEXER_INTERVAL", 15*time.Second),
+		})
+		go indexer.Run(context.Background())
+		log.Println("contract event indexer started")
+	}
+
+	processor := usecase.NewAnchorProcessor(certRepo, chainSvc, int(config.EnvUint("OUTBOX_MAX_ATTEMPTS", 5)))
+	if n, err := processor.BackfillAnchors(context.Background()); err != nil {
+		log.Printf("backfilling primary anchors: %v", err)
+	} else if n > 0 {
+		log.Printf("recorded the primary anchor of %d certificates", n)
+	}
+	if config.EnvBool("OUTBOX_ENABLED", true) {
+		outbox := usecase.NewOutboxWorker(certRepo, processor, usecase.OutboxConfig{
+			StaleAfter:   config.EnvDuration("OUTBOX_STALE_AFTER", 5*time.Minute),
+			PollInterval: config.EnvDuration("OUTBOX_INTERVAL", 10*time.Second),
+		})
+		go outbox.Run(context.Background())
+		log.Println("certificate outbox worker started")
+	}
+
+	if config.EnvBool("REORG_WATCHER_ENABLED", true) {
+		if canonical, ok := chainSvc.(usecase.CanonicalChain); ok {
+			watcher := usecase.NewReorgWatcher(certRepo, canonical, processor, usecase.ReorgConfig{
+				Depth:        config.EnvUint("REORG_DEPTH", 64),
+				PollInterval: config.EnvDuration("REORG_INTERVAL", time.Minute),
+			})
+			go watcher.Run(context.Background())
+			log.Println("reorg watcher started")
+		} else {
+			log.Printf("reorg watcher disabled: blockchain service %T cannot read block hashes", chainSvc)
+		}
+	}
+
+	for _, name := range strings.Split(config.EnvOrDefault("ANCHOR_CHAINS", ""), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		chain, err := repository.NewAnchorChainFromEnv(name)
+		if err != nil {
+			log.Fatalf("initializing anchor chain %s: %v", name, err)
+		}
+		prefix := strings.ToUpper(name) + "_"
+		secondary := usecase.NewSecondaryAnchorer(certRepo, chain, usecase.SecondaryConfig{
+			Name:     name,
+			MaxSize:  int(config.EnvUint(prefix+"ANCHOR_BATCH_SIZE", 1024)),
+			Interval: config.EnvDuration(prefix+"ANCHOR_INTERVAL", time.Hour),
+		})
+		go secondary.Run(context.Background())
+		log.Printf("anchoring on additional chain %s", name)
+	}
+
+	var anchorer usecase.Anchorer = processor
+	switch mode := config.EnvOrDefault("ANCHOR_MODE", "single"); mode {
+	case "single":
+	case "batch":
+		batcher := usecase.NewBatchAnchorer(certRepo, processor, usecase.BatchConfig{
+			MaxSize: int(config.EnvUint("BATCH_MAX_SIZE", 256)),
+			Window:  config.EnvDuration("BATCH_WINDOW", 30*time.Second),
+			Lease:   config.EnvDuration("BATCH_LEASE", 5*time.Minute),
+		})
+		go batcher.Run(context.Background())
+		anchorer = batcher
+		log.Println("merkle batch anchoring enabled")
+	default:
+		log.Fatalf("invalid ANCHOR_MODE %q: must be single or batch", mode)
+	}
+
+	fingerprinter := video.NewFingerprinter().WithSegmentInterval(config.EnvDuration("VIDEO_SEGMENT_INTERVAL", video.DefaultSegmentInterval))
+	certifyUC := usecase.NewCertifyUseCase(certRepo, anchorer).WithVideoFingerprinter(fingerprinter)
+	verifyUC := usecase.NewVerifyUseCase(certRepo, chainSvc).WithVideoFingerprinter(fingerprinter)
 
 	certHandler := handler.NewCertificateHandler(certifyUC, verifyUC)
 
 	mux := http.NewServeMux()
 	certHandler.RegisterRoutes(mux)
+	if target, ok := chainSvc.(usecase.AnchorTarget); ok {
+		handler.NewProofHandler(usecase.NewProofUseCase(certRepo, target)).RegisterRoutes(mux)
+	} else {
+		log.Printf("proof bundles disabled: blockchain service %T has no chain id or contract address", chainSvc)
+	}
+	if tlog, ok := chainSvc.(usecase.TransparencyLog); ok {
+		handler.NewLogHandler(usecase.NewTransparencyLogUseCase(tlog)).RegisterRoutes(mux)
+		log.Println("transparency log endpoints enabled")
+	}
 	handler.RegisterDocsRoutes(mux)
 	handler.RegisterHealthRoutes(mux)
 
@@ -56,3 +154,33 @@ func main() {
 		log.Fatalf("server error: %v", err)
 	}
 }
+
+// openStore picks the storage backend from the DATABASE_URL scheme. The
+// returned *sql.DB is the PostgreSQL connection, which the transparency log
+// shares; it is nil for the in-memory and SQLite stores.
+func openStore(url string) (*sql.DB, certificateStore, error) {
+	switch {
+	case strings.HasPrefix(url, "memory://"):
+		log.Println("using in-memory storage; all data is lost on restart")
+		return nil, repository.NewMemoryCertificateRepo(), nil
+	case strings.HasPrefix(url, "sqlite://"):
+		path := strings.TrimPrefix(url, "sqlite://")
+		db, err := repository.OpenSQLite(context.Background(), path)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Printf("using SQLite database %s", path)
+		return nil, repository.NewSQLiteCertificateRepo(db), nil
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("connecting to database: %w", err)
+	}
+	log.Println("connected to PostgreSQL")
+	return db, repository.NewPostgresCertificateRepo(db), nil
+}
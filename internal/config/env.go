@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 var Fatalf = log.Fatalf
@@ -21,3 +24,78 @@ func EnvOrDefault(key, fallback string) string {
 	}
 	return fallback
 }
+
+func EnvBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		Fatalf("environment variable %s must be a boolean: %v", key, err)
+		return fallback
+	}
+	return b
+}
+
+func EnvUint(key string, fallback uint64) uint64 {
+	n, err := LookupUint(key, fallback)
+	if err != nil {
+		Fatalf("environment variable %s must be a non-negative integer: %v", key, err)
+		return fallback
+	}
+	return n
+}
+
+func EnvDuration(key string, fallback time.Duration) time.Duration {
+	d, err := LookupDuration(key, fallback)
+	if err != nil {
+		Fatalf("environment variable %s must be a positive duration (e.g. 30s)", key)
+		return fallback
+	}
+	return d
+}
+
+// LookupUint is EnvUint for callers that report configuration errors
+// themselves.
+func LookupUint(key string, fallback uint64) (uint64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// LookupDuration is EnvDuration for callers that report configuration errors
+// themselves.
+func LookupDuration(key string, fallback time.Duration) (time.Duration, error) {
+	return lookupDuration(key, fallback, false)
+}
+
+// LookupDelay is LookupDuration allowing zero, for delays that can be turned
+// off.
+func LookupDelay(key string, fallback time.Duration) (time.Duration, error) {
+	return lookupDuration(key, fallback, true)
+}
+
+func lookupDuration(key string, fallback time.Duration, allowZero bool) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	switch {
+	case allowZero && d < 0:
+		return 0, fmt.Errorf("invalid %s: must not be negative", key)
+	case !allowZero && d <= 0:
+		return 0, fmt.Errorf("invalid %s: must be positive", key)
+	}
+	return d, nil
+}
@@ -0,0 +1,52 @@
+package domain
+
+import "strings"
+
+// Anchor records where a certificate was registered on one chain. A
+// certificate has at most one anchor per chain and contract; Batch is set
+// when the anchored hash is a Merkle root rather than the content hash.
+type Anchor struct {
+	ChainID         uint64
+	ContractAddress string
+	TxHash          string
+	BlockNumber     uint64
+	BlockHash       string
+	Batch           *MerkleBatch
+}
+
+func (a Anchor) sameTarget(chainID uint64, contract string) bool {
+	return a.ChainID == chainID && strings.EqualFold(a.ContractAddress, contract)
+}
+
+// RecordAnchor adds the anchor, replacing any previous anchor on the same
+// chain and contract.
+func (c *Certificate) RecordAnchor(a Anchor) {
+	for i := range c.Anchors {
+		if c.Anchors[i].sameTarget(a.ChainID, a.ContractAddress) {
+			c.Anchors[i] = a
+			return
+		}
+	}
+	c.Anchors = append(c.Anchors, a)
+}
+
+// AnchoredOn reports whether the certificate has an anchor on the chain and
+// contract.
+func (c *Certificate) AnchoredOn(chainID uint64, contract string) bool {
+	for _, a := range c.Anchors {
+		if a.sameTarget(chainID, contract) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Certificate) dropAnchorsOf(txHash string) {
+	kept := c.Anchors[:0]
+	for _, a := range c.Anchors {
+		if !strings.EqualFold(a.TxHash, txHash) {
+			kept = append(kept, a)
+		}
+	}
+	c.Anchors = kept
+}
@@ -1,70 +1,74 @@
 package domain
 
 import (
-	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"image"
-	"image/color"
 	"io"
+	"strings"
 	"time"
 )
 
 type Certificate struct {
-	ID             string
-	ContentHash    string
-	PerceptualHash *uint64
+	ID          string
+	ContentHash string
+	// PixelHash is the canonical pixel hash of an image, see
+	// PixelHashFromBytes; empty for other content.
+	PixelHash string
+	// PerceptualHashes is empty unless the content is a decodable image.
+	PerceptualHashes PerceptualHashes
+	// Fingerprint is empty unless the content is a video whose frames can be
+	// decoded.
+	Fingerprint    VideoFingerprint
 	Registrant     string
 	TxHash         string
 	BlockNumber    uint64
+	BlockHash      string
+	Status         CertificateStatus
+	LastError      string
+	Attempts       int
+	ChainMismatch  string
+	Batch          *MerkleBatch
+	Fee            *AnchorFee
+	Anchors        []Anchor
+	TimestampToken []byte
 	CreatedAt      time.Time
 }
 
-func HashContent(r io.Reader) (string, error) {
-	h := sha256.New()
-	if _, err := io.Copy(h, r); err != nil {
-		return "", fmt.Errorf("hashing content: %w", err)
+func CertificateFromRegistration(ev RegistrationEvent) *Certificate {
+	return &Certificate{
+		ContentHash: ev.ContentHash,
+		Registrant:  ev.Registrant,
+		TxHash:      ev.TxHash,
+		BlockNumber: ev.BlockNumber,
+		BlockHash:   ev.BlockHash,
+		Status:      StatusConfirmed,
+		CreatedAt:   ev.RegisteredAt,
 	}
-	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-func PerceptualHashFromBytes(content []byte) *uint64 {
-	img, _, err := image.Decode(bytes.NewReader(content))
-	if err != nil {
-		return nil
+// ReconcileRegistration compares a stored certificate with the on-chain
+// registration of the same content hash and describes any disagreement.
+func (c *Certificate) ReconcileRegistration(ev RegistrationEvent) string {
+	var diffs []string
+	if !strings.EqualFold(c.TxHash, ev.TxHash) {
+		diffs = append(diffs, fmt.Sprintf("tx_hash %s != chain %s", c.TxHash, ev.TxHash))
 	}
-
-	gray := downsampleTo8x8Gray(img)
-	var sum uint64
-	for i := range gray.Pix {
-		sum += uint64(gray.Pix[i])
+	if c.BlockNumber != ev.BlockNumber {
+		diffs = append(diffs, fmt.Sprintf("block_number %d != chain %d", c.BlockNumber, ev.BlockNumber))
 	}
-	avg := uint8(sum / 64)
-
-	var hash uint64
-	for i := range gray.Pix {
-		hash <<= 1
-		if gray.Pix[i] >= avg {
-			hash |= 1
-		}
+	if c.BlockHash != "" && !strings.EqualFold(c.BlockHash, ev.BlockHash) {
+		diffs = append(diffs, fmt.Sprintf("block_hash %s != chain %s", c.BlockHash, ev.BlockHash))
 	}
-
-	return &hash
+	return strings.Join(diffs, "; ")
 }
 
-func downsampleTo8x8Gray(img image.Image) *image.Gray {
-	const size = 8
-	bounds := img.Bounds()
-	gray := image.NewGray(image.Rect(0, 0, size, size))
-	for y := 0; y < size; y++ {
-		sy := bounds.Min.Y + (y*(bounds.Dy()-1))/7
-		for x := 0; x < size; x++ {
-			sx := bounds.Min.X + (x*(bounds.Dx()-1))/7
-			gray.Set(x, y, color.GrayModel.Convert(img.At(sx, sy)))
-		}
+func HashContent(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("hashing content: %w", err)
 	}
-	return gray
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func HammingDistance(a, b uint64) int {
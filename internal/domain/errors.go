@@ -3,6 +3,21 @@ package domain
 import "errors"
 
 var (
-	ErrAlreadyCertified = errors.New("content already certified")
-	ErrNotFound         = errors.New("certificate not found")
+	ErrAlreadyCertified    = errors.New("content already certified")
+	ErrNotFound            = errors.New("certificate not found")
+	ErrTransactionReverted = errors.New("transaction reverted")
+	ErrReceiptTimeout      = errors.New("timed out waiting for transaction receipt")
+	ErrAnchorEventMissing  = errors.New("ContentRegistered event missing from receipt")
+	ErrInvalidTransition   = errors.New("invalid certificate status transition")
+	ErrNotAnchored         = errors.New("certificate is not anchored yet")
+	ErrInvalidProof        = errors.New("invalid proof bundle")
+	ErrSpendCapExceeded    = errors.New("anchor transaction exceeds fee spend cap")
+	ErrTimestampRejected   = errors.New("timestamp request rejected")
+	ErrInvalidTimestamp    = errors.New("invalid timestamp token")
+	ErrNotInLog            = errors.New("hash not found in transparency log")
+	ErrInvalidTreeSize     = errors.New("tree size out of range")
+	ErrInvalidTreeHead     = errors.New("invalid signed tree head")
+	ErrInvalidLogProof     = errors.New("invalid transparency log proof")
+	ErrUnsupportedVideo    = errors.New("unsupported video format")
+	ErrRegistrationUnknown = errors.New("hash is registered on chain by a transaction that cannot be looked up")
 )
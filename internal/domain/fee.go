@@ -0,0 +1,26 @@
+package domain
+
+import "math/big"
+
+// AnchorFee is what the anchoring transaction cost. Every leaf of a Merkle
+// batch records the fee of the shared transaction; FeeShare splits it.
+type AnchorFee struct {
+	GasUsed           uint64
+	EffectiveGasPrice *big.Int
+}
+
+func (f *AnchorFee) Total() *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(f.GasUsed), f.EffectiveGasPrice)
+}
+
+// FeeShare is the part of the anchoring fee attributable to this certificate.
+func (c *Certificate) FeeShare() *big.Int {
+	if c.Fee == nil {
+		return nil
+	}
+	total := c.Fee.Total()
+	if c.Batch != nil && c.Batch.TreeSize > 1 {
+		return total.Div(total, new(big.Int).SetUint64(c.Batch.TreeSize))
+	}
+	return total
+}
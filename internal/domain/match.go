@@ -0,0 +1,65 @@
+package domain
+
+import "sort"
+
+// MatchType says how verified content was matched to a certificate.
+type MatchType string
+
+const (
+	MatchExact MatchType = "exact"
+	// MatchPixel is an image whose bytes differ but whose decoded pixels are
+	// identical, e.g. after its metadata was stripped.
+	MatchPixel      MatchType = "pixel"
+	MatchPerceptual MatchType = "perceptual"
+)
+
+// PerceptualHashBits is the size of a perceptual hash and so the largest
+// possible Hamming distance between two of them.
+const PerceptualHashBits = 64
+
+// SimilarCertificate is a certificate whose perceptual hashes are Distance
+// bits away from the ones searched for, combining the per-algorithm
+// Distances.
+type SimilarCertificate struct {
+	Certificate *Certificate
+	Distance    int
+	Distances   map[HashAlgorithm]int
+	// Video is set for video matches.
+	Video *VideoMatch
+}
+
+// MatchSimilar compares the perceptual hashes of cert with query and reports
+// whether their combined distance is within maxDistance.
+func MatchSimilar(query PerceptualHashes, cert *Certificate, maxDistance int) (SimilarCertificate, bool) {
+	distances := query.Distances(cert.PerceptualHashes)
+	d, ok := CombinedDistance(distances)
+	if !ok || d > maxDistance {
+		return SimilarCertificate{}, false
+	}
+	return SimilarCertificate{Certificate: cert, Distance: d, Distances: distances}, true
+}
+
+// Similarity scales the distance from 1 for identical hashes down to 0 for
+// complementary ones.
+func (s SimilarCertificate) Similarity() float64 {
+	return 1 - float64(s.Distance)/PerceptualHashBits
+}
+
+// RankSimilar orders matches nearest first, the oldest certificate first on
+// ties, and keeps at most limit of them. Video matches are ordered by
+// alignment confidence before distance.
+func RankSimilar(matches []SimilarCertificate, limit int) []SimilarCertificate {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if vi, vj := matches[i].Video, matches[j].Video; vi != nil && vj != nil && vi.Confidence != vj.Confidence {
+			return vi.Confidence > vj.Confidence
+		}
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Certificate.CreatedAt.Before(matches[j].Certificate.CreatedAt)
+	})
+	if limit >= 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
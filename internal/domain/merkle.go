@@ -0,0 +1,216 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// MerkleBatch places a certificate in a batch whose Merkle root, rather than
+// the content hash itself, was anchored on chain.
+type MerkleBatch struct {
+	Root      string
+	LeafIndex uint64
+	TreeSize  uint64
+	Proof     []string
+}
+
+// Merkle trees follow RFC 6962: leaves are hashed as SHA-256(0x00 || data)
+// and interior nodes as SHA-256(0x01 || left || right), so a leaf can never be
+// passed off as an interior node.
+
+func MerkleLeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// MerkleRoot returns the root over the already leaf-hashed inputs.
+func MerkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	}
+	k := splitPoint(len(leaves))
+	return merkleNodeHash(MerkleRoot(leaves[:k]), MerkleRoot(leaves[k:]))
+}
+
+// MerkleInclusionProof returns the audit path for leaves[index], ordered from
+// the leaf towards the root.
+func MerkleInclusionProof(leaves [][]byte, index int) ([][]byte, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("merkle: leaf index %d out of range for %d leaves", index, len(leaves))
+	}
+	return inclusionPath(leaves, index), nil
+}
+
+func inclusionPath(leaves [][]byte, index int) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := splitPoint(len(leaves))
+	if index < k {
+		return append(inclusionPath(leaves[:k], index), MerkleRoot(leaves[k:]))
+	}
+	return append(inclusionPath(leaves[k:], index-k), MerkleRoot(leaves[:k]))
+}
+
+// VerifyMerkleInclusion checks an audit path for leafHash at index in a tree of
+// treeSize leaves against root (RFC 9162, section 2.1.3.2).
+func VerifyMerkleInclusion(leafHash []byte, index, treeSize uint64, proof [][]byte, root []byte) bool {
+	if index >= treeSize {
+		return false
+	}
+
+	fn, sn := index, treeSize-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = merkleNodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(r, root)
+}
+
+// VerifyMerkleConsistency checks that the tree of secondSize leaves with
+// secondRoot extends the tree of firstSize leaves with firstRoot (RFC 9162,
+// section 2.1.4.2).
+func VerifyMerkleConsistency(firstSize, secondSize uint64, proof [][]byte, firstRoot, secondRoot []byte) bool {
+	switch {
+	case firstSize > secondSize:
+		return false
+	case firstSize == secondSize:
+		return len(proof) == 0 && bytes.Equal(firstRoot, secondRoot)
+	case firstSize == 0:
+		return len(proof) == 0
+	case len(proof) == 0:
+		return false
+	}
+
+	if firstSize&(firstSize-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+	fn, sn := firstSize-1, secondSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = merkleNodeHash(c, fr)
+			sr = merkleNodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = merkleNodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(fr, firstRoot) && bytes.Equal(sr, secondRoot)
+}
+
+// splitPoint returns the largest power of two smaller than n.
+func splitPoint(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// AssignMerkleBatch builds a tree over the content hashes of certs, in order,
+// and records each certificate's position and audit path. It returns the hex
+// root that is anchored on chain in place of the individual hashes.
+func AssignMerkleBatch(certs []*Certificate) (string, error) {
+	root, batches, err := BuildMerkleBatches(certs)
+	if err != nil {
+		return "", err
+	}
+	for i, c := range certs {
+		c.Batch = batches[i]
+	}
+	return root, nil
+}
+
+// BuildMerkleBatches is AssignMerkleBatch without touching the certificates'
+// own batch, for anchoring them again on another chain.
+func BuildMerkleBatches(certs []*Certificate) (string, []*MerkleBatch, error) {
+	leaves := make([][]byte, len(certs))
+	for i, c := range certs {
+		raw, err := hex.DecodeString(c.ContentHash)
+		if err != nil {
+			return "", nil, fmt.Errorf("merkle: content hash %q: %w", c.ContentHash, err)
+		}
+		leaves[i] = MerkleLeafHash(raw)
+	}
+
+	root := hex.EncodeToString(MerkleRoot(leaves))
+	batches := make([]*MerkleBatch, len(certs))
+	for i := range certs {
+		proof := EncodeMerklePath(inclusionPath(leaves, i))
+		batches[i] = &MerkleBatch{Root: root, LeafIndex: uint64(i), TreeSize: uint64(len(certs)), Proof: proof}
+	}
+	return root, batches, nil
+}
+
+// AnchorHash is the hash registered on chain for the certificate: the batch
+// root when it was anchored in a batch, otherwise the content hash.
+func (c *Certificate) AnchorHash() string {
+	if c.Batch != nil {
+		return c.Batch.Root
+	}
+	return c.ContentHash
+}
+
+// VerifyInclusion recomputes the batch root from the content hash and the
+// stored audit path.
+func (c *Certificate) VerifyInclusion() bool {
+	if c.Batch == nil {
+		return false
+	}
+	leaf, err := hex.DecodeString(c.ContentHash)
+	if err != nil {
+		return false
+	}
+	root, err := hex.DecodeString(c.Batch.Root)
+	if err != nil {
+		return false
+	}
+	proof, err := decodeHashes(c.Batch.Proof)
+	if err != nil {
+		return false
+	}
+	return VerifyMerkleInclusion(MerkleLeafHash(leaf), c.Batch.LeafIndex, c.Batch.TreeSize, proof, root)
+}
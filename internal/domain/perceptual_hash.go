@@ -0,0 +1,323 @@
+package domain
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// HashAlgorithm names a perceptual hash algorithm.
+type HashAlgorithm string
+
+const (
+	// DCTHash keeps the sign of the low-frequency 8x8 DCT coefficients of a
+	// 32x32 thumbnail relative to their median. It survives recompression,
+	// gamma changes and resizing best.
+	DCTHash HashAlgorithm = "phash"
+	// DifferenceHash records whether brightness rises between horizontal
+	// neighbours of a 9x8 thumbnail.
+	DifferenceHash HashAlgorithm = "dhash"
+	// AverageHash records which pixels of an 8x8 thumbnail are at least as
+	// bright as the mean.
+	AverageHash HashAlgorithm = "ahash"
+	// LegacyAverageHash is the single hash of certificates issued before
+	// hashes were stored per algorithm: an average hash of 8x8 pixels sampled
+	// from the image rather than area-averaged, so it is only comparable with
+	// itself.
+	LegacyAverageHash HashAlgorithm = "ahash_legacy"
+)
+
+// PerceptualHasher computes one kind of 64-bit perceptual hash.
+type PerceptualHasher interface {
+	Algorithm() HashAlgorithm
+	Hash(img image.Image) uint64
+}
+
+// DefaultPerceptualHashers are computed for every certified image.
+func DefaultPerceptualHashers() []PerceptualHasher {
+	return []PerceptualHasher{DCTHasher{}, DifferenceHasher{}, AverageHasher{}}
+}
+
+// VerifyPerceptualHashers are computed for images being verified: the
+// defaults, and the legacy average hash that older certificates carry.
+func VerifyPerceptualHashers() []PerceptualHasher {
+	return append(DefaultPerceptualHashers(), LegacyAverageHasher{})
+}
+
+// PerceptualHashes holds one hash per algorithm.
+type PerceptualHashes map[HashAlgorithm]uint64
+
+// PerceptualHashesFromBytes decodes content and returns its
+// PerceptualHashes, or nil when content is not a decodable image.
+func PerceptualHashesFromBytes(content []byte, hashers ...PerceptualHasher) PerceptualHashes {
+	return DecodeImage(content).PerceptualHashes(hashers...)
+}
+
+// PerceptualHashes hashes the image with every hasher, or
+// DefaultPerceptualHashers when none are given. A nil image has no hashes.
+func (d *DecodedImage) PerceptualHashes(hashers ...PerceptualHasher) PerceptualHashes {
+	if d == nil {
+		return nil
+	}
+	if len(hashers) == 0 {
+		hashers = DefaultPerceptualHashers()
+	}
+
+	hashes := make(PerceptualHashes, len(hashers))
+	for _, h := range hashers {
+		hashes[h.Algorithm()] = h.Hash(d.Image)
+	}
+	return hashes
+}
+
+// Algorithms lists the algorithms in h in a stable order.
+func (h PerceptualHashes) Algorithms() []HashAlgorithm {
+	algs := make([]HashAlgorithm, 0, len(h))
+	for alg := range h {
+		algs = append(algs, alg)
+	}
+	sort.Slice(algs, func(i, j int) bool { return algs[i] < algs[j] })
+	return algs
+}
+
+// Distances returns the Hamming distance for every algorithm both h and
+// other have.
+func (h PerceptualHashes) Distances(other PerceptualHashes) map[HashAlgorithm]int {
+	distances := map[HashAlgorithm]int{}
+	for alg, hash := range h {
+		if o, ok := other[alg]; ok {
+			distances[alg] = HammingDistance(hash, o)
+		}
+	}
+	return distances
+}
+
+// CombinedDistance folds per-algorithm distances into one match decision
+// value: their mean, rounded to the nearest bit. ok is false when there is
+// nothing to compare. Since the mean is never below the smallest distance,
+// a combined distance of d implies some algorithm is within d bits, which is
+// what lets the band index find every match.
+func CombinedDistance(distances map[HashAlgorithm]int) (distance int, ok bool) {
+	if len(distances) == 0 {
+		return 0, false
+	}
+	sum := 0
+	for _, d := range distances {
+		sum += d
+	}
+	return int(math.Round(float64(sum) / float64(len(distances)))), true
+}
+
+type AverageHasher struct{}
+
+func (AverageHasher) Algorithm() HashAlgorithm { return AverageHash }
+
+func (AverageHasher) Hash(img image.Image) uint64 {
+	pix := areaAverage(toGray(img), 8, 8)
+	var sum float64
+	for _, p := range pix {
+		sum += p
+	}
+	avg := sum / float64(len(pix))
+
+	var hash uint64
+	for _, p := range pix {
+		hash <<= 1
+		if p >= avg {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+// LegacyAverageHasher reproduces the hash stored before per-algorithm hashes:
+// the average hash of an 8x8 grid of single pixels, including the corners.
+type LegacyAverageHasher struct{}
+
+func (LegacyAverageHasher) Algorithm() HashAlgorithm { return LegacyAverageHash }
+
+func (LegacyAverageHasher) Hash(img image.Image) uint64 {
+	b := img.Bounds()
+	var pix [64]uint8
+	var sum uint64
+	for y := 0; y < 8; y++ {
+		sy := b.Min.Y + y*(b.Dy()-1)/7
+		for x := 0; x < 8; x++ {
+			sx := b.Min.X + x*(b.Dx()-1)/7
+			pix[y*8+x] = color.GrayModel.Convert(img.At(sx, sy)).(color.Gray).Y
+			sum += uint64(pix[y*8+x])
+		}
+	}
+	avg := uint8(sum / 64)
+
+	var hash uint64
+	for _, p := range pix {
+		hash <<= 1
+		if p >= avg {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+type DifferenceHasher struct{}
+
+func (DifferenceHasher) Algorithm() HashAlgorithm { return DifferenceHash }
+
+func (DifferenceHasher) Hash(img image.Image) uint64 {
+	pix := areaAverage(toGray(img), 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if pix[y*9+x] < pix[y*9+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+type DCTHasher struct{}
+
+func (DCTHasher) Algorithm() HashAlgorithm { return DCTHash }
+
+func (DCTHasher) Hash(img image.Image) uint64 {
+	const size, keep = 32, 8
+	pix := areaAverage(toGray(img), size, size)
+
+	// Separable DCT-II, computing only the low frequencies that are kept.
+	var rows [size][keep]float64
+	for y := 0; y < size; y++ {
+		for u := 0; u < keep; u++ {
+			var sum float64
+			for x := 0; x < size; x++ {
+				sum += pix[y*size+x] * dctCos[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+	var coeffs [keep * keep]float64
+	for v := 0; v < keep; v++ {
+		for u := 0; u < keep; u++ {
+			var sum float64
+			for y := 0; y < size; y++ {
+				sum += rows[y][u] * dctCos[v][y]
+			}
+			coeffs[v*keep+u] = sum
+		}
+	}
+
+	// The DC term only reflects overall brightness, so it is left out of the
+	// median.
+	sorted := append([]float64(nil), coeffs[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash uint64
+	for _, c := range coeffs {
+		hash <<= 1
+		if c > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+var dctCos = func() (table [8][32]float64) {
+	for u := range table {
+		for x := range table[u] {
+			table[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / 64)
+		}
+	}
+	return table
+}()
+
+// toGray converts img to 8-bit luma, reusing the Y plane of JPEG images and
+// reading RGBA and NRGBA pixel buffers directly with the same results as
+// color.GrayModel.
+func toGray(img image.Image) *image.Gray {
+	switch src := img.(type) {
+	case *image.Gray:
+		return src
+	case *image.YCbCr:
+		b := src.Rect
+		gray := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+		for y := 0; y < b.Dy(); y++ {
+			start := src.YOffset(b.Min.X, b.Min.Y+y)
+			copy(gray.Pix[y*gray.Stride:], src.Y[start:start+b.Dx()])
+		}
+		return gray
+	case *image.RGBA, *image.NRGBA:
+		b := img.Bounds()
+		gray := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+		for y := 0; y < b.Dy(); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				gray.Pix[y*gray.Stride+x] = grayAt(src, b.Min.X+x, b.Min.Y+y)
+			}
+		}
+		return gray
+	}
+
+	b := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			gray.Pix[y*gray.Stride+x] = color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+		}
+	}
+	return gray
+}
+
+// grayAt is color.GrayModel applied to a pixel of an *image.RGBA or
+// *image.NRGBA, whose channels it scales to 16 bits as their RGBA methods do.
+func grayAt(img image.Image, x, y int) uint8 {
+	var r, g, b uint32
+	switch src := img.(type) {
+	case *image.RGBA:
+		p := src.Pix[src.PixOffset(x, y):]
+		r, g, b = uint32(p[0])*0x101, uint32(p[1])*0x101, uint32(p[2])*0x101
+	case *image.NRGBA:
+		p := src.Pix[src.PixOffset(x, y):]
+		a := uint32(p[3])
+		r, g, b = uint32(p[0])*0x101*a/0xff, uint32(p[1])*0x101*a/0xff, uint32(p[2])*0x101*a/0xff
+	}
+	return uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+}
+
+// areaAverage shrinks img to w x h, each output pixel the mean of the source
+// pixels it covers, so every source pixel contributes.
+func areaAverage(img *image.Gray, w, h int) []float64 {
+	b := img.Bounds()
+	out := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		y0, y1 := span(y, h, b.Dy())
+		for x := 0; x < w; x++ {
+			x0, x1 := span(x, w, b.Dx())
+			var sum int
+			for sy := y0; sy < y1; sy++ {
+				row := img.Pix[img.PixOffset(b.Min.X, b.Min.Y+sy):]
+				for sx := x0; sx < x1; sx++ {
+					sum += int(row[sx])
+				}
+			}
+			out[y*w+x] = float64(sum) / float64((y1-y0)*(x1-x0))
+		}
+	}
+	return out
+}
+
+// span returns the source range [from, to) covered by output cell i of n when
+// scaling a length of size, never empty.
+func span(i, n, size int) (from, to int) {
+	from = i * size / n
+	to = (i + 1) * size / n
+	if to <= from {
+		to = from + 1
+	}
+	if to > size {
+		from, to = size-1, size
+	}
+	return from, to
+}
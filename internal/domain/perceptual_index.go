@@ -0,0 +1,106 @@
+package domain
+
+// PerceptualHashBands is the number of 16-bit bands a perceptual hash is split
+// into for multi-index hashing.
+const PerceptualHashBands = 4
+
+// maxBandRadius bounds the per-band search radius. Beyond it the probe sets
+// grow past a few thousand values per band and a scan is cheaper.
+const maxBandRadius = 3
+
+// MaxIndexedDistance is the largest match distance the band index can serve:
+// every band is probed within maxBandRadius bits.
+const MaxIndexedDistance = PerceptualHashBands*(maxBandRadius+1) - 1
+
+// HashBands splits a perceptual hash into 16-bit bands, most significant
+// first.
+func HashBands(hash uint64) [PerceptualHashBands]uint16 {
+	var bands [PerceptualHashBands]uint16
+	for i := range bands {
+		bands[i] = uint16(hash >> (16 * (PerceptualHashBands - 1 - i)))
+	}
+	return bands
+}
+
+// BandProbes returns, for each band of hash, every band value within
+// maxDistance/PerceptualHashBands bits of it. Any hash within maxDistance of
+// hash differs from it by at most that many bits in some band, so it matches
+// a probe there. ok is false when maxDistance is too large for probing to pay
+// off and callers should compare every hash instead.
+func BandProbes(hash uint64, maxDistance int) (probes [PerceptualHashBands][]uint16, ok bool) {
+	if maxDistance < 0 {
+		return probes, true
+	}
+	radius := maxDistance / PerceptualHashBands
+	if radius > maxBandRadius {
+		return probes, false
+	}
+	for i, band := range HashBands(hash) {
+		probes[i] = bandNeighbours(band, radius)
+	}
+	return probes, true
+}
+
+// bandNeighbours lists every 16-bit value within radius bits of band,
+// starting with band itself.
+func bandNeighbours(band uint16, radius int) []uint16 {
+	out := []uint16{band}
+	var flip func(v uint16, from uint, left int)
+	flip = func(v uint16, from uint, left int) {
+		for bit := from; bit < 16; bit++ {
+			next := v ^ 1<<bit
+			out = append(out, next)
+			if left > 1 {
+				flip(next, bit+1, left-1)
+			}
+		}
+	}
+	if radius > 0 {
+		flip(band, 0, radius)
+	}
+	return out
+}
+
+// PerceptualProbes returns the BandProbes of every hash in hashes. A
+// certificate whose combined distance is within maxDistance has some
+// algorithm within maxDistance, so it matches one of these probes.
+func PerceptualProbes(hashes PerceptualHashes, maxDistance int) (map[HashAlgorithm][PerceptualHashBands][]uint16, bool) {
+	probes := make(map[HashAlgorithm][PerceptualHashBands][]uint16, len(hashes))
+	for alg, hash := range hashes {
+		p, ok := BandProbes(hash, maxDistance)
+		if !ok {
+			return nil, false
+		}
+		probes[alg] = p
+	}
+	return probes, true
+}
+
+// VideoProbes merges the PerceptualProbes of every segment of clip, so one
+// lookup finds each certified video with a segment near any of them.
+func VideoProbes(clip VideoFingerprint, maxDistance int) (map[HashAlgorithm][PerceptualHashBands][]uint16, bool) {
+	merged := map[HashAlgorithm][PerceptualHashBands][]uint16{}
+	seen := map[HashAlgorithm]*[PerceptualHashBands]map[uint16]bool{}
+	for _, seg := range clip {
+		probes, ok := PerceptualProbes(seg.Hashes, maxDistance)
+		if !ok {
+			return nil, false
+		}
+		for alg, bands := range probes {
+			if seen[alg] == nil {
+				seen[alg] = &[PerceptualHashBands]map[uint16]bool{{}, {}, {}, {}}
+			}
+			m := merged[alg]
+			for i, values := range bands {
+				for _, v := range values {
+					if !seen[alg][i][v] {
+						seen[alg][i][v] = true
+						m[i] = append(m[i], v)
+					}
+				}
+			}
+			merged[alg] = m
+		}
+	}
+	return merged, true
+}
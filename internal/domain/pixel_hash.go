@@ -0,0 +1,240 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"image/color"
+)
+
+// MaxImagePixels bounds the images that are decoded. A few hundred bytes of
+// PNG or TIFF can declare a 60000x60000 image, so the declared dimensions are
+// checked before any pixel buffer is allocated.
+const MaxImagePixels = 50_000_000
+
+// DecodedImage is an uploaded image decoded once for all of its hashes.
+type DecodedImage struct {
+	Image image.Image
+	// Orientation is the EXIF orientation, 1 (as stored) to 8.
+	Orientation int
+}
+
+// DecodeImage decodes content and reads its EXIF orientation. It returns nil
+// when content is not a decodable image or declares more than MaxImagePixels.
+func DecodeImage(content []byte) *DecodedImage {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil || img.Bounds().Empty() {
+		return nil
+	}
+	return &DecodedImage{Image: img, Orientation: exifOrientation(content)}
+}
+
+// PixelHashFromBytes decodes content and returns its PixelHash, or "" when
+// content is not a decodable image.
+func PixelHashFromBytes(content []byte) string {
+	return DecodeImage(content).PixelHash()
+}
+
+// PixelHash returns the canonical pixel hash of the image: the SHA-256 of its
+// width and height as big-endian uint32s followed by its pixels as 8-bit
+// non-premultiplied RGBA, row by row, after applying the EXIF orientation.
+// Stripping or rewriting metadata leaves it unchanged. A nil image has no
+// pixel hash.
+func (d *DecodedImage) PixelHash() string {
+	if d == nil {
+		return ""
+	}
+	pix := orientedPixels(d.Image, d.Orientation)
+
+	h := sha256.New()
+	var dims [8]byte
+	binary.BigEndian.PutUint32(dims[:4], uint32(pix.Rect.Dx()))
+	binary.BigEndian.PutUint32(dims[4:], uint32(pix.Rect.Dy()))
+	h.Write(dims[:])
+	h.Write(pix.Pix)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// orientedPixels copies img into an NRGBA buffer as it is meant to be
+// displayed under the given EXIF orientation (1 to 8).
+func orientedPixels(img image.Image, orientation int) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if orientation >= 5 && orientation <= 8 {
+		w, h = h, w
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if src, ok := img.(*image.NRGBA); ok && (orientation < 2 || orientation > 8) {
+		for y := 0; y < h; y++ {
+			copy(out.Pix[y*out.Stride:(y+1)*out.Stride], src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):])
+		}
+		return out
+	}
+
+	at := nrgbaAt(img)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// (sx, sy) is the stored pixel shown at (x, y).
+			sx, sy := x, y
+			switch orientation {
+			case 2:
+				sx = w - 1 - x
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sy = h - 1 - y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, w-1-x
+			case 7:
+				sx, sy = h-1-y, w-1-x
+			case 8:
+				sx, sy = h-1-y, x
+			}
+			i := out.PixOffset(x, y)
+			out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3] = at(b.Min.X+sx, b.Min.Y+sy)
+		}
+	}
+	return out
+}
+
+// nrgbaAt returns a reader of the non-premultiplied RGBA value of a pixel of
+// img. It reads the pixel buffers of the types image.Decode produces for
+// JPEG and PNG files directly, giving the same values as
+// color.NRGBAModel.Convert(img.At(x, y)).
+func nrgbaAt(img image.Image) func(x, y int) (r, g, b, a uint8) {
+	switch src := img.(type) {
+	case *image.NRGBA:
+		return func(x, y int) (r, g, b, a uint8) {
+			p := src.Pix[src.PixOffset(x, y):]
+			return p[0], p[1], p[2], p[3]
+		}
+	case *image.RGBA:
+		return func(x, y int) (r, g, b, a uint8) {
+			p := src.Pix[src.PixOffset(x, y):]
+			switch p[3] {
+			case 0xff:
+				return p[0], p[1], p[2], 0xff
+			case 0:
+				return 0, 0, 0, 0
+			}
+			// Un-premultiply at 16 bits, as color.NRGBAModel does.
+			a16 := uint32(p[3]) * 0x101
+			unmul := func(v uint8) uint8 { return uint8(uint32(v) * 0x101 * 0xffff / a16 >> 8) }
+			return unmul(p[0]), unmul(p[1]), unmul(p[2]), p[3]
+		}
+	case *image.YCbCr:
+		return func(x, y int) (r, g, b, a uint8) {
+			yi, ci := src.YOffset(x, y), src.COffset(x, y)
+			r, g, b = color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+			return r, g, b, 0xff
+		}
+	}
+	return func(x, y int) (r, g, b, a uint8) {
+		c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+		return c.R, c.G, c.B, c.A
+	}
+}
+
+// exifOrientation finds the EXIF orientation tag of a JPEG, PNG, WebP or TIFF
+// file, defaulting to 1 (as stored) when there is none.
+func exifOrientation(content []byte) int {
+	var tiff []byte
+	switch {
+	case bytes.HasPrefix(content, []byte{0xff, 0xd8}):
+		tiff = jpegExif(content)
+	case bytes.HasPrefix(content, []byte("\x89PNG\r\n\x1a\n")):
+		tiff = pngExif(content)
+	case len(content) >= 12 && string(content[:4]) == "RIFF" && string(content[8:12]) == "WEBP":
+		tiff = bytes.TrimPrefix(webpExif(content), []byte("Exif\x00\x00"))
+	default:
+		tiff = content
+	}
+	if o := tiffOrientation(tiff); o >= 1 && o <= 8 {
+		return o
+	}
+	return 1
+}
+
+// jpegExif returns the TIFF structure of the APP1 Exif segment.
+func jpegExif(b []byte) []byte {
+	b = b[2:]
+	for len(b) >= 4 && b[0] == 0xff {
+		marker, size := b[1], int(binary.BigEndian.Uint16(b[2:]))
+		if marker == 0xda || size < 2 || size+2 > len(b) {
+			return nil
+		}
+		if seg := b[4 : size+2]; marker == 0xe1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return seg[6:]
+		}
+		b = b[size+2:]
+	}
+	return nil
+}
+
+// pngExif returns the eXIf chunk.
+func pngExif(b []byte) []byte {
+	b = b[8:]
+	for len(b) >= 12 {
+		size := int(binary.BigEndian.Uint32(b))
+		if size < 0 || size > len(b)-12 {
+			return nil
+		}
+		if string(b[4:8]) == "eXIf" {
+			return b[8 : 8+size]
+		}
+		b = b[12+size:]
+	}
+	return nil
+}
+
+// webpExif returns the EXIF chunk of an extended WebP file.
+func webpExif(b []byte) []byte {
+	b = b[12:]
+	for len(b) >= 8 {
+		size := int(binary.LittleEndian.Uint32(b[4:]))
+		if size < 0 || size > len(b)-8 {
+			return nil
+		}
+		if string(b[:4]) == "EXIF" {
+			return b[8 : 8+size]
+		}
+		b = b[8+size+size%2:]
+	}
+	return nil
+}
+
+// tiffOrientation reads tag 0x0112 from the first IFD, or returns 0.
+func tiffOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(b[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int64(order.Uint32(b[4:]))
+	if ifd < 8 || ifd+2 > int64(len(b)) {
+		return 0
+	}
+	entries := b[ifd+2:]
+	for n := int(order.Uint16(b[ifd:])); n > 0 && len(entries) >= 12; n-- {
+		if tag, typ := order.Uint16(entries), order.Uint16(entries[2:]); tag == 0x0112 && typ == 3 {
+			return int(order.Uint16(entries[8:]))
+		}
+		entries = entries[12:]
+	}
+	return 0
+}
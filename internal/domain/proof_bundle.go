@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const ProofBundleVersion = 1
+
+const (
+	// ProofSchemeDirect means the content hash itself was registered on chain.
+	ProofSchemeDirect = "direct"
+	// ProofSchemeRFC6962 means the root of an RFC 6962 Merkle tree containing
+	// the content hash was registered on chain.
+	ProofSchemeRFC6962 = "rfc6962"
+)
+
+// ProofBundle is a self-contained anchoring proof. Verify checks that it is
+// internally consistent; a verifier then only needs a node for ChainID to
+// confirm that TxHash emitted ContentRegistered(Root) from ContractAddress in
+// BlockNumber.
+type ProofBundle struct {
+	Version         int      `json:"version"`
+	Scheme          string   `json:"scheme"`
+	ContentHash     string   `json:"content_hash"`
+	LeafIndex       uint64   `json:"leaf_index"`
+	TreeSize        uint64   `json:"tree_size"`
+	Path            []string `json:"path"`
+	Root            string   `json:"root"`
+	TxHash          string   `json:"tx_hash"`
+	BlockNumber     uint64   `json:"block_number"`
+	BlockHash       string   `json:"block_hash,omitempty"`
+	ChainID         uint64   `json:"chain_id"`
+	ContractAddress string   `json:"contract_address"`
+}
+
+func NewProofBundle(c *Certificate, chainID uint64, contract string) (*ProofBundle, error) {
+	if !c.IsAnchored() {
+		return nil, fmt.Errorf("%w: status %s", ErrNotAnchored, c.Status)
+	}
+
+	b := &ProofBundle{
+		Version:         ProofBundleVersion,
+		Scheme:          ProofSchemeDirect,
+		ContentHash:     c.ContentHash,
+		Path:            []string{},
+		Root:            c.ContentHash,
+		TxHash:          c.TxHash,
+		BlockNumber:     c.BlockNumber,
+		BlockHash:       c.BlockHash,
+		ChainID:         chainID,
+		ContractAddress: contract,
+	}
+	if c.Batch != nil {
+		b.Scheme = ProofSchemeRFC6962
+		b.LeafIndex = c.Batch.LeafIndex
+		b.TreeSize = c.Batch.TreeSize
+		b.Path = c.Batch.Proof
+		b.Root = c.Batch.Root
+	}
+	return b, nil
+}
+
+// Verify recomputes the anchored root from the content hash without any
+// external lookups.
+func (b *ProofBundle) Verify() error {
+	if b.Version != ProofBundleVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidProof, b.Version)
+	}
+	if b.TxHash == "" || b.ChainID == 0 || b.ContractAddress == "" {
+		return fmt.Errorf("%w: tx_hash, chain_id and contract_address are required", ErrInvalidProof)
+	}
+
+	leaf, err := decodeHash32(b.ContentHash)
+	if err != nil {
+		return fmt.Errorf("%w: content_hash: %v", ErrInvalidProof, err)
+	}
+	root, err := decodeHash32(b.Root)
+	if err != nil {
+		return fmt.Errorf("%w: root: %v", ErrInvalidProof, err)
+	}
+
+	switch b.Scheme {
+	case ProofSchemeDirect:
+		if len(b.Path) != 0 || !bytes.Equal(leaf, root) {
+			return fmt.Errorf("%w: direct anchor must register the content hash itself", ErrInvalidProof)
+		}
+		return nil
+	case ProofSchemeRFC6962:
+		path := make([][]byte, len(b.Path))
+		for i, p := range b.Path {
+			if path[i], err = decodeHash32(p); err != nil {
+				return fmt.Errorf("%w: path[%d]: %v", ErrInvalidProof, i, err)
+			}
+		}
+		if !VerifyMerkleInclusion(MerkleLeafHash(leaf), b.LeafIndex, b.TreeSize, path, root) {
+			return fmt.Errorf("%w: merkle path does not lead to root", ErrInvalidProof)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown scheme %q", ErrInvalidProof, b.Scheme)
+	}
+}
+
+func decodeHash32(s string) ([]byte, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(s), "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("want 32 bytes, got %d", len(raw))
+	}
+	return raw, nil
+}
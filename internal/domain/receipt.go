@@ -0,0 +1,35 @@
+package domain
+
+import "math/big"
+
+const (
+	ReceiptStatusReverted uint64 = 0
+	ReceiptStatusSuccess  uint64 = 1
+)
+
+type Receipt struct {
+	TxHash      string
+	BlockNumber uint64
+	BlockHash   string
+	GasUsed     uint64
+	Status      uint64
+	Event       *RegistrationEvent
+
+	EffectiveGasPrice *big.Int
+	// TimestampToken is the DER RFC 3161 token when the hash was timestamped
+	// by a TSA instead of registered on chain.
+	TimestampToken []byte
+}
+
+func (r *Receipt) Succeeded() bool {
+	return r.Status == ReceiptStatusSuccess
+}
+
+// Fee is nil when the receipt did not report an effective gas price, e.g. when
+// it was rebuilt from a registration event.
+func (r *Receipt) Fee() *AnchorFee {
+	if r.EffectiveGasPrice == nil {
+		return nil
+	}
+	return &AnchorFee{GasUsed: r.GasUsed, EffectiveGasPrice: r.EffectiveGasPrice}
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+type RegistrationEvent struct {
+	ContentHash  string
+	Registrant   string
+	RegisteredAt time.Time
+	TxHash       string
+	BlockNumber  uint64
+	BlockHash    string
+	LogIndex     uint64
+}
@@ -0,0 +1,106 @@
+package domain
+
+import "fmt"
+
+type CertificateStatus string
+
+const (
+	StatusPending   CertificateStatus = "pending"
+	StatusSubmitted CertificateStatus = "submitted"
+	StatusConfirmed CertificateStatus = "confirmed"
+	StatusFailed    CertificateStatus = "failed"
+	// StatusReorged marks a confirmed certificate whose block left the
+	// canonical chain; it is anchored again like a pending one.
+	StatusReorged CertificateStatus = "reorged"
+)
+
+var allowedTransitions = map[CertificateStatus][]CertificateStatus{
+	StatusPending:   {StatusSubmitted, StatusConfirmed, StatusFailed},
+	StatusSubmitted: {StatusPending, StatusConfirmed, StatusFailed},
+	StatusConfirmed: {StatusReorged},
+	StatusFailed:    {StatusPending},
+	StatusReorged:   {StatusPending, StatusSubmitted, StatusConfirmed, StatusFailed},
+}
+
+func (c *Certificate) IsAnchored() bool {
+	return c.Status == StatusConfirmed
+}
+
+func (c *Certificate) MarkSubmitted(txHash string) error {
+	if err := c.transition(StatusSubmitted); err != nil {
+		return err
+	}
+	c.TxHash = txHash
+	c.LastError = ""
+	return nil
+}
+
+func (c *Certificate) MarkConfirmed(r *Receipt) error {
+	if err := c.transition(StatusConfirmed); err != nil {
+		return err
+	}
+	c.TxHash = r.TxHash
+	c.BlockNumber = r.BlockNumber
+	c.BlockHash = r.BlockHash
+	if fee := r.Fee(); fee != nil {
+		c.Fee = fee
+	}
+	if r.TimestampToken != nil {
+		c.TimestampToken = r.TimestampToken
+	}
+	c.LastError = ""
+	return nil
+}
+
+// MarkReorged keeps the orphaned transaction details for reference until the
+// certificate is anchored again, but drops the anchor they back.
+func (c *Certificate) MarkReorged(reason string) error {
+	if err := c.transition(StatusReorged); err != nil {
+		return err
+	}
+	c.dropAnchorsOf(c.TxHash)
+	c.LastError = reason
+	return nil
+}
+
+func (c *Certificate) MarkFailed(reason string) error {
+	if err := c.transition(StatusFailed); err != nil {
+		return err
+	}
+	c.LastError = reason
+	return nil
+}
+
+// Requeue returns a submitted certificate whose transaction was dropped to the
+// pending state so that it is submitted again.
+func (c *Certificate) Requeue(reason string) error {
+	if err := c.transition(StatusPending); err != nil {
+		return err
+	}
+	c.TxHash = ""
+	c.Batch = nil
+	c.LastError = reason
+	return nil
+}
+
+func (c *Certificate) Retry() error {
+	if c.Status != StatusFailed {
+		return fmt.Errorf("%w: retry requires %s, got %s", ErrInvalidTransition, StatusFailed, c.Status)
+	}
+	c.Status = StatusPending
+	c.TxHash = ""
+	c.Batch = nil
+	c.Attempts = 0
+	c.LastError = ""
+	return nil
+}
+
+func (c *Certificate) transition(to CertificateStatus) error {
+	for _, allowed := range allowedTransitions[c.Status] {
+		if allowed == to {
+			c.Status = to
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, c.Status, to)
+}
@@ -0,0 +1,267 @@
+package domain
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"time"
+)
+
+// MerkleNodeReader returns the hash of the complete subtree of 2^level leaves
+// whose first leaf is index<<level. A transparency log stores these nodes so
+// roots and proofs never need every leaf.
+type MerkleNodeReader func(level uint, index uint64) ([]byte, error)
+
+// MerkleNode is a complete subtree hash to store.
+type MerkleNode struct {
+	Level uint
+	Index uint64
+	Hash  []byte
+}
+
+// Append returns the leaf at index size and every subtree it completes.
+func (r MerkleNodeReader) Append(size uint64, leafHash []byte) ([]MerkleNode, error) {
+	nodes := []MerkleNode{{Level: 0, Index: size, Hash: leafHash}}
+	hash, index := leafHash, size
+	for level := uint(0); index&1 == 1; level++ {
+		left, err := r(level, index-1)
+		if err != nil {
+			return nil, err
+		}
+		hash = merkleNodeHash(left, hash)
+		index >>= 1
+		nodes = append(nodes, MerkleNode{Level: level + 1, Index: index, Hash: hash})
+	}
+	return nodes, nil
+}
+
+// Root returns the root of the first size leaves.
+func (r MerkleNodeReader) Root(size uint64) ([]byte, error) {
+	if size == 0 {
+		return MerkleRoot(nil), nil
+	}
+	return r.subtree(0, size)
+}
+
+// InclusionProof returns the audit path for the leaf at index in the tree of
+// the first size leaves, ordered from the leaf towards the root.
+func (r MerkleNodeReader) InclusionProof(index, size uint64) ([][]byte, error) {
+	if index >= size {
+		return nil, fmt.Errorf("%w: leaf %d is not in a tree of %d leaves", ErrInvalidTreeSize, index, size)
+	}
+	return r.path(index, 0, size)
+}
+
+// ConsistencyProof returns the RFC 6962 proof that the tree of secondSize
+// leaves extends the tree of firstSize leaves.
+func (r MerkleNodeReader) ConsistencyProof(firstSize, secondSize uint64) ([][]byte, error) {
+	if firstSize > secondSize {
+		return nil, fmt.Errorf("%w: %d is larger than %d", ErrInvalidTreeSize, firstSize, secondSize)
+	}
+	if firstSize == 0 || firstSize == secondSize {
+		return [][]byte{}, nil
+	}
+	return r.subproof(firstSize, 0, secondSize, true)
+}
+
+// subtree hashes the leaves [start, start+n). Callers only split at powers of
+// two, so every complete subtree they reach is aligned and stored.
+func (r MerkleNodeReader) subtree(start, n uint64) ([]byte, error) {
+	if n&(n-1) == 0 {
+		level := uint(bits.TrailingZeros64(n))
+		return r(level, start>>level)
+	}
+	k := uint64(splitPoint(int(n)))
+	left, err := r.subtree(start, k)
+	if err != nil {
+		return nil, err
+	}
+	right, err := r.subtree(start+k, n-k)
+	if err != nil {
+		return nil, err
+	}
+	return merkleNodeHash(left, right), nil
+}
+
+func (r MerkleNodeReader) path(index, start, n uint64) ([][]byte, error) {
+	if n <= 1 {
+		return nil, nil
+	}
+	k := uint64(splitPoint(int(n)))
+	var (
+		path    [][]byte
+		sibling []byte
+		err     error
+	)
+	if index < k {
+		if path, err = r.path(index, start, k); err == nil {
+			sibling, err = r.subtree(start+k, n-k)
+		}
+	} else {
+		if path, err = r.path(index-k, start+k, n-k); err == nil {
+			sibling, err = r.subtree(start, k)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(path, sibling), nil
+}
+
+// subproof is SUBPROOF(m, D[start:start+n], complete) from RFC 6962, section
+// 2.1.2.
+func (r MerkleNodeReader) subproof(m, start, n uint64, complete bool) ([][]byte, error) {
+	if m == n {
+		if complete {
+			return nil, nil
+		}
+		hash, err := r.subtree(start, n)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{hash}, nil
+	}
+
+	k := uint64(splitPoint(int(n)))
+	var (
+		proof   [][]byte
+		sibling []byte
+		err     error
+	)
+	if m <= k {
+		if proof, err = r.subproof(m, start, k, complete); err == nil {
+			sibling, err = r.subtree(start+k, n-k)
+		}
+	} else {
+		if proof, err = r.subproof(m-k, start+k, n-k, false); err == nil {
+			sibling, err = r.subtree(start, k)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(proof, sibling), nil
+}
+
+// SignedTreeHead commits the log to its first TreeSize entries.
+type SignedTreeHead struct {
+	TreeSize  uint64
+	RootHash  string
+	Timestamp time.Time
+	Signature []byte
+}
+
+// SignTreeHead signs the root of the first size entries at the given time,
+// truncated to the millisecond precision of the signed structure.
+func SignTreeHead(key ed25519.PrivateKey, size uint64, root []byte, at time.Time) *SignedTreeHead {
+	h := &SignedTreeHead{
+		TreeSize:  size,
+		RootHash:  hex.EncodeToString(root),
+		Timestamp: time.UnixMilli(at.UnixMilli()).UTC(),
+	}
+	h.Signature = ed25519.Sign(key, h.signedData(root))
+	return h
+}
+
+// Verify checks the tree head signature against the log's public key.
+func (h *SignedTreeHead) Verify(pub ed25519.PublicKey) error {
+	root, err := hex.DecodeString(h.RootHash)
+	if err != nil || len(root) != sha256.Size {
+		return fmt.Errorf("%w: root hash %q", ErrInvalidTreeHead, h.RootHash)
+	}
+	if len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, h.signedData(root), h.Signature) {
+		return fmt.Errorf("%w: bad signature for tree size %d", ErrInvalidTreeHead, h.TreeSize)
+	}
+	return nil
+}
+
+// signedData is the RFC 6962 TreeHeadSignature: version v1, signature type
+// tree_hash, timestamp in milliseconds, tree size and root hash.
+func (h *SignedTreeHead) signedData(root []byte) []byte {
+	buf := make([]byte, 0, 2+8+8+len(root))
+	buf = append(buf, 0, 1)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(h.Timestamp.UnixMilli()))
+	buf = binary.BigEndian.AppendUint64(buf, h.TreeSize)
+	return append(buf, root...)
+}
+
+// LogInclusionProof proves that an anchored hash is the leaf at LeafIndex of
+// the log's tree of TreeSize entries.
+type LogInclusionProof struct {
+	LeafIndex uint64
+	TreeSize  uint64
+	AuditPath []string
+}
+
+// Verify checks the proof for hash against the tree head of the same size.
+func (p *LogInclusionProof) Verify(hash string, head *SignedTreeHead) error {
+	leaf, err := hex.DecodeString(hash)
+	if err != nil {
+		return fmt.Errorf("%w: hash %q", ErrInvalidLogProof, hash)
+	}
+	root, err := hex.DecodeString(head.RootHash)
+	if err != nil {
+		return fmt.Errorf("%w: root hash %q", ErrInvalidLogProof, head.RootHash)
+	}
+	path, err := decodeHashes(p.AuditPath)
+	if err != nil {
+		return err
+	}
+	if p.TreeSize != head.TreeSize || !VerifyMerkleInclusion(MerkleLeafHash(leaf), p.LeafIndex, p.TreeSize, path, root) {
+		return fmt.Errorf("%w: leaf %d is not included in tree head %d", ErrInvalidLogProof, p.LeafIndex, head.TreeSize)
+	}
+	return nil
+}
+
+// LogConsistencyProof proves that the log's tree of SecondSize entries is an
+// append-only extension of its tree of FirstSize entries.
+type LogConsistencyProof struct {
+	FirstSize  uint64
+	SecondSize uint64
+	Path       []string
+}
+
+// Verify checks the proof against the tree heads of both sizes.
+func (p *LogConsistencyProof) Verify(first, second *SignedTreeHead) error {
+	firstRoot, err := hex.DecodeString(first.RootHash)
+	if err != nil {
+		return fmt.Errorf("%w: root hash %q", ErrInvalidLogProof, first.RootHash)
+	}
+	secondRoot, err := hex.DecodeString(second.RootHash)
+	if err != nil {
+		return fmt.Errorf("%w: root hash %q", ErrInvalidLogProof, second.RootHash)
+	}
+	path, err := decodeHashes(p.Path)
+	if err != nil {
+		return err
+	}
+	if p.FirstSize != first.TreeSize || p.SecondSize != second.TreeSize ||
+		!VerifyMerkleConsistency(p.FirstSize, p.SecondSize, path, firstRoot, secondRoot) {
+		return fmt.Errorf("%w: tree head %d is not consistent with %d", ErrInvalidLogProof, second.TreeSize, first.TreeSize)
+	}
+	return nil
+}
+
+// EncodeMerklePath hex encodes an audit or consistency path.
+func EncodeMerklePath(path [][]byte) []string {
+	out := make([]string, len(path))
+	for i, h := range path {
+		out[i] = hex.EncodeToString(h)
+	}
+	return out
+}
+
+func decodeHashes(hashes []string) ([][]byte, error) {
+	out := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		raw, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, fmt.Errorf("%w: path entry %q", ErrInvalidLogProof, h)
+		}
+		out[i] = raw
+	}
+	return out, nil
+}
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// VideoSegment is the stretch of a video from Start to End, represented by
+// the perceptual hashes of the frame it starts with.
+type VideoSegment struct {
+	Start  time.Duration
+	End    time.Duration
+	Hashes PerceptualHashes
+}
+
+// VideoFingerprint is the temporal fingerprint of a video: its segments in
+// playback order.
+type VideoFingerprint []VideoSegment
+
+// VideoMatch locates a clip within a certified video.
+type VideoMatch struct {
+	// Start and End bound the certified segments the clip was aligned with.
+	Start time.Duration
+	End   time.Duration
+	// Confidence is the alignment score as a share of a perfect alignment of
+	// the whole clip, from 0 to 1. Skipped and mismatched segments lower it.
+	Confidence float64
+}
+
+// MinVideoCoverage is the share of a clip's segments that must be aligned
+// with matching segments of a certified video for the clip to be a copy of
+// it.
+const MinVideoCoverage = 0.5
+
+// Alignment scores. An aligned pair within the distance threshold scores
+// between 0.5 and 1, nearer pairs higher, so it always outweighs a skipped
+// segment. Certified segments are skipped more cheaply than clip segments:
+// a re-encoded clip with sparser keyframes skips some of them, while a clip
+// segment with no counterpart is evidence against the match.
+const (
+	mismatchScore   = -1
+	skipClipPenalty = 0.5
+	skipCertPenalty = 0.25
+)
+
+// alignMove is the last step of an alignment.
+type alignMove uint8
+
+const (
+	alignStart    alignMove = iota
+	alignPair               // clip and certified segment aligned
+	alignRepeat             // clip segment aligned with the previous one's certified segment
+	alignSkipClip           // clip segment left out
+	alignSkipCert           // certified segment left out
+)
+
+// MatchVideo finds the run of the certificate's fingerprint that clip follows
+// best, by local sequence alignment (Smith-Waterman). Clip segments are
+// aligned in order with certified segments; consecutive clip segments may
+// share one certified segment, for clips with denser keyframes, and either
+// side may skip segments at a cost. The clip matches when at least
+// MinVideoCoverage of its segments are aligned within maxDistance. Distance
+// and Distances then average those aligned pairs, and Video gives the
+// matched time range and the alignment confidence.
+func MatchVideo(clip VideoFingerprint, cert *Certificate, maxDistance int) (SimilarCertificate, bool) {
+	n, m := len(clip), len(cert.Fingerprint)
+	if n == 0 || m == 0 {
+		return SimilarCertificate{}, false
+	}
+
+	score := func(i, j int) (float64, bool) {
+		d, ok := CombinedDistance(clip[i].Hashes.Distances(cert.Fingerprint[j].Hashes))
+		if !ok || d > maxDistance {
+			return mismatchScore, false
+		}
+		return 1 - float64(d)/(2*PerceptualHashBits), true
+	}
+
+	// moves[i*m+j] records how the best alignment ending with clip segment i
+	// and certified segment j got there; prev and cur are rows of scores.
+	moves := make([]alignMove, n*m)
+	prev, cur := make([]float64, m+1), make([]float64, m+1)
+	best, bestI, bestJ := 0.0, -1, -1
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			s, matched := score(i, j)
+			h, move := 0.0, alignStart
+			if v := prev[j] + s; v > h {
+				h, move = v, alignPair
+			}
+			if v := prev[j+1] + s; matched && v > h {
+				h, move = v, alignRepeat
+			}
+			if v := prev[j+1] - skipClipPenalty; v > h {
+				h, move = v, alignSkipClip
+			}
+			if v := cur[j] - skipCertPenalty; v > h {
+				h, move = v, alignSkipCert
+			}
+			cur[j+1], moves[i*m+j] = h, move
+			if h > best {
+				best, bestI, bestJ = h, i, j
+			}
+		}
+		prev, cur = cur, prev
+	}
+	if bestI < 0 {
+		return SimilarCertificate{}, false
+	}
+
+	// Trace the alignment back, collecting the aligned pairs that match. Each
+	// clip segment is aligned at most once.
+	first, last := -1, -1
+	total, pairs := 0, 0
+	sums := map[HashAlgorithm]int{}
+	for i, j := bestI, bestJ; i >= 0 && j >= 0; {
+		move := moves[i*m+j]
+		if move == alignPair || move == alignRepeat {
+			distances := clip[i].Hashes.Distances(cert.Fingerprint[j].Hashes)
+			if d, ok := CombinedDistance(distances); ok && d <= maxDistance {
+				if last < 0 {
+					last = j
+				}
+				first = j
+				total += d
+				pairs++
+				for alg, d := range distances {
+					sums[alg] += d
+				}
+			}
+		}
+		switch move {
+		case alignPair:
+			i, j = i-1, j-1
+		case alignRepeat, alignSkipClip:
+			i--
+		case alignSkipCert:
+			j--
+		default:
+			i = -1
+		}
+	}
+	if pairs == 0 || float64(pairs) < MinVideoCoverage*float64(n) {
+		return SimilarCertificate{}, false
+	}
+
+	distances := make(map[HashAlgorithm]int, len(sums))
+	for alg, sum := range sums {
+		distances[alg] = int(math.Round(float64(sum) / float64(pairs)))
+	}
+	return SimilarCertificate{
+		Certificate: cert,
+		Distance:    int(math.Round(float64(total) / float64(pairs))),
+		Distances:   distances,
+		Video: &VideoMatch{
+			Start:      cert.Fingerprint[first].Start,
+			End:        cert.Fingerprint[last].End,
+			Confidence: math.Min(1, best/float64(n)),
+		},
+	}, true
+}
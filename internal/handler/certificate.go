@@ -2,7 +2,9 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/waizbart/aletheia-api/internal/domain"
 	"github.com/waizbart/aletheia-api/internal/usecase"
@@ -43,7 +45,11 @@ func (h *CertificateHandler) handleCertify(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, toCertDTO(out.Certificate))
+	status := http.StatusCreated
+	if !out.Certificate.IsAnchored() {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, toCertDTO(out.Certificate))
 }
 
 func (h *CertificateHandler) handleVerifyByHash(w http.ResponseWriter, r *http.Request) {
@@ -53,7 +59,13 @@ func (h *CertificateHandler) handleVerifyByHash(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	out, err := h.verify.Execute(r.Context(), usecase.VerifyInput{Hash: hash})
+	in, ok := parseVerifyOptions(w, r)
+	if !ok {
+		return
+	}
+	in.Hash = hash
+
+	out, err := h.verify.Execute(r.Context(), in)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -63,13 +75,19 @@ func (h *CertificateHandler) handleVerifyByHash(w http.ResponseWriter, r *http.R
 }
 
 func (h *CertificateHandler) handleVerifyByFile(w http.ResponseWriter, r *http.Request) {
+	in, ok := parseVerifyOptions(w, r)
+	if !ok {
+		return
+	}
+
 	file, ok := parseMediaUpload(w, r)
 	if !ok {
 		return
 	}
 	defer file.Close()
+	in.Content = file
 
-	out, err := h.verify.Execute(r.Context(), usecase.VerifyInput{Content: file})
+	out, err := h.verify.Execute(r.Context(), in)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -77,3 +95,47 @@ func (h *CertificateHandler) handleVerifyByFile(w http.ResponseWriter, r *http.R
 
 	writeVerifyResponse(w, out)
 }
+
+// maxSimilarLimit caps the perceptual candidates a request may ask for.
+const maxSimilarLimit = 50
+
+func parseVerifyOptions(w http.ResponseWriter, r *http.Request) (usecase.VerifyInput, bool) {
+	var in usecase.VerifyInput
+	confirm, ok := parseConfirmOnChain(w, r)
+	if !ok {
+		return in, false
+	}
+	in.ConfirmOnChain = confirm
+
+	query := r.URL.Query()
+	if raw := query.Get("max_distance"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 || v > domain.MaxIndexedDistance {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("query parameter 'max_distance' must be an integer between 0 and %d", domain.MaxIndexedDistance))
+			return in, false
+		}
+		in.MaxDistance = &v
+	}
+	if raw := query.Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxSimilarLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("query parameter 'limit' must be an integer between 1 and %d", maxSimilarLimit))
+			return in, false
+		}
+		in.Limit = v
+	}
+	return in, true
+}
+
+func parseConfirmOnChain(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	raw := r.URL.Query().Get("confirm_onchain")
+	if raw == "" {
+		return false, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "query parameter 'confirm_onchain' must be a boolean")
+		return false, false
+	}
+	return v, true
+}
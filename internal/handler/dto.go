@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -14,30 +15,184 @@ type certDTO struct {
 	Registrant  string `json:"registrant"`
 	TxHash      string `json:"tx_hash"`
 	BlockNumber uint64 `json:"block_number"`
+	BlockHash   string `json:"block_hash"`
+	Status      string `json:"status"`
 	CreatedAt   string `json:"created_at"`
+
+	MerkleProof *merkleProofDTO `json:"merkle_proof,omitempty"`
+	Fee         *feeDTO         `json:"fee,omitempty"`
+	Anchors     []anchorDTO     `json:"anchors"`
+	// TimestampToken is the DER RFC 3161 token, base64 encoded.
+	TimestampToken []byte `json:"timestamp_token,omitempty"`
+}
+
+type merkleProofDTO struct {
+	Root      string   `json:"root"`
+	LeafIndex uint64   `json:"leaf_index"`
+	TreeSize  uint64   `json:"tree_size"`
+	Proof     []string `json:"proof"`
+}
+
+// anchorDTO is one chain the certificate is anchored on. The merkle proof is
+// relative to that chain's anchored root.
+type anchorDTO struct {
+	ChainID         uint64          `json:"chain_id"`
+	ContractAddress string          `json:"contract_address"`
+	TxHash          string          `json:"tx_hash"`
+	BlockNumber     uint64          `json:"block_number"`
+	BlockHash       string          `json:"block_hash"`
+	MerkleProof     *merkleProofDTO `json:"merkle_proof,omitempty"`
+}
+
+// feeDTO carries wei amounts as decimal strings, since they overflow JSON
+// numbers.
+type feeDTO struct {
+	GasUsed           uint64 `json:"gas_used"`
+	EffectiveGasPrice string `json:"effective_gas_price"`
+	TotalWei          string `json:"total_wei"`
+	ShareWei          string `json:"share_wei"`
 }
 
 func toCertDTO(c *domain.Certificate) certDTO {
-	return certDTO{
+	dto := certDTO{
 		ID:          c.ID,
 		ContentHash: c.ContentHash,
 		Registrant:  c.Registrant,
 		TxHash:      c.TxHash,
 		BlockNumber: c.BlockNumber,
+		BlockHash:   c.BlockHash,
+		Status:      string(c.Status),
 		CreatedAt:   c.CreatedAt.Format(time.RFC3339),
+		MerkleProof: toMerkleProofDTO(c.Batch),
+		Anchors:     make([]anchorDTO, len(c.Anchors)),
+
+		TimestampToken: c.TimestampToken,
+	}
+	for i, a := range c.Anchors {
+		dto.Anchors[i] = anchorDTO{
+			ChainID:         a.ChainID,
+			ContractAddress: a.ContractAddress,
+			TxHash:          a.TxHash,
+			BlockNumber:     a.BlockNumber,
+			BlockHash:       a.BlockHash,
+			MerkleProof:     toMerkleProofDTO(a.Batch),
+		}
+	}
+	if c.Fee != nil {
+		dto.Fee = &feeDTO{
+			GasUsed:           c.Fee.GasUsed,
+			EffectiveGasPrice: c.Fee.EffectiveGasPrice.String(),
+			TotalWei:          c.Fee.Total().String(),
+			ShareWei:          c.FeeShare().String(),
+		}
 	}
+	return dto
+}
+
+func toMerkleProofDTO(b *domain.MerkleBatch) *merkleProofDTO {
+	if b == nil {
+		return nil
+	}
+	return &merkleProofDTO{Root: b.Root, LeafIndex: b.LeafIndex, TreeSize: b.TreeSize, Proof: b.Proof}
+}
+
+// anchoringDTO explains why a stored certificate is not (or no longer)
+// certified, e.g. while it is re-anchored after a chain reorganization.
+type anchoringDTO struct {
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
 }
 
 type verifyDTO struct {
-	Certified   bool     `json:"certified"`
-	Certificate *certDTO `json:"certificate"`
+	Certified         bool          `json:"certified"`
+	Certificate       *certDTO      `json:"certificate"`
+	Anchoring         *anchoringDTO `json:"anchoring,omitempty"`
+	OnChainConfirmed  *bool         `json:"on_chain_confirmed,omitempty"`
+	InclusionVerified *bool         `json:"inclusion_verified,omitempty"`
+	TimestampVerified *bool         `json:"timestamp_verified,omitempty"`
+	TimestampedAt     string        `json:"timestamped_at,omitempty"`
+	MatchType         string        `json:"match_type,omitempty"`
+	Distance          *int          `json:"distance,omitempty"`
+	Similarity        *float64      `json:"similarity,omitempty"`
+	// Distances breaks Distance down by perceptual hash algorithm.
+	Distances    map[domain.HashAlgorithm]int `json:"distances,omitempty"`
+	MatchedRange *videoRangeDTO               `json:"matched_range,omitempty"`
+	// Matches lists the perceptual candidates, nearest first.
+	Matches []similarDTO `json:"matches,omitempty"`
+}
+
+type similarDTO struct {
+	Certificate  certDTO                      `json:"certificate"`
+	Distance     int                          `json:"distance"`
+	Similarity   float64                      `json:"similarity"`
+	Distances    map[domain.HashAlgorithm]int `json:"distances"`
+	MatchedRange *videoRangeDTO               `json:"matched_range,omitempty"`
+}
+
+// videoRangeDTO is the part of a certified video a clip matched, as mm:ss
+// (h:mm:ss past an hour) and in seconds.
+type videoRangeDTO struct {
+	Start        string  `json:"start"`
+	End          string  `json:"end"`
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	Confidence   float64 `json:"confidence"`
+}
+
+func toVideoRangeDTO(m *domain.VideoMatch) *videoRangeDTO {
+	if m == nil {
+		return nil
+	}
+	return &videoRangeDTO{
+		Start:        timecode(m.Start),
+		End:          timecode(m.End),
+		StartSeconds: m.Start.Seconds(),
+		EndSeconds:   m.End.Seconds(),
+		Confidence:   m.Confidence,
+	}
+}
+
+func timecode(d time.Duration) string {
+	s := int(d / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
 }
 
 func writeVerifyResponse(w http.ResponseWriter, out *usecase.VerifyOutput) {
-	resp := verifyDTO{Certified: out.Certified}
+	resp := verifyDTO{
+		Certified:         out.Certified,
+		OnChainConfirmed:  out.OnChainConfirmed,
+		InclusionVerified: out.InclusionVerified,
+		TimestampVerified: out.TimestampVerified,
+	}
+	if out.TimestampedAt != nil {
+		resp.TimestampedAt = out.TimestampedAt.UTC().Format(time.RFC3339Nano)
+	}
 	if out.Certificate != nil {
 		dto := toCertDTO(out.Certificate)
 		resp.Certificate = &dto
+		resp.Anchoring = &anchoringDTO{
+			Status:    string(out.Certificate.Status),
+			Attempts:  out.Certificate.Attempts,
+			LastError: out.Certificate.LastError,
+		}
+		resp.MatchType = string(out.MatchType)
+		resp.Distance = &out.Distance
+		resp.Similarity = &out.Similarity
+		resp.Distances = out.Distances
+		resp.MatchedRange = toVideoRangeDTO(out.Video)
+	}
+	for _, m := range out.Similar {
+		resp.Matches = append(resp.Matches, similarDTO{
+			Certificate:  toCertDTO(m.Certificate),
+			Distance:     m.Distance,
+			Similarity:   m.Similarity(),
+			Distances:    m.Distances,
+			MatchedRange: toVideoRangeDTO(m.Video),
+		})
 	}
 
 	status := http.StatusOK
@@ -46,3 +201,33 @@ func writeVerifyResponse(w http.ResponseWriter, out *usecase.VerifyOutput) {
 	}
 	writeJSON(w, status, resp)
 }
+
+// treeHeadDTO follows RFC 6962 get-sth: the timestamp is in milliseconds, as
+// signed.
+type treeHeadDTO struct {
+	TreeSize  uint64 `json:"tree_size"`
+	RootHash  string `json:"root_hash"`
+	Timestamp int64  `json:"timestamp"`
+	Signature []byte `json:"signature"`
+}
+
+func toTreeHeadDTO(h *domain.SignedTreeHead) treeHeadDTO {
+	return treeHeadDTO{TreeSize: h.TreeSize, RootHash: h.RootHash, Timestamp: h.Timestamp.UnixMilli(), Signature: h.Signature}
+}
+
+type sthDTO struct {
+	treeHeadDTO
+	PublicKey []byte `json:"public_key"`
+}
+
+type logInclusionDTO struct {
+	LeafIndex uint64      `json:"leaf_index"`
+	AuditPath []string    `json:"audit_path"`
+	TreeHead  treeHeadDTO `json:"tree_head"`
+}
+
+type logConsistencyDTO struct {
+	Path           []string    `json:"consistency_path"`
+	FirstTreeHead  treeHeadDTO `json:"first_tree_head"`
+	SecondTreeHead treeHeadDTO `json:"second_tree_head"`
+}
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"context"
+	"crypto/ed25519"
 
+	"github.com/waizbart/aletheia-api/internal/domain"
 	"github.com/waizbart/aletheia-api/internal/usecase"
 )
 
@@ -13,3 +15,14 @@ type Certifier interface {
 type Verifier interface {
 	Execute(ctx context.Context, in usecase.VerifyInput) (*usecase.VerifyOutput, error)
 }
+
+type Prover interface {
+	Execute(ctx context.Context, id string) (*domain.ProofBundle, error)
+}
+
+type LogReader interface {
+	PublicKey() ed25519.PublicKey
+	TreeHead(ctx context.Context) (*domain.SignedTreeHead, error)
+	InclusionProof(ctx context.Context, hash string, treeSize uint64) (*usecase.LogInclusionOutput, error)
+	ConsistencyProof(ctx context.Context, firstSize, secondSize uint64) (*usecase.LogConsistencyOutput, error)
+}
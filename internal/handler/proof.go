@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type ProofHandler struct {
+	prover Prover
+}
+
+func NewProofHandler(prover Prover) *ProofHandler {
+	return &ProofHandler{prover: prover}
+}
+
+func (h *ProofHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /certificates/{id}/proof", h.handleProof)
+}
+
+func (h *ProofHandler) handleProof(w http.ResponseWriter, r *http.Request) {
+	bundle, err := h.prover.Execute(r.Context(), r.PathValue("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, domain.ErrNotAnchored):
+			status = http.StatusConflict
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bundle)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type LogHandler struct {
+	log LogReader
+}
+
+func NewLogHandler(log LogReader) *LogHandler {
+	return &LogHandler{log: log}
+}
+
+func (h *LogHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /log/sth", h.handleTreeHead)
+	mux.HandleFunc("GET /log/proof/inclusion", h.handleInclusionProof)
+	mux.HandleFunc("GET /log/proof/consistency", h.handleConsistencyProof)
+}
+
+func (h *LogHandler) handleTreeHead(w http.ResponseWriter, r *http.Request) {
+	head, err := h.log.TreeHead(r.Context())
+	if err != nil {
+		writeLogError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sthDTO{treeHeadDTO: toTreeHeadDTO(head), PublicKey: h.log.PublicKey()})
+}
+
+func (h *LogHandler) handleInclusionProof(w http.ResponseWriter, r *http.Request) {
+	hash := r.URL.Query().Get("hash")
+	if hash == "" {
+		writeError(w, http.StatusBadRequest, "query parameter 'hash' is required")
+		return
+	}
+	treeSize, ok := parseTreeSize(w, r, "tree_size")
+	if !ok {
+		return
+	}
+
+	out, err := h.log.InclusionProof(r.Context(), hash, treeSize)
+	if err != nil {
+		writeLogError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, logInclusionDTO{
+		LeafIndex: out.Proof.LeafIndex,
+		AuditPath: nonNilPath(out.Proof.AuditPath),
+		TreeHead:  toTreeHeadDTO(out.TreeHead),
+	})
+}
+
+func (h *LogHandler) handleConsistencyProof(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("first") == "" {
+		writeError(w, http.StatusBadRequest, "query parameter 'first' is required")
+		return
+	}
+	first, ok := parseTreeSize(w, r, "first")
+	if !ok {
+		return
+	}
+	second, ok := parseTreeSize(w, r, "second")
+	if !ok {
+		return
+	}
+
+	out, err := h.log.ConsistencyProof(r.Context(), first, second)
+	if err != nil {
+		writeLogError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, logConsistencyDTO{
+		Path:           nonNilPath(out.Proof.Path),
+		FirstTreeHead:  toTreeHeadDTO(out.FirstTreeHead),
+		SecondTreeHead: toTreeHeadDTO(out.SecondTreeHead),
+	})
+}
+
+// parseTreeSize reads an optional tree size; 0 stands for the latest tree.
+func parseTreeSize(w http.ResponseWriter, r *http.Request, name string) (uint64, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return 0, true
+	}
+	v, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("query parameter '%s' must be a non-negative integer", name))
+		return 0, false
+	}
+	return v, true
+}
+
+func writeLogError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrNotInLog):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidTreeSize):
+		status = http.StatusBadRequest
+	}
+	writeError(w, status, err.Error())
+}
+
+func nonNilPath(path []string) []string {
+	if path == nil {
+		return []string{}
+	}
+	return path
+}
@@ -0,0 +1,14 @@
+// Package imaging registers a decoder with the image package for every image
+// type the API accepts, so image.Decode, and with it perceptual hashing, works
+// on all of them. Import it for its side effects.
+package imaging
+
+import (
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
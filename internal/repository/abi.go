@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const abiWordSize = 32
+
+const (
+	isRegisteredSignature      = "isRegistered(bytes32)"
+	registerSignature          = "register(bytes32,address)"
+	contentRegisteredSignature = "ContentRegistered(bytes32,address,uint256)"
+)
+
+var contentRegisteredTopic = "0x" + hex.EncodeToString(keccak256([]byte(contentRegisteredSignature)))
+
+func abiSelector(signature string) []byte {
+	return keccak256([]byte(signature))[:4]
+}
+
+func abiEncodeCall(signature string, args ...[]byte) []byte {
+	out := abiSelector(signature)
+	for _, arg := range args {
+		out = append(out, arg...)
+	}
+	return out
+}
+
+func abiEncodeBytes32(b []byte) ([]byte, error) {
+	if len(b) != abiWordSize {
+		return nil, fmt.Errorf("abi: bytes32 requires 32 bytes, got %d", len(b))
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func abiEncodeAddress(addr string) ([]byte, error) {
+	raw, err := decodeAddress(addr)
+	if err != nil {
+		return nil, fmt.Errorf("abi: %w", err)
+	}
+	return leftPad(raw), nil
+}
+
+func abiEncodeUint256(v *big.Int) ([]byte, error) {
+	if v.Sign() < 0 || v.BitLen() > 256 {
+		return nil, fmt.Errorf("abi: uint256 out of range")
+	}
+	return leftPad(v.Bytes()), nil
+}
+
+func abiDecodeHex(data string) ([]byte, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("abi: decode hex: %w", err)
+	}
+	return raw, nil
+}
+
+func abiWord(data []byte, index int) ([]byte, error) {
+	start := index * abiWordSize
+	if len(data) < start+abiWordSize {
+		return nil, fmt.Errorf("abi: return data too short for word %d", index)
+	}
+	return data[start : start+abiWordSize], nil
+}
+
+func abiDecodeBool(word []byte) (bool, error) {
+	v := new(big.Int).SetBytes(word)
+	if !v.IsUint64() || v.Uint64() > 1 {
+		return false, fmt.Errorf("abi: invalid bool 0x%s", hex.EncodeToString(word))
+	}
+	return v.Uint64() == 1, nil
+}
+
+func abiDecodeAddress(word []byte) (string, error) {
+	for _, b := range word[:12] {
+		if b != 0 {
+			return "", fmt.Errorf("abi: invalid address 0x%s", hex.EncodeToString(word))
+		}
+	}
+	return "0x" + hex.EncodeToString(word[12:]), nil
+}
+
+func abiDecodeUint256(word []byte) *big.Int {
+	return new(big.Int).SetBytes(word)
+}
+
+func leftPad(b []byte) []byte {
+	out := make([]byte, abiWordSize)
+	copy(out[abiWordSize-len(b):], b)
+	return out
+}
@@ -1,20 +1,331 @@
 package repository
 
 import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"database/sql"
+	"encoding/asn1"
+	"encoding/hex"
 	"fmt"
+	"math/big"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/waizbart/aletheia-api/internal/config"
 	"github.com/waizbart/aletheia-api/internal/usecase"
 )
 
-func NewBlockchainServiceFromEnv() (usecase.BlockchainService, error) {
-	rpcURL := os.Getenv("RPC_URL")
-	fromAddress := os.Getenv("FROM_ADDRESS")
-	contractAddress := os.Getenv("CONTRACT_ADDRESS")
+// NewBlockchainServiceFromEnv builds the primary anchoring backend selected by
+// CHAIN_MODE: an EVM chain (the default), an RFC 3161 TSA, a transparency log
+// stored in db or an in-memory simulated chain.
+func NewBlockchainServiceFromEnv(db *sql.DB) (usecase.BlockchainService, error) {
+	switch mode := os.Getenv("CHAIN_MODE"); mode {
+	case "", "evm":
+		svc, err := newRPCServiceFromEnv("")
+		if err != nil {
+			return nil, err
+		}
+		return svc, nil
+	case "tsa":
+		svc, err := newTSAServiceFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return svc, nil
+	case "log":
+		svc, err := newTransparencyLogFromEnv(db)
+		if err != nil {
+			return nil, err
+		}
+		return svc, nil
+	case "simulated":
+		svc, err := newSimulatedServiceFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return svc, nil
+	default:
+		return nil, fmt.Errorf("invalid CHAIN_MODE %q: must be evm, tsa, log or simulated", mode)
+	}
+}
+
+func newSimulatedServiceFromEnv() (*SimulatedBlockchainService, error) {
+	chainID, err := config.LookupUint("SIMULATED_CHAIN_ID", 31337)
+	if err != nil {
+		return nil, err
+	}
+	latency, err := config.LookupDelay("SIMULATED_LATENCY", 0)
+	if err != nil {
+		return nil, err
+	}
+	rate := 0.0
+	if raw := os.Getenv("SIMULATED_FAILURE_RATE"); raw != "" {
+		rate, err = strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid SIMULATED_FAILURE_RATE: must be between 0 and 1")
+		}
+	}
+	contract := os.Getenv("CONTRACT_ADDRESS")
+	if contract == "" {
+		contract = "0x0000000000000000000000000000000000000000"
+	}
+	return NewSimulatedBlockchainService(chainID, contract).WithLatency(latency).WithFailureRate(rate), nil
+}
+
+func newTransparencyLogFromEnv(db *sql.DB) (*PostgresTransparencyLog, error) {
+	raw := os.Getenv("LOG_SIGNING_KEY")
+	if raw == "" {
+		return nil, fmt.Errorf("LOG_SIGNING_KEY is required when CHAIN_MODE is log")
+	}
+	seed, err := hex.DecodeString(strings.TrimPrefix(raw, "0x"))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid LOG_SIGNING_KEY: must be a hex Ed25519 seed of %d bytes", ed25519.SeedSize)
+	}
+	return NewPostgresTransparencyLog(db, ed25519.NewKeyFromSeed(seed))
+}
+
+func newTSAServiceFromEnv() (*TSAService, error) {
+	tsaURL := os.Getenv("TSA_URL")
+	if tsaURL == "" {
+		return nil, fmt.Errorf("TSA_URL is required when CHAIN_MODE is tsa")
+	}
+
+	path := os.Getenv("TSA_CA_FILE")
+	if path == "" {
+		return nil, fmt.Errorf("TSA_CA_FILE is required when CHAIN_MODE is tsa")
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TSA_CA_FILE: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("invalid TSA_CA_FILE: no PEM certificates in %s", path)
+	}
+	timeout, err := config.LookupDuration("TSA_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := NewTSAService(tsaURL, roots)
+	if err != nil {
+		return nil, err
+	}
+	svc.WithTimeout(timeout)
+
+	if raw := os.Getenv("TSA_POLICY"); raw != "" {
+		var policy asn1.ObjectIdentifier
+		for _, arc := range strings.Split(raw, ".") {
+			n, err := strconv.Atoi(arc)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid TSA_POLICY: %q is not a dotted OID", raw)
+			}
+			policy = append(policy, n)
+		}
+		if len(policy) < 2 {
+			return nil, fmt.Errorf("invalid TSA_POLICY: %q is not a dotted OID", raw)
+		}
+		svc.WithRequestPolicy(policy)
+	}
+	return svc, nil
+}
+
+// NewAnchorChainFromEnv configures an additional anchor chain from the
+// variables of the primary chain prefixed with its name, e.g. MAINNET_RPC_URL
+// for "mainnet". Receipt, fee and retry policies are shared with the primary
+// chain.
+func NewAnchorChainFromEnv(name string) (usecase.AnchorChain, error) {
+	prefix := strings.ToUpper(strings.TrimSpace(name)) + "_"
+	if prefix == "_" {
+		return nil, fmt.Errorf("anchor chain name is required")
+	}
+	svc, err := newRPCServiceFromEnv(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
+
+func newRPCServiceFromEnv(prefix string) (*RPCBlockchainService, error) {
+	rpcURL := os.Getenv(prefix + "RPC_URL")
+	privateKey := os.Getenv(prefix + "PRIVATE_KEY")
+	fromAddress := os.Getenv(prefix + "FROM_ADDRESS")
+	contractAddress := os.Getenv(prefix + "CONTRACT_ADDRESS")
+
+	if rpcURL == "" || contractAddress == "" || (fromAddress == "" && privateKey == "") {
+		return nil, fmt.Errorf("%[1]sRPC_URL, %[1]sCONTRACT_ADDRESS, and one of %[1]sFROM_ADDRESS or %[1]sPRIVATE_KEY are required", prefix)
+	}
+
+	policy, err := receiptPolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	fees, err := feePolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	retries, err := retryPolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	startBlock, err := config.LookupUint(prefix+"CONTRACT_START_BLOCK", 0)
+	if err != nil {
+		return nil, err
+	}
+	logRange, err := config.LookupUint("INDEXER_BATCH_SIZE", defaultLogRange)
+	if err != nil {
+		return nil, err
+	}
+
+	if privateKey == "" {
+		svc, err := NewEVMBlockchainService(rpcURL, fromAddress, contractAddress)
+		if err != nil {
+			return nil, err
+		}
+		return svc.WithReceiptPolicy(policy).WithFeePolicy(fees).WithRetryPolicy(retries).WithLogRange(startBlock, logRange), nil
+	}
 
-	if rpcURL == "" || fromAddress == "" || contractAddress == "" {
-		return nil, fmt.Errorf("RPC_URL, FROM_ADDRESS, and CONTRACT_ADDRESS are required")
+	chainID, err := config.LookupUint(prefix+"CHAIN_ID", 0)
+	if err != nil {
+		return nil, err
 	}
 
-	return NewEVMBlockchainService(rpcURL, fromAddress, contractAddress)
+	svc, err := NewSigningEVMBlockchainService(rpcURL, privateKey, contractAddress, chainID)
+	if err != nil {
+		return nil, err
+	}
+	if fromAddress != "" && !strings.EqualFold(fromAddress, svc.FromAddress()) {
+		return nil, fmt.Errorf("%sFROM_ADDRESS %s does not match %sPRIVATE_KEY address %s", prefix, fromAddress, prefix, svc.FromAddress())
+	}
+	return svc.WithReceiptPolicy(policy).WithFeePolicy(fees).WithRetryPolicy(retries).WithLogRange(startBlock, logRange), nil
+}
+
+func receiptPolicyFromEnv() (ReceiptPolicy, error) {
+	policy := DefaultReceiptPolicy()
+
+	timeout, err := config.LookupDuration("RECEIPT_TIMEOUT", policy.Timeout)
+	if err != nil {
+		return policy, err
+	}
+	interval, err := config.LookupDuration("RECEIPT_POLL_INTERVAL", policy.PollInterval)
+	if err != nil {
+		return policy, err
+	}
+	confirmations, err := config.LookupUint("CONFIRMATIONS", policy.Confirmations)
+	if err != nil {
+		return policy, err
+	}
+	rebroadcast, err := config.LookupDelay("REBROADCAST_AFTER", policy.RebroadcastAfter)
+	if err != nil {
+		return policy, err
+	}
+	bump, err := config.LookupUint("FEE_BUMP_PERCENT", uint64(policy.FeeBumpPercent))
+	if err != nil {
+		return policy, err
+	}
+	if bump < 10 {
+		return policy, fmt.Errorf("invalid FEE_BUMP_PERCENT: nodes reject replacements below 10%%")
+	}
+
+	policy.Timeout = timeout
+	policy.PollInterval = interval
+	policy.Confirmations = confirmations
+	policy.RebroadcastAfter = rebroadcast
+	policy.FeeBumpPercent = int64(bump)
+	return policy, nil
+}
+
+func retryPolicyFromEnv() (RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+
+	attempts, err := config.LookupUint("RPC_MAX_ATTEMPTS", uint64(policy.MaxAttempts))
+	if err != nil {
+		return policy, err
+	}
+	if attempts == 0 {
+		return policy, fmt.Errorf("invalid RPC_MAX_ATTEMPTS: must be positive")
+	}
+	base, err := config.LookupDuration("RPC_BACKOFF_BASE", policy.BaseDelay)
+	if err != nil {
+		return policy, err
+	}
+	maxDelay, err := config.LookupDuration("RPC_BACKOFF_MAX", policy.MaxDelay)
+	if err != nil {
+		return policy, err
+	}
+	timeout, err := config.LookupDuration("RPC_TIMEOUT", policy.RequestTimeout)
+	if err != nil {
+		return policy, err
+	}
+	cooldown, err := config.LookupDuration("RPC_COOLDOWN", policy.Cooldown)
+	if err != nil {
+		return policy, err
+	}
+
+	policy.MaxAttempts = int(attempts)
+	policy.BaseDelay = base
+	policy.MaxDelay = maxDelay
+	policy.RequestTimeout = timeout
+	policy.Cooldown = cooldown
+	return policy, nil
+}
+
+func feePolicyFromEnv() (FeePolicy, error) {
+	policy := DefaultFeePolicy()
+
+	buffer, err := config.LookupUint("GAS_LIMIT_BUFFER_PERCENT", policy.GasBufferPercent)
+	if err != nil {
+		return policy, err
+	}
+	blocks, err := config.LookupUint("FEE_HISTORY_BLOCKS", policy.HistoryBlocks)
+	if err != nil {
+		return policy, err
+	}
+	if blocks == 0 || blocks > 1024 {
+		return policy, fmt.Errorf("invalid FEE_HISTORY_BLOCKS: must be between 1 and 1024")
+	}
+	percentile, err := config.LookupUint("FEE_REWARD_PERCENTILE", policy.RewardPercentile)
+	if err != nil {
+		return policy, err
+	}
+	if percentile > 100 {
+		return policy, fmt.Errorf("invalid FEE_REWARD_PERCENTILE: must be at most 100")
+	}
+	multiplier, err := config.LookupUint("BASE_FEE_MULTIPLIER", uint64(policy.BaseFeeMultiplier))
+	if err != nil {
+		return policy, err
+	}
+	if multiplier == 0 {
+		return policy, fmt.Errorf("invalid BASE_FEE_MULTIPLIER: must be positive")
+	}
+
+	policy.GasBufferPercent = buffer
+	policy.HistoryBlocks = blocks
+	policy.RewardPercentile = percentile
+	policy.BaseFeeMultiplier = int64(multiplier)
+	for key, dst := range map[string]**big.Int{
+		"MAX_FEE_PER_GAS":          &policy.MaxFeePerGas,
+		"MAX_PRIORITY_FEE_PER_GAS": &policy.MaxPriorityFeePerGas,
+		"MAX_TX_COST":              &policy.MaxTxCost,
+	} {
+		if *dst, err = weiFromEnv(key); err != nil {
+			return policy, err
+		}
+	}
+	return policy, nil
+}
+
+// weiFromEnv parses an optional decimal wei amount.
+func weiFromEnv(key string) (*big.Int, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, ok := new(big.Int).SetString(raw, 10)
+	if !ok || v.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid %s: must be a positive wei amount", key)
+	}
+	return v, nil
 }
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type rpcLog struct {
+	Address         string   `json:"address"`
+	Topics          []string `json:"topics"`
+	Data            string   `json:"data"`
+	BlockNumber     string   `json:"blockNumber"`
+	BlockHash       string   `json:"blockHash"`
+	TransactionHash string   `json:"transactionHash"`
+	LogIndex        string   `json:"logIndex"`
+	Removed         bool     `json:"removed"`
+}
+
+func (l *rpcLog) isContentRegistered(contract string) bool {
+	return strings.EqualFold(l.Address, contract) &&
+		len(l.Topics) == 3 &&
+		strings.EqualFold(l.Topics[0], contentRegisteredTopic)
+}
+
+func decodeContentRegistered(l *rpcLog) (*domain.RegistrationEvent, error) {
+	hashWord, err := abiDecodeHex(l.Topics[1])
+	if err != nil || len(hashWord) != abiWordSize {
+		return nil, fmt.Errorf("decode ContentRegistered: invalid content hash topic %q", l.Topics[1])
+	}
+	registrantWord, err := abiDecodeHex(l.Topics[2])
+	if err != nil || len(registrantWord) != abiWordSize {
+		return nil, fmt.Errorf("decode ContentRegistered: invalid registrant topic %q", l.Topics[2])
+	}
+	registrant, err := abiDecodeAddress(registrantWord)
+	if err != nil {
+		return nil, fmt.Errorf("decode ContentRegistered: %w", err)
+	}
+
+	data, err := abiDecodeHex(l.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decode ContentRegistered: %w", err)
+	}
+	tsWord, err := abiWord(data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("decode ContentRegistered: %w", err)
+	}
+	ts := abiDecodeUint256(tsWord)
+	if !ts.IsInt64() {
+		return nil, fmt.Errorf("decode ContentRegistered: timestamp out of range")
+	}
+
+	event := &domain.RegistrationEvent{
+		ContentHash:  hex.EncodeToString(hashWord),
+		Registrant:   registrant,
+		RegisteredAt: time.Unix(ts.Int64(), 0).UTC(),
+		TxHash:       l.TransactionHash,
+		BlockHash:    l.BlockHash,
+	}
+	if l.BlockNumber != "" {
+		n, err := parseQuantity(l.BlockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("decode ContentRegistered: block number: %w", err)
+		}
+		event.BlockNumber = n.Uint64()
+	}
+	if l.LogIndex != "" {
+		n, err := parseQuantity(l.LogIndex)
+		if err != nil {
+			return nil, fmt.Errorf("decode ContentRegistered: log index: %w", err)
+		}
+		event.LogIndex = n.Uint64()
+	}
+	return event, nil
+}
+
+// defaultLogRange is how many blocks FindRegistration requests per
+// eth_getLogs call; hosted providers reject unbounded ranges.
+const defaultLogRange = 1000
+
+func (s *RPCBlockchainService) LatestBlock(ctx context.Context) (uint64, error) {
+	head, err := s.rpc.callQuantity(ctx, "eth_blockNumber", nil)
+	if err != nil {
+		return 0, fmt.Errorf("fetch block number: %w", err)
+	}
+	return head.Uint64(), nil
+}
+
+func (s *RPCBlockchainService) BlockHash(ctx context.Context, number uint64) (string, error) {
+	var block *struct {
+		Hash string `json:"hash"`
+	}
+	if err := s.rpc.call(ctx, "eth_getBlockByNumber", []any{encodeQuantity(new(big.Int).SetUint64(number)), false}, &block); err != nil {
+		return "", fmt.Errorf("fetch block %d: %w", number, err)
+	}
+	if block == nil {
+		return "", nil
+	}
+	return block.Hash, nil
+}
+
+func (s *RPCBlockchainService) RegistrationEvents(ctx context.Context, fromBlock, toBlock uint64) ([]domain.RegistrationEvent, error) {
+	var logs []rpcLog
+	err := s.rpc.call(ctx, "eth_getLogs", []any{map[string]any{
+		"address":   s.toAddress,
+		"fromBlock": encodeQuantity(new(big.Int).SetUint64(fromBlock)),
+		"toBlock":   encodeQuantity(new(big.Int).SetUint64(toBlock)),
+		"topics":    []string{contentRegisteredTopic},
+	}}, &logs)
+	if err != nil {
+		return nil, fmt.Errorf("fetch logs: %w", err)
+	}
+
+	events := make([]domain.RegistrationEvent, 0, len(logs))
+	for i := range logs {
+		if logs[i].Removed || !logs[i].isContentRegistered(s.toAddress) {
+			continue
+		}
+		event, err := decodeContentRegistered(&logs[i])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+	return events, nil
+}
+
+// FindRegistration scans back from the head, one log range at a time, down to
+// the start block set with WithLogRange. Registrations being recovered are
+// usually recent, so most lookups need a single call.
+func (s *RPCBlockchainService) FindRegistration(ctx context.Context, hash string) (*domain.RegistrationEvent, error) {
+	data, err := normalizeHashToBytes(hash)
+	if err != nil {
+		return nil, err
+	}
+	head, err := s.LatestBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if head < s.logStart {
+		return nil, nil
+	}
+
+	for to := head; ; {
+		from := s.logStart
+		if to-from >= s.logRange {
+			from = to - s.logRange + 1
+		}
+		var logs []rpcLog
+		err = s.rpc.call(ctx, "eth_getLogs", []any{map[string]any{
+			"address":   s.toAddress,
+			"fromBlock": encodeQuantity(new(big.Int).SetUint64(from)),
+			"toBlock":   encodeQuantity(new(big.Int).SetUint64(to)),
+			"topics":    []any{contentRegisteredTopic, "0x" + hex.EncodeToString(data)},
+		}}, &logs)
+		if err != nil {
+			return nil, fmt.Errorf("fetch logs %d-%d: %w", from, to, err)
+		}
+
+		for i := range logs {
+			if logs[i].Removed || !logs[i].isContentRegistered(s.toAddress) {
+				continue
+			}
+			return decodeContentRegistered(&logs[i])
+		}
+		if from == s.logStart {
+			return nil, nil
+		}
+		to = from - 1
+	}
+}
@@ -1,20 +1,29 @@
 package repository
 
 import (
-	"bytes"
 	"context"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"math/big"
 	"strings"
+	"sync"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
 )
 
 type RPCBlockchainService struct {
-	rpcURL      string
+	rpc         *rpcClient
 	fromAddress string
 	toAddress   string
-	httpClient  *http.Client
+	signer      *LocalSigner
+	nonces      *nonceManager
+	receipts    ReceiptPolicy
+	fees        FeePolicy
+	logStart    uint64
+	logRange    uint64
+
+	chainMu sync.Mutex
+	chainID *big.Int
 }
 
 func NewEVMBlockchainService(rpcURL, fromAddress, anchorAddress string) (*RPCBlockchainService, error) {
@@ -24,73 +33,243 @@ func NewEVMBlockchainService(rpcURL, fromAddress, anchorAddress string) (*RPCBlo
 	if !isHexAddress(anchorAddress) {
 		return nil, fmt.Errorf("invalid anchor address: %s", anchorAddress)
 	}
-	if rpcURL == "" {
+	rpc := newRPCClient(rpcURL)
+	if len(rpc.endpoints) == 0 {
 		return nil, fmt.Errorf("rpc url is required")
 	}
 
 	return &RPCBlockchainService{
-		rpcURL:      rpcURL,
+		rpc:         rpc,
 		fromAddress: fromAddress,
 		toAddress:   anchorAddress,
-		httpClient:  &http.Client{},
+		receipts:    DefaultReceiptPolicy(),
+		fees:        DefaultFeePolicy(),
+		logRange:    defaultLogRange,
 	}, nil
 }
 
-func (s *RPCBlockchainService) RegisterHash(ctx context.Context, hash string) (string, uint64, error) {
+func NewSigningEVMBlockchainService(rpcURL, privateKey, anchorAddress string, chainID uint64) (*RPCBlockchainService, error) {
+	signer, err := NewLocalSigner(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	svc, err := NewEVMBlockchainService(rpcURL, signer.Address(), anchorAddress)
+	if err != nil {
+		return nil, err
+	}
+	svc.signer = signer
+	svc.nonces = newNonceManager(svc.rpc, signer, svc.checkFees)
+	if chainID != 0 {
+		svc.chainID = new(big.Int).SetUint64(chainID)
+	}
+	return svc, nil
+}
+
+func (s *RPCBlockchainService) FromAddress() string {
+	return s.fromAddress
+}
+
+func (s *RPCBlockchainService) ContractAddress() string {
+	return s.toAddress
+}
+
+func (s *RPCBlockchainService) ChainID(ctx context.Context) (uint64, error) {
+	chainID, err := s.resolveChainID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return chainID.Uint64(), nil
+}
+
+func (s *RPCBlockchainService) WithReceiptPolicy(policy ReceiptPolicy) *RPCBlockchainService {
+	s.receipts = policy
+	return s
+}
+
+func (s *RPCBlockchainService) WithRetryPolicy(policy RetryPolicy) *RPCBlockchainService {
+	s.rpc.setPolicy(policy)
+	return s
+}
+
+func (s *RPCBlockchainService) WithFeePolicy(policy FeePolicy) *RPCBlockchainService {
+	s.fees = policy
+	return s
+}
+
+// WithLogRange bounds the eth_getLogs calls of FindRegistration: no block
+// before startBlock is scanned, and each call spans at most blocksPerCall
+// blocks. Zero keeps the default of 1000.
+func (s *RPCBlockchainService) WithLogRange(startBlock, blocksPerCall uint64) *RPCBlockchainService {
+	s.logStart = startBlock
+	if blocksPerCall > 0 {
+		s.logRange = blocksPerCall
+	}
+	return s
+}
+
+func (s *RPCBlockchainService) RegisterHash(ctx context.Context, hash, registrant string) (*domain.Receipt, error) {
+	txHash, err := s.SubmitHash(ctx, hash, registrant)
+	if err != nil {
+		return nil, err
+	}
+	return s.WaitForReceipt(ctx, hash, txHash)
+}
+
+func (s *RPCBlockchainService) SubmitHash(ctx context.Context, hash, registrant string) (string, error) {
+	data, err := normalizeHashToBytes(hash)
+	if err != nil {
+		return "", err
+	}
+
+	calldata, err := s.registerCalldata(data, registrant)
+	if err != nil {
+		return "", err
+	}
+
+	return s.send(ctx, calldata)
+}
+
+func (s *RPCBlockchainService) WaitForReceipt(ctx context.Context, hash, txHash string) (*domain.Receipt, error) {
 	data, err := normalizeHashToBytes(hash)
 	if err != nil {
-		return "", 0, err
+		return nil, err
 	}
 
-	reqBody := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "eth_sendTransaction",
-		"params": []map[string]string{{
-			"from": s.fromAddress,
-			"to":   s.toAddress,
-			"data": "0x" + hex.EncodeToString(data),
-		}},
+	receipt, err := s.waitForReceipt(ctx, txHash)
+	if err != nil {
+		return receipt, err
+	}
+	if receipt.Event == nil || receipt.Event.ContentHash != hex.EncodeToString(data) {
+		return receipt, fmt.Errorf("transaction %s: %w", txHash, domain.ErrAnchorEventMissing)
 	}
+	return receipt, nil
+}
 
-	payload, err := json.Marshal(reqBody)
+func (s *RPCBlockchainService) IsHashRegistered(ctx context.Context, hash string) (bool, error) {
+	data, err := normalizeHashToBytes(hash)
 	if err != nil {
-		return "", 0, fmt.Errorf("marshal rpc request: %w", err)
+		return false, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.rpcURL, bytes.NewReader(payload))
+	hashArg, err := abiEncodeBytes32(data)
 	if err != nil {
-		return "", 0, fmt.Errorf("create rpc request: %w", err)
+		return false, err
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := s.httpClient.Do(httpReq)
+	var out string
+	err = s.rpc.call(ctx, "eth_call", []any{map[string]string{
+		"to":   s.toAddress,
+		"data": "0x" + hex.EncodeToString(abiEncodeCall(isRegisteredSignature, hashArg)),
+	}, "latest"}, &out)
 	if err != nil {
-		return "", 0, fmt.Errorf("send rpc request: %w", err)
+		return false, fmt.Errorf("call isRegistered: %w", err)
 	}
-	defer resp.Body.Close()
 
-	var rpcResp struct {
-		Result string `json:"result"`
-		Error  *struct {
-			Message string `json:"message"`
-		} `json:"error"`
+	ret, err := abiDecodeHex(out)
+	if err != nil {
+		return false, fmt.Errorf("decode isRegistered result: %w", err)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
-		return "", 0, fmt.Errorf("decode rpc response: %w", err)
+	word, err := abiWord(ret, 0)
+	if err != nil {
+		return false, fmt.Errorf("decode isRegistered result: %w", err)
 	}
-	if rpcResp.Error != nil {
-		return "", 0, fmt.Errorf("rpc error: %s", rpcResp.Error.Message)
+	registered, err := abiDecodeBool(word)
+	if err != nil {
+		return false, fmt.Errorf("decode isRegistered result: %w", err)
 	}
-	if rpcResp.Result == "" {
-		return "", 0, fmt.Errorf("rpc error: empty transaction hash")
+	return registered, nil
+}
+
+// registerCalldata encodes register(contentHash, registrant). Registrants that
+// are not EVM addresses (e.g. organization IDs) are recorded on chain as the
+// sending account.
+func (s *RPCBlockchainService) registerCalldata(hash []byte, registrant string) ([]byte, error) {
+	if !isHexAddress(registrant) {
+		registrant = s.fromAddress
 	}
 
-	return rpcResp.Result, 0, nil
+	hashArg, err := abiEncodeBytes32(hash)
+	if err != nil {
+		return nil, err
+	}
+	registrantArg, err := abiEncodeAddress(registrant)
+	if err != nil {
+		return nil, err
+	}
+	return abiEncodeCall(registerSignature, hashArg, registrantArg), nil
 }
 
-func (s *RPCBlockchainService) IsHashRegistered(context.Context, string) (bool, error) {
-	return false, nil
+func (s *RPCBlockchainService) send(ctx context.Context, data []byte) (string, error) {
+	if s.signer != nil {
+		return s.sendSigned(ctx, data)
+	}
+
+	fees, err := s.quoteFees(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	call := map[string]string{
+		"from": s.fromAddress,
+		"to":   s.toAddress,
+		"data": "0x" + hex.EncodeToString(data),
+		"gas":  encodeQuantity(new(big.Int).SetUint64(fees.Gas)),
+	}
+	if fees.Type == LegacyTxType {
+		call["gasPrice"] = encodeQuantity(fees.GasPrice)
+	} else {
+		call["maxFeePerGas"] = encodeQuantity(fees.GasFeeCap)
+		call["maxPriorityFeePerGas"] = encodeQuantity(fees.GasTipCap)
+	}
+
+	var txHash string
+	if err := s.rpc.call(ctx, "eth_sendTransaction", []any{call}, &txHash); err != nil {
+		return "", err
+	}
+	if txHash == "" {
+		return "", fmt.Errorf("rpc error: empty transaction hash")
+	}
+	return txHash, nil
+}
+
+func (s *RPCBlockchainService) sendSigned(ctx context.Context, data []byte) (string, error) {
+	chainID, err := s.resolveChainID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return s.nonces.send(ctx, func(nonce uint64) (*EVMTransaction, error) {
+		return s.buildTransaction(ctx, chainID, nonce, data)
+	})
+}
+
+func (s *RPCBlockchainService) buildTransaction(ctx context.Context, chainID *big.Int, nonce uint64, data []byte) (*EVMTransaction, error) {
+	tx, err := s.quoteFees(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	tx.ChainID = chainID
+	tx.Nonce = nonce
+	tx.To = s.toAddress
+	tx.Value = new(big.Int)
+	tx.Data = data
+	return tx, nil
+}
+
+func (s *RPCBlockchainService) resolveChainID(ctx context.Context) (*big.Int, error) {
+	s.chainMu.Lock()
+	defer s.chainMu.Unlock()
+
+	if s.chainID != nil {
+		return s.chainID, nil
+	}
+
+	chainID, err := s.rpc.callQuantity(ctx, "eth_chainId", nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch chain id: %w", err)
+	}
+	s.chainID = chainID
+	return chainID, nil
 }
 
 func normalizeHashToBytes(hash string) ([]byte, error) {
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	LegacyTxType     uint8 = 0x00
+	DynamicFeeTxType uint8 = 0x02
+)
+
+type EVMTransaction struct {
+	Type      uint8
+	ChainID   *big.Int
+	Nonce     uint64
+	GasPrice  *big.Int
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+	Gas       uint64
+	To        string
+	Value     *big.Int
+	Data      []byte
+}
+
+func (tx *EVMTransaction) signingHash() ([]byte, error) {
+	to, err := decodeAddress(tx.To)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tx.Type {
+	case LegacyTxType:
+		payload := rlpEncodeList(
+			rlpEncodeUint(tx.Nonce),
+			rlpEncodeBigInt(tx.GasPrice),
+			rlpEncodeUint(tx.Gas),
+			rlpEncodeBytes(to),
+			rlpEncodeBigInt(tx.Value),
+			rlpEncodeBytes(tx.Data),
+			rlpEncodeBigInt(tx.ChainID),
+			rlpEncodeUint(0),
+			rlpEncodeUint(0),
+		)
+		return keccak256(payload), nil
+	case DynamicFeeTxType:
+		payload := rlpEncodeList(tx.dynamicFeeFields(to)...)
+		return keccak256(append([]byte{DynamicFeeTxType}, payload...)), nil
+	default:
+		return nil, fmt.Errorf("unsupported transaction type: %d", tx.Type)
+	}
+}
+
+func (tx *EVMTransaction) encodeSigned(recoveryID byte, r, s []byte) ([]byte, error) {
+	to, err := decodeAddress(tx.To)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tx.Type {
+	case LegacyTxType:
+		v := new(big.Int).Mul(tx.ChainID, big.NewInt(2))
+		v.Add(v, big.NewInt(35+int64(recoveryID)))
+		return rlpEncodeList(
+			rlpEncodeUint(tx.Nonce),
+			rlpEncodeBigInt(tx.GasPrice),
+			rlpEncodeUint(tx.Gas),
+			rlpEncodeBytes(to),
+			rlpEncodeBigInt(tx.Value),
+			rlpEncodeBytes(tx.Data),
+			rlpEncodeBigInt(v),
+			rlpEncodeBigInt(new(big.Int).SetBytes(r)),
+			rlpEncodeBigInt(new(big.Int).SetBytes(s)),
+		), nil
+	case DynamicFeeTxType:
+		fields := append(tx.dynamicFeeFields(to),
+			rlpEncodeUint(uint64(recoveryID)),
+			rlpEncodeBigInt(new(big.Int).SetBytes(r)),
+			rlpEncodeBigInt(new(big.Int).SetBytes(s)),
+		)
+		return append([]byte{DynamicFeeTxType}, rlpEncodeList(fields...)...), nil
+	default:
+		return nil, fmt.Errorf("unsupported transaction type: %d", tx.Type)
+	}
+}
+
+func (tx *EVMTransaction) dynamicFeeFields(to []byte) [][]byte {
+	return [][]byte{
+		rlpEncodeBigInt(tx.ChainID),
+		rlpEncodeUint(tx.Nonce),
+		rlpEncodeBigInt(tx.GasTipCap),
+		rlpEncodeBigInt(tx.GasFeeCap),
+		rlpEncodeUint(tx.Gas),
+		rlpEncodeBytes(to),
+		rlpEncodeBigInt(tx.Value),
+		rlpEncodeBytes(tx.Data),
+		rlpEncodeList(),
+	}
+}
+
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+func decodeAddress(addr string) ([]byte, error) {
+	if !isHexAddress(addr) {
+		return nil, fmt.Errorf("invalid address: %s", addr)
+	}
+	return hex.DecodeString(strings.TrimPrefix(addr, "0x"))
+}
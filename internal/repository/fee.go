@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+// FeePolicy controls the gas limit and fees of anchor transactions. Nil caps
+// are unlimited.
+type FeePolicy struct {
+	GasBufferPercent  uint64
+	HistoryBlocks     uint64
+	RewardPercentile  uint64
+	BaseFeeMultiplier int64
+
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	// MaxTxCost caps gas limit times the highest gas price the transaction
+	// may pay.
+	MaxTxCost *big.Int
+}
+
+func DefaultFeePolicy() FeePolicy {
+	return FeePolicy{
+		GasBufferPercent:  20,
+		HistoryBlocks:     10,
+		RewardPercentile:  50,
+		BaseFeeMultiplier: 2,
+	}
+}
+
+type feeHistory struct {
+	BaseFeePerGas []string   `json:"baseFeePerGas"`
+	Reward        [][]string `json:"reward"`
+}
+
+// quoteFees returns a transaction carrying only the type, gas limit and fees
+// for sending data to the anchor contract. Chains whose fee history reports no
+// base fee get a legacy gas price.
+func (s *RPCBlockchainService) quoteFees(ctx context.Context, data []byte) (*EVMTransaction, error) {
+	estimate, err := s.rpc.callQuantity(ctx, "eth_estimateGas", []any{map[string]string{
+		"from": s.fromAddress,
+		"to":   s.toAddress,
+		"data": "0x" + hex.EncodeToString(data),
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("estimate gas: %w", err)
+	}
+	tx := &EVMTransaction{Gas: estimate.Uint64() * (100 + s.fees.GasBufferPercent) / 100}
+
+	var history feeHistory
+	err = s.rpc.call(ctx, "eth_feeHistory", []any{
+		encodeQuantity(new(big.Int).SetUint64(s.fees.HistoryBlocks)),
+		"latest",
+		[]uint64{s.fees.RewardPercentile},
+	}, &history)
+	if err != nil {
+		return nil, fmt.Errorf("fetch fee history: %w", err)
+	}
+
+	baseFee := new(big.Int)
+	if n := len(history.BaseFeePerGas); n > 0 {
+		// The last entry is the base fee of the next block.
+		if baseFee, err = parseQuantity(history.BaseFeePerGas[n-1]); err != nil {
+			return nil, fmt.Errorf("parse base fee: %w", err)
+		}
+	}
+
+	if baseFee.Sign() == 0 {
+		gasPrice, err := s.rpc.callQuantity(ctx, "eth_gasPrice", nil)
+		if err != nil {
+			return nil, fmt.Errorf("fetch gas price: %w", err)
+		}
+		tx.Type = LegacyTxType
+		tx.GasPrice = gasPrice
+		return tx, s.checkFees(tx)
+	}
+
+	tip, err := medianReward(history.Reward)
+	if err != nil {
+		return nil, fmt.Errorf("parse fee history reward: %w", err)
+	}
+	if tip == nil {
+		if tip, err = s.rpc.callQuantity(ctx, "eth_maxPriorityFeePerGas", nil); err != nil {
+			return nil, fmt.Errorf("fetch priority fee: %w", err)
+		}
+	}
+	tip = capFee(tip, s.fees.MaxPriorityFeePerGas)
+
+	feeCap := new(big.Int).Mul(baseFee, big.NewInt(s.fees.BaseFeeMultiplier))
+	feeCap = capFee(feeCap.Add(feeCap, tip), s.fees.MaxFeePerGas)
+	if feeCap.Cmp(baseFee) < 0 {
+		return nil, fmt.Errorf("%w: base fee %s is above max fee per gas %s", domain.ErrSpendCapExceeded, baseFee, feeCap)
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+
+	tx.Type = DynamicFeeTxType
+	tx.GasTipCap = tip
+	tx.GasFeeCap = feeCap
+	return tx, s.checkFees(tx)
+}
+
+// checkFees rejects transactions whose worst-case price or cost exceeds the
+// policy caps.
+func (s *RPCBlockchainService) checkFees(tx *EVMTransaction) error {
+	price := tx.GasFeeCap
+	if tx.Type == LegacyTxType {
+		price = tx.GasPrice
+	}
+	if max := s.fees.MaxFeePerGas; max != nil && price.Cmp(max) > 0 {
+		return fmt.Errorf("%w: gas price %s is above max fee per gas %s", domain.ErrSpendCapExceeded, price, max)
+	}
+	if tip, max := tx.GasTipCap, s.fees.MaxPriorityFeePerGas; tip != nil && max != nil && tip.Cmp(max) > 0 {
+		return fmt.Errorf("%w: priority fee %s is above max priority fee per gas %s", domain.ErrSpendCapExceeded, tip, max)
+	}
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas), price)
+	if max := s.fees.MaxTxCost; max != nil && cost.Cmp(max) > 0 {
+		return fmt.Errorf("%w: cost %s wei is above %s wei", domain.ErrSpendCapExceeded, cost, max)
+	}
+	return nil
+}
+
+// medianReward returns the median non-zero priority fee of the sampled
+// blocks, or nil when every sampled block was empty.
+func medianReward(rewards [][]string) (*big.Int, error) {
+	var tips []*big.Int
+	for _, block := range rewards {
+		if len(block) == 0 {
+			continue
+		}
+		tip, err := parseQuantity(block[0])
+		if err != nil {
+			return nil, err
+		}
+		if tip.Sign() > 0 {
+			tips = append(tips, tip)
+		}
+	}
+	if len(tips) == 0 {
+		return nil, nil
+	}
+	sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+	return tips[len(tips)/2], nil
+}
+
+func capFee(fee, max *big.Int) *big.Int {
+	if max != nil && fee.Cmp(max) > 0 {
+		return new(big.Int).Set(max)
+	}
+	return fee
+}
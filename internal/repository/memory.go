@@ -0,0 +1,499 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type memoryRecord struct {
+	seq          int
+	cert         *domain.Certificate
+	updatedAt    time.Time
+	claimedUntil time.Time
+}
+
+// bandKey addresses one band value of one perceptual hash algorithm.
+type bandKey struct {
+	alg   domain.HashAlgorithm
+	band  int
+	value uint16
+}
+
+// MemoryCertificateRepo keeps certificates in process memory with the same
+// semantics as PostgresCertificateRepo, for demos and integration tests. All
+// data is lost on restart.
+type MemoryCertificateRepo struct {
+	mu          sync.Mutex
+	records     []*memoryRecord
+	byID        map[string]*memoryRecord
+	byHash      map[string]*memoryRecord
+	byPixelHash map[string]*memoryRecord
+	bands       map[bandKey][]*memoryRecord
+	videoBands  map[bandKey][]*memoryRecord
+	checkpoints map[string]uint64
+	now         func() time.Time
+}
+
+func NewMemoryCertificateRepo() *MemoryCertificateRepo {
+	return &MemoryCertificateRepo{
+		byID:        map[string]*memoryRecord{},
+		byHash:      map[string]*memoryRecord{},
+		byPixelHash: map[string]*memoryRecord{},
+		bands:       map[bandKey][]*memoryRecord{},
+		videoBands:  map[bandKey][]*memoryRecord{},
+		checkpoints: map[string]uint64{},
+		now:         time.Now,
+	}
+}
+
+func (r *MemoryCertificateRepo) Save(_ context.Context, cert *domain.Certificate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byHash[cert.ContentHash]; ok {
+		return fmt.Errorf("memory save: %w", domain.ErrAlreadyCertified)
+	}
+	id, err := newUUID()
+	if err != nil {
+		return fmt.Errorf("memory save: %w", err)
+	}
+
+	stored := &domain.Certificate{
+		ID:               id,
+		ContentHash:      cert.ContentHash,
+		PixelHash:        cert.PixelHash,
+		PerceptualHashes: clonePerceptualHashes(cert.PerceptualHashes),
+		Fingerprint:      cloneFingerprint(cert.Fingerprint),
+		Registrant:       cert.Registrant,
+		TxHash:           cert.TxHash,
+		BlockNumber:      cert.BlockNumber,
+		BlockHash:        cert.BlockHash,
+		Status:           cert.Status,
+		LastError:        cert.LastError,
+		Attempts:         cert.Attempts,
+		ChainMismatch:    cert.ChainMismatch,
+		CreatedAt:        cert.CreatedAt,
+	}
+	rec := &memoryRecord{seq: len(r.records), cert: stored, updatedAt: r.now()}
+	r.records = append(r.records, rec)
+	r.byID[id] = rec
+	r.byHash[cert.ContentHash] = rec
+	if _, ok := r.byPixelHash[cert.PixelHash]; !ok && cert.PixelHash != "" {
+		r.byPixelHash[cert.PixelHash] = rec
+	}
+	indexBands(r.bands, rec, stored.PerceptualHashes)
+	for _, seg := range stored.Fingerprint {
+		indexBands(r.videoBands, rec, seg.Hashes)
+	}
+	cert.ID = id
+	return nil
+}
+
+func (r *MemoryCertificateRepo) UpdateAnchorState(_ context.Context, cert *domain.Certificate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.byID[cert.ID]
+	if !ok {
+		return fmt.Errorf("memory update anchor state: %w", domain.ErrNotFound)
+	}
+	stored := rec.cert
+	stored.Registrant = cert.Registrant
+	stored.TxHash = cert.TxHash
+	stored.BlockNumber = cert.BlockNumber
+	stored.BlockHash = cert.BlockHash
+	stored.Status = cert.Status
+	stored.LastError = cert.LastError
+	stored.Attempts = cert.Attempts
+	stored.Batch = cloneBatch(cert.Batch)
+	stored.Fee = cloneFee(cert.Fee)
+	stored.TimestampToken = cloneBytes(cert.TimestampToken)
+	rec.updatedAt = r.now()
+
+	// Like syncAnchors: upsert every anchor, then drop the ones the
+	// certificate no longer has.
+	keep := make(map[string]bool, len(cert.Anchors))
+	for _, a := range cert.Anchors {
+		upsertMemoryAnchor(stored, a)
+		keep[anchorKey(a.ChainID, a.ContractAddress)] = true
+	}
+	anchors := stored.Anchors[:0]
+	for _, a := range stored.Anchors {
+		if keep[anchorKey(a.ChainID, a.ContractAddress)] {
+			anchors = append(anchors, a)
+		}
+	}
+	stored.Anchors = anchors
+	return nil
+}
+
+func (r *MemoryCertificateRepo) SaveAnchor(_ context.Context, certID string, anchor domain.Anchor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.byID[certID]
+	if !ok {
+		return fmt.Errorf("memory save anchor: %w", domain.ErrNotFound)
+	}
+	upsertMemoryAnchor(rec.cert, anchor)
+	return nil
+}
+
+func upsertMemoryAnchor(c *domain.Certificate, a domain.Anchor) {
+	a.ContractAddress = strings.ToLower(a.ContractAddress)
+	a.Batch = cloneBatch(a.Batch)
+	for i := range c.Anchors {
+		if c.Anchors[i].ChainID == a.ChainID && c.Anchors[i].ContractAddress == a.ContractAddress {
+			c.Anchors[i] = a
+			return
+		}
+	}
+	c.Anchors = append(c.Anchors, a)
+}
+
+func anchorKey(chainID uint64, contract string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(contract))
+}
+
+func (r *MemoryCertificateRepo) FindUnanchored(_ context.Context, chainID uint64, contract string, limit int) ([]*domain.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := anchorKey(chainID, contract)
+	matches := r.filter(func(rec *memoryRecord) bool {
+		if rec.cert.Status != domain.StatusConfirmed {
+			return false
+		}
+		for _, a := range rec.cert.Anchors {
+			if anchorKey(a.ChainID, a.ContractAddress) == key {
+				return false
+			}
+		}
+		return true
+	})
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i].cert, matches[j].cert
+		if a.BlockNumber != b.BlockNumber {
+			return a.BlockNumber < b.BlockNumber
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return cloneRecords(limitRecords(matches, limit)), nil
+}
+
+// ClaimUnfinished leases certificates left unfinished for staleAfter to a
+// single worker for another staleAfter, sharing the lease with ClaimPending.
+func (r *MemoryCertificateRepo) ClaimUnfinished(_ context.Context, limit int, staleAfter time.Duration) ([]*domain.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	matches := r.filter(func(rec *memoryRecord) bool {
+		switch rec.cert.Status {
+		case domain.StatusPending, domain.StatusSubmitted, domain.StatusReorged:
+		default:
+			return false
+		}
+		return rec.updatedAt.Before(now.Add(-staleAfter)) && !rec.claimedUntil.After(now)
+	})
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].updatedAt.Before(matches[j].updatedAt) })
+
+	matches = limitRecords(matches, limit)
+	for _, rec := range matches {
+		rec.claimedUntil = now.Add(staleAfter)
+		rec.updatedAt = now
+	}
+	return cloneRecords(matches), nil
+}
+
+// ClaimPending leases pending certificates to a single batcher until the lease
+// expires, oldest first.
+func (r *MemoryCertificateRepo) ClaimPending(_ context.Context, limit int, lease time.Duration) ([]*domain.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	matches := r.filter(func(rec *memoryRecord) bool {
+		return rec.cert.Status == domain.StatusPending && !rec.claimedUntil.After(now)
+	})
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].cert.CreatedAt.Before(matches[j].cert.CreatedAt) })
+
+	matches = limitRecords(matches, limit)
+	for _, rec := range matches {
+		rec.claimedUntil = now.Add(lease)
+		rec.updatedAt = now
+	}
+	return cloneRecords(matches), nil
+}
+
+func (r *MemoryCertificateRepo) FindByMerkleRoot(_ context.Context, root string) ([]*domain.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matches := r.filter(func(rec *memoryRecord) bool {
+		return rec.cert.Batch != nil && rec.cert.Batch.Root == root
+	})
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].cert.Batch.LeafIndex < matches[j].cert.Batch.LeafIndex })
+	return cloneRecords(matches), nil
+}
+
+func (r *MemoryCertificateRepo) FindConfirmedSince(_ context.Context, fromBlock uint64) ([]*domain.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matches := r.filter(func(rec *memoryRecord) bool {
+		return rec.cert.Status == domain.StatusConfirmed && rec.cert.BlockNumber >= fromBlock
+	})
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].cert.BlockNumber < matches[j].cert.BlockNumber })
+	return cloneRecords(matches), nil
+}
+
+func (r *MemoryCertificateRepo) FindByHash(_ context.Context, contentHash string) (*domain.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, ok := r.byHash[contentHash]; ok {
+		return cloneCertificate(rec.cert), nil
+	}
+	return nil, nil
+}
+
+// FindByPixelHash returns the first certificate saved with pixelHash.
+func (r *MemoryCertificateRepo) FindByPixelHash(_ context.Context, pixelHash string) (*domain.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, ok := r.byPixelHash[pixelHash]; ok {
+		return cloneCertificate(rec.cert), nil
+	}
+	return nil, nil
+}
+
+func (r *MemoryCertificateRepo) FindByID(_ context.Context, id string) (*domain.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, ok := r.byID[id]; ok {
+		return cloneCertificate(rec.cert), nil
+	}
+	return nil, nil
+}
+
+// FindByPerceptualHash returns the certificates whose combined distance to
+// hashes is within maxDistance, nearest first. Candidates come from the band
+// index unless the distance is too large to probe.
+func (r *MemoryCertificateRepo) FindByPerceptualHash(_ context.Context, hashes domain.PerceptualHashes, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	candidates := r.records
+	if probes, ok := domain.PerceptualProbes(hashes, maxDistance); ok {
+		candidates = probeBands(r.bands, probes)
+	}
+	return rankRecords(candidates, func(c *domain.Certificate) (domain.SimilarCertificate, bool) {
+		return domain.MatchSimilar(hashes, c, maxDistance)
+	}, limit), nil
+}
+
+// FindByVideoFingerprint returns the certified videos clip is a copy of, most
+// confident first. Candidates come from the segment band index unless the
+// distance is too large to probe.
+func (r *MemoryCertificateRepo) FindByVideoFingerprint(_ context.Context, clip domain.VideoFingerprint, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	candidates := r.records
+	if probes, ok := domain.VideoProbes(clip, maxDistance); ok {
+		candidates = probeBands(r.videoBands, probes)
+	}
+	return rankRecords(candidates, func(c *domain.Certificate) (domain.SimilarCertificate, bool) {
+		return domain.MatchVideo(clip, c, maxDistance)
+	}, limit), nil
+}
+
+func indexBands(index map[bandKey][]*memoryRecord, rec *memoryRecord, hashes domain.PerceptualHashes) {
+	for alg, hash := range hashes {
+		for i, band := range domain.HashBands(hash) {
+			key := bandKey{alg: alg, band: i, value: band}
+			if n := len(index[key]); n == 0 || index[key][n-1] != rec {
+				index[key] = append(index[key], rec)
+			}
+		}
+	}
+}
+
+// probeBands returns the records indexed under any probe, in insertion order.
+func probeBands(index map[bandKey][]*memoryRecord, probes map[domain.HashAlgorithm][domain.PerceptualHashBands][]uint16) []*memoryRecord {
+	var candidates []*memoryRecord
+	seen := map[*memoryRecord]bool{}
+	for alg, bands := range probes {
+		for i, values := range bands {
+			for _, v := range values {
+				for _, rec := range index[bandKey{alg: alg, band: i, value: v}] {
+					if !seen[rec] {
+						seen[rec] = true
+						candidates = append(candidates, rec)
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].seq < candidates[j].seq })
+	return candidates
+}
+
+// rankRecords keeps the limit nearest matches, as copies. Callers hold mu.
+func rankRecords(candidates []*memoryRecord, match matchFunc, limit int) []domain.SimilarCertificate {
+	var matches []domain.SimilarCertificate
+	for _, rec := range candidates {
+		if m, ok := match(rec.cert); ok {
+			matches = append(matches, m)
+		}
+	}
+	matches = domain.RankSimilar(matches, limit)
+	for i := range matches {
+		matches[i].Certificate = cloneCertificate(matches[i].Certificate)
+	}
+	return matches
+}
+
+func (r *MemoryCertificateRepo) FlagChainMismatch(_ context.Context, id, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.byID[id]
+	if !ok {
+		return fmt.Errorf("memory flag chain mismatch: %w", domain.ErrNotFound)
+	}
+	rec.cert.ChainMismatch = reason
+	return nil
+}
+
+func (r *MemoryCertificateRepo) LoadCheckpoint(_ context.Context, name string) (uint64, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	block, ok := r.checkpoints[name]
+	return block, ok, nil
+}
+
+func (r *MemoryCertificateRepo) SaveCheckpoint(_ context.Context, name string, block uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checkpoints[name] = block
+	return nil
+}
+
+// filter returns the matching records in insertion order. Callers hold mu.
+func (r *MemoryCertificateRepo) filter(match func(*memoryRecord) bool) []*memoryRecord {
+	var out []*memoryRecord
+	for _, rec := range r.records {
+		if match(rec) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+func limitRecords(recs []*memoryRecord, limit int) []*memoryRecord {
+	if limit >= 0 && len(recs) > limit {
+		return recs[:limit]
+	}
+	return recs
+}
+
+func cloneRecords(recs []*memoryRecord) []*domain.Certificate {
+	certs := make([]*domain.Certificate, len(recs))
+	for i, rec := range recs {
+		certs[i] = cloneCertificate(rec.cert)
+	}
+	return certs
+}
+
+// cloneCertificate copies everything callers may mutate, so stored state only
+// changes through the repository.
+func cloneCertificate(c *domain.Certificate) *domain.Certificate {
+	out := *c
+	out.PerceptualHashes = clonePerceptualHashes(c.PerceptualHashes)
+	out.Fingerprint = cloneFingerprint(c.Fingerprint)
+	out.Batch = cloneBatch(c.Batch)
+	out.Fee = cloneFee(c.Fee)
+	out.TimestampToken = cloneBytes(c.TimestampToken)
+	out.Anchors = nil
+	for _, a := range c.Anchors {
+		a.Batch = cloneBatch(a.Batch)
+		out.Anchors = append(out.Anchors, a)
+	}
+	return &out
+}
+
+func cloneFingerprint(fp domain.VideoFingerprint) domain.VideoFingerprint {
+	if fp == nil {
+		return nil
+	}
+	out := make(domain.VideoFingerprint, len(fp))
+	for i, seg := range fp {
+		seg.Hashes = clonePerceptualHashes(seg.Hashes)
+		out[i] = seg
+	}
+	return out
+}
+
+func clonePerceptualHashes(h domain.PerceptualHashes) domain.PerceptualHashes {
+	if h == nil {
+		return nil
+	}
+	out := make(domain.PerceptualHashes, len(h))
+	for alg, hash := range h {
+		out[alg] = hash
+	}
+	return out
+}
+
+func cloneBatch(b *domain.MerkleBatch) *domain.MerkleBatch {
+	if b == nil {
+		return nil
+	}
+	out := *b
+	out.Proof = append([]string(nil), b.Proof...)
+	return &out
+}
+
+func cloneFee(f *domain.AnchorFee) *domain.AnchorFee {
+	if f == nil {
+		return nil
+	}
+	out := *f
+	if f.EffectiveGasPrice != nil {
+		out.EffectiveGasPrice = new(big.Int).Set(f.EffectiveGasPrice)
+	}
+	return &out
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID, for stores without a
+// database-side default.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate id: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
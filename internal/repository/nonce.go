@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// nonceManager assigns nonces for a local signer. Submissions are serialized
+// so concurrent certifications never race for the same nonce; the counter is
+// resynced from eth_getTransactionCount whenever a send fails, and broadcast
+// transactions are kept so they can be replaced with higher fees.
+type nonceManager struct {
+	rpc       *rpcClient
+	signer    *LocalSigner
+	checkFees func(*EVMTransaction) error
+
+	mu       sync.Mutex
+	next     uint64
+	synced   bool
+	inflight map[string]*EVMTransaction
+}
+
+func newNonceManager(rpc *rpcClient, signer *LocalSigner, checkFees func(*EVMTransaction) error) *nonceManager {
+	return &nonceManager{rpc: rpc, signer: signer, checkFees: checkFees, inflight: map[string]*EVMTransaction{}}
+}
+
+// send builds a transaction for the next nonce, signs and broadcasts it. A
+// nonce conflict reported by the node triggers one resync and retry.
+func (m *nonceManager) send(ctx context.Context, build func(nonce uint64) (*EVMTransaction, error)) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if !m.synced {
+			if err := m.resync(ctx); err != nil {
+				return "", err
+			}
+		}
+
+		tx, err := build(m.next)
+		if err != nil {
+			return "", err
+		}
+
+		txHash, err := m.broadcast(ctx, tx)
+		if err != nil {
+			m.synced = false
+			if isNonceConflict(err) && attempt == 0 {
+				continue
+			}
+			return "", err
+		}
+
+		m.next++
+		m.inflight[txHash] = tx
+		return txHash, nil
+	}
+}
+
+// replace rebroadcasts the transaction behind txHash with the same nonce and
+// fees raised by bumpPercent. It returns an empty hash when the node reports
+// that the nonce was already used, i.e. some version of it has been mined, or
+// when the bumped fees would exceed the fee policy.
+func (m *nonceManager) replace(ctx context.Context, txHash string, bumpPercent int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx, ok := m.inflight[txHash]
+	if !ok {
+		fetched, err := m.fetch(ctx, txHash)
+		if err != nil || fetched == nil {
+			return "", err
+		}
+		tx = fetched
+	}
+
+	bumped := *tx
+	bumped.GasPrice = bumpFee(tx.GasPrice, bumpPercent)
+	bumped.GasTipCap = bumpFee(tx.GasTipCap, bumpPercent)
+	bumped.GasFeeCap = bumpFee(tx.GasFeeCap, bumpPercent)
+	if m.checkFees(&bumped) != nil {
+		return "", nil
+	}
+
+	newHash, err := m.broadcast(ctx, &bumped)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "nonce too low") {
+			return "", nil
+		}
+		return "", fmt.Errorf("replace transaction %s: %w", txHash, err)
+	}
+	m.inflight[newHash] = &bumped
+	return newHash, nil
+}
+
+func (m *nonceManager) forget(hashes ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range hashes {
+		delete(m.inflight, h)
+	}
+}
+
+func (m *nonceManager) resync(ctx context.Context) error {
+	nonce, err := m.rpc.callQuantity(ctx, "eth_getTransactionCount", []any{m.signer.Address(), "pending"})
+	if err != nil {
+		return fmt.Errorf("fetch nonce: %w", err)
+	}
+	m.next = nonce.Uint64()
+	m.synced = true
+	return nil
+}
+
+func (m *nonceManager) broadcast(ctx context.Context, tx *EVMTransaction) (string, error) {
+	raw, err := m.signer.SignTx(tx)
+	if err != nil {
+		return "", err
+	}
+
+	var txHash string
+	if err := m.rpc.call(ctx, "eth_sendRawTransaction", []any{"0x" + hex.EncodeToString(raw)}, &txHash); err != nil {
+		return "", err
+	}
+	if txHash == "" {
+		return "", fmt.Errorf("rpc error: empty transaction hash")
+	}
+	return txHash, nil
+}
+
+// fetch rebuilds a transaction sent before a restart from the node's mempool.
+// It returns nil when the node no longer knows the transaction or it was sent
+// by another account.
+func (m *nonceManager) fetch(ctx context.Context, txHash string) (*EVMTransaction, error) {
+	var raw *struct {
+		Type                 string `json:"type"`
+		From                 string `json:"from"`
+		To                   string `json:"to"`
+		Nonce                string `json:"nonce"`
+		Gas                  string `json:"gas"`
+		GasPrice             string `json:"gasPrice"`
+		MaxFeePerGas         string `json:"maxFeePerGas"`
+		MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas"`
+		Value                string `json:"value"`
+		Input                string `json:"input"`
+		ChainID              string `json:"chainId"`
+	}
+	if err := m.rpc.call(ctx, "eth_getTransactionByHash", []any{txHash}, &raw); err != nil {
+		return nil, fmt.Errorf("fetch transaction: %w", err)
+	}
+	if raw == nil || !strings.EqualFold(raw.From, m.signer.Address()) {
+		return nil, nil
+	}
+
+	quantities := map[string]string{"nonce": raw.Nonce, "gas": raw.Gas, "value": raw.Value, "chainId": raw.ChainID}
+	values := map[string]*big.Int{}
+	for name, q := range quantities {
+		v, err := parseQuantity(q)
+		if err != nil {
+			return nil, fmt.Errorf("parse transaction %s: %w", name, err)
+		}
+		values[name] = v
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(raw.Input, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("parse transaction input: %w", err)
+	}
+
+	tx := &EVMTransaction{
+		ChainID: values["chainId"],
+		Nonce:   values["nonce"].Uint64(),
+		Gas:     values["gas"].Uint64(),
+		To:      raw.To,
+		Value:   values["value"],
+		Data:    data,
+	}
+	if raw.MaxFeePerGas == "" {
+		tx.Type = LegacyTxType
+		tx.GasPrice, err = parseQuantity(raw.GasPrice)
+	} else {
+		tx.Type = DynamicFeeTxType
+		if tx.GasFeeCap, err = parseQuantity(raw.MaxFeePerGas); err == nil {
+			tx.GasTipCap, err = parseQuantity(raw.MaxPriorityFeePerGas)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse transaction fees: %w", err)
+	}
+	return tx, nil
+}
+
+// bumpFee raises a fee by percent, and by at least one wei.
+func bumpFee(fee *big.Int, percent int64) *big.Int {
+	if fee == nil {
+		return nil
+	}
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+percent))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(fee) <= 0 {
+		bumped.Add(fee, big.NewInt(1))
+	}
+	return bumped
+}
+
+func isNonceConflict(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"nonce too low", "nonce too high", "replacement transaction underpriced", "already known", "known transaction"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,13 +3,40 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"github.com/waizbart/aletheia-api/internal/domain"
 )
 
+const certificateColumns = `id, content_hash, pixel_hash, registrant, tx_hash, block_number, block_hash, status, last_error, attempts, chain_mismatch, merkle_root, leaf_index, tree_size, merkle_proof, gas_used, effective_gas_price, timestamp_token, created_at`
+
+const anchorColumns = `certificate_id, chain_id, contract_address, tx_hash, block_number, block_hash, merkle_root, leaf_index, tree_size, merkle_proof`
+
+const perceptualHashColumns = `certificate_id, algorithm, hash, band0, band1, band2, band3`
+
+const videoSegmentColumns = `certificate_id, position, start_ms, end_ms, algorithm, hash, band0, band1, band2, band3`
+
+const (
+	uniqueViolation           = "23505"
+	invalidTextRepresentation = "22P02"
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 type PostgresCertificateRepo struct {
 	db *sql.DB
 }
@@ -20,99 +47,805 @@ func NewPostgresCertificateRepo(db *sql.DB) *PostgresCertificateRepo {
 
 func (r *PostgresCertificateRepo) Save(ctx context.Context, cert *domain.Certificate) error {
 	const q = `
-		INSERT INTO certificates (content_hash, perceptual_hash, registrant, tx_hash, block_number, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO certificates (content_hash, pixel_hash, registrant, tx_hash, block_number, block_hash, status, last_error, attempts, chain_mismatch, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id`
 
-	var perceptualHash sql.NullInt64
-	if cert.PerceptualHash != nil {
-		perceptualHash = sql.NullInt64{Int64: int64(*cert.PerceptualHash), Valid: true}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("postgres save: %w", err)
 	}
+	defer tx.Rollback()
 
-	err := r.db.QueryRowContext(ctx, q,
+	var id string
+	err = tx.QueryRowContext(ctx, q,
 		cert.ContentHash,
-		perceptualHash,
+		cert.PixelHash,
 		cert.Registrant,
 		cert.TxHash,
 		cert.BlockNumber,
+		cert.BlockHash,
+		cert.Status,
+		cert.LastError,
+		cert.Attempts,
+		cert.ChainMismatch,
 		cert.CreatedAt,
-	).Scan(&cert.ID)
+	).Scan(&id)
 
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("postgres save: %w", domain.ErrAlreadyCertified)
+	}
 	if err != nil {
 		return fmt.Errorf("postgres save: %w", err)
 	}
+
+	const insertHash = `INSERT INTO perceptual_hashes (` + perceptualHashColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	for _, alg := range cert.PerceptualHashes.Algorithms() {
+		if _, err := tx.ExecContext(ctx, insertHash, perceptualHashRow(id, alg, cert.PerceptualHashes[alg])...); err != nil {
+			return fmt.Errorf("postgres save perceptual hash: %w", err)
+		}
+	}
+	const insertSegment = `INSERT INTO video_segments (` + videoSegmentColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	for _, row := range videoSegmentRows(id, cert.Fingerprint) {
+		if _, err := tx.ExecContext(ctx, insertSegment, row...); err != nil {
+			return fmt.Errorf("postgres save video segment: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("postgres save: %w", err)
+	}
+	cert.ID = id
 	return nil
 }
 
-func (r *PostgresCertificateRepo) FindByHash(ctx context.Context, contentHash string) (*domain.Certificate, error) {
+func (r *PostgresCertificateRepo) UpdateAnchorState(ctx context.Context, cert *domain.Certificate) error {
 	const q = `
-		SELECT id, content_hash, perceptual_hash, registrant, tx_hash, block_number, created_at
-		FROM certificates
-		WHERE content_hash = $1`
+		UPDATE certificates
+		SET registrant = $2, tx_hash = $3, block_number = $4, block_hash = $5,
+		    status = $6, last_error = $7, attempts = $8,
+		    merkle_root = $9, leaf_index = $10, tree_size = $11, merkle_proof = $12,
+		    gas_used = $13, effective_gas_price = $14, timestamp_token = $15,
+		    updated_at = NOW()
+		WHERE id = $1`
 
-	cert := &domain.Certificate{}
-	var perceptualHash sql.NullInt64
-	err := r.db.QueryRowContext(ctx, q, contentHash).Scan(
-		&cert.ID,
-		&cert.ContentHash,
-		&perceptualHash,
-		&cert.Registrant,
-		&cert.TxHash,
-		&cert.BlockNumber,
-		&cert.CreatedAt,
+	root, index, treeSize, proof := batchColumns(cert.Batch)
+	var (
+		gasUsed  sql.NullInt64
+		gasPrice sql.NullString
+	)
+	if f := cert.Fee; f != nil {
+		gasUsed = sql.NullInt64{Int64: int64(f.GasUsed), Valid: true}
+		gasPrice = sql.NullString{String: f.EffectiveGasPrice.String(), Valid: true}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("postgres update anchor state: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q,
+		cert.ID,
+		cert.Registrant,
+		cert.TxHash,
+		cert.BlockNumber,
+		cert.BlockHash,
+		cert.Status,
+		cert.LastError,
+		cert.Attempts,
+		root,
+		index,
+		treeSize,
+		proof,
+		gasUsed,
+		gasPrice,
+		cert.TimestampToken,
 	)
+	if err != nil {
+		return fmt.Errorf("postgres update anchor state: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("postgres update anchor state: %w", domain.ErrNotFound)
+	}
+	if err := syncAnchors(ctx, tx, cert); err != nil {
+		return fmt.Errorf("postgres update anchor state: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("postgres update anchor state: %w", err)
+	}
+	return nil
+}
+
+// syncAnchors stores the certificate's anchors and removes the ones it no
+// longer has, such as a primary anchor orphaned by a reorg.
+func syncAnchors(ctx context.Context, tx *sql.Tx, cert *domain.Certificate) error {
+	keys := make([]string, len(cert.Anchors))
+	for i, a := range cert.Anchors {
+		if err := upsertAnchor(ctx, tx, cert.ID, a); err != nil {
+			return err
+		}
+		keys[i] = strconv.FormatUint(a.ChainID, 10) + ":" + strings.ToLower(a.ContractAddress)
+	}
+
+	const q = `
+		DELETE FROM anchors
+		WHERE certificate_id = $1 AND NOT (chain_id::text || ':' || contract_address = ANY($2))`
+
+	if _, err := tx.ExecContext(ctx, q, cert.ID, pq.Array(keys)); err != nil {
+		return fmt.Errorf("delete anchors: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresCertificateRepo) SaveAnchor(ctx context.Context, certID string, anchor domain.Anchor) error {
+	if err := upsertAnchor(ctx, r.db, certID, anchor); err != nil {
+		return fmt.Errorf("postgres save anchor: %w", err)
+	}
+	return nil
+}
+
+func upsertAnchor(ctx context.Context, db execer, certID string, a domain.Anchor) error {
+	const q = `
+		INSERT INTO anchors (` + anchorColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (certificate_id, chain_id, contract_address) DO UPDATE
+		SET tx_hash = EXCLUDED.tx_hash, block_number = EXCLUDED.block_number, block_hash = EXCLUDED.block_hash,
+		    merkle_root = EXCLUDED.merkle_root, leaf_index = EXCLUDED.leaf_index,
+		    tree_size = EXCLUDED.tree_size, merkle_proof = EXCLUDED.merkle_proof`
+
+	root, index, treeSize, proof := batchColumns(a.Batch)
+	_, err := db.ExecContext(ctx, q,
+		certID,
+		a.ChainID,
+		strings.ToLower(a.ContractAddress),
+		a.TxHash,
+		a.BlockNumber,
+		a.BlockHash,
+		root,
+		index,
+		treeSize,
+		proof,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert anchor: %w", err)
+	}
+	return nil
+}
+
+// FindUnanchored returns confirmed certificates missing from the chain, in
+// the order they were anchored on the primary chain.
+func (r *PostgresCertificateRepo) FindUnanchored(ctx context.Context, chainID uint64, contract string, limit int) ([]*domain.Certificate, error) {
+	q := `
+		SELECT ` + certificateColumns + ` FROM certificates c
+		WHERE status = 'confirmed'
+		  AND NOT EXISTS (
+			SELECT 1 FROM anchors a
+			WHERE a.certificate_id = c.id AND a.chain_id = $1 AND a.contract_address = $2
+		  )
+		ORDER BY block_number, created_at
+		LIMIT $3`
+
+	certs, err := r.queryCertificates(ctx, q, chainID, strings.ToLower(contract), limit)
+	if err != nil {
+		return nil, fmt.Errorf("postgres find unanchored: %w", err)
+	}
+	return certs, nil
+}
+
+// loadAnchors attaches the stored anchors to the certificates.
+func (r *PostgresCertificateRepo) loadAnchors(ctx context.Context, certs ...*domain.Certificate) error {
+	if len(certs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(certs))
+	byID := make(map[string]*domain.Certificate, len(certs))
+	for i, c := range certs {
+		ids[i] = c.ID
+		byID[c.ID] = c
+	}
+
+	q := `SELECT ` + anchorColumns + ` FROM anchors WHERE certificate_id = ANY($1::uuid[]) ORDER BY created_at, chain_id`
+
+	rows, err := r.db.QueryContext(ctx, q, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("load anchors: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			certID          string
+			a               domain.Anchor
+			root            sql.NullString
+			index, treeSize sql.NullInt64
+			proof           []string
+		)
+		err := rows.Scan(&certID, &a.ChainID, &a.ContractAddress, &a.TxHash, &a.BlockNumber, &a.BlockHash,
+			&root, &index, &treeSize, pq.Array(&proof))
+		if err != nil {
+			return fmt.Errorf("load anchors scan: %w", err)
+		}
+		a.Batch = batchFromColumns(root, index, treeSize, proof)
+		if c := byID[certID]; c != nil {
+			c.Anchors = append(c.Anchors, a)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load anchors rows: %w", err)
+	}
+	return nil
+}
+
+// loadPerceptualHashes attaches the stored perceptual hashes to the
+// certificates.
+func (r *PostgresCertificateRepo) loadPerceptualHashes(ctx context.Context, certs ...*domain.Certificate) error {
+	if len(certs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(certs))
+	for i, c := range certs {
+		ids[i] = c.ID
+	}
+
+	q := `SELECT certificate_id, algorithm, hash FROM perceptual_hashes WHERE certificate_id = ANY($1::uuid[])`
+
+	hashes, err := r.queryPerceptualHashes(ctx, q, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("load perceptual hashes: %w", err)
+	}
+	for _, c := range certs {
+		c.PerceptualHashes = hashes[c.ID]
+	}
+	return nil
+}
+
+// loadFingerprints attaches the stored video fingerprints to the
+// certificates.
+func (r *PostgresCertificateRepo) loadFingerprints(ctx context.Context, certs ...*domain.Certificate) error {
+	if len(certs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(certs))
+	for i, c := range certs {
+		ids[i] = c.ID
+	}
+
+	q := `SELECT certificate_id, position, start_ms, end_ms, algorithm, hash FROM video_segments WHERE certificate_id = ANY($1::uuid[])`
+
+	fingerprints, err := r.queryVideoSegments(ctx, q, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("load fingerprints: %w", err)
+	}
+	for _, c := range certs {
+		c.Fingerprint = fingerprints[c.ID]
+	}
+	return nil
+}
+
+// queryVideoSegments groups the (certificate_id, position, start_ms, end_ms,
+// algorithm, hash) rows of q by certificate.
+func (r *PostgresCertificateRepo) queryVideoSegments(ctx context.Context, q string, args ...any) (map[string]domain.VideoFingerprint, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanVideoSegments(rows)
+}
+
+// queryPerceptualHashes groups the (certificate_id, algorithm, hash) rows of q
+// by certificate.
+func (r *PostgresCertificateRepo) queryPerceptualHashes(ctx context.Context, q string, args ...any) (map[string]domain.PerceptualHashes, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanPerceptualHashes(rows)
+}
+
+// ClaimUnfinished leases certificates left unfinished for staleAfter to a
+// single worker for another staleAfter, sharing the lease with ClaimPending.
+func (r *PostgresCertificateRepo) ClaimUnfinished(ctx context.Context, limit int, staleAfter time.Duration) ([]*domain.Certificate, error) {
+	q := `
+		UPDATE certificates
+		SET claimed_until = NOW() + make_interval(secs => $1), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM certificates
+			WHERE status IN ('pending', 'submitted', 'reorged')
+			  AND updated_at < NOW() - make_interval(secs => $1)
+			  AND (claimed_until IS NULL OR claimed_until < NOW())
+			ORDER BY updated_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + certificateColumns
+
+	certs, err := r.queryCertificates(ctx, q, staleAfter.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("postgres claim unfinished: %w", err)
+	}
+	return certs, nil
+}
+
+// ClaimPending leases pending certificates to a single batcher until the lease
+// expires, oldest first.
+func (r *PostgresCertificateRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*domain.Certificate, error) {
+	q := `
+		UPDATE certificates
+		SET claimed_until = NOW() + make_interval(secs => $1), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM certificates
+			WHERE status = 'pending'
+			  AND (claimed_until IS NULL OR claimed_until < NOW())
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + certificateColumns
+
+	certs, err := r.queryCertificates(ctx, q, lease.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("postgres claim pending: %w", err)
+	}
+	sortByCreatedAt(certs)
+	return certs, nil
+}
+
+func (r *PostgresCertificateRepo) FindByMerkleRoot(ctx context.Context, root string) ([]*domain.Certificate, error) {
+	q := `SELECT ` + certificateColumns + ` FROM certificates WHERE merkle_root = $1 ORDER BY leaf_index`
+
+	certs, err := r.queryCertificates(ctx, q, root)
+	if err != nil {
+		return nil, fmt.Errorf("postgres find by merkle root: %w", err)
+	}
+	return certs, nil
+}
+
+func (r *PostgresCertificateRepo) FindConfirmedSince(ctx context.Context, fromBlock uint64) ([]*domain.Certificate, error) {
+	q := `SELECT ` + certificateColumns + ` FROM certificates WHERE status = 'confirmed' AND block_number >= $1 ORDER BY block_number`
+
+	certs, err := r.queryCertificates(ctx, q, fromBlock)
+	if err != nil {
+		return nil, fmt.Errorf("postgres find confirmed since: %w", err)
+	}
+	return certs, nil
+}
+
+func (r *PostgresCertificateRepo) queryCertificates(ctx context.Context, q string, args ...any) ([]*domain.Certificate, error) {
+	certs, err := r.scanCertificates(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadRelated(ctx, certs...); err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+// loadRelated attaches anchors, perceptual hashes and video fingerprints to
+// the certificates.
+func (r *PostgresCertificateRepo) loadRelated(ctx context.Context, certs ...*domain.Certificate) error {
+	if err := r.loadAnchors(ctx, certs...); err != nil {
+		return err
+	}
+	if err := r.loadPerceptualHashes(ctx, certs...); err != nil {
+		return err
+	}
+	return r.loadFingerprints(ctx, certs...)
+}
 
+func (r *PostgresCertificateRepo) scanCertificates(ctx context.Context, q string, args ...any) ([]*domain.Certificate, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var certs []*domain.Certificate
+	for rows.Next() {
+		cert, err := scanCertificate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return certs, nil
+}
+
+func (r *PostgresCertificateRepo) FindByHash(ctx context.Context, contentHash string) (*domain.Certificate, error) {
+	q := `SELECT ` + certificateColumns + ` FROM certificates WHERE content_hash = $1`
+
+	cert, err := scanCertificate(r.db.QueryRowContext(ctx, q, contentHash))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("postgres find by hash: %w", err)
 	}
-	if perceptualHash.Valid {
-		v := uint64(perceptualHash.Int64)
-		cert.PerceptualHash = &v
+	if err := r.loadRelated(ctx, cert); err != nil {
+		return nil, fmt.Errorf("postgres find by hash: %w", err)
 	}
 	return cert, nil
 }
 
-func (r *PostgresCertificateRepo) FindByPerceptualHash(ctx context.Context, hash uint64, maxDistance int) (*domain.Certificate, error) {
-	const q = `
-		SELECT id, content_hash, perceptual_hash, registrant, tx_hash, block_number, created_at
-		FROM certificates
-		WHERE perceptual_hash IS NOT NULL`
+func (r *PostgresCertificateRepo) FindByPixelHash(ctx context.Context, pixelHash string) (*domain.Certificate, error) {
+	if pixelHash == "" {
+		return nil, nil
+	}
+	q := `SELECT ` + certificateColumns + ` FROM certificates WHERE pixel_hash = $1 ORDER BY created_at, id LIMIT 1`
+
+	cert, err := scanCertificate(r.db.QueryRowContext(ctx, q, pixelHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("postgres find by pixel hash: %w", err)
+	}
+	if err := r.loadRelated(ctx, cert); err != nil {
+		return nil, fmt.Errorf("postgres find by pixel hash: %w", err)
+	}
+	return cert, nil
+}
+
+func (r *PostgresCertificateRepo) FindByID(ctx context.Context, id string) (*domain.Certificate, error) {
+	q := `SELECT ` + certificateColumns + ` FROM certificates WHERE id = $1`
+
+	cert, err := scanCertificate(r.db.QueryRowContext(ctx, q, id))
+	var pqErr *pq.Error
+	if err == sql.ErrNoRows || (errors.As(err, &pqErr) && pqErr.Code == invalidTextRepresentation) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("postgres find by id: %w", err)
+	}
+	if err := r.loadRelated(ctx, cert); err != nil {
+		return nil, fmt.Errorf("postgres find by id: %w", err)
+	}
+	return cert, nil
+}
+
+// FindByPerceptualHash returns the certificates whose combined distance to
+// hashes is within maxDistance, nearest first. Candidates are looked up
+// through the indexed hash bands of each algorithm; only distances too large
+// to probe fall back to scanning every perceptual hash.
+func (r *PostgresCertificateRepo) FindByPerceptualHash(ctx context.Context, hashes domain.PerceptualHashes, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	q := `SELECT certificate_id, algorithm, hash FROM perceptual_hashes`
+
+	var args []any
+	if probes, ok := domain.PerceptualProbes(hashes, maxDistance); ok {
+		var filter string
+		filter, args = bandFilter(probes)
+		q += ` WHERE certificate_id IN (SELECT certificate_id FROM perceptual_hashes WHERE ` + filter + `)`
+	}
 
-	rows, err := r.db.QueryContext(ctx, q)
+	found, err := r.queryPerceptualHashes(ctx, q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("postgres find by perceptual hash: %w", err)
 	}
-	defer rows.Close()
+	candidates := make(map[string]*domain.Certificate, len(found))
+	for id, h := range found {
+		candidates[id] = &domain.Certificate{ID: id, PerceptualHashes: h}
+	}
+	matches, err := r.findSimilar(ctx, candidates, func(c *domain.Certificate) (domain.SimilarCertificate, bool) {
+		return domain.MatchSimilar(hashes, c, maxDistance)
+	}, limit)
+	if err != nil {
+		return nil, fmt.Errorf("postgres find by perceptual hash: %w", err)
+	}
+	return matches, nil
+}
 
+// FindByVideoFingerprint returns the certified videos clip is a copy of, most
+// confident first. Candidates are the videos with a segment in the probed hash
+// bands of any clip segment.
+func (r *PostgresCertificateRepo) FindByVideoFingerprint(ctx context.Context, clip domain.VideoFingerprint, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+	if len(clip) == 0 {
+		return nil, nil
+	}
+	q := `SELECT certificate_id, position, start_ms, end_ms, algorithm, hash FROM video_segments`
+
+	var args []any
+	if probes, ok := domain.VideoProbes(clip, maxDistance); ok {
+		var filter string
+		filter, args = bandFilter(probes)
+		q += ` WHERE certificate_id IN (SELECT certificate_id FROM video_segments WHERE ` + filter + `)`
+	}
+
+	found, err := r.queryVideoSegments(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("postgres find by video fingerprint: %w", err)
+	}
+	candidates := make(map[string]*domain.Certificate, len(found))
+	for id, fp := range found {
+		candidates[id] = &domain.Certificate{ID: id, Fingerprint: fp}
+	}
+	matches, err := r.findSimilar(ctx, candidates, func(c *domain.Certificate) (domain.SimilarCertificate, bool) {
+		return domain.MatchVideo(clip, c, maxDistance)
+	}, limit)
+	if err != nil {
+		return nil, fmt.Errorf("postgres find by video fingerprint: %w", err)
+	}
+	return matches, nil
+}
+
+// findSimilar loads the candidates that match, keeps the limit nearest and
+// attaches their anchors.
+func (r *PostgresCertificateRepo) findSimilar(ctx context.Context, candidates map[string]*domain.Certificate, match matchFunc, limit int) ([]domain.SimilarCertificate, error) {
+	ids := matchingIDs(candidates, match)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	certs, err := r.scanCertificates(ctx, `SELECT `+certificateColumns+` FROM certificates WHERE id = ANY($1::uuid[])`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	matches := rankMatches(candidates, certs, match, limit)
+	if err := r.loadAnchors(ctx, similarCertificates(matches)...); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func (r *PostgresCertificateRepo) FlagChainMismatch(ctx context.Context, id, reason string) error {
+	const q = `UPDATE certificates SET chain_mismatch = $2 WHERE id = $1`
+
+	res, err := r.db.ExecContext(ctx, q, id, reason)
+	if err != nil {
+		return fmt.Errorf("postgres flag chain mismatch: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("postgres flag chain mismatch: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresCertificateRepo) LoadCheckpoint(ctx context.Context, name string) (uint64, bool, error) {
+	const q = `SELECT block_number FROM indexer_checkpoints WHERE name = $1`
+
+	var block uint64
+	err := r.db.QueryRowContext(ctx, q, name).Scan(&block)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("postgres load checkpoint: %w", err)
+	}
+	return block, true, nil
+}
+
+func (r *PostgresCertificateRepo) SaveCheckpoint(ctx context.Context, name string, block uint64) error {
+	const q = `
+		INSERT INTO indexer_checkpoints (name, block_number, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (name) DO UPDATE SET block_number = EXCLUDED.block_number, updated_at = NOW()`
+
+	if _, err := r.db.ExecContext(ctx, q, name, block); err != nil {
+		return fmt.Errorf("postgres save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func scanCertificate(row rowScanner) (*domain.Certificate, error) {
+	cert := &domain.Certificate{}
 	var (
-		best     *domain.Certificate
-		bestDist = maxDistance + 1
+		root            sql.NullString
+		index, treeSize sql.NullInt64
+		proof           []string
+		gasUsed         sql.NullInt64
+		gasPrice        sql.NullString
+	)
+	err := row.Scan(
+		&cert.ID,
+		&cert.ContentHash,
+		&cert.PixelHash,
+		&cert.Registrant,
+		&cert.TxHash,
+		&cert.BlockNumber,
+		&cert.BlockHash,
+		&cert.Status,
+		&cert.LastError,
+		&cert.Attempts,
+		&cert.ChainMismatch,
+		&root,
+		&index,
+		&treeSize,
+		pq.Array(&proof),
+		&gasUsed,
+		&gasPrice,
+		&cert.TimestampToken,
+		&cert.CreatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	cert.Batch = batchFromColumns(root, index, treeSize, proof)
+	if gasPrice.Valid {
+		price, ok := new(big.Int).SetString(gasPrice.String, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid effective gas price %q", gasPrice.String)
+		}
+		cert.Fee = &domain.AnchorFee{GasUsed: uint64(gasUsed.Int64), EffectiveGasPrice: price}
+	}
+	return cert, nil
+}
 
-	for rows.Next() {
-		cert := &domain.Certificate{}
-		var pHash sql.NullInt64
-		if err := rows.Scan(&cert.ID, &cert.ContentHash, &pHash, &cert.Registrant, &cert.TxHash, &cert.BlockNumber, &cert.CreatedAt); err != nil {
-			return nil, fmt.Errorf("postgres find by perceptual hash scan: %w", err)
+// perceptualHashRow returns the perceptualHashColumns values storing one
+// hash together with its index bands.
+func perceptualHashRow(certID string, alg domain.HashAlgorithm, hash uint64) []any {
+	bands := domain.HashBands(hash)
+	return []any{certID, string(alg), int64(hash), int64(bands[0]), int64(bands[1]), int64(bands[2]), int64(bands[3])}
+}
+
+// videoSegmentRows returns the videoSegmentColumns values storing fp, one row
+// per segment and algorithm.
+func videoSegmentRows(certID string, fp domain.VideoFingerprint) [][]any {
+	var rows [][]any
+	for i, seg := range fp {
+		for _, alg := range seg.Hashes.Algorithms() {
+			hashRow := perceptualHashRow(certID, alg, seg.Hashes[alg])
+			row := append([]any{certID, i, seg.Start.Milliseconds(), seg.End.Milliseconds()}, hashRow[1:]...)
+			rows = append(rows, row)
 		}
-		if !pHash.Valid {
-			continue
+	}
+	return rows
+}
+
+func scanPerceptualHashes(rows *sql.Rows) (map[string]domain.PerceptualHashes, error) {
+	hashes := map[string]domain.PerceptualHashes{}
+	for rows.Next() {
+		var (
+			certID, alg string
+			hash        int64
+		)
+		if err := rows.Scan(&certID, &alg, &hash); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
 		}
-		v := uint64(pHash.Int64)
-		cert.PerceptualHash = &v
-		d := domain.HammingDistance(hash, v)
-		if d <= maxDistance && d < bestDist {
-			bestDist = d
-			best = cert
+		if hashes[certID] == nil {
+			hashes[certID] = domain.PerceptualHashes{}
 		}
+		hashes[certID][domain.HashAlgorithm(alg)] = uint64(hash)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return hashes, nil
+}
 
+func scanVideoSegments(rows *sql.Rows) (map[string]domain.VideoFingerprint, error) {
+	fingerprints := map[string]domain.VideoFingerprint{}
+	for rows.Next() {
+		var (
+			certID, alg          string
+			position             int
+			startMS, endMS, hash int64
+		)
+		if err := rows.Scan(&certID, &position, &startMS, &endMS, &alg, &hash); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		if position < 0 {
+			return nil, fmt.Errorf("invalid segment position %d", position)
+		}
+		fp := fingerprints[certID]
+		for len(fp) <= position {
+			fp = append(fp, domain.VideoSegment{Hashes: domain.PerceptualHashes{}})
+		}
+		fp[position].Start = time.Duration(startMS) * time.Millisecond
+		fp[position].End = time.Duration(endMS) * time.Millisecond
+		fp[position].Hashes[domain.HashAlgorithm(alg)] = uint64(hash)
+		fingerprints[certID] = fp
+	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("postgres find by perceptual hash rows: %w", err)
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return fingerprints, nil
+}
+
+// bandFilter builds a condition matching the rows whose bands hit a probe of
+// their algorithm, with its arguments numbered from $1.
+func bandFilter(probes map[domain.HashAlgorithm][domain.PerceptualHashBands][]uint16) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	for _, alg := range probedAlgorithms(probes) {
+		n := len(args)
+		conds = append(conds, fmt.Sprintf(`(algorithm = $%d AND (band0 = ANY($%d) OR band1 = ANY($%d) OR band2 = ANY($%d) OR band3 = ANY($%d)))`,
+			n+1, n+2, n+3, n+4, n+5))
+		args = append(args, string(alg))
+		for _, values := range probes[alg] {
+			args = append(args, pq.Array(bandValues(values)))
+		}
+	}
+	return strings.Join(conds, ` OR `), args
+}
+
+func probedAlgorithms(probes map[domain.HashAlgorithm][domain.PerceptualHashBands][]uint16) []domain.HashAlgorithm {
+	algs := make([]domain.HashAlgorithm, 0, len(probes))
+	for alg := range probes {
+		algs = append(algs, alg)
+	}
+	sort.Slice(algs, func(i, j int) bool { return algs[i] < algs[j] })
+	return algs
+}
+
+// matchFunc decides whether a certificate, with the hashes or fingerprint
+// it was found by, is similar to what was searched for.
+type matchFunc func(*domain.Certificate) (domain.SimilarCertificate, bool)
+
+// matchingIDs returns the ids of the candidates that match.
+func matchingIDs(candidates map[string]*domain.Certificate, match matchFunc) []string {
+	var ids []string
+	for id, c := range candidates {
+		if _, ok := match(c); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// rankMatches gives the loaded certs the hashes and fingerprints of their
+// candidates and keeps the limit nearest matches.
+func rankMatches(candidates map[string]*domain.Certificate, certs []*domain.Certificate, match matchFunc, limit int) []domain.SimilarCertificate {
+	var matches []domain.SimilarCertificate
+	for _, cert := range certs {
+		if c := candidates[cert.ID]; c != nil {
+			cert.PerceptualHashes, cert.Fingerprint = c.PerceptualHashes, c.Fingerprint
+		}
+		if m, ok := match(cert); ok {
+			matches = append(matches, m)
+		}
+	}
+	return domain.RankSimilar(matches, limit)
+}
+
+func similarCertificates(matches []domain.SimilarCertificate) []*domain.Certificate {
+	certs := make([]*domain.Certificate, len(matches))
+	for i, m := range matches {
+		certs[i] = m.Certificate
+	}
+	return certs
+}
+
+func bandValues(values []uint16) []int64 {
+	out := make([]int64, len(values))
+	for i, v := range values {
+		out[i] = int64(v)
 	}
+	return out
+}
+
+func batchColumns(b *domain.MerkleBatch) (root sql.NullString, index, treeSize sql.NullInt64, proof any) {
+	if b == nil {
+		return root, index, treeSize, pq.Array([]string(nil))
+	}
+	root = sql.NullString{String: b.Root, Valid: true}
+	index = sql.NullInt64{Int64: int64(b.LeafIndex), Valid: true}
+	treeSize = sql.NullInt64{Int64: int64(b.TreeSize), Valid: true}
+	return root, index, treeSize, pq.Array(b.Proof)
+}
+
+func batchFromColumns(root sql.NullString, index, treeSize sql.NullInt64, proof []string) *domain.MerkleBatch {
+	if !root.Valid {
+		return nil
+	}
+	return &domain.MerkleBatch{
+		Root:      root.String,
+		LeafIndex: uint64(index.Int64),
+		TreeSize:  uint64(treeSize.Int64),
+		Proof:     proof,
+	}
+}
 
-	return best, nil
+// sortByCreatedAt restores claim order, which UPDATE ... RETURNING does not
+// guarantee, so batches keep their leaves in submission order.
+func sortByCreatedAt(certs []*domain.Certificate) {
+	sort.SliceStable(certs, func(i, j int) bool { return certs[i].CreatedAt.Before(certs[j].CreatedAt) })
 }
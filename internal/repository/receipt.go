@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type ReceiptPolicy struct {
+	Timeout       time.Duration
+	PollInterval  time.Duration
+	Confirmations uint64
+	// RebroadcastAfter is how long a locally signed transaction may stay
+	// unmined before it is replaced with FeeBumpPercent higher fees. Zero
+	// disables replacement.
+	RebroadcastAfter time.Duration
+	FeeBumpPercent   int64
+}
+
+func DefaultReceiptPolicy() ReceiptPolicy {
+	return ReceiptPolicy{
+		Timeout:          2 * time.Minute,
+		PollInterval:     2 * time.Second,
+		Confirmations:    1,
+		RebroadcastAfter: 30 * time.Second,
+		FeeBumpPercent:   15,
+	}
+}
+
+type rpcReceipt struct {
+	TransactionHash string   `json:"transactionHash"`
+	BlockNumber     string   `json:"blockNumber"`
+	BlockHash       string   `json:"blockHash"`
+	GasUsed         string   `json:"gasUsed"`
+	GasPrice        string   `json:"effectiveGasPrice"`
+	Status          string   `json:"status"`
+	Logs            []rpcLog `json:"logs"`
+}
+
+func (r *rpcReceipt) toDomain(contract string) (*domain.Receipt, error) {
+	blockNumber, err := parseQuantity(r.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("parse receipt block number: %w", err)
+	}
+	gasUsed, err := parseQuantity(r.GasUsed)
+	if err != nil {
+		return nil, fmt.Errorf("parse receipt gas used: %w", err)
+	}
+	status, err := parseQuantity(r.Status)
+	if err != nil {
+		return nil, fmt.Errorf("parse receipt status: %w", err)
+	}
+
+	receipt := &domain.Receipt{
+		TxHash:      r.TransactionHash,
+		BlockNumber: blockNumber.Uint64(),
+		BlockHash:   r.BlockHash,
+		GasUsed:     gasUsed.Uint64(),
+		Status:      status.Uint64(),
+	}
+	if r.GasPrice != "" {
+		if receipt.EffectiveGasPrice, err = parseQuantity(r.GasPrice); err != nil {
+			return nil, fmt.Errorf("parse receipt effective gas price: %w", err)
+		}
+	}
+
+	for i := range r.Logs {
+		if !r.Logs[i].isContentRegistered(contract) {
+			continue
+		}
+		event, err := decodeContentRegistered(&r.Logs[i])
+		if err != nil {
+			return nil, err
+		}
+		receipt.Event = event
+		break
+	}
+	return receipt, nil
+}
+
+// waitForReceipt polls until txHash, or a fee-bumped replacement of it, is
+// mined with enough confirmations.
+func (s *RPCBlockchainService) waitForReceipt(ctx context.Context, txHash string) (*domain.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.receipts.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(s.receipts.PollInterval)
+	defer ticker.Stop()
+
+	hashes := []string{txHash}
+	lastBroadcast := time.Now()
+	for {
+		for _, h := range hashes {
+			receipt, err := s.pollReceipt(ctx, h)
+			if err != nil {
+				return nil, receiptWaitError(ctx, txHash, err)
+			}
+			if receipt == nil {
+				continue
+			}
+			if s.nonces != nil {
+				s.nonces.forget(hashes...)
+			}
+			if !receipt.Succeeded() {
+				return receipt, fmt.Errorf("transaction %s: %w", h, domain.ErrTransactionReverted)
+			}
+			return receipt, nil
+		}
+
+		if s.nonces != nil && s.receipts.RebroadcastAfter > 0 && time.Since(lastBroadcast) >= s.receipts.RebroadcastAfter {
+			replacement, err := s.nonces.replace(ctx, hashes[len(hashes)-1], s.receipts.FeeBumpPercent)
+			if err != nil {
+				return nil, receiptWaitError(ctx, txHash, err)
+			}
+			if replacement != "" {
+				hashes = append(hashes, replacement)
+			}
+			lastBroadcast = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, receiptWaitError(ctx, txHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *RPCBlockchainService) pollReceipt(ctx context.Context, txHash string) (*domain.Receipt, error) {
+	var raw *rpcReceipt
+	if err := s.rpc.call(ctx, "eth_getTransactionReceipt", []any{txHash}, &raw); err != nil {
+		return nil, fmt.Errorf("fetch receipt: %w", err)
+	}
+	if raw == nil || raw.BlockNumber == "" {
+		return nil, nil
+	}
+
+	receipt, err := raw.toDomain(s.toAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.receipts.Confirmations > 1 {
+		head, err := s.rpc.callQuantity(ctx, "eth_blockNumber", nil)
+		if err != nil {
+			return nil, fmt.Errorf("fetch block number: %w", err)
+		}
+		if head.Uint64() < receipt.BlockNumber || head.Uint64()-receipt.BlockNumber+1 < s.receipts.Confirmations {
+			return nil, nil
+		}
+	}
+
+	return receipt, nil
+}
+
+func receiptWaitError(ctx context.Context, txHash string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("transaction %s: %w", txHash, domain.ErrReceiptTimeout)
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"math/big"
+)
+
+func rlpEncodeBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+func rlpEncodeUint(v uint64) []byte {
+	return rlpEncodeBytes(new(big.Int).SetUint64(v).Bytes())
+}
+
+func rlpEncodeBigInt(v *big.Int) []byte {
+	if v == nil {
+		return rlpEncodeBytes(nil)
+	}
+	return rlpEncodeBytes(v.Bytes())
+}
+
+func rlpEncodeList(items ...[]byte) []byte {
+	size := 0
+	for _, item := range items {
+		size += len(item)
+	}
+	out := rlpHeader(0xc0, size)
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+func rlpHeader(offset byte, size int) []byte {
+	if size < 56 {
+		return []byte{offset + byte(size)}
+	}
+	lenBytes := new(big.Int).SetInt64(int64(size)).Bytes()
+	return append([]byte{offset + 55 + byte(len(lenBytes))}, lenBytes...)
+}
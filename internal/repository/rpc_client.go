@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how JSON-RPC calls are retried and how failing
+// endpoints are taken out of rotation.
+type RetryPolicy struct {
+	MaxAttempts    int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	RequestTimeout time.Duration
+	// Cooldown is how long an endpoint is skipped after a retryable failure.
+	// It doubles with every consecutive failure, up to 8x.
+	Cooldown time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		BaseDelay:      200 * time.Millisecond,
+		MaxDelay:       5 * time.Second,
+		RequestTimeout: 10 * time.Second,
+		Cooldown:       30 * time.Second,
+	}
+}
+
+// nonIdempotentMethods are only retried when the request provably never
+// reached the node, since a resend could broadcast a second transaction.
+var nonIdempotentMethods = map[string]bool{
+	"eth_sendTransaction":    true,
+	"eth_sendRawTransaction": true,
+}
+
+// retryableRPCCodes are JSON-RPC errors reported by overloaded or degraded
+// providers (EIP-1474 resource unavailable and limit exceeded, internal error).
+var retryableRPCCodes = map[int]bool{
+	-32002: true,
+	-32005: true,
+	-32603: true,
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return "rpc error: " + e.Message
+}
+
+// retryableError marks failures worth retrying on another endpoint; every
+// other error is fatal.
+type retryableError struct {
+	err error
+	// undelivered means the request never reached the endpoint.
+	undelivered bool
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+type rpcEndpoint struct {
+	url       string
+	failures  int
+	downUntil time.Time
+}
+
+type rpcClient struct {
+	httpClient *http.Client
+	policy     RetryPolicy
+
+	mu        sync.Mutex
+	endpoints []*rpcEndpoint
+}
+
+// newRPCClient accepts one or more comma-separated endpoint URLs, in order of
+// preference.
+func newRPCClient(urls string) *rpcClient {
+	c := &rpcClient{}
+	for _, u := range strings.Split(urls, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			c.endpoints = append(c.endpoints, &rpcEndpoint{url: u})
+		}
+	}
+	c.setPolicy(DefaultRetryPolicy())
+	return c
+}
+
+func (c *rpcClient) setPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	c.policy = policy
+	c.httpClient = &http.Client{Timeout: policy.RequestTimeout}
+}
+
+func (c *rpcClient) call(ctx context.Context, method string, params []any, result any) error {
+	payload, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal rpc request: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		ep := c.pick()
+		err := c.post(ctx, ep.url, payload, result)
+		if ctx.Err() == nil {
+			c.record(ep, err)
+		}
+
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) || attempt >= c.policy.MaxAttempts {
+			return err
+		}
+		if nonIdempotentMethods[method] && !retryable.undelivered {
+			return err
+		}
+		if werr := c.backoff(ctx, attempt); werr != nil {
+			return err
+		}
+	}
+}
+
+func (c *rpcClient) post(ctx context.Context, url string, payload []byte, result any) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create rpc request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		err = fmt.Errorf("send rpc request: %w", err)
+		if ctx.Err() != nil {
+			return err
+		}
+		var opErr *net.OpError
+		return &retryableError{err: err, undelivered: errors.As(err, &opErr) && opErr.Op == "dial"}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		io.Copy(io.Discard, resp.Body)
+		return &retryableError{err: fmt.Errorf("rpc http status %d", resp.StatusCode)}
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("decode rpc response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		if retryableRPCCodes[rpcResp.Error.Code] {
+			return &retryableError{err: rpcResp.Error}
+		}
+		return rpcResp.Error
+	}
+	if result == nil || len(rpcResp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("decode rpc result: %w", err)
+	}
+	return nil
+}
+
+// pick returns the first healthy endpoint in order of preference, or the one
+// that recovers soonest when all are cooling down.
+func (c *rpcClient) pick() *rpcEndpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	best := c.endpoints[0]
+	for _, ep := range c.endpoints {
+		if !ep.downUntil.After(now) {
+			return ep
+		}
+		if ep.downUntil.Before(best.downUntil) {
+			best = ep
+		}
+	}
+	return best
+}
+
+func (c *rpcClient) record(ep *rpcEndpoint, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var retryable *retryableError
+	if !errors.As(err, &retryable) {
+		ep.failures = 0
+		ep.downUntil = time.Time{}
+		return
+	}
+	ep.failures++
+	ep.downUntil = time.Now().Add(c.policy.Cooldown << min(ep.failures-1, 3))
+}
+
+// backoff sleeps for an exponentially growing delay with full jitter.
+func (c *rpcClient) backoff(ctx context.Context, attempt int) error {
+	delay := min(c.policy.BaseDelay<<min(attempt-1, 16), c.policy.MaxDelay)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(rand.N(delay) + 1)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *rpcClient) callQuantity(ctx context.Context, method string, params []any) (*big.Int, error) {
+	var raw string
+	if err := c.call(ctx, method, params, &raw); err != nil {
+		return nil, err
+	}
+	return parseQuantity(raw)
+}
+
+func parseQuantity(raw string) (*big.Int, error) {
+	digits := strings.TrimPrefix(raw, "0x")
+	if digits == "" || digits == raw {
+		return nil, fmt.Errorf("invalid hex quantity: %q", raw)
+	}
+	v, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity: %q", raw)
+	}
+	return v, nil
+}
+
+func encodeQuantity(v *big.Int) string {
+	return "0x" + v.Text(16)
+}
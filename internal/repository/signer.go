@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+type LocalSigner struct {
+	key     *secp256k1.PrivateKey
+	address string
+}
+
+func NewLocalSigner(privateKeyHex string) (*LocalSigner, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(privateKeyHex, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("decode private key: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("private key must be 32 bytes")
+	}
+
+	var scalar secp256k1.ModNScalar
+	if overflow := scalar.SetByteSlice(raw); overflow || scalar.IsZero() {
+		return nil, fmt.Errorf("private key is out of range")
+	}
+
+	key := secp256k1.NewPrivateKey(&scalar)
+	pub := key.PubKey().SerializeUncompressed()
+	address := "0x" + hex.EncodeToString(keccak256(pub[1:])[12:])
+
+	return &LocalSigner{key: key, address: address}, nil
+}
+
+func (s *LocalSigner) Address() string {
+	return s.address
+}
+
+func (s *LocalSigner) SignTx(tx *EVMTransaction) ([]byte, error) {
+	if tx.ChainID == nil || tx.ChainID.Sign() <= 0 {
+		return nil, fmt.Errorf("chain id is required to sign transactions")
+	}
+
+	hash, err := tx.signingHash()
+	if err != nil {
+		return nil, fmt.Errorf("sign transaction: %w", err)
+	}
+
+	sig := ecdsa.SignCompact(s.key, hash, false)
+	recoveryID := sig[0] - 27
+
+	raw, err := tx.encodeSigned(recoveryID, sig[1:33], sig[33:65])
+	if err != nil {
+		return nil, fmt.Errorf("sign transaction: %w", err)
+	}
+	return raw, nil
+}
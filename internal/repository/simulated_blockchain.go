@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+// ErrSimulatedFailure is returned by the simulated chain for injected failures.
+var ErrSimulatedFailure = errors.New("simulated chain failure")
+
+// SimulatedBlockchainService is an in-memory chain for development and tests.
+// Every registration is mined immediately in its own block, with transaction
+// and block hashes derived from the chain ID and block number, so a fresh
+// chain fed the same hashes always produces the same receipts.
+type SimulatedBlockchainService struct {
+	chainID  uint64
+	contract string
+
+	mu          sync.Mutex
+	block       uint64
+	events      map[string]*domain.RegistrationEvent
+	latency     time.Duration
+	failureRate float64
+	rand        *rand.Rand
+	now         func() time.Time
+}
+
+func NewSimulatedBlockchainService(chainID uint64, contractAddress string) *SimulatedBlockchainService {
+	return &SimulatedBlockchainService{
+		chainID:  chainID,
+		contract: contractAddress,
+		events:   map[string]*domain.RegistrationEvent{},
+		rand:     rand.New(rand.NewSource(1)),
+		now:      time.Now,
+	}
+}
+
+// WithLatency delays every call by d, or until the context is done.
+func (s *SimulatedBlockchainService) WithLatency(d time.Duration) *SimulatedBlockchainService {
+	s.latency = d
+	return s
+}
+
+// WithFailureRate fails that fraction of calls, between 0 and 1, with
+// ErrSimulatedFailure. Failures are drawn from a fixed seed.
+func (s *SimulatedBlockchainService) WithFailureRate(rate float64) *SimulatedBlockchainService {
+	s.failureRate = rate
+	return s
+}
+
+func (s *SimulatedBlockchainService) RegisterHash(ctx context.Context, hash, registrant string) (*domain.Receipt, error) {
+	data, err := normalizeHashToBytes(hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.simulateCall(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	contentHash := hex.EncodeToString(data)
+	if _, ok := s.events[contentHash]; ok {
+		return nil, fmt.Errorf("register %s: %w: hash already registered", contentHash, domain.ErrTransactionReverted)
+	}
+
+	s.block++
+	event := &domain.RegistrationEvent{
+		ContentHash:  contentHash,
+		Registrant:   registrant,
+		RegisteredAt: s.now().UTC(),
+		TxHash:       s.simulatedHash("tx", s.block),
+		BlockNumber:  s.block,
+		BlockHash:    s.simulatedHash("block", s.block),
+	}
+	s.events[contentHash] = event
+
+	return &domain.Receipt{
+		TxHash:      event.TxHash,
+		BlockNumber: event.BlockNumber,
+		BlockHash:   event.BlockHash,
+		Status:      domain.ReceiptStatusSuccess,
+		Event:       event,
+	}, nil
+}
+
+func (s *SimulatedBlockchainService) IsHashRegistered(ctx context.Context, hash string) (bool, error) {
+	event, err := s.FindRegistration(ctx, hash)
+	return event != nil, err
+}
+
+func (s *SimulatedBlockchainService) FindRegistration(ctx context.Context, hash string) (*domain.RegistrationEvent, error) {
+	data, err := normalizeHashToBytes(hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.simulateCall(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if event, ok := s.events[hex.EncodeToString(data)]; ok {
+		copied := *event
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *SimulatedBlockchainService) ChainID(context.Context) (uint64, error) {
+	return s.chainID, nil
+}
+
+func (s *SimulatedBlockchainService) ContractAddress() string {
+	return s.contract
+}
+
+func (s *SimulatedBlockchainService) LatestBlock(ctx context.Context) (uint64, error) {
+	if err := s.simulateCall(ctx); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.block, nil
+}
+
+func (s *SimulatedBlockchainService) BlockHash(ctx context.Context, number uint64) (string, error) {
+	if err := s.simulateCall(ctx); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if number > s.block {
+		return "", nil
+	}
+	return s.simulatedHash("block", number), nil
+}
+
+func (s *SimulatedBlockchainService) RegistrationEvents(ctx context.Context, fromBlock, toBlock uint64) ([]domain.RegistrationEvent, error) {
+	if err := s.simulateCall(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []domain.RegistrationEvent
+	for _, event := range s.events {
+		if event.BlockNumber >= fromBlock && event.BlockNumber <= toBlock {
+			events = append(events, *event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].BlockNumber < events[j].BlockNumber })
+	return events, nil
+}
+
+func (s *SimulatedBlockchainService) simulateCall(ctx context.Context) error {
+	if s.latency > 0 {
+		timer := time.NewTimer(s.latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	s.mu.Lock()
+	failed := s.failureRate > 0 && s.rand.Float64() < s.failureRate
+	s.mu.Unlock()
+	if failed {
+		return ErrSimulatedFailure
+	}
+	return nil
+}
+
+func (s *SimulatedBlockchainService) simulatedHash(kind string, block uint64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("simulated-%s:%d:%d", kind, s.chainID, block)))
+	return "0x" + hex.EncodeToString(sum[:])
+}
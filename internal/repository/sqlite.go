@@ -0,0 +1,875 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+// sqliteSchema mirrors the certificate, anchor, perceptual hash, video segment
+// and checkpoint tables from
+// migrations/ with SQLite types: ids are generated in Go, timestamps are
+// fixed-width UTC text so they sort and compare as strings, and proofs are
+// JSON arrays.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS certificates (
+    id                  TEXT PRIMARY KEY,
+    content_hash        TEXT NOT NULL UNIQUE,
+    pixel_hash          TEXT NOT NULL DEFAULT '',
+    registrant          TEXT NOT NULL,
+    tx_hash             TEXT NOT NULL,
+    block_number        INTEGER NOT NULL,
+    block_hash          TEXT NOT NULL DEFAULT '',
+    chain_mismatch      TEXT NOT NULL DEFAULT '',
+    status              TEXT NOT NULL DEFAULT 'confirmed'
+        CHECK (status IN ('pending', 'submitted', 'confirmed', 'failed', 'reorged')),
+    last_error          TEXT NOT NULL DEFAULT '',
+    attempts            INTEGER NOT NULL DEFAULT 0,
+    merkle_root         TEXT,
+    leaf_index          INTEGER,
+    tree_size           INTEGER,
+    merkle_proof        TEXT,
+    claimed_until       TEXT,
+    gas_used            INTEGER,
+    effective_gas_price TEXT,
+    timestamp_token     BLOB,
+    created_at          TEXT NOT NULL,
+    updated_at          TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_certificates_outbox ON certificates(updated_at)
+    WHERE status IN ('pending', 'submitted', 'reorged');
+CREATE INDEX IF NOT EXISTS idx_certificates_merkle_root ON certificates(merkle_root)
+    WHERE merkle_root IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_certificates_confirmed_block ON certificates(block_number)
+    WHERE status = 'confirmed';
+CREATE INDEX IF NOT EXISTS idx_certificates_pixel_hash ON certificates(pixel_hash)
+    WHERE pixel_hash <> '';
+
+CREATE TABLE IF NOT EXISTS indexer_checkpoints (
+    name         TEXT PRIMARY KEY,
+    block_number INTEGER NOT NULL,
+    updated_at   TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS anchors (
+    certificate_id   TEXT NOT NULL REFERENCES certificates(id) ON DELETE CASCADE,
+    chain_id         INTEGER NOT NULL,
+    contract_address TEXT NOT NULL,
+    tx_hash          TEXT NOT NULL,
+    block_number     INTEGER NOT NULL,
+    block_hash       TEXT NOT NULL DEFAULT '',
+    merkle_root      TEXT,
+    leaf_index       INTEGER,
+    tree_size        INTEGER,
+    merkle_proof     TEXT,
+    created_at       TEXT NOT NULL,
+    PRIMARY KEY (certificate_id, chain_id, contract_address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_anchors_chain ON anchors(chain_id, contract_address);
+
+CREATE TABLE IF NOT EXISTS perceptual_hashes (
+    certificate_id TEXT NOT NULL REFERENCES certificates(id) ON DELETE CASCADE,
+    algorithm      TEXT NOT NULL,
+    hash           INTEGER NOT NULL,
+    band0          INTEGER NOT NULL,
+    band1          INTEGER NOT NULL,
+    band2          INTEGER NOT NULL,
+    band3          INTEGER NOT NULL,
+    PRIMARY KEY (certificate_id, algorithm)
+);
+
+CREATE INDEX IF NOT EXISTS idx_perceptual_hashes_band0 ON perceptual_hashes(algorithm, band0);
+CREATE INDEX IF NOT EXISTS idx_perceptual_hashes_band1 ON perceptual_hashes(algorithm, band1);
+CREATE INDEX IF NOT EXISTS idx_perceptual_hashes_band2 ON perceptual_hashes(algorithm, band2);
+CREATE INDEX IF NOT EXISTS idx_perceptual_hashes_band3 ON perceptual_hashes(algorithm, band3);
+
+CREATE TABLE IF NOT EXISTS video_segments (
+    certificate_id TEXT NOT NULL REFERENCES certificates(id) ON DELETE CASCADE,
+    position       INTEGER NOT NULL,
+    start_ms       INTEGER NOT NULL,
+    end_ms         INTEGER NOT NULL,
+    algorithm      TEXT NOT NULL,
+    hash           INTEGER NOT NULL,
+    band0          INTEGER NOT NULL,
+    band1          INTEGER NOT NULL,
+    band2          INTEGER NOT NULL,
+    band3          INTEGER NOT NULL,
+    PRIMARY KEY (certificate_id, position, algorithm)
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_segments_band0 ON video_segments(algorithm, band0);
+CREATE INDEX IF NOT EXISTS idx_video_segments_band1 ON video_segments(algorithm, band1);
+CREATE INDEX IF NOT EXISTS idx_video_segments_band2 ON video_segments(algorithm, band2);
+CREATE INDEX IF NOT EXISTS idx_video_segments_band3 ON video_segments(algorithm, band3);
+`
+
+// sqliteTimeLayout has a fixed width so stored timestamps order correctly as
+// text.
+const sqliteTimeLayout = "2006-01-02 15:04:05.000000000"
+
+// OpenSQLite opens or creates the database at path, which may be ":memory:",
+// and applies the schema. SQLite allows a single writer, so the pool is
+// limited to one connection; that also keeps ":memory:" databases shared.
+func OpenSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("apply sqlite schema: %w", err)
+	}
+	return db, nil
+}
+
+type SQLiteCertificateRepo struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+func NewSQLiteCertificateRepo(db *sql.DB) *SQLiteCertificateRepo {
+	return &SQLiteCertificateRepo{db: db, now: time.Now}
+}
+
+func (r *SQLiteCertificateRepo) Save(ctx context.Context, cert *domain.Certificate) error {
+	const q = `
+		INSERT INTO certificates (id, content_hash, pixel_hash, registrant, tx_hash, block_number, block_hash, status, last_error, attempts, chain_mismatch, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	id, err := newUUID()
+	if err != nil {
+		return fmt.Errorf("sqlite save: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite save: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, q,
+		id,
+		cert.ContentHash,
+		cert.PixelHash,
+		cert.Registrant,
+		cert.TxHash,
+		int64(cert.BlockNumber),
+		cert.BlockHash,
+		cert.Status,
+		cert.LastError,
+		cert.Attempts,
+		cert.ChainMismatch,
+		formatSQLiteTime(cert.CreatedAt),
+		formatSQLiteTime(r.now()),
+	)
+	if isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return fmt.Errorf("sqlite save: %w", domain.ErrAlreadyCertified)
+	}
+	if err != nil {
+		return fmt.Errorf("sqlite save: %w", err)
+	}
+
+	const insertHash = `INSERT INTO perceptual_hashes (` + perceptualHashColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	for _, alg := range cert.PerceptualHashes.Algorithms() {
+		if _, err := tx.ExecContext(ctx, insertHash, perceptualHashRow(id, alg, cert.PerceptualHashes[alg])...); err != nil {
+			return fmt.Errorf("sqlite save perceptual hash: %w", err)
+		}
+	}
+	const insertSegment = `INSERT INTO video_segments (` + videoSegmentColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	for _, row := range videoSegmentRows(id, cert.Fingerprint) {
+		if _, err := tx.ExecContext(ctx, insertSegment, row...); err != nil {
+			return fmt.Errorf("sqlite save video segment: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite save: %w", err)
+	}
+	cert.ID = id
+	return nil
+}
+
+func (r *SQLiteCertificateRepo) UpdateAnchorState(ctx context.Context, cert *domain.Certificate) error {
+	const q = `
+		UPDATE certificates
+		SET registrant = ?, tx_hash = ?, block_number = ?, block_hash = ?,
+		    status = ?, last_error = ?, attempts = ?,
+		    merkle_root = ?, leaf_index = ?, tree_size = ?, merkle_proof = ?,
+		    gas_used = ?, effective_gas_price = ?, timestamp_token = ?,
+		    updated_at = ?
+		WHERE id = ?`
+
+	root, index, treeSize, proof, err := sqliteBatchColumns(cert.Batch)
+	if err != nil {
+		return fmt.Errorf("sqlite update anchor state: %w", err)
+	}
+	var (
+		gasUsed  sql.NullInt64
+		gasPrice sql.NullString
+	)
+	if f := cert.Fee; f != nil {
+		gasUsed = sql.NullInt64{Int64: int64(f.GasUsed), Valid: true}
+		gasPrice = sql.NullString{String: f.EffectiveGasPrice.String(), Valid: true}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite update anchor state: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q,
+		cert.Registrant,
+		cert.TxHash,
+		int64(cert.BlockNumber),
+		cert.BlockHash,
+		cert.Status,
+		cert.LastError,
+		cert.Attempts,
+		root,
+		index,
+		treeSize,
+		proof,
+		gasUsed,
+		gasPrice,
+		cert.TimestampToken,
+		formatSQLiteTime(r.now()),
+		cert.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite update anchor state: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("sqlite update anchor state: %w", domain.ErrNotFound)
+	}
+	if err := r.syncAnchors(ctx, tx, cert); err != nil {
+		return fmt.Errorf("sqlite update anchor state: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite update anchor state: %w", err)
+	}
+	return nil
+}
+
+// syncAnchors stores the certificate's anchors and removes the ones it no
+// longer has, like its Postgres counterpart.
+func (r *SQLiteCertificateRepo) syncAnchors(ctx context.Context, tx *sql.Tx, cert *domain.Certificate) error {
+	keys := make([]string, len(cert.Anchors))
+	for i, a := range cert.Anchors {
+		if err := r.upsertAnchor(ctx, tx, cert.ID, a); err != nil {
+			return err
+		}
+		keys[i] = strconv.FormatUint(a.ChainID, 10) + ":" + strings.ToLower(a.ContractAddress)
+	}
+	encoded, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("encode anchor keys: %w", err)
+	}
+
+	const q = `
+		DELETE FROM anchors
+		WHERE certificate_id = ? AND (chain_id || ':' || contract_address) NOT IN (SELECT value FROM json_each(?))`
+
+	if _, err := tx.ExecContext(ctx, q, cert.ID, string(encoded)); err != nil {
+		return fmt.Errorf("delete anchors: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteCertificateRepo) SaveAnchor(ctx context.Context, certID string, anchor domain.Anchor) error {
+	err := r.upsertAnchor(ctx, r.db, certID, anchor)
+	if isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY) {
+		return fmt.Errorf("sqlite save anchor: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("sqlite save anchor: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteCertificateRepo) upsertAnchor(ctx context.Context, db execer, certID string, a domain.Anchor) error {
+	const q = `
+		INSERT INTO anchors (` + anchorColumns + `, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (certificate_id, chain_id, contract_address) DO UPDATE
+		SET tx_hash = excluded.tx_hash, block_number = excluded.block_number, block_hash = excluded.block_hash,
+		    merkle_root = excluded.merkle_root, leaf_index = excluded.leaf_index,
+		    tree_size = excluded.tree_size, merkle_proof = excluded.merkle_proof`
+
+	root, index, treeSize, proof, err := sqliteBatchColumns(a.Batch)
+	if err != nil {
+		return fmt.Errorf("upsert anchor: %w", err)
+	}
+	_, err = db.ExecContext(ctx, q,
+		certID,
+		int64(a.ChainID),
+		strings.ToLower(a.ContractAddress),
+		a.TxHash,
+		int64(a.BlockNumber),
+		a.BlockHash,
+		root,
+		index,
+		treeSize,
+		proof,
+		formatSQLiteTime(r.now()),
+	)
+	if err != nil {
+		return fmt.Errorf("upsert anchor: %w", err)
+	}
+	return nil
+}
+
+// FindUnanchored returns confirmed certificates missing from the chain, in
+// the order they were anchored on the primary chain.
+func (r *SQLiteCertificateRepo) FindUnanchored(ctx context.Context, chainID uint64, contract string, limit int) ([]*domain.Certificate, error) {
+	q := `
+		SELECT ` + certificateColumns + ` FROM certificates c
+		WHERE status = 'confirmed'
+		  AND NOT EXISTS (
+			SELECT 1 FROM anchors a
+			WHERE a.certificate_id = c.id AND a.chain_id = ? AND a.contract_address = ?
+		  )
+		ORDER BY block_number, created_at
+		LIMIT ?`
+
+	certs, err := r.queryCertificates(ctx, q, int64(chainID), strings.ToLower(contract), limit)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find unanchored: %w", err)
+	}
+	return certs, nil
+}
+
+// loadAnchors attaches the stored anchors to the certificates.
+func (r *SQLiteCertificateRepo) loadAnchors(ctx context.Context, certs ...*domain.Certificate) error {
+	if len(certs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(certs))
+	byID := make(map[string]*domain.Certificate, len(certs))
+	for i, c := range certs {
+		ids[i] = c.ID
+		byID[c.ID] = c
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("load anchors: %w", err)
+	}
+
+	q := `SELECT ` + anchorColumns + ` FROM anchors WHERE certificate_id IN (SELECT value FROM json_each(?)) ORDER BY created_at, chain_id`
+
+	rows, err := r.db.QueryContext(ctx, q, string(encoded))
+	if err != nil {
+		return fmt.Errorf("load anchors: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			certID          string
+			a               domain.Anchor
+			root, proof     sql.NullString
+			index, treeSize sql.NullInt64
+		)
+		err := rows.Scan(&certID, &a.ChainID, &a.ContractAddress, &a.TxHash, &a.BlockNumber, &a.BlockHash,
+			&root, &index, &treeSize, &proof)
+		if err != nil {
+			return fmt.Errorf("load anchors scan: %w", err)
+		}
+		if a.Batch, err = sqliteBatchFromColumns(root, index, treeSize, proof); err != nil {
+			return fmt.Errorf("load anchors scan: %w", err)
+		}
+		if c := byID[certID]; c != nil {
+			c.Anchors = append(c.Anchors, a)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load anchors rows: %w", err)
+	}
+	return nil
+}
+
+// loadPerceptualHashes attaches the stored perceptual hashes to the
+// certificates.
+func (r *SQLiteCertificateRepo) loadPerceptualHashes(ctx context.Context, certs ...*domain.Certificate) error {
+	if len(certs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(certs))
+	for i, c := range certs {
+		ids[i] = c.ID
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("load perceptual hashes: %w", err)
+	}
+
+	q := `SELECT certificate_id, algorithm, hash FROM perceptual_hashes WHERE certificate_id IN (SELECT value FROM json_each(?))`
+
+	hashes, err := r.queryPerceptualHashes(ctx, q, string(encoded))
+	if err != nil {
+		return fmt.Errorf("load perceptual hashes: %w", err)
+	}
+	for _, c := range certs {
+		c.PerceptualHashes = hashes[c.ID]
+	}
+	return nil
+}
+
+// loadFingerprints attaches the stored video fingerprints to the
+// certificates.
+func (r *SQLiteCertificateRepo) loadFingerprints(ctx context.Context, certs ...*domain.Certificate) error {
+	if len(certs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(certs))
+	for i, c := range certs {
+		ids[i] = c.ID
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("load fingerprints: %w", err)
+	}
+
+	q := `SELECT certificate_id, position, start_ms, end_ms, algorithm, hash FROM video_segments WHERE certificate_id IN (SELECT value FROM json_each(?))`
+
+	fingerprints, err := r.queryVideoSegments(ctx, q, string(encoded))
+	if err != nil {
+		return fmt.Errorf("load fingerprints: %w", err)
+	}
+	for _, c := range certs {
+		c.Fingerprint = fingerprints[c.ID]
+	}
+	return nil
+}
+
+func (r *SQLiteCertificateRepo) queryVideoSegments(ctx context.Context, q string, args ...any) (map[string]domain.VideoFingerprint, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanVideoSegments(rows)
+}
+
+func (r *SQLiteCertificateRepo) queryPerceptualHashes(ctx context.Context, q string, args ...any) (map[string]domain.PerceptualHashes, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanPerceptualHashes(rows)
+}
+
+// ClaimUnfinished leases certificates left unfinished for staleAfter to a
+// single worker for another staleAfter, sharing the lease with ClaimPending.
+func (r *SQLiteCertificateRepo) ClaimUnfinished(ctx context.Context, limit int, staleAfter time.Duration) ([]*domain.Certificate, error) {
+	q := `
+		UPDATE certificates
+		SET claimed_until = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM certificates
+			WHERE status IN ('pending', 'submitted', 'reorged')
+			  AND updated_at < ?
+			  AND (claimed_until IS NULL OR claimed_until < ?)
+			ORDER BY updated_at
+			LIMIT ?
+		)
+		RETURNING ` + certificateColumns
+
+	now := r.now()
+	certs, err := r.queryCertificates(ctx, q,
+		formatSQLiteTime(now.Add(staleAfter)), formatSQLiteTime(now),
+		formatSQLiteTime(now.Add(-staleAfter)), formatSQLiteTime(now), limit)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite claim unfinished: %w", err)
+	}
+	return certs, nil
+}
+
+// ClaimPending leases pending certificates to a single batcher until the lease
+// expires, oldest first.
+func (r *SQLiteCertificateRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*domain.Certificate, error) {
+	q := `
+		UPDATE certificates
+		SET claimed_until = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM certificates
+			WHERE status = 'pending'
+			  AND (claimed_until IS NULL OR claimed_until < ?)
+			ORDER BY created_at
+			LIMIT ?
+		)
+		RETURNING ` + certificateColumns
+
+	now := r.now()
+	certs, err := r.queryCertificates(ctx, q,
+		formatSQLiteTime(now.Add(lease)), formatSQLiteTime(now), formatSQLiteTime(now), limit)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite claim pending: %w", err)
+	}
+	sortByCreatedAt(certs)
+	return certs, nil
+}
+
+func (r *SQLiteCertificateRepo) FindByMerkleRoot(ctx context.Context, root string) ([]*domain.Certificate, error) {
+	q := `SELECT ` + certificateColumns + ` FROM certificates WHERE merkle_root = ? ORDER BY leaf_index`
+
+	certs, err := r.queryCertificates(ctx, q, root)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find by merkle root: %w", err)
+	}
+	return certs, nil
+}
+
+func (r *SQLiteCertificateRepo) FindConfirmedSince(ctx context.Context, fromBlock uint64) ([]*domain.Certificate, error) {
+	q := `SELECT ` + certificateColumns + ` FROM certificates WHERE status = 'confirmed' AND block_number >= ? ORDER BY block_number`
+
+	certs, err := r.queryCertificates(ctx, q, int64(fromBlock))
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find confirmed since: %w", err)
+	}
+	return certs, nil
+}
+
+func (r *SQLiteCertificateRepo) queryCertificates(ctx context.Context, q string, args ...any) ([]*domain.Certificate, error) {
+	certs, err := r.scanCertificates(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadRelated(ctx, certs...); err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+// loadRelated attaches anchors, perceptual hashes and video fingerprints to
+// the certificates.
+func (r *SQLiteCertificateRepo) loadRelated(ctx context.Context, certs ...*domain.Certificate) error {
+	if err := r.loadAnchors(ctx, certs...); err != nil {
+		return err
+	}
+	if err := r.loadPerceptualHashes(ctx, certs...); err != nil {
+		return err
+	}
+	return r.loadFingerprints(ctx, certs...)
+}
+
+func (r *SQLiteCertificateRepo) scanCertificates(ctx context.Context, q string, args ...any) ([]*domain.Certificate, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var certs []*domain.Certificate
+	for rows.Next() {
+		cert, err := scanSQLiteCertificate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return certs, nil
+}
+
+func (r *SQLiteCertificateRepo) FindByHash(ctx context.Context, contentHash string) (*domain.Certificate, error) {
+	cert, err := r.findOne(ctx, `content_hash = ?`, contentHash)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find by hash: %w", err)
+	}
+	return cert, nil
+}
+
+func (r *SQLiteCertificateRepo) FindByPixelHash(ctx context.Context, pixelHash string) (*domain.Certificate, error) {
+	if pixelHash == "" {
+		return nil, nil
+	}
+	cert, err := r.findOne(ctx, `pixel_hash = ? ORDER BY created_at, id LIMIT 1`, pixelHash)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find by pixel hash: %w", err)
+	}
+	return cert, nil
+}
+
+func (r *SQLiteCertificateRepo) FindByID(ctx context.Context, id string) (*domain.Certificate, error) {
+	cert, err := r.findOne(ctx, `id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find by id: %w", err)
+	}
+	return cert, nil
+}
+
+func (r *SQLiteCertificateRepo) findOne(ctx context.Context, where string, arg any) (*domain.Certificate, error) {
+	q := `SELECT ` + certificateColumns + ` FROM certificates WHERE ` + where
+
+	cert, err := scanSQLiteCertificate(r.db.QueryRowContext(ctx, q, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadRelated(ctx, cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// FindByPerceptualHash returns the certificates whose combined distance to
+// hashes is within maxDistance, nearest first, probing the indexed hash bands
+// like its Postgres counterpart.
+func (r *SQLiteCertificateRepo) FindByPerceptualHash(ctx context.Context, hashes domain.PerceptualHashes, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	q := `SELECT certificate_id, algorithm, hash FROM perceptual_hashes`
+
+	var args []any
+	if probes, ok := domain.PerceptualProbes(hashes, maxDistance); ok {
+		filter, filterArgs, err := sqliteBandFilter(probes)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite find by perceptual hash: %w", err)
+		}
+		q += ` WHERE certificate_id IN (SELECT certificate_id FROM perceptual_hashes WHERE ` + filter + `)`
+		args = filterArgs
+	}
+
+	found, err := r.queryPerceptualHashes(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find by perceptual hash: %w", err)
+	}
+	candidates := make(map[string]*domain.Certificate, len(found))
+	for id, h := range found {
+		candidates[id] = &domain.Certificate{ID: id, PerceptualHashes: h}
+	}
+	matches, err := r.findSimilar(ctx, candidates, func(c *domain.Certificate) (domain.SimilarCertificate, bool) {
+		return domain.MatchSimilar(hashes, c, maxDistance)
+	}, limit)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find by perceptual hash: %w", err)
+	}
+	return matches, nil
+}
+
+// FindByVideoFingerprint returns the certified videos clip is a copy of, most
+// confident first, probing the indexed segment hash bands like its Postgres
+// counterpart.
+func (r *SQLiteCertificateRepo) FindByVideoFingerprint(ctx context.Context, clip domain.VideoFingerprint, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+	if len(clip) == 0 {
+		return nil, nil
+	}
+	q := `SELECT certificate_id, position, start_ms, end_ms, algorithm, hash FROM video_segments`
+
+	var args []any
+	if probes, ok := domain.VideoProbes(clip, maxDistance); ok {
+		filter, filterArgs, err := sqliteBandFilter(probes)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite find by video fingerprint: %w", err)
+		}
+		q += ` WHERE certificate_id IN (SELECT certificate_id FROM video_segments WHERE ` + filter + `)`
+		args = filterArgs
+	}
+
+	found, err := r.queryVideoSegments(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find by video fingerprint: %w", err)
+	}
+	candidates := make(map[string]*domain.Certificate, len(found))
+	for id, fp := range found {
+		candidates[id] = &domain.Certificate{ID: id, Fingerprint: fp}
+	}
+	matches, err := r.findSimilar(ctx, candidates, func(c *domain.Certificate) (domain.SimilarCertificate, bool) {
+		return domain.MatchVideo(clip, c, maxDistance)
+	}, limit)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite find by video fingerprint: %w", err)
+	}
+	return matches, nil
+}
+
+func (r *SQLiteCertificateRepo) findSimilar(ctx context.Context, candidates map[string]*domain.Certificate, match matchFunc, limit int) ([]domain.SimilarCertificate, error) {
+	ids := matchingIDs(candidates, match)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := r.scanCertificates(ctx, `SELECT `+certificateColumns+` FROM certificates WHERE id IN (SELECT value FROM json_each(?))`, string(encoded))
+	if err != nil {
+		return nil, err
+	}
+	matches := rankMatches(candidates, certs, match, limit)
+	if err := r.loadAnchors(ctx, similarCertificates(matches)...); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func (r *SQLiteCertificateRepo) FlagChainMismatch(ctx context.Context, id, reason string) error {
+	const q = `UPDATE certificates SET chain_mismatch = ? WHERE id = ?`
+
+	res, err := r.db.ExecContext(ctx, q, reason, id)
+	if err != nil {
+		return fmt.Errorf("sqlite flag chain mismatch: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("sqlite flag chain mismatch: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteCertificateRepo) LoadCheckpoint(ctx context.Context, name string) (uint64, bool, error) {
+	const q = `SELECT block_number FROM indexer_checkpoints WHERE name = ?`
+
+	var block int64
+	err := r.db.QueryRowContext(ctx, q, name).Scan(&block)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("sqlite load checkpoint: %w", err)
+	}
+	return uint64(block), true, nil
+}
+
+func (r *SQLiteCertificateRepo) SaveCheckpoint(ctx context.Context, name string, block uint64) error {
+	const q = `
+		INSERT INTO indexer_checkpoints (name, block_number, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET block_number = excluded.block_number, updated_at = excluded.updated_at`
+
+	if _, err := r.db.ExecContext(ctx, q, name, int64(block), formatSQLiteTime(r.now())); err != nil {
+		return fmt.Errorf("sqlite save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func scanSQLiteCertificate(row rowScanner) (*domain.Certificate, error) {
+	cert := &domain.Certificate{}
+	var (
+		root, proof     sql.NullString
+		index, treeSize sql.NullInt64
+		gasUsed         sql.NullInt64
+		gasPrice        sql.NullString
+		createdAt       string
+	)
+	err := row.Scan(
+		&cert.ID,
+		&cert.ContentHash,
+		&cert.PixelHash,
+		&cert.Registrant,
+		&cert.TxHash,
+		&cert.BlockNumber,
+		&cert.BlockHash,
+		&cert.Status,
+		&cert.LastError,
+		&cert.Attempts,
+		&cert.ChainMismatch,
+		&root,
+		&index,
+		&treeSize,
+		&proof,
+		&gasUsed,
+		&gasPrice,
+		&cert.TimestampToken,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if cert.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+		return nil, fmt.Errorf("invalid created_at %q: %w", createdAt, err)
+	}
+	if cert.Batch, err = sqliteBatchFromColumns(root, index, treeSize, proof); err != nil {
+		return nil, err
+	}
+	if gasPrice.Valid {
+		price, ok := new(big.Int).SetString(gasPrice.String, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid effective gas price %q", gasPrice.String)
+		}
+		cert.Fee = &domain.AnchorFee{GasUsed: uint64(gasUsed.Int64), EffectiveGasPrice: price}
+	}
+	return cert, nil
+}
+
+// sqliteBandFilter is bandFilter with the probe values passed as JSON arrays.
+func sqliteBandFilter(probes map[domain.HashAlgorithm][domain.PerceptualHashBands][]uint16) (string, []any, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	for _, alg := range probedAlgorithms(probes) {
+		conds = append(conds, `(algorithm = ? AND (band0 IN (SELECT value FROM json_each(?)) OR band1 IN (SELECT value FROM json_each(?))
+		    OR band2 IN (SELECT value FROM json_each(?)) OR band3 IN (SELECT value FROM json_each(?))))`)
+		args = append(args, string(alg))
+		for _, values := range probes[alg] {
+			encoded, err := json.Marshal(bandValues(values))
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, string(encoded))
+		}
+	}
+	return strings.Join(conds, ` OR `), args, nil
+}
+
+func sqliteBatchColumns(b *domain.MerkleBatch) (root sql.NullString, index, treeSize sql.NullInt64, proof sql.NullString, err error) {
+	if b == nil {
+		return root, index, treeSize, proof, nil
+	}
+	encoded, err := json.Marshal(b.Proof)
+	if err != nil {
+		return root, index, treeSize, proof, fmt.Errorf("encode merkle proof: %w", err)
+	}
+	root = sql.NullString{String: b.Root, Valid: true}
+	index = sql.NullInt64{Int64: int64(b.LeafIndex), Valid: true}
+	treeSize = sql.NullInt64{Int64: int64(b.TreeSize), Valid: true}
+	proof = sql.NullString{String: string(encoded), Valid: true}
+	return root, index, treeSize, proof, nil
+}
+
+func sqliteBatchFromColumns(root sql.NullString, index, treeSize sql.NullInt64, proof sql.NullString) (*domain.MerkleBatch, error) {
+	var path []string
+	if proof.Valid {
+		if err := json.Unmarshal([]byte(proof.String), &path); err != nil {
+			return nil, fmt.Errorf("invalid merkle proof: %w", err)
+		}
+	}
+	return batchFromColumns(root, index, treeSize, path), nil
+}
+
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func isSQLiteError(err error, codes ...int) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	for _, code := range codes {
+		if sqliteErr.Code() == code {
+			return true
+		}
+	}
+	return false
+}
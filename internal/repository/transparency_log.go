@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// PostgresTransparencyLog anchors hashes as leaves of an append-only RFC 6962
+// Merkle log kept in Postgres instead of on a chain. Each append stores the
+// complete subtrees it creates and a tree head signed with the log's Ed25519
+// key, so roots and proofs for any published size are served from stored
+// nodes.
+type PostgresTransparencyLog struct {
+	db  *sql.DB
+	key ed25519.PrivateKey
+	now func() time.Time
+}
+
+func NewPostgresTransparencyLog(db *sql.DB, key ed25519.PrivateKey) (*PostgresTransparencyLog, error) {
+	if db == nil {
+		return nil, fmt.Errorf("transparency log requires a database")
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("transparency log signing key must be %d bytes", ed25519.PrivateKeySize)
+	}
+	return &PostgresTransparencyLog{db: db, key: key, now: time.Now}, nil
+}
+
+func (l *PostgresTransparencyLog) PublicKey() ed25519.PublicKey {
+	return l.key.Public().(ed25519.PublicKey)
+}
+
+// RegisterHash appends hash to the log and signs the new tree head. A hash
+// that is already logged keeps its original entry. The receipt's block number
+// is the leaf index.
+func (l *PostgresTransparencyLog) RegisterHash(ctx context.Context, hash, registrant string) (*domain.Receipt, error) {
+	raw, err := normalizeHashToBytes(hash)
+	if err != nil {
+		return nil, err
+	}
+	contentHash := hex.EncodeToString(raw)
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("transparency log append: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Appends are serialized so leaf indices, nodes and tree heads advance
+	// together; readers are not blocked.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE log_tree_heads IN EXCLUSIVE MODE`); err != nil {
+		return nil, fmt.Errorf("transparency log append: %w", err)
+	}
+	existing, err := findLogEntry(ctx, tx, contentHash)
+	if err != nil {
+		return nil, fmt.Errorf("transparency log append: %w", err)
+	}
+	if existing != nil {
+		return logReceipt(existing), nil
+	}
+
+	var size uint64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(tree_size), 0) FROM log_tree_heads`).Scan(&size); err != nil {
+		return nil, fmt.Errorf("transparency log append: %w", err)
+	}
+
+	nodes := logNodeReader(ctx, tx)
+	appended, err := nodes.Append(size, domain.MerkleLeafHash(raw))
+	if err != nil {
+		return nil, fmt.Errorf("transparency log append: %w", err)
+	}
+	for _, n := range appended {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO log_nodes (level, node_index, hash) VALUES ($1, $2, $3)`, n.Level, n.Index, n.Hash); err != nil {
+			return nil, fmt.Errorf("transparency log append: %w", err)
+		}
+	}
+	root, err := nodes.Root(size + 1)
+	if err != nil {
+		return nil, fmt.Errorf("transparency log append: %w", err)
+	}
+	head := domain.SignTreeHead(l.key, size+1, root, l.now())
+
+	const insertEntry = `
+		INSERT INTO log_entries (leaf_index, content_hash, registrant, created_at)
+		VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, insertEntry, size, contentHash, registrant, head.Timestamp); err != nil {
+		return nil, fmt.Errorf("transparency log append: %w", err)
+	}
+	const insertHead = `
+		INSERT INTO log_tree_heads (tree_size, root_hash, timestamp_ms, signature)
+		VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, insertHead, head.TreeSize, head.RootHash, head.Timestamp.UnixMilli(), head.Signature); err != nil {
+		return nil, fmt.Errorf("transparency log append: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transparency log append: %w", err)
+	}
+
+	return logReceipt(&domain.RegistrationEvent{
+		ContentHash:  contentHash,
+		Registrant:   registrant,
+		RegisteredAt: head.Timestamp,
+		BlockNumber:  size,
+	}), nil
+}
+
+func (l *PostgresTransparencyLog) IsHashRegistered(ctx context.Context, hash string) (bool, error) {
+	event, err := l.FindRegistration(ctx, hash)
+	return event != nil, err
+}
+
+func (l *PostgresTransparencyLog) FindRegistration(ctx context.Context, hash string) (*domain.RegistrationEvent, error) {
+	raw, err := normalizeHashToBytes(hash)
+	if err != nil {
+		return nil, err
+	}
+	event, err := findLogEntry(ctx, l.db, hex.EncodeToString(raw))
+	if err != nil {
+		return nil, fmt.Errorf("transparency log lookup: %w", err)
+	}
+	return event, nil
+}
+
+// LatestTreeHead signs a tree head for the empty tree until the first append.
+func (l *PostgresTransparencyLog) LatestTreeHead(ctx context.Context) (*domain.SignedTreeHead, error) {
+	head, err := scanTreeHead(l.db.QueryRowContext(ctx, `
+		SELECT tree_size, root_hash, timestamp_ms, signature
+		FROM log_tree_heads ORDER BY tree_size DESC LIMIT 1`))
+	if err == sql.ErrNoRows {
+		return domain.SignTreeHead(l.key, 0, domain.MerkleRoot(nil), l.now()), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transparency log tree head: %w", err)
+	}
+	return head, nil
+}
+
+func (l *PostgresTransparencyLog) TreeHead(ctx context.Context, treeSize uint64) (*domain.SignedTreeHead, error) {
+	head, err := scanTreeHead(l.db.QueryRowContext(ctx, `
+		SELECT tree_size, root_hash, timestamp_ms, signature
+		FROM log_tree_heads WHERE tree_size = $1`, treeSize))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("transparency log tree head: %w: no tree head of size %d", domain.ErrInvalidTreeSize, treeSize)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transparency log tree head: %w", err)
+	}
+	return head, nil
+}
+
+func (l *PostgresTransparencyLog) InclusionProof(ctx context.Context, hash string, treeSize uint64) (*domain.LogInclusionProof, error) {
+	event, err := l.FindRegistration(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, fmt.Errorf("transparency log inclusion: %w", domain.ErrNotInLog)
+	}
+	path, err := logNodeReader(ctx, l.db).InclusionProof(event.BlockNumber, treeSize)
+	if err != nil {
+		return nil, fmt.Errorf("transparency log inclusion: %w", err)
+	}
+	return &domain.LogInclusionProof{LeafIndex: event.BlockNumber, TreeSize: treeSize, AuditPath: domain.EncodeMerklePath(path)}, nil
+}
+
+func (l *PostgresTransparencyLog) ConsistencyProof(ctx context.Context, firstSize, secondSize uint64) (*domain.LogConsistencyProof, error) {
+	path, err := logNodeReader(ctx, l.db).ConsistencyProof(firstSize, secondSize)
+	if err != nil {
+		return nil, fmt.Errorf("transparency log consistency: %w", err)
+	}
+	return &domain.LogConsistencyProof{FirstSize: firstSize, SecondSize: secondSize, Path: domain.EncodeMerklePath(path)}, nil
+}
+
+func logNodeReader(ctx context.Context, q rowQueryer) domain.MerkleNodeReader {
+	return func(level uint, index uint64) ([]byte, error) {
+		var hash []byte
+		err := q.QueryRowContext(ctx, `SELECT hash FROM log_nodes WHERE level = $1 AND node_index = $2`, level, index).Scan(&hash)
+		if err != nil {
+			return nil, fmt.Errorf("node %d/%d: %w", level, index, err)
+		}
+		return hash, nil
+	}
+}
+
+func findLogEntry(ctx context.Context, q rowQueryer, contentHash string) (*domain.RegistrationEvent, error) {
+	event := &domain.RegistrationEvent{ContentHash: contentHash}
+	err := q.QueryRowContext(ctx, `
+		SELECT leaf_index, registrant, created_at
+		FROM log_entries WHERE content_hash = $1`, contentHash,
+	).Scan(&event.BlockNumber, &event.Registrant, &event.RegisteredAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	event.RegisteredAt = event.RegisteredAt.UTC()
+	return event, nil
+}
+
+func scanTreeHead(row rowScanner) (*domain.SignedTreeHead, error) {
+	var (
+		head        domain.SignedTreeHead
+		timestampMs int64
+	)
+	if err := row.Scan(&head.TreeSize, &head.RootHash, &timestampMs, &head.Signature); err != nil {
+		return nil, err
+	}
+	head.Timestamp = time.UnixMilli(timestampMs).UTC()
+	return &head, nil
+}
+
+func logReceipt(event *domain.RegistrationEvent) *domain.Receipt {
+	return &domain.Receipt{
+		BlockNumber: event.BlockNumber,
+		Status:      domain.ReceiptStatusSuccess,
+		Event:       event,
+	}
+}
@@ -0,0 +1,437 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+const (
+	tsaStatusGranted         = 0
+	tsaStatusGrantedWithMods = 1
+	maxTimestampReplySize    = 1 << 20
+)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status pkiStatusInfo
+	Token  asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+type tstAccuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time   `asn1:"generalized"`
+	Accuracy       tstAccuracy `asn1:"optional"`
+	Ordering       bool        `asn1:"optional,default:false"`
+	Nonce          *big.Int    `asn1:"optional"`
+}
+
+// TSAService anchors hashes by obtaining RFC 3161 timestamp tokens from a
+// time-stamping authority instead of registering them on chain. The DER token
+// is returned in the receipt and verified again on every read.
+type TSAService struct {
+	url        string
+	httpClient *http.Client
+	roots      *x509.CertPool
+	policy     asn1.ObjectIdentifier
+}
+
+// NewTSAService requests tokens from tsaURL and trusts those whose signing
+// certificate chains to one of roots at the time they were issued.
+func NewTSAService(tsaURL string, roots *x509.CertPool) (*TSAService, error) {
+	if tsaURL == "" {
+		return nil, fmt.Errorf("tsa url is required")
+	}
+	if roots == nil {
+		return nil, fmt.Errorf("tsa roots are required")
+	}
+	return &TSAService{
+		url:        tsaURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		roots:      roots,
+	}, nil
+}
+
+func (s *TSAService) WithTimeout(timeout time.Duration) *TSAService {
+	s.httpClient = &http.Client{Timeout: timeout}
+	return s
+}
+
+// WithRequestPolicy asks the TSA to issue tokens under the given policy.
+func (s *TSAService) WithRequestPolicy(policy asn1.ObjectIdentifier) *TSAService {
+	s.policy = policy
+	return s
+}
+
+func (s *TSAService) RegisterHash(ctx context.Context, hash, registrant string) (*domain.Receipt, error) {
+	digest, err := normalizeHashToBytes(hash)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	req, err := asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: messageImprint{HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256}, HashedMessage: digest},
+		ReqPolicy:      s.policy,
+		Nonce:          nonce,
+		CertReq:        true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal timestamp request: %w", err)
+	}
+
+	token, err := s.requestToken(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	genTime, err := s.verifyToken(token, digest, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.Receipt{
+		Status:         domain.ReceiptStatusSuccess,
+		TimestampToken: token,
+		Event: &domain.RegistrationEvent{
+			ContentHash:  hex.EncodeToString(digest),
+			Registrant:   registrant,
+			RegisteredAt: genTime,
+		},
+	}, nil
+}
+
+// IsHashRegistered is always false: a TSA keeps no registry of what it
+// timestamped, so the stored token is the only evidence.
+func (s *TSAService) IsHashRegistered(_ context.Context, hash string) (bool, error) {
+	if _, err := normalizeHashToBytes(hash); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// VerifyTimestamp checks that token is a valid timestamp of hash and returns
+// the time it asserts.
+func (s *TSAService) VerifyTimestamp(hash string, token []byte) (time.Time, error) {
+	digest, err := normalizeHashToBytes(hash)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.verifyToken(token, digest, nil)
+}
+
+func (s *TSAService) requestToken(ctx context.Context, req []byte) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(req))
+	if err != nil {
+		return nil, fmt.Errorf("create timestamp request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/timestamp-query")
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("send timestamp request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTimestampReplySize))
+	if err != nil {
+		return nil, fmt.Errorf("read timestamp reply: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tsa http status %d", resp.StatusCode)
+	}
+
+	var reply timeStampResp
+	if rest, err := asn1.Unmarshal(body, &reply); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("decode timestamp reply: malformed DER")
+	}
+	if st := reply.Status; st.Status != tsaStatusGranted && st.Status != tsaStatusGrantedWithMods {
+		return nil, fmt.Errorf("%w: status %d %s", domain.ErrTimestampRejected, st.Status, strings.Join(st.StatusString, "; "))
+	}
+	if len(reply.Token.FullBytes) == 0 {
+		return nil, fmt.Errorf("%w: reply carries no token", domain.ErrTimestampRejected)
+	}
+	return reply.Token.FullBytes, nil
+}
+
+// verifyToken checks the token's CMS signature and that it timestamps digest,
+// with the given nonce unless nonce is nil.
+func (s *TSAService) verifyToken(token, digest []byte, nonce *big.Int) (time.Time, error) {
+	sd, info, err := parseTimestampToken(token)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", domain.ErrInvalidTimestamp, err)
+	}
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) || !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return time.Time{}, fmt.Errorf("%w: token timestamps a different hash", domain.ErrInvalidTimestamp)
+	}
+	if nonce != nil && (info.Nonce == nil || info.Nonce.Cmp(nonce) != 0) {
+		return time.Time{}, fmt.Errorf("%w: nonce mismatch", domain.ErrInvalidTimestamp)
+	}
+	if err := s.verifySignature(sd, info.GenTime); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", domain.ErrInvalidTimestamp, err)
+	}
+	return info.GenTime, nil
+}
+
+func parseTimestampToken(token []byte) (*signedData, *tstInfo, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(token, &ci); err != nil || len(rest) > 0 {
+		return nil, nil, fmt.Errorf("malformed content info")
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, nil, fmt.Errorf("content type %v is not signed data", ci.ContentType)
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, nil, fmt.Errorf("malformed signed data: %v", err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) || len(sd.EncapContentInfo.EContent) == 0 {
+		return nil, nil, fmt.Errorf("signed content is not a TSTInfo")
+	}
+
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &info); err != nil {
+		return nil, nil, fmt.Errorf("malformed TSTInfo: %v", err)
+	}
+	return &sd, &info, nil
+}
+
+func (s *TSAService) verifySignature(sd *signedData, genTime time.Time) error {
+	if len(sd.SignerInfos) != 1 {
+		return fmt.Errorf("expected one signer, got %d", len(sd.SignerInfos))
+	}
+	si := sd.SignerInfos[0]
+
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse certificates: %v", err)
+	}
+	signer := findSigner(si.SID, certs)
+	if signer == nil {
+		return fmt.Errorf("signer certificate not included")
+	}
+	if !hasTimeStampingUsage(signer) {
+		return fmt.Errorf("signer certificate is not valid for time stamping")
+	}
+
+	hash, ok := digestHash(si.DigestAlgorithm.Algorithm)
+	if !ok {
+		return fmt.Errorf("unsupported digest algorithm %v", si.DigestAlgorithm.Algorithm)
+	}
+	signed := sd.EncapContentInfo.EContent
+	if len(si.SignedAttrs.FullBytes) > 0 {
+		if signed, err = checkSignedAttrs(si.SignedAttrs.FullBytes, signed, hash); err != nil {
+			return err
+		}
+	}
+
+	alg, ok := signatureAlgorithm(signer.PublicKeyAlgorithm, hash)
+	if !ok {
+		return fmt.Errorf("unsupported %v signature with %v", signer.PublicKeyAlgorithm, hash)
+	}
+	if err := signer.CheckSignature(alg, signed, si.Signature); err != nil {
+		return fmt.Errorf("bad signature: %v", err)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs {
+		intermediates.AddCert(c)
+	}
+	_, err = signer.Verify(x509.VerifyOptions{
+		Roots:         s.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		CurrentTime:   genTime,
+	})
+	if err != nil {
+		return fmt.Errorf("untrusted signer: %v", err)
+	}
+	return nil
+}
+
+// checkSignedAttrs verifies the content type and message digest attributes
+// and returns the bytes the signature covers: the attributes re-tagged as a
+// SET, as CMS requires.
+func checkSignedAttrs(raw, content []byte, hash crypto.Hash) ([]byte, error) {
+	signed := append([]byte{0x31}, raw[1:]...)
+
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil {
+		return nil, fmt.Errorf("malformed signed attributes: %v", err)
+	}
+
+	var contentTypeOK, digestOK bool
+	for _, a := range attrs {
+		switch {
+		case a.Type.Equal(oidContentType):
+			var ct asn1.ObjectIdentifier
+			_, err := asn1.Unmarshal(a.Values.Bytes, &ct)
+			contentTypeOK = err == nil && ct.Equal(oidTSTInfo)
+		case a.Type.Equal(oidMessageDigest):
+			var md []byte
+			_, err := asn1.Unmarshal(a.Values.Bytes, &md)
+			h := hash.New()
+			h.Write(content)
+			digestOK = err == nil && bytes.Equal(md, h.Sum(nil))
+		}
+	}
+	if !contentTypeOK {
+		return nil, fmt.Errorf("signed content type attribute missing or wrong")
+	}
+	if !digestOK {
+		return nil, fmt.Errorf("message digest does not match TSTInfo")
+	}
+	return signed, nil
+}
+
+func findSigner(sid asn1.RawValue, certs []*x509.Certificate) *x509.Certificate {
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, c := range certs {
+			if bytes.Equal(c.SubjectKeyId, sid.Bytes) {
+				return c
+			}
+		}
+		return nil
+	}
+
+	var ias issuerAndSerial
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return nil
+	}
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) && c.SerialNumber.Cmp(ias.Serial) == 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+func hasTimeStampingUsage(c *x509.Certificate) bool {
+	for _, u := range c.ExtKeyUsage {
+		if u == x509.ExtKeyUsageTimeStamping {
+			return true
+		}
+	}
+	return false
+}
+
+func digestHash(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, true
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, true
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+func signatureAlgorithm(key x509.PublicKeyAlgorithm, hash crypto.Hash) (x509.SignatureAlgorithm, bool) {
+	algs := map[x509.PublicKeyAlgorithm]map[crypto.Hash]x509.SignatureAlgorithm{
+		x509.RSA: {
+			crypto.SHA256: x509.SHA256WithRSA,
+			crypto.SHA384: x509.SHA384WithRSA,
+			crypto.SHA512: x509.SHA512WithRSA,
+		},
+		x509.ECDSA: {
+			crypto.SHA256: x509.ECDSAWithSHA256,
+			crypto.SHA384: x509.ECDSAWithSHA384,
+			crypto.SHA512: x509.ECDSAWithSHA512,
+		},
+	}
+	alg, ok := algs[key][hash]
+	return alg, ok
+}
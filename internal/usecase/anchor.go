@@ -0,0 +1,293 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+const defaultMaxAttempts = 5
+
+// backfillBatchSize is how many certificates BackfillAnchors loads at a time.
+const backfillBatchSize = 500
+
+// AnchorProcessor drives certificates through the outbox state machine,
+// persisting every transition so that any step can be resumed after a crash.
+// Before each (re)submission the contract is asked whether the hash is already
+// registered, which keeps recovery from anchoring the same content twice.
+type AnchorProcessor struct {
+	repo        OutboxRepository
+	chain       BlockchainService
+	maxAttempts int
+
+	chainMu sync.Mutex
+	chainID uint64
+}
+
+func NewAnchorProcessor(repo OutboxRepository, chain BlockchainService, maxAttempts int) *AnchorProcessor {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	return &AnchorProcessor{repo: repo, chain: chain, maxAttempts: maxAttempts}
+}
+
+func (p *AnchorProcessor) Process(ctx context.Context, cert *domain.Certificate) error {
+	return p.ProcessBatch(ctx, []*domain.Certificate{cert})
+}
+
+// ProcessBatch anchors certificates that share one anchor hash and status
+// (a single certificate, or every leaf of a Merkle batch) with one transaction.
+// Reorged certificates are resubmitted like pending ones, keeping their batch.
+func (p *AnchorProcessor) ProcessBatch(ctx context.Context, certs []*domain.Certificate) error {
+	if len(certs) == 0 {
+		return nil
+	}
+	if _, _, err := p.anchorTarget(ctx); err != nil {
+		return p.recordError(ctx, certs, err)
+	}
+	for {
+		var err error
+		switch certs[0].Status {
+		case domain.StatusPending, domain.StatusReorged:
+			err = p.submit(ctx, certs)
+		case domain.StatusSubmitted:
+			err = p.confirm(ctx, certs)
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *AnchorProcessor) submit(ctx context.Context, certs []*domain.Certificate) error {
+	if done, err := p.recoverRegistered(ctx, certs); done || err != nil {
+		return err
+	}
+
+	hash, registrant := certs[0].AnchorHash(), anchorRegistrant(certs)
+	for _, cert := range certs {
+		cert.Attempts++
+	}
+
+	submitter, twoPhase := p.chain.(TransactionSubmitter)
+	if !twoPhase {
+		receipt, err := p.chain.RegisterHash(ctx, hash, registrant)
+		if err != nil {
+			return p.fail(ctx, certs, err)
+		}
+		return p.confirmAll(ctx, certs, receipt)
+	}
+
+	txHash, err := submitter.SubmitHash(ctx, hash, registrant)
+	if err != nil {
+		return p.fail(ctx, certs, err)
+	}
+	return p.saveAll(ctx, certs, func(c *domain.Certificate) error { return c.MarkSubmitted(txHash) })
+}
+
+func (p *AnchorProcessor) confirm(ctx context.Context, certs []*domain.Certificate) error {
+	submitter, ok := p.chain.(TransactionSubmitter)
+	if !ok {
+		return p.saveAll(ctx, certs, func(c *domain.Certificate) error {
+			return c.Requeue("blockchain service cannot track submitted transactions")
+		})
+	}
+
+	receipt, err := submitter.WaitForReceipt(ctx, certs[0].AnchorHash(), certs[0].TxHash)
+	if err == nil {
+		return p.confirmAll(ctx, certs, receipt)
+	}
+	if !errors.Is(err, domain.ErrReceiptTimeout) {
+		return p.fail(ctx, certs, err)
+	}
+
+	if done, rerr := p.recoverRegistered(ctx, certs); done || rerr != nil {
+		return rerr
+	}
+	if serr := p.saveAll(ctx, certs, func(c *domain.Certificate) error { return c.Requeue(err.Error()) }); serr != nil {
+		return serr
+	}
+	return err
+}
+
+// recoverRegistered confirms the certificates from chain state when their
+// anchor hash is already registered, e.g. after a crash between broadcasting
+// and persisting. The stored transaction may have been reorged or replaced,
+// so when the registering transaction cannot be looked up the certificates
+// fail instead of being confirmed by it.
+func (p *AnchorProcessor) recoverRegistered(ctx context.Context, certs []*domain.Certificate) (bool, error) {
+	hash := certs[0].AnchorHash()
+	registered, err := p.chain.IsHashRegistered(ctx, hash)
+	if err != nil {
+		return false, p.recordError(ctx, certs, fmt.Errorf("checking registration: %w", err))
+	}
+	if !registered {
+		return false, nil
+	}
+
+	var event *domain.RegistrationEvent
+	if lookup, ok := p.chain.(RegistrationLookup); ok {
+		event, err = lookup.FindRegistration(ctx, hash)
+		if err != nil {
+			return false, p.recordError(ctx, certs, fmt.Errorf("looking up registration: %w", err))
+		}
+	}
+	if event == nil {
+		cause := fmt.Errorf("%w: %s", domain.ErrRegistrationUnknown, hash)
+		if err := p.saveAll(ctx, certs, func(c *domain.Certificate) error { return c.MarkFailed(cause.Error()) }); err != nil {
+			return true, err
+		}
+		return true, cause
+	}
+	return true, p.confirmAll(ctx, certs, &domain.Receipt{TxHash: event.TxHash, BlockNumber: event.BlockNumber, BlockHash: event.BlockHash, Event: event})
+}
+
+// confirmAll marks the certificates confirmed by the receipt and, when the
+// chain identifies itself, records the anchor on it.
+func (p *AnchorProcessor) confirmAll(ctx context.Context, certs []*domain.Certificate, receipt *domain.Receipt) error {
+	target, chainID, err := p.anchorTarget(ctx)
+	if err != nil {
+		return p.recordError(ctx, certs, err)
+	}
+	return p.saveAll(ctx, certs, func(c *domain.Certificate) error {
+		if err := c.MarkConfirmed(receipt); err != nil || target == nil {
+			return err
+		}
+		c.RecordAnchor(domain.Anchor{
+			ChainID:         chainID,
+			ContractAddress: target.ContractAddress(),
+			TxHash:          c.TxHash,
+			BlockNumber:     c.BlockNumber,
+			BlockHash:       c.BlockHash,
+			Batch:           c.Batch,
+		})
+		return nil
+	})
+}
+
+// BackfillAnchors records the primary anchor of confirmed certificates that
+// have none, such as those confirmed before anchors were stored per chain,
+// from their transaction and block. It returns how many were recorded.
+func (p *AnchorProcessor) BackfillAnchors(ctx context.Context) (int, error) {
+	repo, ok := p.repo.(AnchorRepository)
+	if !ok {
+		return 0, nil
+	}
+	target, chainID, err := p.anchorTarget(ctx)
+	if err != nil || target == nil {
+		return 0, err
+	}
+
+	recorded := 0
+	for {
+		certs, err := repo.FindUnanchored(ctx, chainID, target.ContractAddress(), backfillBatchSize)
+		if err != nil {
+			return recorded, fmt.Errorf("backfilling anchors: %w", err)
+		}
+		for _, c := range certs {
+			anchor := domain.Anchor{
+				ChainID:         chainID,
+				ContractAddress: target.ContractAddress(),
+				TxHash:          c.TxHash,
+				BlockNumber:     c.BlockNumber,
+				BlockHash:       c.BlockHash,
+				Batch:           c.Batch,
+			}
+			if err := repo.SaveAnchor(ctx, c.ID, anchor); err != nil {
+				return recorded, fmt.Errorf("backfilling anchors: %w", err)
+			}
+			recorded++
+		}
+		if len(certs) < backfillBatchSize {
+			return recorded, nil
+		}
+	}
+}
+
+// anchorTarget returns the chain the processor anchors on, or nil when the
+// service cannot identify it. The chain id is resolved before anything is
+// submitted and then cached, so confirming never fails on it.
+func (p *AnchorProcessor) anchorTarget(ctx context.Context) (AnchorTarget, uint64, error) {
+	target, ok := p.chain.(AnchorTarget)
+	if !ok {
+		return nil, 0, nil
+	}
+	p.chainMu.Lock()
+	defer p.chainMu.Unlock()
+	if p.chainID == 0 {
+		chainID, err := target.ChainID(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("reading chain id: %w", err)
+		}
+		p.chainID = chainID
+	}
+	return target, p.chainID, nil
+}
+
+func (p *AnchorProcessor) fail(ctx context.Context, certs []*domain.Certificate, cause error) error {
+	for _, cert := range certs {
+		if isPermanentAnchorError(cause) || cert.Attempts >= p.maxAttempts {
+			if err := p.save(ctx, cert, cert.MarkFailed(cause.Error())); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.recordError(ctx, []*domain.Certificate{cert}, cause); err != cause {
+			return err
+		}
+	}
+	return cause
+}
+
+// recordError keeps the certificates in their current state with the error
+// attached. Pending certificates leave their batch so that they can be
+// batched again.
+func (p *AnchorProcessor) recordError(ctx context.Context, certs []*domain.Certificate, cause error) error {
+	for _, cert := range certs {
+		cert.LastError = cause.Error()
+		if cert.Status == domain.StatusPending {
+			cert.Batch = nil
+		}
+		if err := p.save(ctx, cert, nil); err != nil {
+			return err
+		}
+	}
+	return cause
+}
+
+func (p *AnchorProcessor) saveAll(ctx context.Context, certs []*domain.Certificate, transition func(*domain.Certificate) error) error {
+	for _, cert := range certs {
+		if err := p.save(ctx, cert, transition(cert)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *AnchorProcessor) save(ctx context.Context, cert *domain.Certificate, transitionErr error) error {
+	if transitionErr != nil {
+		return transitionErr
+	}
+	if err := p.repo.UpdateAnchorState(ctx, cert); err != nil {
+		return fmt.Errorf("persisting %s state: %w", cert.Status, err)
+	}
+	return nil
+}
+
+// anchorRegistrant is the registrant recorded on chain. A batch has no single
+// registrant, so the sending account is recorded instead.
+func anchorRegistrant(certs []*domain.Certificate) string {
+	if len(certs) == 1 && certs[0].Batch == nil {
+		return certs[0].Registrant
+	}
+	return ""
+}
+
+func isPermanentAnchorError(err error) bool {
+	return errors.Is(err, domain.ErrTransactionReverted) || errors.Is(err, domain.ErrAnchorEventMissing)
+}
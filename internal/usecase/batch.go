@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type BatchConfig struct {
+	MaxSize int
+	Window  time.Duration
+	Lease   time.Duration
+}
+
+// BatchAnchorer collects pending certificates and anchors them as one Merkle
+// root, flushing every Window or as soon as MaxSize certificates are queued.
+// It takes the place of the AnchorProcessor in CertifyUseCase, so certify only
+// queues the certificate and returns it pending.
+type BatchAnchorer struct {
+	repo      BatchRepository
+	processor *AnchorProcessor
+	cfg       BatchConfig
+	queued    atomic.Int64
+	full      chan struct{}
+}
+
+func NewBatchAnchorer(repo BatchRepository, processor *AnchorProcessor, cfg BatchConfig) *BatchAnchorer {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 256
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 30 * time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 5 * time.Minute
+	}
+	return &BatchAnchorer{repo: repo, processor: processor, cfg: cfg, full: make(chan struct{}, 1)}
+}
+
+type BatchResult struct {
+	Root     string
+	Size     int
+	Anchored bool
+}
+
+// Process queues the certificate for the next batch.
+func (b *BatchAnchorer) Process(_ context.Context, _ *domain.Certificate) error {
+	if b.queued.Add(1) >= int64(b.cfg.MaxSize) {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *BatchAnchorer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(b.cfg.Window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-b.full:
+		}
+
+		for {
+			res, err := b.Flush(ctx)
+			if err != nil {
+				log.Printf("batch: %v", err)
+			} else if res.Size > 0 {
+				log.Printf("batch: root %s with %d certificates, anchored %t", res.Root, res.Size, res.Anchored)
+			}
+			if err != nil || res.Size < b.cfg.MaxSize {
+				break
+			}
+		}
+	}
+}
+
+// Flush claims up to MaxSize pending certificates and anchors their Merkle
+// root in a single transaction. Certificates whose anchoring fails transiently
+// stay pending and are batched again once their lease expires.
+func (b *BatchAnchorer) Flush(ctx context.Context) (*BatchResult, error) {
+	b.queued.Store(0)
+
+	certs, err := b.repo.ClaimPending(ctx, b.cfg.MaxSize, b.cfg.Lease)
+	if err != nil {
+		return nil, fmt.Errorf("claiming pending certificates: %w", err)
+	}
+	if len(certs) == 0 {
+		return &BatchResult{}, nil
+	}
+
+	root, err := domain.AssignMerkleBatch(certs)
+	if err != nil {
+		return nil, fmt.Errorf("building batch: %w", err)
+	}
+
+	res := &BatchResult{Root: root, Size: len(certs)}
+	if err := b.processor.ProcessBatch(ctx, certs); err != nil {
+		return res, fmt.Errorf("anchoring batch %s: %w", root, err)
+	}
+	res.Anchored = true
+	return res, nil
+}
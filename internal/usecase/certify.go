@@ -11,12 +11,20 @@ import (
 )
 
 type CertifyUseCase struct {
-	repo  CertificateRepository
-	chain BlockchainService
+	repo          OutboxRepository
+	anchorer      Anchorer
+	fingerprinter VideoFingerprinter
 }
 
-func NewCertifyUseCase(repo CertificateRepository, chain BlockchainService) *CertifyUseCase {
-	return &CertifyUseCase{repo: repo, chain: chain}
+func NewCertifyUseCase(repo OutboxRepository, anchorer Anchorer) *CertifyUseCase {
+	return &CertifyUseCase{repo: repo, anchorer: anchorer}
+}
+
+// WithVideoFingerprinter stores a temporal fingerprint for every video the
+// fingerprinter can read.
+func (uc *CertifyUseCase) WithVideoFingerprinter(f VideoFingerprinter) *CertifyUseCase {
+	uc.fingerprinter = f
+	return uc
 }
 
 type CertifyInput struct {
@@ -28,6 +36,10 @@ type CertifyOutput struct {
 	Certificate *domain.Certificate
 }
 
+// Execute persists the certificate as pending before anything is sent to the
+// chain, then hands it to the anchorer: inline anchoring, or a queued batch.
+// Transient chain errors leave the certificate pending or submitted for the
+// outbox worker to finish.
 func (uc *CertifyUseCase) Execute(ctx context.Context, in CertifyInput) (*CertifyOutput, error) {
 	content, err := io.ReadAll(in.Content)
 	if err != nil {
@@ -35,33 +47,59 @@ func (uc *CertifyUseCase) Execute(ctx context.Context, in CertifyInput) (*Certif
 	}
 
 	contentHash, _ := domain.HashContent(bytes.NewReader(content))
-	perceptualHash := domain.PerceptualHashFromBytes(content)
+	img := domain.DecodeImage(content)
+	pixelHash := img.PixelHash()
+	perceptualHashes := img.PerceptualHashes()
+	fingerprint := videoFingerprint(uc.fingerprinter, content, perceptualHashes)
 
-	existing, err := uc.repo.FindByHash(ctx, contentHash)
+	cert, err := uc.repo.FindByHash(ctx, contentHash)
 	if err != nil {
 		return nil, fmt.Errorf("certify: checking existing: %w", err)
 	}
-	if existing != nil {
+
+	switch {
+	case cert == nil:
+		cert = &domain.Certificate{
+			ContentHash:      contentHash,
+			PixelHash:        pixelHash,
+			PerceptualHashes: perceptualHashes,
+			Fingerprint:      fingerprint,
+			Registrant:       in.Registrant,
+			Status:           domain.StatusPending,
+			CreatedAt:        time.Now().UTC(),
+		}
+		if err := uc.repo.Save(ctx, cert); err != nil {
+			return nil, fmt.Errorf("certify: saving certificate: %w", err)
+		}
+	case cert.Status == domain.StatusFailed:
+		if err := cert.Retry(); err != nil {
+			return nil, fmt.Errorf("certify: %w", err)
+		}
+		cert.Registrant = in.Registrant
+		if err := uc.repo.UpdateAnchorState(ctx, cert); err != nil {
+			return nil, fmt.Errorf("certify: saving certificate: %w", err)
+		}
+	default:
 		return nil, fmt.Errorf("certify: %w", domain.ErrAlreadyCertified)
 	}
 
-	txHash, blockNum, err := uc.chain.RegisterHash(ctx, contentHash)
-	if err != nil {
+	if err := uc.anchorer.Process(ctx, cert); err != nil && cert.Status == domain.StatusFailed {
 		return nil, fmt.Errorf("certify: registering on chain: %w", err)
 	}
 
-	cert := &domain.Certificate{
-		ContentHash:    contentHash,
-		PerceptualHash: perceptualHash,
-		Registrant:     in.Registrant,
-		TxHash:         txHash,
-		BlockNumber:    blockNum,
-		CreatedAt:      time.Now().UTC(),
-	}
+	return &CertifyOutput{Certificate: cert}, nil
+}
 
-	if err := uc.repo.Save(ctx, cert); err != nil {
-		return nil, fmt.Errorf("certify: saving certificate: %w", err)
+// videoFingerprint fingerprints content that is not an image. Videos the
+// fingerprinter cannot read are still certified and verified, by content hash
+// only.
+func videoFingerprint(f VideoFingerprinter, content []byte, perceptualHashes domain.PerceptualHashes) domain.VideoFingerprint {
+	if f == nil || len(perceptualHashes) > 0 {
+		return nil
 	}
-
-	return &CertifyOutput{Certificate: cert}, nil
+	fp, err := f.Fingerprint(content)
+	if err != nil {
+		return nil
+	}
+	return fp
 }
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type IndexerConfig struct {
+	Name          string
+	StartBlock    uint64
+	BatchSize     uint64
+	Confirmations uint64
+	PollInterval  time.Duration
+}
+
+type IndexerUseCase struct {
+	source      RegistrationEventSource
+	repo        ReconciliationRepository
+	checkpoints CheckpointStore
+	cfg         IndexerConfig
+}
+
+func NewIndexerUseCase(source RegistrationEventSource, repo ReconciliationRepository, checkpoints CheckpointStore, cfg IndexerConfig) *IndexerUseCase {
+	if cfg.Name == "" {
+		cfg.Name = "content_registered"
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.Confirmations == 0 {
+		cfg.Confirmations = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 15 * time.Second
+	}
+	return &IndexerUseCase{source: source, repo: repo, checkpoints: checkpoints, cfg: cfg}
+}
+
+type IndexerResult struct {
+	FromBlock  uint64
+	ToBlock    uint64
+	Events     int
+	Inserted   int
+	Flagged    int
+	CaughtUp   bool
+	Checkpoint uint64
+}
+
+func (uc *IndexerUseCase) Run(ctx context.Context) error {
+	ticker := time.NewTicker(uc.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		res, err := uc.SyncOnce(ctx)
+		if err != nil {
+			log.Printf("indexer %s: %v", uc.cfg.Name, err)
+		} else if res.Events > 0 {
+			log.Printf("indexer %s: blocks %d-%d, %d events, %d inserted, %d flagged",
+				uc.cfg.Name, res.FromBlock, res.ToBlock, res.Events, res.Inserted, res.Flagged)
+		}
+
+		if err == nil && !res.CaughtUp {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (uc *IndexerUseCase) SyncOnce(ctx context.Context) (*IndexerResult, error) {
+	checkpoint, ok, err := uc.checkpoints.LoadCheckpoint(ctx, uc.cfg.Name)
+	if err != nil {
+		return nil, fmt.Errorf("indexer: loading checkpoint: %w", err)
+	}
+
+	from := uc.cfg.StartBlock
+	if ok {
+		from = checkpoint + 1
+	}
+
+	head, err := uc.source.LatestBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("indexer: fetching head: %w", err)
+	}
+
+	res := &IndexerResult{FromBlock: from, Checkpoint: checkpoint}
+	if head+1 < uc.cfg.Confirmations || head+1-uc.cfg.Confirmations < from {
+		res.CaughtUp = true
+		return res, nil
+	}
+	safeHead := head + 1 - uc.cfg.Confirmations
+
+	to := from + uc.cfg.BatchSize - 1
+	if to >= safeHead {
+		to = safeHead
+		res.CaughtUp = true
+	}
+	res.ToBlock = to
+
+	events, err := uc.source.RegistrationEvents(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("indexer: fetching events %d-%d: %w", from, to, err)
+	}
+	res.Events = len(events)
+
+	for _, ev := range events {
+		inserted, flagged, err := uc.reconcile(ctx, ev)
+		if err != nil {
+			return nil, err
+		}
+		if inserted {
+			res.Inserted++
+		}
+		if flagged {
+			res.Flagged++
+		}
+	}
+
+	if err := uc.checkpoints.SaveCheckpoint(ctx, uc.cfg.Name, to); err != nil {
+		return nil, fmt.Errorf("indexer: saving checkpoint: %w", err)
+	}
+	res.Checkpoint = to
+	return res, nil
+}
+
+func (uc *IndexerUseCase) reconcile(ctx context.Context, ev domain.RegistrationEvent) (inserted, flagged bool, err error) {
+	cert, err := uc.repo.FindByHash(ctx, ev.ContentHash)
+	if err != nil {
+		return false, false, fmt.Errorf("indexer: finding %s: %w", ev.ContentHash, err)
+	}
+
+	if cert != nil {
+		flagged, err := uc.reconcileCertificate(ctx, cert, ev)
+		return false, flagged, err
+	}
+
+	batch, err := uc.repo.FindByMerkleRoot(ctx, ev.ContentHash)
+	if err != nil {
+		return false, false, fmt.Errorf("indexer: finding batch %s: %w", ev.ContentHash, err)
+	}
+	if len(batch) > 0 {
+		for _, member := range batch {
+			f, err := uc.reconcileCertificate(ctx, member, ev)
+			if err != nil {
+				return false, flagged, err
+			}
+			flagged = flagged || f
+		}
+		return false, flagged, nil
+	}
+
+	if err := uc.repo.Save(ctx, domain.CertificateFromRegistration(ev)); err != nil {
+		return false, false, fmt.Errorf("indexer: inserting %s: %w", ev.ContentHash, err)
+	}
+	return true, false, nil
+}
+
+func (uc *IndexerUseCase) reconcileCertificate(ctx context.Context, cert *domain.Certificate, ev domain.RegistrationEvent) (bool, error) {
+	if !cert.IsAnchored() {
+		// Pending and submitted certificates are owned by the outbox, which
+		// recovers registrations it has not yet recorded.
+		return false, nil
+	}
+
+	mismatch := cert.ReconcileRegistration(ev)
+	if mismatch == cert.ChainMismatch {
+		return false, nil
+	}
+	if err := uc.repo.FlagChainMismatch(ctx, cert.ID, mismatch); err != nil {
+		return false, fmt.Errorf("indexer: flagging %s: %w", cert.ID, err)
+	}
+	return mismatch != "", nil
+}
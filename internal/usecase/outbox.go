@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type OutboxConfig struct {
+	BatchSize    int
+	StaleAfter   time.Duration
+	PollInterval time.Duration
+}
+
+type OutboxWorker struct {
+	repo      OutboxRepository
+	processor *AnchorProcessor
+	cfg       OutboxConfig
+}
+
+func NewOutboxWorker(repo OutboxRepository, processor *AnchorProcessor, cfg OutboxConfig) *OutboxWorker {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 5 * time.Minute
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+	return &OutboxWorker{repo: repo, processor: processor, cfg: cfg}
+}
+
+type OutboxResult struct {
+	Claimed   int
+	Completed int
+	Failed    int
+}
+
+func (w *OutboxWorker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		res, err := w.RunOnce(ctx)
+		if err != nil {
+			log.Printf("outbox: %v", err)
+		} else if res.Claimed > 0 {
+			log.Printf("outbox: claimed %d, completed %d, errored %d", res.Claimed, res.Completed, res.Failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims certificates that have been pending or submitted for longer
+// than StaleAfter (so in-flight requests are left alone) and resumes them.
+func (w *OutboxWorker) RunOnce(ctx context.Context) (*OutboxResult, error) {
+	certs, err := w.repo.ClaimUnfinished(ctx, w.cfg.BatchSize, w.cfg.StaleAfter)
+	if err != nil {
+		return nil, fmt.Errorf("claiming unfinished certificates: %w", err)
+	}
+
+	res := &OutboxResult{Claimed: len(certs)}
+	for _, group := range groupByAnchor(certs) {
+		if err := w.processor.ProcessBatch(ctx, group); err != nil {
+			log.Printf("outbox: anchor %s (%d certificates, %s): %v", group[0].AnchorHash(), len(group), group[0].Status, err)
+			res.Failed += len(group)
+			continue
+		}
+		res.Completed += len(group)
+	}
+	return res, nil
+}
+
+// groupByAnchor groups claimed certificates that belong to the same submitted
+// transaction, so a batch is confirmed with one receipt lookup.
+func groupByAnchor(certs []*domain.Certificate) [][]*domain.Certificate {
+	type key struct {
+		hash, txHash string
+		status       domain.CertificateStatus
+	}
+	var groups [][]*domain.Certificate
+	index := map[key]int{}
+	for _, cert := range certs {
+		k := key{cert.AnchorHash(), cert.TxHash, cert.Status}
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], cert)
+	}
+	return groups
+}
@@ -2,6 +2,8 @@ package usecase
 
 import (
 	"context"
+	"crypto/ed25519"
+	"time"
 
 	"github.com/waizbart/aletheia-api/internal/domain"
 )
@@ -9,10 +11,116 @@ import (
 type CertificateRepository interface {
 	Save(ctx context.Context, cert *domain.Certificate) error
 	FindByHash(ctx context.Context, contentHash string) (*domain.Certificate, error)
-	FindByPerceptualHash(ctx context.Context, hash uint64, maxDistance int) (*domain.Certificate, error)
+	// FindByPixelHash returns the oldest certificate of an image with the
+	// given canonical pixel hash.
+	FindByPixelHash(ctx context.Context, pixelHash string) (*domain.Certificate, error)
+	// FindByPerceptualHash returns up to limit certificates whose combined
+	// distance to hashes is within maxDistance bits, nearest first.
+	FindByPerceptualHash(ctx context.Context, hashes domain.PerceptualHashes, maxDistance, limit int) ([]domain.SimilarCertificate, error)
+	// FindByVideoFingerprint returns up to limit certificates of videos that
+	// clip is a copy of, according to domain.MatchVideo, most confident first.
+	FindByVideoFingerprint(ctx context.Context, clip domain.VideoFingerprint, maxDistance, limit int) ([]domain.SimilarCertificate, error)
+}
+
+// VideoFingerprinter extracts the temporal fingerprint of video content. It
+// fails with domain.ErrUnsupportedVideo for containers and codecs it cannot
+// read, which include H.264 and HEVC.
+type VideoFingerprinter interface {
+	Fingerprint(content []byte) (domain.VideoFingerprint, error)
 }
 
 type BlockchainService interface {
-	RegisterHash(ctx context.Context, hash string) (txHash string, blockNum uint64, err error)
+	RegisterHash(ctx context.Context, hash, registrant string) (*domain.Receipt, error)
 	IsHashRegistered(ctx context.Context, hash string) (bool, error)
 }
+
+type RegistrationEventSource interface {
+	LatestBlock(ctx context.Context) (uint64, error)
+	RegistrationEvents(ctx context.Context, fromBlock, toBlock uint64) ([]domain.RegistrationEvent, error)
+}
+
+type ReconciliationRepository interface {
+	CertificateRepository
+	FindByMerkleRoot(ctx context.Context, root string) ([]*domain.Certificate, error)
+	FlagChainMismatch(ctx context.Context, id, reason string) error
+}
+
+type CheckpointStore interface {
+	LoadCheckpoint(ctx context.Context, name string) (block uint64, ok bool, err error)
+	SaveCheckpoint(ctx context.Context, name string, block uint64) error
+}
+
+type TransactionSubmitter interface {
+	SubmitHash(ctx context.Context, hash, registrant string) (txHash string, err error)
+	WaitForReceipt(ctx context.Context, hash, txHash string) (*domain.Receipt, error)
+}
+
+type RegistrationLookup interface {
+	FindRegistration(ctx context.Context, hash string) (*domain.RegistrationEvent, error)
+}
+
+type OutboxRepository interface {
+	CertificateRepository
+	UpdateAnchorState(ctx context.Context, cert *domain.Certificate) error
+	ClaimUnfinished(ctx context.Context, limit int, staleAfter time.Duration) ([]*domain.Certificate, error)
+}
+
+type BatchRepository interface {
+	OutboxRepository
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*domain.Certificate, error)
+}
+
+type ReorgRepository interface {
+	OutboxRepository
+	FindConfirmedSince(ctx context.Context, fromBlock uint64) ([]*domain.Certificate, error)
+}
+
+type CanonicalChain interface {
+	LatestBlock(ctx context.Context) (uint64, error)
+	// BlockHash returns "" when the chain has no block at number.
+	BlockHash(ctx context.Context, number uint64) (string, error)
+}
+
+// TimestampVerifier checks an RFC 3161 token stored for an anchor hash and
+// returns the time it asserts.
+type TimestampVerifier interface {
+	VerifyTimestamp(hash string, token []byte) (time.Time, error)
+}
+
+// TransparencyLog is an append-only Merkle log that anchors hashes in place of
+// a chain. TreeHead fails with domain.ErrInvalidTreeSize for sizes the log
+// never published.
+type TransparencyLog interface {
+	PublicKey() ed25519.PublicKey
+	LatestTreeHead(ctx context.Context) (*domain.SignedTreeHead, error)
+	TreeHead(ctx context.Context, treeSize uint64) (*domain.SignedTreeHead, error)
+	InclusionProof(ctx context.Context, hash string, treeSize uint64) (*domain.LogInclusionProof, error)
+	ConsistencyProof(ctx context.Context, firstSize, secondSize uint64) (*domain.LogConsistencyProof, error)
+}
+
+type Anchorer interface {
+	Process(ctx context.Context, cert *domain.Certificate) error
+}
+
+type AnchorTarget interface {
+	ChainID(ctx context.Context) (uint64, error)
+	ContractAddress() string
+}
+
+// AnchorChain is a chain certificates are anchored on in addition to the
+// primary one.
+type AnchorChain interface {
+	BlockchainService
+	AnchorTarget
+}
+
+type AnchorRepository interface {
+	// FindUnanchored returns confirmed certificates without an anchor on the
+	// chain and contract, oldest first.
+	FindUnanchored(ctx context.Context, chainID uint64, contract string, limit int) ([]*domain.Certificate, error)
+	SaveAnchor(ctx context.Context, certID string, anchor domain.Anchor) error
+}
+
+type ProofRepository interface {
+	FindByID(ctx context.Context, id string) (*domain.Certificate, error)
+}
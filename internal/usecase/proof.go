@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type ProofUseCase struct {
+	repo   ProofRepository
+	target AnchorTarget
+}
+
+func NewProofUseCase(repo ProofRepository, target AnchorTarget) *ProofUseCase {
+	return &ProofUseCase{repo: repo, target: target}
+}
+
+func (uc *ProofUseCase) Execute(ctx context.Context, id string) (*domain.ProofBundle, error) {
+	cert, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("proof: %w", err)
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("proof: %w", domain.ErrNotFound)
+	}
+
+	chainID, err := uc.target.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("proof: resolving chain id: %w", err)
+	}
+
+	bundle, err := domain.NewProofBundle(cert, chainID, uc.target.ContractAddress())
+	if err != nil {
+		return nil, fmt.Errorf("proof: %w", err)
+	}
+	if err := bundle.Verify(); err != nil {
+		return nil, fmt.Errorf("proof: %w", err)
+	}
+	return bundle, nil
+}
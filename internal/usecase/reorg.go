@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type ReorgConfig struct {
+	// Depth is how many blocks below the head are re-checked.
+	Depth        uint64
+	PollInterval time.Duration
+}
+
+// ReorgWatcher compares the block hashes recorded for recently confirmed
+// certificates with the canonical chain. Certificates whose block was
+// replaced are marked reorged and anchored again.
+type ReorgWatcher struct {
+	repo      ReorgRepository
+	chain     CanonicalChain
+	processor *AnchorProcessor
+	cfg       ReorgConfig
+}
+
+func NewReorgWatcher(repo ReorgRepository, chain CanonicalChain, processor *AnchorProcessor, cfg ReorgConfig) *ReorgWatcher {
+	if cfg.Depth == 0 {
+		cfg.Depth = 64
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Minute
+	}
+	return &ReorgWatcher{repo: repo, chain: chain, processor: processor, cfg: cfg}
+}
+
+type ReorgResult struct {
+	Checked    int
+	Reorged    int
+	Reanchored int
+}
+
+func (w *ReorgWatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		res, err := w.RunOnce(ctx)
+		if err != nil {
+			log.Printf("reorg watcher: %v", err)
+		} else if res.Reorged > 0 {
+			log.Printf("reorg watcher: %d of %d certificates reorged, %d re-anchored", res.Reorged, res.Checked, res.Reanchored)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *ReorgWatcher) RunOnce(ctx context.Context) (*ReorgResult, error) {
+	head, err := w.chain.LatestBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching latest block: %w", err)
+	}
+	var from uint64
+	if head > w.cfg.Depth {
+		from = head - w.cfg.Depth
+	}
+
+	certs, err := w.repo.FindConfirmedSince(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("loading confirmed certificates: %w", err)
+	}
+
+	res := &ReorgResult{Checked: len(certs)}
+	canonical := map[uint64]string{}
+	var orphaned []*domain.Certificate
+	for _, cert := range certs {
+		if cert.BlockHash == "" {
+			continue
+		}
+		hash, ok := canonical[cert.BlockNumber]
+		if !ok {
+			if hash, err = w.chain.BlockHash(ctx, cert.BlockNumber); err != nil {
+				return res, fmt.Errorf("fetching block %d: %w", cert.BlockNumber, err)
+			}
+			canonical[cert.BlockNumber] = hash
+		}
+		if strings.EqualFold(hash, cert.BlockHash) {
+			continue
+		}
+
+		if err := cert.MarkReorged(reorgReason(cert, hash)); err != nil {
+			return res, err
+		}
+		if err := w.repo.UpdateAnchorState(ctx, cert); err != nil {
+			return res, fmt.Errorf("persisting reorged state: %w", err)
+		}
+		orphaned = append(orphaned, cert)
+	}
+	res.Reorged = len(orphaned)
+
+	for _, group := range groupByAnchor(orphaned) {
+		if err := w.processor.ProcessBatch(ctx, group); err != nil {
+			log.Printf("reorg watcher: re-anchoring %s (%d certificates): %v", group[0].AnchorHash(), len(group), err)
+			continue
+		}
+		res.Reanchored += len(group)
+	}
+	return res, nil
+}
+
+func reorgReason(cert *domain.Certificate, canonical string) string {
+	if canonical == "" {
+		return fmt.Sprintf("block %d of transaction %s is no longer on the canonical chain", cert.BlockNumber, cert.TxHash)
+	}
+	return fmt.Sprintf("block %d of transaction %s was replaced: %s != canonical %s", cert.BlockNumber, cert.TxHash, cert.BlockHash, canonical)
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+type SecondaryConfig struct {
+	// Name identifies the chain in logs.
+	Name     string
+	MaxSize  int
+	Interval time.Duration
+}
+
+// SecondaryAnchorer periodically anchors certificates that are confirmed on
+// the primary chain on one more chain, e.g. checkpointing an L2 into mainnet.
+// Every run registers a single Merkle root over up to MaxSize certificates and
+// records an anchor, with its own audit path, for each of them.
+type SecondaryAnchorer struct {
+	repo  AnchorRepository
+	chain AnchorChain
+	cfg   SecondaryConfig
+}
+
+func NewSecondaryAnchorer(repo AnchorRepository, chain AnchorChain, cfg SecondaryConfig) *SecondaryAnchorer {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 1024
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	return &SecondaryAnchorer{repo: repo, chain: chain, cfg: cfg}
+}
+
+type SecondaryResult struct {
+	ChainID uint64
+	Hash    string
+	Size    int
+}
+
+func (s *SecondaryAnchorer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			res, err := s.RunOnce(ctx)
+			if err != nil {
+				log.Printf("anchor chain %s: %v", s.cfg.Name, err)
+			} else if res.Size > 0 {
+				log.Printf("anchor chain %s: anchored %d certificates on chain %d as %s", s.cfg.Name, res.Size, res.ChainID, res.Hash)
+			}
+			if err != nil || res.Size < s.cfg.MaxSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce anchors the oldest certificates missing from the chain. A single
+// certificate is anchored by its content hash, like on the primary chain.
+func (s *SecondaryAnchorer) RunOnce(ctx context.Context) (*SecondaryResult, error) {
+	chainID, err := s.chain.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading chain id: %w", err)
+	}
+	contract := s.chain.ContractAddress()
+
+	certs, err := s.repo.FindUnanchored(ctx, chainID, contract, s.cfg.MaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("loading unanchored certificates: %w", err)
+	}
+	res := &SecondaryResult{ChainID: chainID, Size: len(certs)}
+	if len(certs) == 0 {
+		return res, nil
+	}
+
+	batches := make([]*domain.MerkleBatch, len(certs))
+	hash, registrant := certs[0].ContentHash, certs[0].Registrant
+	if len(certs) > 1 {
+		if hash, batches, err = domain.BuildMerkleBatches(certs); err != nil {
+			return res, fmt.Errorf("building batch: %w", err)
+		}
+		registrant = ""
+	}
+	res.Hash = hash
+
+	receipt, err := s.register(ctx, res.Hash, registrant)
+	if err != nil {
+		return res, fmt.Errorf("anchoring %s: %w", res.Hash, err)
+	}
+
+	for i, cert := range certs {
+		anchor := domain.Anchor{
+			ChainID:         chainID,
+			ContractAddress: contract,
+			TxHash:          receipt.TxHash,
+			BlockNumber:     receipt.BlockNumber,
+			BlockHash:       receipt.BlockHash,
+			Batch:           batches[i],
+		}
+		if err := s.repo.SaveAnchor(ctx, cert.ID, anchor); err != nil {
+			return res, fmt.Errorf("persisting anchor of %s: %w", cert.ID, err)
+		}
+		cert.RecordAnchor(anchor)
+	}
+	return res, nil
+}
+
+// register anchors the hash unless an earlier run already did, e.g. one that
+// crashed before persisting its anchors.
+func (s *SecondaryAnchorer) register(ctx context.Context, hash, registrant string) (*domain.Receipt, error) {
+	registered, err := s.chain.IsHashRegistered(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("checking registration: %w", err)
+	}
+	if !registered {
+		return s.chain.RegisterHash(ctx, hash, registrant)
+	}
+
+	lookup, ok := s.chain.(RegistrationLookup)
+	if !ok {
+		return nil, fmt.Errorf("%w: registered without a transaction to record", domain.ErrAlreadyCertified)
+	}
+	event, err := lookup.FindRegistration(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("looking up registration: %w", err)
+	}
+	if event == nil {
+		return nil, fmt.Errorf("%w: registration event not found", domain.ErrAlreadyCertified)
+	}
+	return &domain.Receipt{TxHash: event.TxHash, BlockNumber: event.BlockNumber, BlockHash: event.BlockHash, Event: event}, nil
+}
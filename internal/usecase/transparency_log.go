@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"strings"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+// TransparencyLogUseCase serves tree heads and proofs from the log, checking
+// every signature and proof before it is handed out.
+type TransparencyLogUseCase struct {
+	log TransparencyLog
+}
+
+func NewTransparencyLogUseCase(log TransparencyLog) *TransparencyLogUseCase {
+	return &TransparencyLogUseCase{log: log}
+}
+
+type LogInclusionOutput struct {
+	Proof    *domain.LogInclusionProof
+	TreeHead *domain.SignedTreeHead
+}
+
+type LogConsistencyOutput struct {
+	Proof          *domain.LogConsistencyProof
+	FirstTreeHead  *domain.SignedTreeHead
+	SecondTreeHead *domain.SignedTreeHead
+}
+
+func (uc *TransparencyLogUseCase) PublicKey() ed25519.PublicKey {
+	return uc.log.PublicKey()
+}
+
+func (uc *TransparencyLogUseCase) TreeHead(ctx context.Context) (*domain.SignedTreeHead, error) {
+	return uc.treeHead(ctx, 0)
+}
+
+// InclusionProof proves hash against the tree head of treeSize entries, or the
+// latest one when treeSize is 0.
+func (uc *TransparencyLogUseCase) InclusionProof(ctx context.Context, hash string, treeSize uint64) (*LogInclusionOutput, error) {
+	hash = strings.ToLower(strings.TrimPrefix(hash, "0x"))
+	head, err := uc.treeHead(ctx, treeSize)
+	if err != nil {
+		return nil, err
+	}
+	if head.TreeSize == 0 {
+		return nil, fmt.Errorf("log inclusion: %w", domain.ErrNotInLog)
+	}
+
+	proof, err := uc.log.InclusionProof(ctx, hash, head.TreeSize)
+	if err != nil {
+		return nil, fmt.Errorf("log inclusion: %w", err)
+	}
+	if err := proof.Verify(hash, head); err != nil {
+		return nil, fmt.Errorf("log inclusion: %w", err)
+	}
+	return &LogInclusionOutput{Proof: proof, TreeHead: head}, nil
+}
+
+// ConsistencyProof proves that the tree head of secondSize entries, or the
+// latest one when secondSize is 0, extends the one of firstSize entries.
+func (uc *TransparencyLogUseCase) ConsistencyProof(ctx context.Context, firstSize, secondSize uint64) (*LogConsistencyOutput, error) {
+	if firstSize == 0 {
+		return nil, fmt.Errorf("log consistency: %w: first tree size must be positive", domain.ErrInvalidTreeSize)
+	}
+	second, err := uc.treeHead(ctx, secondSize)
+	if err != nil {
+		return nil, err
+	}
+	if firstSize > second.TreeSize {
+		return nil, fmt.Errorf("log consistency: %w: %d is larger than %d", domain.ErrInvalidTreeSize, firstSize, second.TreeSize)
+	}
+	first, err := uc.treeHead(ctx, firstSize)
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := uc.log.ConsistencyProof(ctx, first.TreeSize, second.TreeSize)
+	if err != nil {
+		return nil, fmt.Errorf("log consistency: %w", err)
+	}
+	if err := proof.Verify(first, second); err != nil {
+		return nil, fmt.Errorf("log consistency: %w", err)
+	}
+	return &LogConsistencyOutput{Proof: proof, FirstTreeHead: first, SecondTreeHead: second}, nil
+}
+
+func (uc *TransparencyLogUseCase) treeHead(ctx context.Context, treeSize uint64) (*domain.SignedTreeHead, error) {
+	var (
+		head *domain.SignedTreeHead
+		err  error
+	)
+	if treeSize == 0 {
+		head, err = uc.log.LatestTreeHead(ctx)
+	} else {
+		head, err = uc.log.TreeHead(ctx, treeSize)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("log tree head: %w", err)
+	}
+	if err := head.Verify(uc.log.PublicKey()); err != nil {
+		return nil, fmt.Errorf("log tree head: %w", err)
+	}
+	return head, nil
+}
@@ -5,31 +5,83 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/waizbart/aletheia-api/internal/domain"
 )
 
 type VerifyUseCase struct {
-	repo CertificateRepository
+	repo          CertificateRepository
+	chain         BlockchainService
+	fingerprinter VideoFingerprinter
 }
 
-func NewVerifyUseCase(repo CertificateRepository) *VerifyUseCase {
-	return &VerifyUseCase{repo: repo}
+func NewVerifyUseCase(repo CertificateRepository, chain BlockchainService) *VerifyUseCase {
+	return &VerifyUseCase{repo: repo, chain: chain}
 }
 
+// WithVideoFingerprinter lets uploaded videos without an exact match be
+// matched against certified videos by their fingerprints.
+func (uc *VerifyUseCase) WithVideoFingerprinter(f VideoFingerprinter) *VerifyUseCase {
+	uc.fingerprinter = f
+	return uc
+}
+
+const (
+	// DefaultMaxDistance is the perceptual match threshold, in differing bits,
+	// used when a request sets none.
+	DefaultMaxDistance = 8
+	// DefaultSimilarLimit is how many perceptual candidates are returned when
+	// a request sets no limit.
+	DefaultSimilarLimit = 5
+)
+
 type VerifyInput struct {
-	Content io.Reader
-	Hash    string
+	Content        io.Reader
+	Hash           string
+	ConfirmOnChain bool
+	// MaxDistance overrides DefaultMaxDistance for perceptual matching.
+	MaxDistance *int
+	// Limit overrides DefaultSimilarLimit when positive.
+	Limit int
 }
 
 type VerifyOutput struct {
-	Certified   bool
-	Certificate *domain.Certificate
+	Certified        bool
+	Certificate      *domain.Certificate
+	OnChainConfirmed *bool
+	// InclusionVerified is set for batched certificates: whether the stored
+	// audit path proves the content hash is a leaf of the anchored root.
+	InclusionVerified *bool
+	// TimestampVerified is set for certificates anchored with an RFC 3161
+	// token: whether the token is a valid timestamp of the anchor hash.
+	TimestampVerified *bool
+	TimestampedAt     *time.Time
+	// MatchType says how Certificate was found. Distance and Similarity
+	// compare its perceptual hashes with the content's, combined across
+	// algorithms and, for videos, averaged over the matched segments; an
+	// exact or pixel match has distance 0 and similarity 1. Distances breaks
+	// a perceptual match down by algorithm.
+	MatchType  domain.MatchType
+	Distance   int
+	Similarity float64
+	Distances  map[domain.HashAlgorithm]int
+	// Video locates a matched video clip within Certificate.
+	Video *domain.VideoMatch
+	// Similar lists the perceptual candidates, nearest first, starting with
+	// Certificate.
+	Similar []domain.SimilarCertificate
 }
 
+// Execute looks the content up by its SHA-256, then for images by canonical
+// pixel hash, and finally by perceptual hash or video fingerprint.
 func (uc *VerifyUseCase) Execute(ctx context.Context, in VerifyInput) (*VerifyOutput, error) {
 	hash := in.Hash
-	var perceptualHash *uint64
+	var (
+		pixelHash        string
+		perceptualHashes domain.PerceptualHashes
+		fingerprint      domain.VideoFingerprint
+	)
 
 	if in.Content != nil {
 		content, err := io.ReadAll(in.Content)
@@ -40,7 +92,10 @@ func (uc *VerifyUseCase) Execute(ctx context.Context, in VerifyInput) (*VerifyOu
 		computed, _ := domain.HashContent(bytes.NewReader(content))
 		hash = computed
 
-		perceptualHash = domain.PerceptualHashFromBytes(content)
+		img := domain.DecodeImage(content)
+		pixelHash = img.PixelHash()
+		perceptualHashes = img.PerceptualHashes(domain.VerifyPerceptualHashers()...)
+		fingerprint = videoFingerprint(uc.fingerprinter, content, perceptualHashes)
 	}
 
 	if hash == "" {
@@ -52,17 +107,87 @@ func (uc *VerifyUseCase) Execute(ctx context.Context, in VerifyInput) (*VerifyOu
 		return nil, fmt.Errorf("verify: %w", err)
 	}
 
+	matchType := domain.MatchExact
+	if cert == nil && pixelHash != "" {
+		if cert, err = uc.repo.FindByPixelHash(ctx, pixelHash); err != nil {
+			return nil, fmt.Errorf("verify: %w", err)
+		}
+		matchType = domain.MatchPixel
+	}
+
+	match := domain.SimilarCertificate{Certificate: cert}
+	var similar []domain.SimilarCertificate
 	if cert == nil {
-		if perceptualHash != nil {
-			cert, err = uc.repo.FindByPerceptualHash(ctx, *perceptualHash, 8)
-			if err != nil {
-				return nil, fmt.Errorf("verify: %w", err)
-			}
+		maxDistance, limit := DefaultMaxDistance, DefaultSimilarLimit
+		if in.MaxDistance != nil {
+			maxDistance = *in.MaxDistance
+		}
+		if in.Limit > 0 {
+			limit = in.Limit
+		}
+		switch {
+		case len(perceptualHashes) > 0:
+			similar, err = uc.repo.FindByPerceptualHash(ctx, perceptualHashes, maxDistance, limit)
+		case len(fingerprint) > 0:
+			similar, err = uc.repo.FindByVideoFingerprint(ctx, fingerprint, maxDistance, limit)
 		}
-		if cert == nil {
+		if err != nil {
+			return nil, fmt.Errorf("verify: %w", err)
+		}
+		if len(similar) == 0 {
 			return &VerifyOutput{Certified: false}, nil
 		}
+		match, matchType = nearestAnchored(similar), domain.MatchPerceptual
+		cert = match.Certificate
+	}
+
+	out := &VerifyOutput{
+		Certified:   cert.IsAnchored(),
+		Certificate: cert,
+		MatchType:   matchType,
+		Distance:    match.Distance,
+		Similarity:  match.Similarity(),
+		Distances:   match.Distances,
+		Video:       match.Video,
+		Similar:     similar,
+	}
+	if cert.Batch != nil {
+		included := cert.VerifyInclusion()
+		out.InclusionVerified = &included
+		out.Certified = out.Certified && included
+	}
+	if verifier, ok := uc.chain.(TimestampVerifier); ok && cert.TimestampToken != nil {
+		at, err := verifier.VerifyTimestamp(cert.AnchorHash(), cert.TimestampToken)
+		valid := err == nil
+		out.TimestampVerified = &valid
+		if valid {
+			out.TimestampedAt = &at
+		}
+		out.Certified = out.Certified && valid
+	}
+	if in.ConfirmOnChain {
+		if uc.chain == nil {
+			return nil, fmt.Errorf("verify: on-chain confirmation is not available")
+		}
+		registered, err := uc.chain.IsHashRegistered(ctx, cert.AnchorHash())
+		if err != nil {
+			return nil, fmt.Errorf("verify: confirming on chain: %w", err)
+		}
+		out.OnChainConfirmed = &registered
 	}
 
-	return &VerifyOutput{Certified: true, Certificate: cert}, nil
+	return out, nil
+}
+
+// nearestAnchored picks the candidate a verdict is based on: the nearest
+// anchored one, so a closer pending or failed certificate does not hide a
+// certified match. Without one, the nearest candidate is reported as not
+// certified.
+func nearestAnchored(similar []domain.SimilarCertificate) domain.SimilarCertificate {
+	for _, s := range similar {
+		if s.Certificate.IsAnchored() {
+			return s
+		}
+	}
+	return similar[0]
 }
@@ -0,0 +1,359 @@
+// Package video extracts temporal fingerprints from uploaded videos. It
+// demuxes ISO BMFF files (MP4 and QuickTime MOV) in pure Go and hashes the
+// keyframes of codecs whose samples are still images.
+package video
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+var errTruncated = errors.New("truncated box")
+
+// maxSamples bounds the samples of a track, about four and a half hours at
+// 60 frames per second, so a forged sample count cannot exhaust memory.
+const maxSamples = 1 << 20
+
+// Track is the first video track of an ISO BMFF file.
+type Track struct {
+	// Codec is the four-character code of the sample entry, e.g. "avc1".
+	Codec    string
+	Duration time.Duration
+	Samples  []Sample
+}
+
+// Sample locates one frame in the file, in decode order.
+type Sample struct {
+	Time   time.Duration
+	Offset int64
+	Size   int64
+	Sync   bool
+}
+
+// DemuxBMFF reads the sample tables of the first video track. Fragmented
+// files, whose samples are described in moof boxes, are not supported.
+func DemuxBMFF(content []byte) (*Track, error) {
+	top, err := readBoxes(content)
+	if err != nil {
+		return nil, fmt.Errorf("demux: not an ISO BMFF file (%v): %w", err, domain.ErrUnsupportedVideo)
+	}
+	moov := find(top, "moov")
+	if moov == nil {
+		return nil, fmt.Errorf("demux: no moov box: %w", domain.ErrUnsupportedVideo)
+	}
+	traks, err := readBoxes(moov.body)
+	if err != nil {
+		return nil, fmt.Errorf("demux moov: %w", err)
+	}
+	for _, trak := range traks {
+		if trak.typ != "trak" {
+			continue
+		}
+		track, err := readTrack(trak.body, int64(len(content)))
+		if err != nil {
+			return nil, fmt.Errorf("demux trak: %w", err)
+		}
+		if track != nil {
+			return track, nil
+		}
+	}
+	return nil, fmt.Errorf("demux: no video track: %w", domain.ErrUnsupportedVideo)
+}
+
+type box struct {
+	typ  string
+	body []byte
+}
+
+func readBoxes(b []byte) ([]box, error) {
+	var boxes []box
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, errTruncated
+		}
+		size, header := uint64(binary.BigEndian.Uint32(b)), uint64(8)
+		typ := string(b[4:8])
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return nil, errTruncated
+			}
+			size, header = binary.BigEndian.Uint64(b[8:]), 16
+		}
+		if size < header || size > uint64(len(b)) {
+			return nil, fmt.Errorf("%s: %w", typ, errTruncated)
+		}
+		boxes = append(boxes, box{typ: typ, body: b[header:size]})
+		b = b[size:]
+	}
+	return boxes, nil
+}
+
+func find(boxes []box, typ string) *box {
+	for i := range boxes {
+		if boxes[i].typ == typ {
+			return &boxes[i]
+		}
+	}
+	return nil
+}
+
+// findPath descends through nested boxes, one type per level.
+func findPath(b []byte, path ...string) (*box, error) {
+	var found *box
+	for _, typ := range path {
+		boxes, err := readBoxes(b)
+		if err != nil {
+			return nil, err
+		}
+		if found = find(boxes, typ); found == nil {
+			return nil, nil
+		}
+		b = found.body
+	}
+	return found, nil
+}
+
+// readTrack returns nil for tracks that are not video.
+func readTrack(trak []byte, fileSize int64) (*Track, error) {
+	hdlr, err := findPath(trak, "mdia", "hdlr")
+	if err != nil || hdlr == nil {
+		return nil, err
+	}
+	r := reader{b: hdlr.body}
+	r.skip(8)
+	if string(r.bytes(4)) != "vide" {
+		return nil, nil
+	}
+
+	mdhd, err := findPath(trak, "mdia", "mdhd")
+	if err != nil {
+		return nil, err
+	}
+	stbl, err := findPath(trak, "mdia", "minf", "stbl")
+	if err != nil {
+		return nil, err
+	}
+	if mdhd == nil || stbl == nil {
+		return nil, fmt.Errorf("video track without mdhd or stbl: %w", domain.ErrUnsupportedVideo)
+	}
+
+	r = reader{b: mdhd.body}
+	var timescale, duration uint64
+	if version := r.u8(); r.skip(3) && version == 1 {
+		r.skip(16)
+		timescale, duration = uint64(r.u32()), r.u64()
+	} else {
+		r.skip(8)
+		timescale, duration = uint64(r.u32()), uint64(r.u32())
+	}
+	if r.err != nil || timescale == 0 {
+		return nil, fmt.Errorf("mdhd: %w", errTruncated)
+	}
+
+	tables, err := readBoxes(stbl.body)
+	if err != nil {
+		return nil, err
+	}
+	track := &Track{Duration: scaleTime(duration, timescale)}
+	if stsd := find(tables, "stsd"); stsd != nil && len(stsd.body) >= 16 {
+		track.Codec = string(stsd.body[12:16])
+	}
+	if track.Samples, err = readSamples(tables, timescale, fileSize); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// readSamples combines the sample tables into one entry per sample.
+func readSamples(tables []box, timescale uint64, fileSize int64) ([]Sample, error) {
+	stsz, stsc, stts := find(tables, "stsz"), find(tables, "stsc"), find(tables, "stts")
+	chunkOffsets, err := readChunkOffsets(tables)
+	if err != nil {
+		return nil, err
+	}
+	if stsz == nil || stsc == nil || stts == nil {
+		return nil, fmt.Errorf("missing sample tables: %w", domain.ErrUnsupportedVideo)
+	}
+
+	// stsc runs give the samples per chunk from each first chunk on.
+	r := reader{b: stsc.body}
+	r.skip(4)
+	runs := make([][2]uint32, r.count(12))
+	for i := range runs {
+		runs[i] = [2]uint32{r.u32(), r.u32()}
+		r.skip(4)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("stsc: %w", r.err)
+	}
+	lastChunk := func(run int) uint32 {
+		last := uint32(len(chunkOffsets))
+		if run+1 < len(runs) {
+			last = min(last, runs[run+1][0]-1)
+		}
+		return last
+	}
+	// chunked is the number of samples the chunks hold, up to maxSamples.
+	var chunked uint64
+	for run, entry := range runs {
+		if first, last := entry[0], lastChunk(run); first >= 1 && first <= last {
+			chunked = min(chunked+uint64(last-first+1)*uint64(entry[1]), maxSamples)
+		}
+	}
+
+	r = reader{b: stsz.body}
+	r.skip(4)
+	uniform := int64(r.u32())
+	var count int
+	if uniform == 0 {
+		count = r.count(4)
+	} else if count = r.count(0); int64(count) > fileSize/uniform {
+		return nil, fmt.Errorf("stsz: %d samples of %d bytes: %w", count, uniform, errTruncated)
+	}
+	if r.err == nil && count > maxSamples {
+		return nil, fmt.Errorf("stsz: %d samples exceed the limit of %d: %w", count, maxSamples, domain.ErrUnsupportedVideo)
+	}
+	if r.err == nil && uint64(count) > chunked {
+		return nil, fmt.Errorf("stsc: %d of %d samples in chunks", chunked, count)
+	}
+	samples := make([]Sample, count)
+	for i := range samples {
+		samples[i].Size = uniform
+		if uniform == 0 {
+			samples[i].Size = int64(r.u32())
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("stsz: %w", r.err)
+	}
+
+	next := 0
+	for run, entry := range runs {
+		for chunk := entry[0]; chunk >= 1 && chunk <= lastChunk(run) && next < len(samples); chunk++ {
+			offset := chunkOffsets[chunk-1]
+			for n := uint32(0); n < entry[1] && next < len(samples); n++ {
+				samples[next].Offset = offset
+				offset += samples[next].Size
+				next++
+			}
+		}
+	}
+	for _, s := range samples {
+		if s.Offset < 0 || s.Size < 0 || s.Offset > fileSize || s.Size > fileSize-s.Offset {
+			return nil, fmt.Errorf("sample at %d: %w", s.Offset, errTruncated)
+		}
+	}
+
+	r = reader{b: stts.body}
+	r.skip(4)
+	var decodeTime uint64
+	next = 0
+	for n := r.count(8); n > 0; n-- {
+		sampleCount, delta := r.u32(), uint64(r.u32())
+		for ; sampleCount > 0 && next < len(samples); sampleCount-- {
+			samples[next].Time = scaleTime(decodeTime, timescale)
+			decodeTime += delta
+			next++
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("stts: %w", r.err)
+	}
+
+	// Without stss every sample is a sync sample.
+	stss := find(tables, "stss")
+	for i := range samples {
+		samples[i].Sync = stss == nil
+	}
+	if stss != nil {
+		r = reader{b: stss.body}
+		r.skip(4)
+		for n := r.count(4); n > 0; n-- {
+			if i := int(r.u32()) - 1; i >= 0 && i < len(samples) {
+				samples[i].Sync = true
+			}
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("stss: %w", r.err)
+		}
+	}
+	return samples, nil
+}
+
+func readChunkOffsets(tables []box) ([]int64, error) {
+	table, wide := find(tables, "stco"), false
+	if table == nil {
+		table, wide = find(tables, "co64"), true
+	}
+	if table == nil {
+		return nil, fmt.Errorf("missing chunk offsets: %w", domain.ErrUnsupportedVideo)
+	}
+	r := reader{b: table.body}
+	r.skip(4)
+	size := 4
+	if wide {
+		size = 8
+	}
+	offsets := make([]int64, r.count(size))
+	for i := range offsets {
+		if wide {
+			offsets[i] = int64(r.u64())
+		} else {
+			offsets[i] = int64(r.u32())
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%s: %w", table.typ, r.err)
+	}
+	return offsets, nil
+}
+
+func scaleTime(units, timescale uint64) time.Duration {
+	return time.Duration(float64(units) / float64(timescale) * float64(time.Second))
+}
+
+// reader decodes big-endian fields, recording the first overrun in err.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || len(r.b) < n {
+		r.err = errTruncated
+		return make([]byte, n)
+	}
+	out := r.b[:n]
+	r.b = r.b[n:]
+	return out
+}
+
+func (r *reader) skip(n int) bool {
+	r.bytes(n)
+	return r.err == nil
+}
+
+func (r *reader) u8() uint8   { return r.bytes(1)[0] }
+func (r *reader) u32() uint32 { return binary.BigEndian.Uint32(r.bytes(4)) }
+func (r *reader) u64() uint64 { return binary.BigEndian.Uint64(r.bytes(8)) }
+
+// count reads an entry count and checks that entries of size bytes each fit
+// in the rest of the box, so a corrupt count cannot force a huge allocation.
+// Callers that bound the count themselves pass 0.
+func (r *reader) count(size int) int {
+	n := int(r.u32())
+	if r.err == nil && size > 0 && n > len(r.b)/size {
+		r.err = errTruncated
+	}
+	if r.err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,73 @@
+package video
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+// DefaultSegmentInterval is the shortest segment a fingerprint is cut into.
+const DefaultSegmentInterval = time.Second
+
+// stillImageCodecs are the sample entry types whose samples image.Decode can
+// read: Motion JPEG and PNG. Decoding H.264, HEVC, VP8 or VP9 keyframes needs
+// a video decoder and is out of scope.
+var stillImageCodecs = map[string]bool{
+	"jpeg": true,
+	"mjpa": true,
+	"mjpg": true,
+	"png ": true,
+}
+
+// Fingerprinter cuts a video into segments at its keyframes, at most one
+// per interval, and hashes the frame each segment starts with. Only Motion
+// JPEG and PNG tracks are fingerprinted: H.264 (avc1), HEVC (hvc1, hev1), VP9
+// and AV1 samples need a video decoder and are reported as unsupported, so
+// such videos can only be matched by SHA-256.
+type Fingerprinter struct {
+	interval time.Duration
+	hashers  []domain.PerceptualHasher
+}
+
+func NewFingerprinter() *Fingerprinter {
+	return &Fingerprinter{interval: DefaultSegmentInterval, hashers: domain.DefaultPerceptualHashers()}
+}
+
+func (f *Fingerprinter) WithSegmentInterval(d time.Duration) *Fingerprinter {
+	f.interval = d
+	return f
+}
+
+func (f *Fingerprinter) Fingerprint(content []byte) (domain.VideoFingerprint, error) {
+	track, err := DemuxBMFF(content)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint: %w", err)
+	}
+	if !stillImageCodecs[track.Codec] {
+		return nil, fmt.Errorf("fingerprint: codec %q: only Motion JPEG and PNG tracks can be fingerprinted: %w", track.Codec, domain.ErrUnsupportedVideo)
+	}
+
+	var fp domain.VideoFingerprint
+	for _, s := range track.Samples {
+		if !s.Sync || (len(fp) > 0 && s.Time < fp[len(fp)-1].Start+f.interval) {
+			continue
+		}
+		// DecodeImage skips keyframes declaring more than MaxImagePixels
+		// before allocating them.
+		hashes := domain.DecodeImage(content[s.Offset : s.Offset+s.Size]).PerceptualHashes(f.hashers...)
+		if hashes == nil {
+			continue
+		}
+		if len(fp) > 0 {
+			fp[len(fp)-1].End = s.Time
+		}
+		fp = append(fp, domain.VideoSegment{Start: s.Time, Hashes: hashes})
+	}
+	if len(fp) == 0 {
+		return nil, fmt.Errorf("fingerprint: no decodable keyframes: %w", domain.ErrUnsupportedVideo)
+	}
+	last := &fp[len(fp)-1]
+	last.End = max(track.Duration, last.Start)
+	return fp, nil
+}
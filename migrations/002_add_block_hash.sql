@@ -0,0 +1 @@
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS block_hash TEXT NOT NULL DEFAULT '';
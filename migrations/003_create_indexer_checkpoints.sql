@@ -0,0 +1,7 @@
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS chain_mismatch TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS indexer_checkpoints (
+    name         TEXT PRIMARY KEY,
+    block_number BIGINT NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,11 @@
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'confirmed';
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE certificates DROP CONSTRAINT IF EXISTS certificates_status_check;
+ALTER TABLE certificates ADD CONSTRAINT certificates_status_check
+    CHECK (status IN ('pending', 'submitted', 'confirmed', 'failed'));
+
+CREATE INDEX IF NOT EXISTS idx_certificates_outbox ON certificates(updated_at)
+    WHERE status IN ('pending', 'submitted');
@@ -0,0 +1,8 @@
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS merkle_root TEXT;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS leaf_index BIGINT;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS tree_size BIGINT;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS merkle_proof TEXT[];
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_certificates_merkle_root ON certificates(merkle_root)
+    WHERE merkle_root IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS gas_used BIGINT;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS effective_gas_price NUMERIC(78, 0);
@@ -0,0 +1,10 @@
+ALTER TABLE certificates DROP CONSTRAINT IF EXISTS certificates_status_check;
+ALTER TABLE certificates ADD CONSTRAINT certificates_status_check
+    CHECK (status IN ('pending', 'submitted', 'confirmed', 'failed', 'reorged'));
+
+DROP INDEX IF EXISTS idx_certificates_outbox;
+CREATE INDEX IF NOT EXISTS idx_certificates_outbox ON certificates(updated_at)
+    WHERE status IN ('pending', 'submitted', 'reorged');
+
+CREATE INDEX IF NOT EXISTS idx_certificates_confirmed_block ON certificates(block_number)
+    WHERE status = 'confirmed';
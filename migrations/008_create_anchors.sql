@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS anchors (
+    certificate_id   UUID NOT NULL REFERENCES certificates(id) ON DELETE CASCADE,
+    chain_id         BIGINT NOT NULL,
+    contract_address TEXT NOT NULL,
+    tx_hash          TEXT NOT NULL,
+    block_number     BIGINT NOT NULL,
+    block_hash       TEXT NOT NULL DEFAULT '',
+    merkle_root      TEXT,
+    leaf_index       BIGINT,
+    tree_size        BIGINT,
+    merkle_proof     TEXT[],
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (certificate_id, chain_id, contract_address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_anchors_chain ON anchors(chain_id, contract_address);
@@ -0,0 +1 @@
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS timestamp_token BYTEA;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS log_entries (
+    leaf_index   BIGINT PRIMARY KEY,
+    content_hash TEXT NOT NULL UNIQUE,
+    registrant   TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS log_nodes (
+    level      INT NOT NULL,
+    node_index BIGINT NOT NULL,
+    hash       BYTEA NOT NULL,
+    PRIMARY KEY (level, node_index)
+);
+
+CREATE TABLE IF NOT EXISTS log_tree_heads (
+    tree_size    BIGINT PRIMARY KEY,
+    root_hash    TEXT NOT NULL,
+    timestamp_ms BIGINT NOT NULL,
+    signature    BYTEA NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION reject_log_rewrite() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'transparency log table % is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS log_entries_append_only ON log_entries;
+CREATE TRIGGER log_entries_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON log_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION reject_log_rewrite();
+
+DROP TRIGGER IF EXISTS log_nodes_append_only ON log_nodes;
+CREATE TRIGGER log_nodes_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON log_nodes
+    FOR EACH STATEMENT EXECUTE FUNCTION reject_log_rewrite();
+
+DROP TRIGGER IF EXISTS log_tree_heads_append_only ON log_tree_heads;
+CREATE TRIGGER log_tree_heads_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON log_tree_heads
+    FOR EACH STATEMENT EXECUTE FUNCTION reject_log_rewrite();
@@ -0,0 +1,17 @@
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS phash_band0 INTEGER;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS phash_band1 INTEGER;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS phash_band2 INTEGER;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS phash_band3 INTEGER;
+
+UPDATE certificates
+SET phash_band0 = (perceptual_hash >> 48) & 65535,
+    phash_band1 = (perceptual_hash >> 32) & 65535,
+    phash_band2 = (perceptual_hash >> 16) & 65535,
+    phash_band3 = perceptual_hash & 65535
+WHERE perceptual_hash IS NOT NULL AND phash_band0 IS NULL;
+
+DROP INDEX IF EXISTS idx_certificates_perceptual_hash;
+CREATE INDEX IF NOT EXISTS idx_certificates_phash_band0 ON certificates(phash_band0);
+CREATE INDEX IF NOT EXISTS idx_certificates_phash_band1 ON certificates(phash_band1);
+CREATE INDEX IF NOT EXISTS idx_certificates_phash_band2 ON certificates(phash_band2);
+CREATE INDEX IF NOT EXISTS idx_certificates_phash_band3 ON certificates(phash_band3);
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS perceptual_hashes (
+    certificate_id UUID NOT NULL REFERENCES certificates(id) ON DELETE CASCADE,
+    algorithm      TEXT NOT NULL,
+    hash           BIGINT NOT NULL,
+    band0          INTEGER NOT NULL,
+    band1          INTEGER NOT NULL,
+    band2          INTEGER NOT NULL,
+    band3          INTEGER NOT NULL,
+    PRIMARY KEY (certificate_id, algorithm)
+);
+
+CREATE INDEX IF NOT EXISTS idx_perceptual_hashes_band0 ON perceptual_hashes(algorithm, band0);
+CREATE INDEX IF NOT EXISTS idx_perceptual_hashes_band1 ON perceptual_hashes(algorithm, band1);
+CREATE INDEX IF NOT EXISTS idx_perceptual_hashes_band2 ON perceptual_hashes(algorithm, band2);
+CREATE INDEX IF NOT EXISTS idx_perceptual_hashes_band3 ON perceptual_hashes(algorithm, band3);
+
+INSERT INTO perceptual_hashes (certificate_id, algorithm, hash, band0, band1, band2, band3)
+SELECT id, 'ahash_legacy', perceptual_hash,
+       (perceptual_hash >> 48) & 65535, (perceptual_hash >> 32) & 65535,
+       (perceptual_hash >> 16) & 65535, perceptual_hash & 65535
+FROM certificates
+WHERE perceptual_hash IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS idx_certificates_phash_band0;
+DROP INDEX IF EXISTS idx_certificates_phash_band1;
+DROP INDEX IF EXISTS idx_certificates_phash_band2;
+DROP INDEX IF EXISTS idx_certificates_phash_band3;
+ALTER TABLE certificates DROP COLUMN IF EXISTS phash_band0;
+ALTER TABLE certificates DROP COLUMN IF EXISTS phash_band1;
+ALTER TABLE certificates DROP COLUMN IF EXISTS phash_band2;
+ALTER TABLE certificates DROP COLUMN IF EXISTS phash_band3;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS video_segments (
+    certificate_id UUID NOT NULL REFERENCES certificates(id) ON DELETE CASCADE,
+    position       INTEGER NOT NULL,
+    start_ms       BIGINT NOT NULL,
+    end_ms         BIGINT NOT NULL,
+    algorithm      TEXT NOT NULL,
+    hash           BIGINT NOT NULL,
+    band0          INTEGER NOT NULL,
+    band1          INTEGER NOT NULL,
+    band2          INTEGER NOT NULL,
+    band3          INTEGER NOT NULL,
+    PRIMARY KEY (certificate_id, position, algorithm)
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_segments_band0 ON video_segments(algorithm, band0);
+CREATE INDEX IF NOT EXISTS idx_video_segments_band1 ON video_segments(algorithm, band1);
+CREATE INDEX IF NOT EXISTS idx_video_segments_band2 ON video_segments(algorithm, band2);
+CREATE INDEX IF NOT EXISTS idx_video_segments_band3 ON video_segments(algorithm, band3);
@@ -0,0 +1,3 @@
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS pixel_hash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_certificates_pixel_hash ON certificates(pixel_hash) WHERE pixel_hash <> '';
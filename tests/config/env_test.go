@@ -3,6 +3,7 @@ package config_test
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/waizbart/aletheia-api/internal/config"
 )
@@ -50,3 +51,82 @@ func TestEnvOrDefault_Missing(t *testing.T) {
 		t.Errorf("got %q, want %q", got, "fallback")
 	}
 }
+
+func captureFatal(t *testing.T) *bool {
+	t.Helper()
+	original := config.Fatalf
+	t.Cleanup(func() { config.Fatalf = original })
+
+	called := new(bool)
+	config.Fatalf = func(format string, args ...any) { *called = true }
+	return called
+}
+
+func TestEnvBool(t *testing.T) {
+	t.Setenv("TEST_ENV_BOOL", "")
+	if got := config.EnvBool("TEST_ENV_BOOL", true); !got {
+		t.Error("expected fallback true")
+	}
+
+	t.Setenv("TEST_ENV_BOOL", "false")
+	if got := config.EnvBool("TEST_ENV_BOOL", true); got {
+		t.Error("expected false")
+	}
+
+	called := captureFatal(t)
+	t.Setenv("TEST_ENV_BOOL", "nope")
+	if got := config.EnvBool("TEST_ENV_BOOL", true); !got || !*called {
+		t.Errorf("invalid bool: got %v, fatal called %v", got, *called)
+	}
+}
+
+func TestEnvUint(t *testing.T) {
+	t.Setenv("TEST_ENV_UINT", "")
+	if got := config.EnvUint("TEST_ENV_UINT", 7); got != 7 {
+		t.Errorf("got %d, want 7", got)
+	}
+
+	t.Setenv("TEST_ENV_UINT", "42")
+	if got := config.EnvUint("TEST_ENV_UINT", 7); got != 42 {
+		t.Errorf("got %d, want 42", got)
+	}
+
+	called := captureFatal(t)
+	t.Setenv("TEST_ENV_UINT", "-1")
+	if got := config.EnvUint("TEST_ENV_UINT", 7); got != 7 || !*called {
+		t.Errorf("invalid uint: got %d, fatal called %v", got, *called)
+	}
+}
+
+func TestEnvDuration(t *testing.T) {
+	t.Setenv("TEST_ENV_DURATION", "")
+	if got := config.EnvDuration("TEST_ENV_DURATION", time.Second); got != time.Second {
+		t.Errorf("got %v, want 1s", got)
+	}
+
+	t.Setenv("TEST_ENV_DURATION", "250ms")
+	if got := config.EnvDuration("TEST_ENV_DURATION", time.Second); got != 250*time.Millisecond {
+		t.Errorf("got %v, want 250ms", got)
+	}
+
+	called := captureFatal(t)
+	t.Setenv("TEST_ENV_DURATION", "0s")
+	if got := config.EnvDuration("TEST_ENV_DURATION", time.Second); got != time.Second || !*called {
+		t.Errorf("invalid duration: got %v, fatal called %v", got, *called)
+	}
+}
+
+func TestLookupDelay(t *testing.T) {
+	t.Setenv("TEST_ENV_DELAY", "0")
+	if got, err := config.LookupDelay("TEST_ENV_DELAY", time.Second); got != 0 || err != nil {
+		t.Errorf("zero delay: got %v, %v", got, err)
+	}
+	if _, err := config.LookupDuration("TEST_ENV_DELAY", time.Second); err == nil {
+		t.Error("expected a zero duration to be rejected")
+	}
+
+	t.Setenv("TEST_ENV_DELAY", "-1s")
+	if _, err := config.LookupDelay("TEST_ENV_DELAY", time.Second); err == nil {
+		t.Error("expected a negative delay to be rejected")
+	}
+}
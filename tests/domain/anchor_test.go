@@ -0,0 +1,62 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+const anchorContract = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+
+func TestRecordAnchor_ReplacesSameChainAndContract(t *testing.T) {
+	cert := &domain.Certificate{}
+	cert.RecordAnchor(domain.Anchor{ChainID: 10, ContractAddress: anchorContract, TxHash: "0xold"})
+	cert.RecordAnchor(domain.Anchor{ChainID: 1, ContractAddress: anchorContract, TxHash: "0xmainnet"})
+	cert.RecordAnchor(domain.Anchor{ChainID: 10, ContractAddress: strings.ToLower(anchorContract), TxHash: "0xnew"})
+
+	if len(cert.Anchors) != 2 {
+		t.Fatalf("anchors = %+v, want 2", cert.Anchors)
+	}
+	if cert.Anchors[0].TxHash != "0xnew" || cert.Anchors[1].TxHash != "0xmainnet" {
+		t.Fatalf("anchors = %+v", cert.Anchors)
+	}
+	if !cert.AnchoredOn(1, strings.ToUpper(anchorContract)) || cert.AnchoredOn(137, anchorContract) {
+		t.Fatal("AnchoredOn disagrees with recorded anchors")
+	}
+}
+
+func TestMarkReorged_DropsOrphanedAnchorOnly(t *testing.T) {
+	cert := &domain.Certificate{Status: domain.StatusConfirmed, TxHash: "0xl2"}
+	cert.RecordAnchor(domain.Anchor{ChainID: 10, ContractAddress: anchorContract, TxHash: "0xl2"})
+	cert.RecordAnchor(domain.Anchor{ChainID: 1, ContractAddress: anchorContract, TxHash: "0xmainnet"})
+
+	if err := cert.MarkReorged("block replaced"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cert.Anchors) != 1 || cert.Anchors[0].ChainID != 1 {
+		t.Fatalf("anchors = %+v, want only the mainnet anchor", cert.Anchors)
+	}
+}
+
+func TestBuildMerkleBatches_LeavesCertificatesUntouched(t *testing.T) {
+	certs := []*domain.Certificate{
+		{ContentHash: strings.Repeat("01", 32)},
+		{ContentHash: strings.Repeat("02", 32)},
+		{ContentHash: strings.Repeat("03", 32)},
+	}
+	root, batches, err := domain.BuildMerkleBatches(certs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, c := range certs {
+		if c.Batch != nil {
+			t.Fatalf("cert %d batch was assigned", i)
+		}
+		c.Batch = batches[i]
+		if c.Batch.Root != root || !c.VerifyInclusion() {
+			t.Fatalf("cert %d: batch %+v does not prove inclusion in %s", i, c.Batch, root)
+		}
+	}
+}
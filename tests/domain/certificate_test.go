@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/waizbart/aletheia-api/internal/domain"
 )
@@ -42,3 +43,49 @@ func TestHashContent_ErrorReader(t *testing.T) {
 		t.Fatal("expected error, got nil")
 	}
 }
+
+func TestCertificateFromRegistration(t *testing.T) {
+	ev := domain.RegistrationEvent{
+		ContentHash:  "abc",
+		Registrant:   "0x1111111111111111111111111111111111111111",
+		RegisteredAt: time.Unix(1700000000, 0).UTC(),
+		TxHash:       "0xtx",
+		BlockNumber:  7,
+		BlockHash:    "0xblock",
+	}
+
+	cert := domain.CertificateFromRegistration(ev)
+	if cert.ContentHash != "abc" || cert.Registrant != ev.Registrant || cert.TxHash != "0xtx" ||
+		cert.BlockNumber != 7 || cert.BlockHash != "0xblock" || !cert.CreatedAt.Equal(ev.RegisteredAt) {
+		t.Fatalf("certificate = %+v", cert)
+	}
+}
+
+func TestCertificate_ReconcileRegistration(t *testing.T) {
+	ev := domain.RegistrationEvent{TxHash: "0xABC", BlockNumber: 7, BlockHash: "0xB1"}
+
+	tests := []struct {
+		name string
+		cert domain.Certificate
+		want []string
+	}{
+		{"consistent", domain.Certificate{TxHash: "0xabc", BlockNumber: 7, BlockHash: "0xb1"}, nil},
+		{"unknown block hash", domain.Certificate{TxHash: "0xabc", BlockNumber: 7}, nil},
+		{"tx hash", domain.Certificate{TxHash: "0xdef", BlockNumber: 7}, []string{"tx_hash 0xdef != chain 0xABC"}},
+		{"block", domain.Certificate{TxHash: "0xabc", BlockNumber: 0, BlockHash: "0xb2"}, []string{"block_number 0 != chain 7", "block_hash 0xb2 != chain 0xB1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cert.ReconcileRegistration(ev)
+			if len(tt.want) == 0 && got != "" {
+				t.Fatalf("expected no mismatch, got %q", got)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("mismatch %q does not mention %q", got, w)
+				}
+			}
+		})
+	}
+}
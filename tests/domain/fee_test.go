@@ -0,0 +1,45 @@
+package domain_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+func TestCertificate_MarkConfirmedRecordsFee(t *testing.T) {
+	cert := &domain.Certificate{Status: domain.StatusSubmitted}
+	receipt := &domain.Receipt{TxHash: "0xtx", GasUsed: 21000, EffectiveGasPrice: big.NewInt(2_000_000_000)}
+
+	if err := cert.MarkConfirmed(receipt); err != nil {
+		t.Fatalf("MarkConfirmed: %v", err)
+	}
+	if cert.Fee == nil || cert.Fee.Total().String() != "42000000000000" || cert.FeeShare().String() != "42000000000000" {
+		t.Fatalf("fee = %+v", cert.Fee)
+	}
+}
+
+func TestCertificate_MarkConfirmedWithoutGasPriceKeepsFee(t *testing.T) {
+	fee := &domain.AnchorFee{GasUsed: 1, EffectiveGasPrice: big.NewInt(1)}
+	cert := &domain.Certificate{Status: domain.StatusPending, Fee: fee}
+
+	if err := cert.MarkConfirmed(&domain.Receipt{TxHash: "0xtx"}); err != nil {
+		t.Fatalf("MarkConfirmed: %v", err)
+	}
+	if cert.Fee != fee {
+		t.Fatalf("fee = %+v, want unchanged", cert.Fee)
+	}
+}
+
+func TestCertificate_FeeShareSplitsBatchFee(t *testing.T) {
+	cert := &domain.Certificate{
+		Batch: &domain.MerkleBatch{TreeSize: 3},
+		Fee:   &domain.AnchorFee{GasUsed: 30000, EffectiveGasPrice: big.NewInt(10)},
+	}
+	if got := cert.FeeShare().String(); got != "100000" {
+		t.Fatalf("share = %s, want 100000", got)
+	}
+	if (&domain.Certificate{}).FeeShare() != nil {
+		t.Fatal("share of unanchored certificate should be nil")
+	}
+}
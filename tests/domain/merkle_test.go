@@ -0,0 +1,79 @@
+package domain_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+func TestMerkleLeafHash_RFC6962EmptyLeaf(t *testing.T) {
+	want := "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"
+	if got := hex.EncodeToString(domain.MerkleLeafHash(nil)); got != want {
+		t.Fatalf("leaf hash = %s, want %s", got, want)
+	}
+}
+
+func TestMerkleInclusion_AllSizesAndIndices(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		leaves := make([][]byte, size)
+		for i := range leaves {
+			leaves[i] = domain.MerkleLeafHash([]byte(fmt.Sprintf("leaf-%d", i)))
+		}
+		root := domain.MerkleRoot(leaves)
+
+		for i := range leaves {
+			proof, err := domain.MerkleInclusionProof(leaves, i)
+			if err != nil {
+				t.Fatalf("size %d index %d: %v", size, i, err)
+			}
+			if !domain.VerifyMerkleInclusion(leaves[i], uint64(i), uint64(size), proof, root) {
+				t.Fatalf("size %d index %d: valid proof rejected", size, i)
+			}
+			if size > 1 && domain.VerifyMerkleInclusion(leaves[(i+1)%size], uint64(i), uint64(size), proof, root) {
+				t.Fatalf("size %d index %d: proof accepted for another leaf", size, i)
+			}
+		}
+	}
+}
+
+func TestMerkleInclusionProof_OutOfRange(t *testing.T) {
+	if _, err := domain.MerkleInclusionProof([][]byte{domain.MerkleLeafHash(nil)}, 1); err == nil {
+		t.Fatal("expected out of range error")
+	}
+}
+
+func TestAssignMerkleBatch(t *testing.T) {
+	certs := make([]*domain.Certificate, 5)
+	for i := range certs {
+		sum := sha256.Sum256([]byte{byte(i)})
+		certs[i] = &domain.Certificate{ContentHash: hex.EncodeToString(sum[:])}
+	}
+
+	root, err := domain.AssignMerkleBatch(certs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, c := range certs {
+		if c.Batch == nil || c.Batch.Root != root || c.Batch.LeafIndex != uint64(i) || c.Batch.TreeSize != 5 {
+			t.Fatalf("cert %d batch = %+v", i, c.Batch)
+		}
+		if c.AnchorHash() != root {
+			t.Fatalf("anchor hash = %s, want root", c.AnchorHash())
+		}
+		if !c.VerifyInclusion() {
+			t.Fatalf("cert %d: inclusion not verified", i)
+		}
+	}
+
+	certs[0].ContentHash = certs[1].ContentHash
+	if certs[0].VerifyInclusion() {
+		t.Fatal("inclusion verified for a different content hash")
+	}
+
+	if _, err := domain.AssignMerkleBatch([]*domain.Certificate{{ContentHash: "zz"}}); err == nil {
+		t.Fatal("expected error for non-hex content hash")
+	}
+}
@@ -5,45 +5,186 @@ import (
 	"image"
 	"image/color"
 	"image/jpeg"
+	"image/png"
+	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/waizbart/aletheia-api/internal/domain"
 )
 
-func TestPerceptualHashFromBytes_IsStableAcrossCompression(t *testing.T) {
-	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
-	for y := 0; y < 64; y++ {
-		for x := 0; x < 64; x++ {
-			img.Set(x, y, color.RGBA{R: uint8(x * 3), G: uint8(y * 3), B: uint8((x + y) * 2), A: 255})
+// scene draws a textured picture with large shapes, closer to a photo than a
+// plain gradient.
+func scene(seed int64, w, h int) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	type blob struct{ x, y, r float64 }
+	var blobs []blob
+	for i := 0; i < 6; i++ {
+		blobs = append(blobs, blob{rng.Float64(), rng.Float64(), 0.1 + 0.2*rng.Float64()})
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			fx, fy := float64(x)/float64(w), float64(y)/float64(h)
+			v := 60 + 80*fx
+			for i, b := range blobs {
+				if math.Hypot(fx-b.x, fy-b.y) < b.r {
+					v += float64(40 * (i%3 - 1))
+				}
+			}
+			v += 12 * math.Sin(fx*40) * math.Sin(fy*40)
+			c := uint8(math.Max(0, math.Min(255, v)))
+			img.Set(x, y, color.RGBA{R: c, G: uint8(int(c) * 3 / 4), B: 255 - c, A: 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image, quality int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func gamma(img *image.RGBA, g float64) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	for i, v := range img.Pix {
+		if i%4 == 3 {
+			out.Pix[i] = v
+			continue
+		}
+		out.Pix[i] = uint8(math.Round(255 * math.Pow(float64(v)/255, g)))
+	}
+	return out
+}
+
+// resize scales img with nearest-neighbour sampling.
+func resize(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.Set(x, y, img.At(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h))
+		}
+	}
+	return out
+}
+
+func TestPerceptualHashesFromBytes_HashesWithEveryDefaultAlgorithm(t *testing.T) {
+	hashes := domain.PerceptualHashesFromBytes(encodePNG(t, scene(1, 64, 64)))
+	want := []domain.HashAlgorithm{domain.AverageHash, domain.DifferenceHash, domain.DCTHash}
+	got := hashes.Algorithms()
+	if len(got) != len(want) {
+		t.Fatalf("algorithms = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("algorithms = %v, want %v", got, want)
 		}
 	}
 
-	var lowQ bytes.Buffer
-	if err := jpeg.Encode(&lowQ, img, &jpeg.Options{Quality: 40}); err != nil {
-		t.Fatalf("encode low quality: %v", err)
+	only := domain.PerceptualHashesFromBytes(encodePNG(t, scene(1, 64, 64)), domain.DCTHasher{})
+	if len(only) != 1 || only[domain.DCTHash] != hashes[domain.DCTHash] {
+		t.Fatalf("explicit hashers = %v, want only the dct hash %016x", only, hashes[domain.DCTHash])
+	}
+}
+
+// The legacy average hash must reproduce the values older certificates
+// stored, which were computed from 8x8 sampled pixels.
+func TestLegacyAverageHasher_MatchesStoredHashes(t *testing.T) {
+	for name, tt := range map[string]struct {
+		content []byte
+		want    uint64
+	}{
+		"png":  {encodePNG(t, scene(1, 64, 64)), 0x1fdf1f1f0f0f0303},
+		"jpeg": {encodeJPEG(t, scene(2, 48, 32), 90), 0x1f1f1f1f0f0f0f0f},
+	} {
+		hashes := domain.PerceptualHashesFromBytes(tt.content, domain.VerifyPerceptualHashers()...)
+		if got := hashes[domain.LegacyAverageHash]; got != tt.want {
+			t.Errorf("%s: legacy hash = %#016x, want %#016x", name, got, tt.want)
+		}
+		if len(hashes) != 4 {
+			t.Errorf("%s: verify hashes = %v, want the defaults and the legacy hash", name, hashes)
+		}
 	}
+}
+
+func TestPerceptualHashes_SurviveEdits(t *testing.T) {
+	original := scene(1, 256, 192)
+	reference := domain.PerceptualHashesFromBytes(encodePNG(t, original))
+	unrelated := domain.PerceptualHashesFromBytes(encodePNG(t, scene(2, 256, 192)))
 
-	var highQ bytes.Buffer
-	if err := jpeg.Encode(&highQ, img, &jpeg.Options{Quality: 90}); err != nil {
-		t.Fatalf("encode high quality: %v", err)
+	edits := map[string][]byte{
+		"jpeg quality 30": encodeJPEG(t, original, 30),
+		"gamma 0.7":       encodePNG(t, gamma(original, 0.7)),
+		"gamma 1.4":       encodePNG(t, gamma(original, 1.4)),
+		"downscaled":      encodePNG(t, resize(original, 100, 75)),
+		"upscaled":        encodeJPEG(t, resize(original, 512, 384), 80),
+	}
+	for name, content := range edits {
+		t.Run(name, func(t *testing.T) {
+			distances := reference.Distances(domain.PerceptualHashesFromBytes(content))
+			if d := distances[domain.DCTHash]; d > 6 {
+				t.Fatalf("dct hash moved %d bits", d)
+			}
+			if d, ok := domain.CombinedDistance(distances); !ok || d > 6 {
+				t.Fatalf("combined distance = %d, %v", d, ok)
+			}
+		})
 	}
 
-	lowHash := domain.PerceptualHashFromBytes(lowQ.Bytes())
-	highHash := domain.PerceptualHashFromBytes(highQ.Bytes())
-	if lowHash == nil || highHash == nil {
-		t.Fatal("expected perceptual hashes for both jpeg encodings")
+	if d, _ := domain.CombinedDistance(reference.Distances(unrelated)); d <= 8 {
+		t.Fatalf("unrelated images are only %d bits apart, within the default verify threshold", d)
 	}
+}
 
-	d := domain.HammingDistance(*lowHash, *highHash)
-	if d > 8 {
-		t.Fatalf("distance too large for same image: got %d", d)
+func TestPerceptualHashesFromBytes_NonImage(t *testing.T) {
+	if h := domain.PerceptualHashesFromBytes([]byte("not-an-image")); h != nil {
+		t.Fatalf("expected nil hashes for non-image bytes, got %v", h)
 	}
 }
 
-func TestPerceptualHashFromBytes_NonImage(t *testing.T) {
-	h := domain.PerceptualHashFromBytes([]byte("not-an-image"))
-	if h != nil {
-		t.Fatal("expected nil hash for non-image bytes")
+func TestCombinedDistance(t *testing.T) {
+	if _, ok := domain.CombinedDistance(nil); ok {
+		t.Fatal("expected no combined distance without shared algorithms")
+	}
+	d, ok := domain.CombinedDistance(map[domain.HashAlgorithm]int{domain.DCTHash: 2, domain.DifferenceHash: 3, domain.AverageHash: 5})
+	if !ok || d != 3 {
+		t.Fatalf("combined = %d, %v, want the rounded mean 3", d, ok)
+	}
+
+	a := domain.PerceptualHashes{domain.DCTHash: 0b1111, domain.AverageHash: 0}
+	b := domain.PerceptualHashes{domain.DCTHash: 0b0011, domain.DifferenceHash: 1}
+	if got := a.Distances(b); len(got) != 1 || got[domain.DCTHash] != 2 {
+		t.Fatalf("distances = %v, want only the shared dct hash", got)
+	}
+}
+
+func TestMatchSimilar(t *testing.T) {
+	cert := &domain.Certificate{PerceptualHashes: domain.PerceptualHashes{domain.DCTHash: 0, domain.AverageHash: 0xff}}
+	query := domain.PerceptualHashes{domain.DCTHash: 0b1, domain.AverageHash: 0xff}
+
+	m, ok := domain.MatchSimilar(query, cert, 1)
+	if !ok || m.Certificate != cert || m.Distance != 1 || m.Distances[domain.DCTHash] != 1 || m.Distances[domain.AverageHash] != 0 {
+		t.Fatalf("match = %+v, %v", m, ok)
+	}
+	if _, ok := domain.MatchSimilar(query, cert, 0); ok {
+		t.Fatal("expected no match within 0 bits")
+	}
+	if _, ok := domain.MatchSimilar(domain.PerceptualHashes{domain.DifferenceHash: 0}, cert, 64); ok {
+		t.Fatal("expected no match without shared algorithms")
 	}
 }
 
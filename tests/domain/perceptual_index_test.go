@@ -0,0 +1,71 @@
+package domain_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+func TestHashBands_MostSignificantFirst(t *testing.T) {
+	bands := domain.HashBands(0x1111_2222_3333_4444)
+	if bands != [4]uint16{0x1111, 0x2222, 0x3333, 0x4444} {
+		t.Fatalf("bands = %x", bands)
+	}
+}
+
+func TestBandProbes_CoverEveryHashWithinDistance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, maxDistance := range []int{0, 3, 4, 8, 15} {
+		for i := 0; i < 500; i++ {
+			query := rng.Uint64()
+			other := query
+			for _, bit := range rng.Perm(64)[:rng.Intn(maxDistance+1)] {
+				other ^= 1 << bit
+			}
+
+			probes, ok := domain.BandProbes(query, maxDistance)
+			if !ok {
+				t.Fatalf("distance %d: probing refused", maxDistance)
+			}
+			if !probed(probes, domain.HashBands(other)) {
+				t.Fatalf("distance %d: %016x within %d bits of %016x was not probed", maxDistance, other, domain.HammingDistance(query, other), query)
+			}
+		}
+	}
+}
+
+func TestBandProbes_Sizes(t *testing.T) {
+	probes, ok := domain.BandProbes(0, 8)
+	if !ok || len(probes[0]) != 1+16+120 {
+		t.Fatalf("probes per band = %d, want 137", len(probes[0]))
+	}
+	seen := map[uint16]bool{}
+	for _, v := range probes[0] {
+		if seen[v] || domain.HammingDistance(0, uint64(v)) > 2 {
+			t.Fatalf("unexpected probe %016b", v)
+		}
+		seen[v] = true
+	}
+
+	if probes, ok := domain.BandProbes(0, -1); !ok || len(probes[0]) != 0 {
+		t.Fatalf("negative distance probes = %d, %v", len(probes[0]), ok)
+	}
+	if _, ok := domain.BandProbes(0, domain.MaxIndexedDistance); !ok {
+		t.Fatalf("expected probing up to %d", domain.MaxIndexedDistance)
+	}
+	if _, ok := domain.BandProbes(0, domain.MaxIndexedDistance+1); ok {
+		t.Fatal("expected probing to be refused for large distances")
+	}
+}
+
+func probed(probes [domain.PerceptualHashBands][]uint16, bands [domain.PerceptualHashBands]uint16) bool {
+	for i, values := range probes {
+		for _, v := range values {
+			if v == bands[i] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package domain_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"runtime"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+// exifTIFF is a big-endian TIFF structure whose only IFD entry is the
+// orientation tag.
+func exifTIFF(orientation uint16) []byte {
+	b := []byte("MM\x00*\x00\x00\x00\x08\x00\x01")
+	b = binary.BigEndian.AppendUint16(b, 0x0112)
+	b = binary.BigEndian.AppendUint16(b, 3)
+	b = binary.BigEndian.AppendUint32(b, 1)
+	b = binary.BigEndian.AppendUint16(b, orientation)
+	return append(b, 0, 0, 0, 0, 0, 0)
+}
+
+// withJPEGSegment inserts an APPn segment right after the SOI marker.
+func withJPEGSegment(content []byte, marker byte, payload []byte) []byte {
+	seg := []byte{0xff, marker}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	seg = append(seg, payload...)
+	return append(append(append([]byte(nil), content[:2]...), seg...), content[2:]...)
+}
+
+// withPNGChunk inserts a chunk right after IHDR.
+func withPNGChunk(content []byte, typ string, data []byte) []byte {
+	const afterIHDR = 8 + 12 + 13
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, typ...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	return append(append(append([]byte(nil), content[:afterIHDR]...), chunk...), content[afterIHDR:]...)
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	out := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(out, out.Rect, img, img.Bounds().Min, draw.Src)
+	return out
+}
+
+// rotateCW turns img a quarter turn clockwise.
+func rotateCW(img *image.NRGBA) *image.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, h, w))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.Set(h-1-y, x, img.At(x, y))
+		}
+	}
+	return out
+}
+
+func flipH(img *image.NRGBA) *image.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	out := image.NewNRGBA(img.Rect)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.Set(w-1-x, y, img.At(x, y))
+		}
+	}
+	return out
+}
+
+func TestPixelHashFromBytes_IgnoresMetadata(t *testing.T) {
+	img := scene(3, 48, 32)
+	plain := encodeJPEG(t, img, 90)
+	want := domain.PixelHashFromBytes(plain)
+	if len(want) != 64 {
+		t.Fatalf("pixel hash = %q", want)
+	}
+
+	xmp := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), `<x:xmpmeta xmlns:x="adobe:ns:meta/"/>`...)
+	for name, content := range map[string][]byte{
+		"exif":    withJPEGSegment(plain, 0xe1, append([]byte("Exif\x00\x00"), exifTIFF(1)...)),
+		"xmp":     withJPEGSegment(plain, 0xe1, xmp),
+		"comment": withJPEGSegment(plain, 0xfe, []byte("re-saved")),
+	} {
+		if got := domain.PixelHashFromBytes(content); got != want {
+			t.Errorf("%s: pixel hash = %s, want %s", name, got, want)
+		}
+	}
+	pngPlain := encodePNG(t, img)
+	if got, want := domain.PixelHashFromBytes(withPNGChunk(pngPlain, "tEXt", []byte("Comment\x00re-saved"))), domain.PixelHashFromBytes(pngPlain); got != want {
+		t.Errorf("png text: pixel hash = %s, want %s", got, want)
+	}
+
+	if domain.PixelHashFromBytes(pngPlain) == domain.PixelHashFromBytes(encodePNG(t, gamma(img, 0.9))) {
+		t.Error("different pixels share a pixel hash")
+	}
+	if got := domain.PixelHashFromBytes([]byte("not an image")); got != "" {
+		t.Errorf("non-image pixel hash = %q, want empty", got)
+	}
+}
+
+func TestPixelHashFromBytes_AppliesOrientation(t *testing.T) {
+	display := toNRGBA(scene(4, 24, 16))
+	want := domain.PixelHashFromBytes(encodePNG(t, display))
+
+	rotate := func(img *image.NRGBA, quarters int) *image.NRGBA {
+		for ; quarters > 0; quarters-- {
+			img = rotateCW(img)
+		}
+		return img
+	}
+	// stored is the image a camera writes for the display image with each
+	// orientation, the inverse of the transform the tag asks for.
+	stored := map[uint16]*image.NRGBA{
+		2: flipH(display),
+		3: rotate(display, 2),
+		4: flipH(rotate(display, 2)),
+		5: flipH(rotate(display, 1)),
+		6: rotate(display, 3),
+		7: flipH(rotate(display, 3)),
+		8: rotate(display, 1),
+	}
+	for orientation, img := range stored {
+		content := withPNGChunk(encodePNG(t, img), "eXIf", exifTIFF(orientation))
+		if got := domain.PixelHashFromBytes(content); got != want {
+			t.Errorf("orientation %d: pixel hash = %s, want %s", orientation, got, want)
+		}
+		if got := domain.PixelHashFromBytes(encodePNG(t, img)); got == want {
+			t.Errorf("orientation %d: untagged image hashed as oriented", orientation)
+		}
+	}
+
+	// A JPEG tagged to be turned clockwise hashes like its decoded pixels
+	// turned clockwise.
+	plain := encodeJPEG(t, scene(5, 24, 16), 90)
+	decoded, err := jpeg.Decode(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	tagged := withJPEGSegment(plain, 0xe1, append([]byte("Exif\x00\x00"), exifTIFF(6)...))
+	if got, want := domain.PixelHashFromBytes(tagged), domain.PixelHashFromBytes(encodePNG(t, rotateCW(toNRGBA(decoded)))); got != want {
+		t.Errorf("rotated jpeg: pixel hash = %s, want %s", got, want)
+	}
+}
+
+// opaque hides the concrete type of an image, so it is read through At.
+type opaque struct{ image.Image }
+
+func TestDecodedImage_FastPathsMatchGenericConversion(t *testing.T) {
+	rgba := scene(6, 37, 23)
+	translucent := image.NewRGBA(rgba.Rect)
+	nrgba := image.NewNRGBA(rgba.Rect)
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		a := uint8(i / 4 * 7 % 256)
+		copy(nrgba.Pix[i:], []byte{rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2], a})
+		for c := 0; c < 3; c++ {
+			translucent.Pix[i+c] = uint8(uint32(rgba.Pix[i+c]) * uint32(a) / 0xff)
+		}
+		translucent.Pix[i+3] = a
+	}
+	jpegImg, err := jpeg.Decode(bytes.NewReader(encodeJPEG(t, rgba, 90)))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	ycbcr444 := image.NewYCbCr(rgba.Rect, image.YCbCrSubsampleRatio444)
+	for i := range ycbcr444.Y {
+		ycbcr444.Y[i], ycbcr444.Cb[i], ycbcr444.Cr[i] = uint8(i*3), uint8(i*5), uint8(i*11)
+	}
+
+	tests := []struct {
+		name       string
+		img        image.Image
+		perceptual bool
+	}{
+		{"rgba", rgba, true},
+		{"translucent rgba", translucent, true},
+		{"nrgba", nrgba, true},
+		{"sub-image", nrgba.SubImage(image.Rect(3, 2, 30, 20)), true},
+		{"ycbcr 4:2:0", jpegImg, false},
+		{"ycbcr 4:4:4", ycbcr444.SubImage(image.Rect(1, 1, 20, 22)), false},
+	}
+	for _, tt := range tests {
+		for orientation := 1; orientation <= 8; orientation++ {
+			fast := &domain.DecodedImage{Image: tt.img, Orientation: orientation}
+			generic := &domain.DecodedImage{Image: opaque{tt.img}, Orientation: orientation}
+			if got, want := fast.PixelHash(), generic.PixelHash(); got != want {
+				t.Errorf("%s, orientation %d: pixel hash = %s, want %s", tt.name, orientation, got, want)
+			}
+		}
+		if !tt.perceptual {
+			continue
+		}
+		fast := (&domain.DecodedImage{Image: tt.img}).PerceptualHashes(domain.VerifyPerceptualHashers()...)
+		generic := (&domain.DecodedImage{Image: opaque{tt.img}}).PerceptualHashes(domain.VerifyPerceptualHashers()...)
+		for alg, h := range generic {
+			if fast[alg] != h {
+				t.Errorf("%s: %s = %016x, want %016x", tt.name, alg, fast[alg], h)
+			}
+		}
+	}
+
+	if img := domain.DecodeImage([]byte("not an image")); img != nil || img.PixelHash() != "" || img.PerceptualHashes() != nil {
+		t.Fatalf("non-image decoded as %+v", img)
+	}
+}
+
+// withPNGSize rewrites the dimensions declared in the IHDR chunk.
+func withPNGSize(content []byte, width, height uint32) []byte {
+	out := append([]byte(nil), content...)
+	binary.BigEndian.PutUint32(out[16:], width)
+	binary.BigEndian.PutUint32(out[20:], height)
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+func TestDecodeImage_RejectsOversizedImagesBeforeDecoding(t *testing.T) {
+	bomb := withPNGSize(encodePNG(t, scene(0, 8, 8)), 60000, 60000)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if img := domain.DecodeImage(bomb); img != nil {
+		t.Fatalf("decoded a %v image declared larger than MaxImagePixels", img.Image.Bounds())
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("allocated %d bytes for a rejected image", allocated)
+	}
+	if domain.PixelHashFromBytes(bomb) != "" || domain.PerceptualHashesFromBytes(bomb) != nil {
+		t.Error("hashed an oversized image")
+	}
+
+	if domain.DecodeImage(encodePNG(t, scene(0, 8, 8))) == nil {
+		t.Error("rejected an image within the limit")
+	}
+}
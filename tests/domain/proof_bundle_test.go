@@ -0,0 +1,99 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+const contract = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+
+func anchoredCert(hash string) *domain.Certificate {
+	return &domain.Certificate{ContentHash: hash, TxHash: "0xtx", BlockNumber: 10, BlockHash: "0xblock", Status: domain.StatusConfirmed}
+}
+
+func TestNewProofBundle_Direct(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	b, err := domain.NewProofBundle(anchoredCert(hash), 31337, contract)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Version != domain.ProofBundleVersion || b.Scheme != domain.ProofSchemeDirect || b.Root != hash || b.ChainID != 31337 {
+		t.Fatalf("bundle = %+v", b)
+	}
+	if err := b.Verify(); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestNewProofBundle_BatchRoundTripsThroughJSON(t *testing.T) {
+	certs := []*domain.Certificate{
+		anchoredCert(strings.Repeat("01", 32)),
+		anchoredCert(strings.Repeat("02", 32)),
+		anchoredCert(strings.Repeat("03", 32)),
+	}
+	if _, err := domain.AssignMerkleBatch(certs); err != nil {
+		t.Fatalf("assign batch: %v", err)
+	}
+
+	b, err := domain.NewProofBundle(certs[2], 1, contract)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw, _ := json.Marshal(b)
+
+	var decoded domain.ProofBundle
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded.Scheme != domain.ProofSchemeRFC6962 || decoded.LeafIndex != 2 || decoded.TreeSize != 3 {
+		t.Fatalf("decoded = %+v", decoded)
+	}
+	if err := decoded.Verify(); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestNewProofBundle_NotAnchored(t *testing.T) {
+	cert := anchoredCert(strings.Repeat("ab", 32))
+	cert.Status = domain.StatusSubmitted
+	if _, err := domain.NewProofBundle(cert, 1, contract); !errors.Is(err, domain.ErrNotAnchored) {
+		t.Fatalf("err = %v, want ErrNotAnchored", err)
+	}
+}
+
+func TestProofBundle_VerifyRejectsTampering(t *testing.T) {
+	certs := []*domain.Certificate{anchoredCert(strings.Repeat("01", 32)), anchoredCert(strings.Repeat("02", 32))}
+	domain.AssignMerkleBatch(certs)
+
+	tests := []struct {
+		name   string
+		mutate func(*domain.ProofBundle)
+	}{
+		{"version", func(b *domain.ProofBundle) { b.Version = 2 }},
+		{"missing chain id", func(b *domain.ProofBundle) { b.ChainID = 0 }},
+		{"content hash", func(b *domain.ProofBundle) { b.ContentHash = strings.Repeat("03", 32) }},
+		{"leaf index", func(b *domain.ProofBundle) { b.LeafIndex = 1 }},
+		{"path", func(b *domain.ProofBundle) { b.Path = []string{strings.Repeat("00", 32)} }},
+		{"malformed path", func(b *domain.ProofBundle) { b.Path = []string{"zz"} }},
+		{"root", func(b *domain.ProofBundle) { b.Root = strings.Repeat("ff", 32) }},
+		{"scheme", func(b *domain.ProofBundle) { b.Scheme = "sparse" }},
+		{"direct with path", func(b *domain.ProofBundle) { b.Scheme = domain.ProofSchemeDirect }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := domain.NewProofBundle(certs[0], 1, contract)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.mutate(b)
+			if err := b.Verify(); !errors.Is(err, domain.ErrInvalidProof) {
+				t.Fatalf("err = %v, want ErrInvalidProof", err)
+			}
+		})
+	}
+}
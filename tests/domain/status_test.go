@@ -0,0 +1,93 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+func TestCertificate_StatusTransitions(t *testing.T) {
+	cert := &domain.Certificate{Status: domain.StatusPending}
+
+	if err := cert.MarkSubmitted("0xtx"); err != nil {
+		t.Fatalf("MarkSubmitted: %v", err)
+	}
+	if cert.Status != domain.StatusSubmitted || cert.TxHash != "0xtx" {
+		t.Fatalf("cert = %+v, want submitted with tx", cert)
+	}
+
+	if err := cert.Requeue("dropped"); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	if cert.Status != domain.StatusPending || cert.TxHash != "" || cert.LastError != "dropped" {
+		t.Fatalf("cert = %+v, want pending without tx", cert)
+	}
+
+	if err := cert.MarkConfirmed(&domain.Receipt{TxHash: "0xmined", BlockNumber: 7, BlockHash: "0xblock"}); err != nil {
+		t.Fatalf("MarkConfirmed: %v", err)
+	}
+	if !cert.IsAnchored() || cert.BlockNumber != 7 || cert.BlockHash != "0xblock" || cert.LastError != "" {
+		t.Fatalf("cert = %+v, want confirmed with receipt fields", cert)
+	}
+}
+
+func TestCertificate_InvalidTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		from domain.CertificateStatus
+		do   func(*domain.Certificate) error
+	}{
+		{"confirm confirmed", domain.StatusConfirmed, func(c *domain.Certificate) error { return c.MarkConfirmed(&domain.Receipt{}) }},
+		{"submit failed", domain.StatusFailed, func(c *domain.Certificate) error { return c.MarkSubmitted("0xtx") }},
+		{"requeue confirmed", domain.StatusConfirmed, func(c *domain.Certificate) error { return c.Requeue("x") }},
+		{"retry pending", domain.StatusPending, func(c *domain.Certificate) error { return c.Retry() }},
+		{"reorg pending", domain.StatusPending, func(c *domain.Certificate) error { return c.MarkReorged("x") }},
+		{"reorg reorged", domain.StatusReorged, func(c *domain.Certificate) error { return c.MarkReorged("x") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := &domain.Certificate{Status: tt.from}
+			err := tt.do(cert)
+			if !errors.Is(err, domain.ErrInvalidTransition) {
+				t.Fatalf("err = %v, want ErrInvalidTransition", err)
+			}
+			if cert.Status != tt.from {
+				t.Fatalf("status = %q, want unchanged %q", cert.Status, tt.from)
+			}
+		})
+	}
+}
+
+func TestCertificate_RetryResetsFailedCertificate(t *testing.T) {
+	cert := &domain.Certificate{Status: domain.StatusFailed, TxHash: "0xtx", Attempts: 5, LastError: "reverted"}
+
+	if err := cert.Retry(); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if cert.Status != domain.StatusPending || cert.Attempts != 0 || cert.TxHash != "" || cert.LastError != "" {
+		t.Fatalf("cert = %+v, want reset pending certificate", cert)
+	}
+}
+
+func TestCertificate_ReorgedCertificateIsAnchoredAgain(t *testing.T) {
+	cert := &domain.Certificate{Status: domain.StatusConfirmed, TxHash: "0xold", BlockNumber: 7, BlockHash: "0xb7"}
+
+	if err := cert.MarkReorged("block 7 replaced"); err != nil {
+		t.Fatalf("MarkReorged: %v", err)
+	}
+	if cert.IsAnchored() || cert.TxHash != "0xold" || cert.LastError != "block 7 replaced" {
+		t.Fatalf("cert = %+v, want reorged keeping the orphaned tx", cert)
+	}
+
+	if err := cert.MarkSubmitted("0xnew"); err != nil {
+		t.Fatalf("MarkSubmitted: %v", err)
+	}
+	if err := cert.MarkConfirmed(&domain.Receipt{TxHash: "0xnew", BlockNumber: 9, BlockHash: "0xb9"}); err != nil {
+		t.Fatalf("MarkConfirmed: %v", err)
+	}
+	if !cert.IsAnchored() || cert.BlockHash != "0xb9" {
+		t.Fatalf("cert = %+v, want confirmed again", cert)
+	}
+}
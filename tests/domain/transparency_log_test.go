@@ -0,0 +1,204 @@
+package domain_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+// nodeStore keeps the complete subtree hashes of an append-only tree.
+type nodeStore struct {
+	nodes map[[2]uint64][]byte
+	size  uint64
+}
+
+func newNodeStore() *nodeStore {
+	return &nodeStore{nodes: map[[2]uint64][]byte{}}
+}
+
+func (s *nodeStore) read(level uint, index uint64) ([]byte, error) {
+	hash, ok := s.nodes[[2]uint64{uint64(level), index}]
+	if !ok {
+		return nil, fmt.Errorf("missing node %d/%d", level, index)
+	}
+	return hash, nil
+}
+
+func (s *nodeStore) append(t *testing.T, leafHash []byte) {
+	t.Helper()
+	nodes, err := domain.MerkleNodeReader(s.read).Append(s.size, leafHash)
+	if err != nil {
+		t.Fatalf("append leaf %d: %v", s.size, err)
+	}
+	for _, n := range nodes {
+		s.nodes[[2]uint64{uint64(n.Level), n.Index}] = n.Hash
+	}
+	s.size++
+}
+
+// rfc6962Leaves are the inputs of the reference Merkle tree test vectors.
+var rfc6962Leaves = []string{"", "00", "10", "2021", "3031", "40414243", "5051525354555657", "606162636465666768696a6b6c6d6e6f"}
+
+func TestMerkleNodeReader_RFC6962Roots(t *testing.T) {
+	want := []string{
+		"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+		"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+		"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+	}
+
+	store := newNodeStore()
+	for i, leaf := range rfc6962Leaves {
+		raw, _ := hex.DecodeString(leaf)
+		store.append(t, domain.MerkleLeafHash(raw))
+
+		root, err := domain.MerkleNodeReader(store.read).Root(uint64(i + 1))
+		if err != nil {
+			t.Fatalf("size %d: %v", i+1, err)
+		}
+		if got := hex.EncodeToString(root); got != want[i] {
+			t.Fatalf("size %d root = %s, want %s", i+1, got, want[i])
+		}
+	}
+}
+
+func TestMerkleNodeReader_MatchesLeafTree(t *testing.T) {
+	store := newNodeStore()
+	var leaves [][]byte
+	for size := 1; size <= 33; size++ {
+		leaf := domain.MerkleLeafHash([]byte(fmt.Sprintf("leaf-%d", size-1)))
+		leaves = append(leaves, leaf)
+		store.append(t, leaf)
+	}
+
+	reader := domain.MerkleNodeReader(store.read)
+	for size := 1; size <= len(leaves); size++ {
+		root, err := reader.Root(uint64(size))
+		if err != nil || !bytes.Equal(root, domain.MerkleRoot(leaves[:size])) {
+			t.Fatalf("size %d: root mismatch (err %v)", size, err)
+		}
+		for i := 0; i < size; i++ {
+			got, err := reader.InclusionProof(uint64(i), uint64(size))
+			if err != nil {
+				t.Fatalf("size %d index %d: %v", size, i, err)
+			}
+			want, _ := domain.MerkleInclusionProof(leaves[:size], i)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Fatalf("size %d index %d: audit path differs from the leaf tree", size, i)
+			}
+		}
+	}
+
+	if _, err := reader.InclusionProof(5, 5); !errors.Is(err, domain.ErrInvalidTreeSize) {
+		t.Fatalf("expected ErrInvalidTreeSize, got %v", err)
+	}
+}
+
+func TestMerkleConsistency_AllSizes(t *testing.T) {
+	store := newNodeStore()
+	var roots [][]byte
+	reader := domain.MerkleNodeReader(store.read)
+	for size := 1; size <= 17; size++ {
+		store.append(t, domain.MerkleLeafHash([]byte(fmt.Sprintf("leaf-%d", size-1))))
+		root, err := reader.Root(uint64(size))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		roots = append(roots, root)
+	}
+
+	for second := 1; second <= len(roots); second++ {
+		for first := 1; first <= second; first++ {
+			proof, err := reader.ConsistencyProof(uint64(first), uint64(second))
+			if err != nil {
+				t.Fatalf("%d -> %d: %v", first, second, err)
+			}
+			if !domain.VerifyMerkleConsistency(uint64(first), uint64(second), proof, roots[first-1], roots[second-1]) {
+				t.Fatalf("%d -> %d: valid proof rejected", first, second)
+			}
+			if first < second && domain.VerifyMerkleConsistency(uint64(first), uint64(second), proof, roots[second-1], roots[second-1]) {
+				t.Fatalf("%d -> %d: proof accepted for the wrong first root", first, second)
+			}
+			if first > 1 && first < second && domain.VerifyMerkleConsistency(uint64(first-1), uint64(second), proof, roots[first-2], roots[second-1]) {
+				t.Fatalf("%d -> %d: proof accepted for another first size", first, second)
+			}
+		}
+	}
+
+	if _, err := reader.ConsistencyProof(3, 2); !errors.Is(err, domain.ErrInvalidTreeSize) {
+		t.Fatalf("expected ErrInvalidTreeSize, got %v", err)
+	}
+}
+
+func TestSignedTreeHead_SignAndVerify(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(nil)
+	root := domain.MerkleLeafHash([]byte("root"))
+	at := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC)
+
+	head := domain.SignTreeHead(key, 4, root, at)
+	if !head.Timestamp.Equal(at.Truncate(time.Millisecond)) || head.RootHash != hex.EncodeToString(root) {
+		t.Fatalf("head = %+v", head)
+	}
+	if err := head.Verify(pub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	tampered := *head
+	tampered.TreeSize = 5
+	for name, check := range map[string]error{
+		"other key":      head.Verify(otherPub),
+		"tampered size":  tampered.Verify(pub),
+		"malformed root": (&domain.SignedTreeHead{RootHash: "zz", Signature: head.Signature}).Verify(pub),
+	} {
+		if !errors.Is(check, domain.ErrInvalidTreeHead) {
+			t.Fatalf("%s: expected ErrInvalidTreeHead, got %v", name, check)
+		}
+	}
+}
+
+func TestLogProofs_VerifyAgainstTreeHeads(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	store := newNodeStore()
+	reader := domain.MerkleNodeReader(store.read)
+	hashes := make([]string, 6)
+	heads := make([]*domain.SignedTreeHead, len(hashes))
+	for i := range hashes {
+		raw := bytes.Repeat([]byte{byte(i + 1)}, 32)
+		hashes[i] = hex.EncodeToString(raw)
+		store.append(t, domain.MerkleLeafHash(raw))
+		root, _ := reader.Root(store.size)
+		heads[i] = domain.SignTreeHead(key, store.size, root, time.Now())
+	}
+
+	path, _ := reader.InclusionProof(2, 6)
+	inclusion := &domain.LogInclusionProof{LeafIndex: 2, TreeSize: 6, AuditPath: domain.EncodeMerklePath(path)}
+	if err := inclusion.Verify(hashes[2], heads[5]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := inclusion.Verify(hashes[3], heads[5]); !errors.Is(err, domain.ErrInvalidLogProof) {
+		t.Fatalf("expected ErrInvalidLogProof for another hash, got %v", err)
+	}
+	if err := inclusion.Verify(hashes[2], heads[4]); !errors.Is(err, domain.ErrInvalidLogProof) {
+		t.Fatalf("expected ErrInvalidLogProof for another tree head, got %v", err)
+	}
+
+	path, _ = reader.ConsistencyProof(3, 6)
+	consistency := &domain.LogConsistencyProof{FirstSize: 3, SecondSize: 6, Path: domain.EncodeMerklePath(path)}
+	if err := consistency.Verify(heads[2], heads[5]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := consistency.Verify(heads[1], heads[5]); !errors.Is(err, domain.ErrInvalidLogProof) {
+		t.Fatalf("expected ErrInvalidLogProof for another first head, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package domain_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+)
+
+// randomVideo returns a fingerprint of one-second segments with unrelated
+// hashes.
+func randomVideo(rng *rand.Rand, seconds int) domain.VideoFingerprint {
+	fp := make(domain.VideoFingerprint, seconds)
+	for i := range fp {
+		fp[i] = domain.VideoSegment{
+			Start:  time.Duration(i) * time.Second,
+			End:    time.Duration(i+1) * time.Second,
+			Hashes: domain.PerceptualHashes{domain.DCTHash: rng.Uint64(), domain.DifferenceHash: rng.Uint64(), domain.AverageHash: rng.Uint64()},
+		}
+	}
+	return fp
+}
+
+// excerpt copies the given segments of fp as a clip starting at zero, every
+// hash a few bits off as after re-encoding.
+func excerpt(rng *rand.Rand, fp domain.VideoFingerprint, segments ...int) domain.VideoFingerprint {
+	clip := make(domain.VideoFingerprint, len(segments))
+	for i, s := range segments {
+		hashes := domain.PerceptualHashes{}
+		for alg, h := range fp[s].Hashes {
+			for _, bit := range rng.Perm(64)[:rng.Intn(4)] {
+				h ^= 1 << bit
+			}
+			hashes[alg] = h
+		}
+		clip[i] = domain.VideoSegment{Start: time.Duration(i) * time.Second, End: time.Duration(i+1) * time.Second, Hashes: hashes}
+	}
+	return clip
+}
+
+func span(from, to int) []int {
+	var s []int
+	for i := from; i < to; i++ {
+		s = append(s, i)
+	}
+	return s
+}
+
+func TestMatchVideo_LocatesExcerpt(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	cert := &domain.Certificate{Fingerprint: randomVideo(rng, 300)}
+
+	tests := []struct {
+		name          string
+		segments      []int
+		start, end    time.Duration
+		minConfidence float64
+	}{
+		{"excerpt", span(80, 90), 80 * time.Second, 90 * time.Second, 0.95},
+		{"sparser keyframes", []int{80, 82, 84, 86, 88}, 80 * time.Second, 89 * time.Second, 0.7},
+		{"denser keyframes", []int{80, 80, 81, 81, 82, 82, 83, 83}, 80 * time.Second, 84 * time.Second, 0.95},
+		{"inserted frames", append(append(span(80, 86), 200, 20), span(86, 90)...), 80 * time.Second, 90 * time.Second, 0.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := domain.MatchVideo(excerpt(rng, cert.Fingerprint, tt.segments...), cert, 8)
+			if !ok {
+				t.Fatal("expected a match")
+			}
+			if m.Video == nil || m.Video.Start != tt.start || m.Video.End != tt.end {
+				t.Fatalf("matched range = %+v, want %v to %v", m.Video, tt.start, tt.end)
+			}
+			if m.Video.Confidence < tt.minConfidence || m.Video.Confidence > 1 {
+				t.Fatalf("confidence = %f, want at least %f", m.Video.Confidence, tt.minConfidence)
+			}
+			if m.Distance > 3 || len(m.Distances) != 3 {
+				t.Fatalf("distance = %d, %v", m.Distance, m.Distances)
+			}
+		})
+	}
+}
+
+func TestMatchVideo_RequiresOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	cert := &domain.Certificate{Fingerprint: randomVideo(rng, 60)}
+
+	reversed := excerpt(rng, cert.Fingerprint, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10)
+	if m, ok := domain.MatchVideo(reversed, cert, 8); ok {
+		t.Fatalf("reversed clip matched %+v", m.Video)
+	}
+	if _, ok := domain.MatchVideo(randomVideo(rng, 10), cert, 8); ok {
+		t.Fatal("unrelated clip matched")
+	}
+	if _, ok := domain.MatchVideo(excerpt(rng, cert.Fingerprint, 10, 11, 12), &domain.Certificate{}, 8); ok {
+		t.Fatal("certificate without fingerprint matched")
+	}
+}
+
+func TestRankSimilar_OrdersVideosByConfidence(t *testing.T) {
+	low := domain.SimilarCertificate{Certificate: &domain.Certificate{ID: "low"}, Distance: 1, Video: &domain.VideoMatch{Confidence: 0.6}}
+	high := domain.SimilarCertificate{Certificate: &domain.Certificate{ID: "high"}, Distance: 4, Video: &domain.VideoMatch{Confidence: 0.9}}
+
+	ranked := domain.RankSimilar([]domain.SimilarCertificate{low, high}, 2)
+	if ranked[0].Certificate.ID != "high" {
+		t.Fatalf("ranked %s first, want the more confident alignment", ranked[0].Certificate.ID)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -25,6 +26,7 @@ func certifyOK(_ context.Context, _ usecase.CertifyInput) (*usecase.CertifyOutpu
 			Registrant:  "tester",
 			TxHash:      "0xdef",
 			BlockNumber: 1,
+			Status:      domain.StatusConfirmed,
 			CreatedAt:   fixedTime,
 		},
 	}, nil
@@ -76,6 +78,31 @@ func TestHandleCertify_ValidUpload(t *testing.T) {
 	}
 }
 
+func TestHandleCertify_PendingAnchorIsAccepted(t *testing.T) {
+	cert := &mockCertifier{
+		executeFn: func(_ context.Context, _ usecase.CertifyInput) (*usecase.CertifyOutput, error) {
+			return &usecase.CertifyOutput{
+				Certificate: &domain.Certificate{ID: "1", ContentHash: "abc123", Status: domain.StatusPending, CreatedAt: fixedTime},
+			}, nil
+		},
+	}
+	mux := setupMux(cert, &mockVerifier{})
+
+	req := newUploadRequest(t, http.MethodPost, "/certificates", "image/png", []byte("img"))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusAccepted)
+	}
+
+	var body map[string]any
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body["status"] != "pending" {
+		t.Errorf("status field = %v, want pending", body["status"])
+	}
+}
+
 func TestHandleCertify_MissingFile(t *testing.T) {
 	mux := setupMux(&mockCertifier{}, &mockVerifier{})
 
@@ -278,3 +305,394 @@ func TestCertifyResponse_ContainsAllFields(t *testing.T) {
 		t.Errorf("Content-Type = %q, want application/json", ct)
 	}
 }
+
+func TestHandleVerify_ConfirmOnChainParam(t *testing.T) {
+	var got usecase.VerifyInput
+	ver := &mockVerifier{executeFn: func(_ context.Context, in usecase.VerifyInput) (*usecase.VerifyOutput, error) {
+		got = in
+		confirmed := true
+		return &usecase.VerifyOutput{
+			Certified:        true,
+			Certificate:      &domain.Certificate{ID: "1", CreatedAt: fixedTime},
+			OnChainConfirmed: &confirmed,
+		}, nil
+	}}
+	mux := setupMux(&mockCertifier{}, ver)
+
+	req := httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc&confirm_onchain=true", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if !got.ConfirmOnChain {
+		t.Fatal("expected ConfirmOnChain to be forwarded to the use case")
+	}
+	var body map[string]any
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body["on_chain_confirmed"] != true {
+		t.Errorf("on_chain_confirmed = %v, want true", body["on_chain_confirmed"])
+	}
+
+	req = newUploadRequest(t, http.MethodPost, "/certificates/verify?confirm_onchain=1", "image/png", []byte("img"))
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !got.ConfirmOnChain {
+		t.Fatalf("file verify status = %d, confirm = %v", rr.Code, got.ConfirmOnChain)
+	}
+}
+
+func TestHandleVerify_InvalidConfirmOnChainParam(t *testing.T) {
+	mux := setupMux(&mockCertifier{}, &mockVerifier{executeFn: verifyFound})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc&confirm_onchain=maybe", nil),
+		newUploadRequest(t, http.MethodPost, "/certificates/verify?confirm_onchain=maybe", "image/png", []byte("img")),
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d, want %d", req.Method, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestVerifyResponse_OmitsOnChainWhenNotRequested(t *testing.T) {
+	mux := setupMux(&mockCertifier{}, &mockVerifier{executeFn: verifyFound})
+
+	req := httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var body map[string]any
+	json.NewDecoder(rr.Body).Decode(&body)
+	if _, ok := body["on_chain_confirmed"]; ok {
+		t.Error("on_chain_confirmed should be omitted when confirmation was not requested")
+	}
+}
+
+func TestVerifyResponse_IncludesMerkleProofForBatchedCertificate(t *testing.T) {
+	included := true
+	ver := &mockVerifier{
+		executeFn: func(_ context.Context, _ usecase.VerifyInput) (*usecase.VerifyOutput, error) {
+			return &usecase.VerifyOutput{
+				Certified:         true,
+				InclusionVerified: &included,
+				Certificate: &domain.Certificate{
+					ID:          "1",
+					ContentHash: "abc123",
+					Status:      domain.StatusConfirmed,
+					Batch:       &domain.MerkleBatch{Root: "root", LeafIndex: 2, TreeSize: 3, Proof: []string{"p0", "p1"}},
+					CreatedAt:   fixedTime,
+				},
+			}, nil
+		},
+	}
+	mux := setupMux(&mockCertifier{}, ver)
+
+	req := httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc123", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var body struct {
+		InclusionVerified *bool `json:"inclusion_verified"`
+		Certificate       struct {
+			MerkleProof struct {
+				Root      string   `json:"root"`
+				LeafIndex uint64   `json:"leaf_index"`
+				TreeSize  uint64   `json:"tree_size"`
+				Proof     []string `json:"proof"`
+			} `json:"merkle_proof"`
+		} `json:"certificate"`
+	}
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body.InclusionVerified == nil || !*body.InclusionVerified {
+		t.Errorf("inclusion_verified = %v, want true", body.InclusionVerified)
+	}
+	mp := body.Certificate.MerkleProof
+	if mp.Root != "root" || mp.LeafIndex != 2 || mp.TreeSize != 3 || len(mp.Proof) != 2 {
+		t.Errorf("merkle_proof = %+v", mp)
+	}
+}
+
+func TestVerifyResponse_IncludesAnchorFee(t *testing.T) {
+	ver := &mockVerifier{
+		executeFn: func(_ context.Context, _ usecase.VerifyInput) (*usecase.VerifyOutput, error) {
+			return &usecase.VerifyOutput{
+				Certified: true,
+				Certificate: &domain.Certificate{
+					ID:        "1",
+					Status:    domain.StatusConfirmed,
+					Batch:     &domain.MerkleBatch{Root: "root", TreeSize: 4},
+					Fee:       &domain.AnchorFee{GasUsed: 50000, EffectiveGasPrice: big.NewInt(30_000_000_000)},
+					CreatedAt: fixedTime,
+				},
+			}, nil
+		},
+	}
+	mux := setupMux(&mockCertifier{}, ver)
+
+	req := httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc123", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var body struct {
+		Certificate struct {
+			Fee map[string]any `json:"fee"`
+		} `json:"certificate"`
+	}
+	json.NewDecoder(rr.Body).Decode(&body)
+	fee := body.Certificate.Fee
+	if fee["gas_used"] != float64(50000) || fee["effective_gas_price"] != "30000000000" ||
+		fee["total_wei"] != "1500000000000000" || fee["share_wei"] != "375000000000000" {
+		t.Errorf("fee = %v", fee)
+	}
+}
+
+func TestVerifyResponse_ExposesAnchoringStatusOfReorgedCertificate(t *testing.T) {
+	ver := &mockVerifier{
+		executeFn: func(_ context.Context, _ usecase.VerifyInput) (*usecase.VerifyOutput, error) {
+			return &usecase.VerifyOutput{
+				Certified: false,
+				Certificate: &domain.Certificate{
+					ID:        "1",
+					Status:    domain.StatusReorged,
+					Attempts:  1,
+					LastError: "block 7 replaced",
+					CreatedAt: fixedTime,
+				},
+			}, nil
+		},
+	}
+	mux := setupMux(&mockCertifier{}, ver)
+
+	req := httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc123", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var body struct {
+		Certified bool `json:"certified"`
+		Anchoring struct {
+			Status    string `json:"status"`
+			Attempts  int    `json:"attempts"`
+			LastError string `json:"last_error"`
+		} `json:"anchoring"`
+	}
+	json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusNotFound || body.Certified {
+		t.Fatalf("status = %d, certified = %v", rr.Code, body.Certified)
+	}
+	if body.Anchoring.Status != "reorged" || body.Anchoring.Attempts != 1 || body.Anchoring.LastError != "block 7 replaced" {
+		t.Errorf("anchoring = %+v", body.Anchoring)
+	}
+}
+
+func TestVerifyResponse_ListsAnchorsOnEveryChain(t *testing.T) {
+	ver := &mockVerifier{
+		executeFn: func(_ context.Context, _ usecase.VerifyInput) (*usecase.VerifyOutput, error) {
+			return &usecase.VerifyOutput{
+				Certified: true,
+				Certificate: &domain.Certificate{
+					ID:     "1",
+					Status: domain.StatusConfirmed,
+					Anchors: []domain.Anchor{
+						{ChainID: 10, ContractAddress: "0xl2", TxHash: "0xa", BlockNumber: 7},
+						{ChainID: 1, ContractAddress: "0xl1", TxHash: "0xb", BlockNumber: 3,
+							Batch: &domain.MerkleBatch{Root: "root", LeafIndex: 2, TreeSize: 4, Proof: []string{"p"}}},
+					},
+					CreatedAt: fixedTime,
+				},
+			}, nil
+		},
+	}
+	mux := setupMux(&mockCertifier{}, ver)
+
+	req := httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc123", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var body struct {
+		Certificate struct {
+			Anchors []struct {
+				ChainID     uint64          `json:"chain_id"`
+				TxHash      string          `json:"tx_hash"`
+				MerkleProof *map[string]any `json:"merkle_proof"`
+			} `json:"anchors"`
+		} `json:"certificate"`
+	}
+	json.NewDecoder(rr.Body).Decode(&body)
+	anchors := body.Certificate.Anchors
+	if len(anchors) != 2 || anchors[0].ChainID != 10 || anchors[0].MerkleProof != nil ||
+		anchors[1].TxHash != "0xb" || anchors[1].MerkleProof == nil || (*anchors[1].MerkleProof)["root"] != "root" {
+		t.Errorf("anchors = %+v", anchors)
+	}
+}
+
+func TestVerifyResponse_IncludesTimestampVerification(t *testing.T) {
+	verified := true
+	stampedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ver := &mockVerifier{
+		executeFn: func(_ context.Context, _ usecase.VerifyInput) (*usecase.VerifyOutput, error) {
+			return &usecase.VerifyOutput{
+				Certified:         true,
+				Certificate:       &domain.Certificate{ID: "1", Status: domain.StatusConfirmed, TimestampToken: []byte{0x30, 0x03}, CreatedAt: fixedTime},
+				TimestampVerified: &verified,
+				TimestampedAt:     &stampedAt,
+			}, nil
+		},
+	}
+	mux := setupMux(&mockCertifier{}, ver)
+
+	req := httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc123", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var body struct {
+		Certificate struct {
+			TimestampToken string `json:"timestamp_token"`
+		} `json:"certificate"`
+		TimestampVerified *bool  `json:"timestamp_verified"`
+		TimestampedAt     string `json:"timestamped_at"`
+	}
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body.Certificate.TimestampToken != "MAM=" || body.TimestampVerified == nil || !*body.TimestampVerified ||
+		body.TimestampedAt != "2026-03-01T12:00:00Z" {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestHandleVerify_SimilarityParams(t *testing.T) {
+	var got usecase.VerifyInput
+	match := &domain.Certificate{ID: "2", Status: domain.StatusConfirmed, CreatedAt: fixedTime}
+	ver := &mockVerifier{executeFn: func(_ context.Context, in usecase.VerifyInput) (*usecase.VerifyOutput, error) {
+		got = in
+		distances := map[domain.HashAlgorithm]int{domain.DCTHash: 3, domain.DifferenceHash: 4, domain.AverageHash: 5}
+		similar := []domain.SimilarCertificate{{Certificate: match, Distance: 4, Distances: distances}, {Certificate: &domain.Certificate{ID: "3", CreatedAt: fixedTime}, Distance: 12}}
+		return &usecase.VerifyOutput{
+			Certified:   true,
+			Certificate: match,
+			MatchType:   domain.MatchPerceptual,
+			Distance:    4,
+			Similarity:  similar[0].Similarity(),
+			Distances:   distances,
+			Similar:     similar,
+		}, nil
+	}}
+	mux := setupMux(&mockCertifier{}, ver)
+
+	req := newUploadRequest(t, http.MethodPost, "/certificates/verify?max_distance=12&limit=2", "image/png", []byte("img"))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || got.MaxDistance == nil || *got.MaxDistance != 12 || got.Limit != 2 {
+		t.Fatalf("status = %d, input = %+v", rr.Code, got)
+	}
+
+	var body struct {
+		MatchType  string         `json:"match_type"`
+		Distance   *int           `json:"distance"`
+		Similarity *float64       `json:"similarity"`
+		Distances  map[string]int `json:"distances"`
+		Matches    []struct {
+			Certificate struct {
+				ID string `json:"id"`
+			} `json:"certificate"`
+			Distance   int     `json:"distance"`
+			Similarity float64 `json:"similarity"`
+		} `json:"matches"`
+	}
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body.MatchType != "perceptual" || body.Distance == nil || *body.Distance != 4 || body.Similarity == nil || *body.Similarity != 0.9375 ||
+		body.Distances["phash"] != 3 || body.Distances["ahash"] != 5 {
+		t.Fatalf("body = %+v", body)
+	}
+	if len(body.Matches) != 2 || body.Matches[1].Certificate.ID != "3" || body.Matches[1].Distance != 12 || body.Matches[1].Similarity != 0.8125 {
+		t.Fatalf("matches = %+v", body.Matches)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if got.MaxDistance != nil || got.Limit != 0 {
+		t.Fatalf("defaults = %+v", got)
+	}
+
+	for _, query := range []string{"max_distance=-1", "max_distance=16", "max_distance=65", "max_distance=near", "limit=0", "limit=51"} {
+		req := httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc&"+query, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestVerifyResponse_ExactMatch(t *testing.T) {
+	ver := &mockVerifier{executeFn: func(_ context.Context, _ usecase.VerifyInput) (*usecase.VerifyOutput, error) {
+		return &usecase.VerifyOutput{
+			Certified:   true,
+			Certificate: &domain.Certificate{ID: "1", Status: domain.StatusConfirmed, CreatedAt: fixedTime},
+			MatchType:   domain.MatchExact,
+			Similarity:  1,
+		}, nil
+	}}
+	mux := setupMux(&mockCertifier{}, ver)
+
+	req := httptest.NewRequest(http.MethodGet, "/certificates/verify?hash=abc", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var body map[string]any
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body["match_type"] != "exact" || body["distance"] != 0.0 || body["similarity"] != 1.0 || body["matched_range"] != nil {
+		t.Fatalf("body = %v", body)
+	}
+	if _, ok := body["matches"]; ok {
+		t.Error("matches should be omitted for exact matches")
+	}
+}
+
+func TestVerifyResponse_IncludesMatchedVideoRange(t *testing.T) {
+	match := &domain.Certificate{ID: "1", Status: domain.StatusConfirmed, CreatedAt: fixedTime}
+	video := &domain.VideoMatch{Start: 80 * time.Second, End: 90500 * time.Millisecond, Confidence: 0.875}
+	ver := &mockVerifier{executeFn: func(_ context.Context, _ usecase.VerifyInput) (*usecase.VerifyOutput, error) {
+		return &usecase.VerifyOutput{
+			Certified:   true,
+			Certificate: match,
+			MatchType:   domain.MatchPerceptual,
+			Distance:    3,
+			Video:       video,
+			Similar: []domain.SimilarCertificate{
+				{Certificate: match, Distance: 3, Video: video},
+				{Certificate: &domain.Certificate{ID: "2", CreatedAt: fixedTime}, Distance: 5, Video: &domain.VideoMatch{Start: 4000 * time.Second, End: 4010 * time.Second}},
+			},
+		}, nil
+	}}
+	mux := setupMux(&mockCertifier{}, ver)
+
+	req := newUploadRequest(t, http.MethodPost, "/certificates/verify", "video/mp4", []byte("clip"))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	type videoRange struct {
+		Start        string  `json:"start"`
+		End          string  `json:"end"`
+		StartSeconds float64 `json:"start_seconds"`
+		EndSeconds   float64 `json:"end_seconds"`
+		Confidence   float64 `json:"confidence"`
+	}
+	var body struct {
+		MatchedRange *videoRange `json:"matched_range"`
+		Matches      []struct {
+			MatchedRange *videoRange `json:"matched_range"`
+		} `json:"matches"`
+	}
+	json.NewDecoder(rr.Body).Decode(&body)
+	want := videoRange{Start: "01:20", End: "01:30", StartSeconds: 80, EndSeconds: 90.5, Confidence: 0.875}
+	if body.MatchedRange == nil || *body.MatchedRange != want {
+		t.Fatalf("matched_range = %+v, want %+v", body.MatchedRange, want)
+	}
+	if len(body.Matches) != 2 || body.Matches[1].MatchedRange == nil || body.Matches[1].MatchedRange.Start != "1:06:40" {
+		t.Fatalf("matches = %+v", body.Matches)
+	}
+}
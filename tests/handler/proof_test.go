@@ -0,0 +1,66 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/handler"
+)
+
+type mockProver struct {
+	executeFn func(ctx context.Context, id string) (*domain.ProofBundle, error)
+}
+
+func (m *mockProver) Execute(ctx context.Context, id string) (*domain.ProofBundle, error) {
+	return m.executeFn(ctx, id)
+}
+
+func TestHandleProof(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"found", nil, http.StatusOK},
+		{"not found", fmt.Errorf("proof: %w", domain.ErrNotFound), http.StatusNotFound},
+		{"not anchored", fmt.Errorf("proof: %w", domain.ErrNotAnchored), http.StatusConflict},
+		{"internal", fmt.Errorf("proof: boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotID string
+			prover := &mockProver{executeFn: func(_ context.Context, id string) (*domain.ProofBundle, error) {
+				gotID = id
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &domain.ProofBundle{Version: domain.ProofBundleVersion, Scheme: domain.ProofSchemeDirect, ContentHash: "abc", Root: "abc", ChainID: 1}, nil
+			}}
+			mux := http.NewServeMux()
+			handler.NewProofHandler(prover).RegisterRoutes(mux)
+
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/certificates/42/proof", nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if gotID != "42" {
+				t.Fatalf("id = %q, want 42", gotID)
+			}
+			if tt.err == nil {
+				var body map[string]any
+				json.NewDecoder(rr.Body).Decode(&body)
+				if body["version"] != float64(1) || body["scheme"] != "direct" || body["chain_id"] != float64(1) {
+					t.Errorf("body = %v", body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package handler_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/handler"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+type mockLogReader struct {
+	head          *domain.SignedTreeHead
+	err           error
+	gotHash       string
+	gotSizes      [2]uint64
+	publicKey     ed25519.PublicKey
+	inclusionPath []string
+}
+
+func (m *mockLogReader) PublicKey() ed25519.PublicKey { return m.publicKey }
+
+func (m *mockLogReader) TreeHead(context.Context) (*domain.SignedTreeHead, error) {
+	return m.head, m.err
+}
+
+func (m *mockLogReader) InclusionProof(_ context.Context, hash string, treeSize uint64) (*usecase.LogInclusionOutput, error) {
+	m.gotHash, m.gotSizes[0] = hash, treeSize
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &usecase.LogInclusionOutput{Proof: &domain.LogInclusionProof{LeafIndex: 2, TreeSize: m.head.TreeSize, AuditPath: m.inclusionPath}, TreeHead: m.head}, nil
+}
+
+func (m *mockLogReader) ConsistencyProof(_ context.Context, first, second uint64) (*usecase.LogConsistencyOutput, error) {
+	m.gotSizes = [2]uint64{first, second}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &usecase.LogConsistencyOutput{Proof: &domain.LogConsistencyProof{FirstSize: first, SecondSize: m.head.TreeSize, Path: []string{"aa"}}, FirstTreeHead: m.head, SecondTreeHead: m.head}, nil
+}
+
+func newLogMux(reader *mockLogReader) *http.ServeMux {
+	mux := http.NewServeMux()
+	handler.NewLogHandler(reader).RegisterRoutes(mux)
+	return mux
+}
+
+func testTreeHead() *domain.SignedTreeHead {
+	return &domain.SignedTreeHead{TreeSize: 5, RootHash: "ab", Timestamp: time.UnixMilli(1772366400123), Signature: []byte{1, 2}}
+}
+
+func TestLogHandler_TreeHead(t *testing.T) {
+	reader := &mockLogReader{head: testTreeHead(), publicKey: ed25519.PublicKey{9, 9}}
+	rr := httptest.NewRecorder()
+	newLogMux(reader).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/log/sth", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body)
+	}
+	var body map[string]any
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body["tree_size"] != float64(5) || body["root_hash"] != "ab" || body["timestamp"] != float64(1772366400123) ||
+		body["signature"] != base64.StdEncoding.EncodeToString([]byte{1, 2}) || body["public_key"] != base64.StdEncoding.EncodeToString([]byte{9, 9}) {
+		t.Fatalf("body = %v", body)
+	}
+}
+
+func TestLogHandler_InclusionProof(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		err        error
+		wantStatus int
+	}{
+		{"latest", "?hash=abc", nil, http.StatusOK},
+		{"at size", "?hash=abc&tree_size=3", nil, http.StatusOK},
+		{"missing hash", "", nil, http.StatusBadRequest},
+		{"bad tree size", "?hash=abc&tree_size=-1", nil, http.StatusBadRequest},
+		{"not in log", "?hash=abc", fmt.Errorf("log inclusion: %w", domain.ErrNotInLog), http.StatusNotFound},
+		{"unpublished size", "?hash=abc&tree_size=99", fmt.Errorf("log tree head: %w", domain.ErrInvalidTreeSize), http.StatusBadRequest},
+		{"forged proof", "?hash=abc", fmt.Errorf("log inclusion: %w", domain.ErrInvalidLogProof), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &mockLogReader{head: testTreeHead(), err: tt.err}
+			rr := httptest.NewRecorder()
+			newLogMux(reader).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/log/proof/inclusion"+tt.query, nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rr.Code, tt.wantStatus, rr.Body)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			var body struct {
+				LeafIndex uint64         `json:"leaf_index"`
+				AuditPath []string       `json:"audit_path"`
+				TreeHead  map[string]any `json:"tree_head"`
+			}
+			json.NewDecoder(rr.Body).Decode(&body)
+			if reader.gotHash != "abc" || body.LeafIndex != 2 || body.AuditPath == nil || body.TreeHead["tree_size"] != float64(5) {
+				t.Fatalf("hash = %q, body = %+v", reader.gotHash, body)
+			}
+		})
+	}
+}
+
+func TestLogHandler_ConsistencyProof(t *testing.T) {
+	reader := &mockLogReader{head: testTreeHead()}
+	mux := newLogMux(reader)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/log/proof/consistency?first=2&second=5", nil))
+	if rr.Code != http.StatusOK || reader.gotSizes != [2]uint64{2, 5} {
+		t.Fatalf("status = %d, sizes = %v", rr.Code, reader.gotSizes)
+	}
+	var body map[string]any
+	json.NewDecoder(rr.Body).Decode(&body)
+	if _, ok := body["consistency_path"].([]any); !ok || body["first_tree_head"] == nil || body["second_tree_head"] == nil {
+		t.Fatalf("body = %v", body)
+	}
+
+	for _, query := range []string{"", "?second=5", "?first=x"} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/log/proof/consistency"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%q: status = %d, want 400", query, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package imaging_test
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	_ "github.com/waizbart/aletheia-api/internal/imaging"
+)
+
+// The corpus holds the same 64x48 picture in every image format the API
+// accepts.
+var corpus = map[string]string{
+	"sample.jpg":  "jpeg",
+	"sample.png":  "png",
+	"sample.gif":  "gif",
+	"sample.webp": "webp",
+	"sample.bmp":  "bmp",
+	"sample.tiff": "tiff",
+}
+
+func TestDecoders_ProducePerceptualHashForEveryFormat(t *testing.T) {
+	reference := perceptualHashes(t, "sample.png")
+	for name, format := range corpus {
+		t.Run(format, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatalf("read %s: %v", name, err)
+			}
+			cfg, got, err := image.DecodeConfig(bytes.NewReader(content))
+			if err != nil || got != format || cfg.Width != 64 || cfg.Height != 48 {
+				t.Fatalf("decode config = %+v, %q, %v", cfg, got, err)
+			}
+
+			distances := perceptualHashes(t, name).Distances(reference)
+			if d, ok := domain.CombinedDistance(distances); !ok || d > 4 {
+				t.Fatalf("perceptual hashes are %v bits from the png's", distances)
+			}
+		})
+	}
+}
+
+func perceptualHashes(t *testing.T, name string) domain.PerceptualHashes {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	hashes := domain.PerceptualHashesFromBytes(content)
+	if len(hashes) == 0 {
+		t.Fatalf("no perceptual hashes for %s", name)
+	}
+	return hashes
+}
@@ -0,0 +1,160 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+const registerSelector = "0xd22057a9"
+
+func sentCalldata(t *testing.T, stub *rpcStub) string {
+	t.Helper()
+	calls := stub.callsTo("eth_sendTransaction")
+	if len(calls) != 1 {
+		t.Fatalf("eth_sendTransaction calls = %d, want 1", len(calls))
+	}
+	var tx map[string]string
+	json.Unmarshal(calls[0][0], &tx)
+	return tx["data"]
+}
+
+func TestRegisterHash_EncodesRegisterCall(t *testing.T) {
+	registrant := "0x3333333333333333333333333333333333333333"
+	svc, stub := newReceiptService(t, map[string]rpcHandler{
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x1", "0x1")),
+	})
+
+	if _, err := svc.RegisterHash(context.Background(), validHash, registrant); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := registerSelector + validHash + strings.Repeat("0", 24) + strings.TrimPrefix(registrant, "0x")
+	if got := sentCalldata(t, stub); got != want {
+		t.Fatalf("calldata = %s, want %s", got, want)
+	}
+}
+
+func TestRegisterHash_NonAddressRegistrantUsesSender(t *testing.T) {
+	svc, stub := newReceiptService(t, map[string]rpcHandler{
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x1", "0x1")),
+	})
+
+	if _, err := svc.RegisterHash(context.Background(), validHash, "newsroom-42"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := registerSelector + validHash + strings.Repeat("0", 24) + strings.TrimPrefix(validAddr1, "0x")
+	if got := sentCalldata(t, stub); got != want {
+		t.Fatalf("calldata = %s, want %s", got, want)
+	}
+}
+
+func TestRegisterHash_DecodesContentRegisteredEvent(t *testing.T) {
+	receipt := minedReceipt("0xtx", "0x9", "0x1")
+	log := contentRegisteredLog(validAddr2, validHash, validAddr1, 1700000000)
+	log["transactionHash"] = "0xtx"
+	log["blockNumber"] = "0x9"
+	log["blockHash"] = "0xblockhash"
+	log["logIndex"] = "0x3"
+	receipt["logs"] = []any{
+		contentRegisteredLog("0x4444444444444444444444444444444444444444", validHash, validAddr1, 1),
+		log,
+	}
+	svc, _ := newReceiptService(t, map[string]rpcHandler{"eth_getTransactionReceipt": result(receipt)})
+
+	got, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := domain.RegistrationEvent{
+		ContentHash:  validHash,
+		Registrant:   validAddr1,
+		RegisteredAt: time.Unix(1700000000, 0).UTC(),
+		TxHash:       "0xtx",
+		BlockNumber:  9,
+		BlockHash:    "0xblockhash",
+		LogIndex:     3,
+	}
+	if got.Event == nil || *got.Event != want {
+		t.Fatalf("event = %+v, want %+v", got.Event, want)
+	}
+}
+
+func TestRegisterHash_MissingEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		logs []any
+	}{
+		{"no logs", nil},
+		{"other contract", []any{contentRegisteredLog("0x4444444444444444444444444444444444444444", validHash, validAddr1, 1)}},
+		{"other hash", []any{contentRegisteredLog(validAddr2, strings.Repeat("cd", 32), validAddr1, 1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := minedReceipt("0xtx", "0x1", "0x1")
+			receipt["logs"] = tt.logs
+			svc, _ := newReceiptService(t, map[string]rpcHandler{"eth_getTransactionReceipt": result(receipt)})
+
+			_, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+			if !errors.Is(err, domain.ErrAnchorEventMissing) {
+				t.Fatalf("expected ErrAnchorEventMissing, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRegisterHash_MalformedEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(log map[string]any)
+		want   string
+	}{
+		{"hash topic", func(l map[string]any) { l["topics"].([]string)[1] = "0xzz" }, "invalid content hash topic"},
+		{"registrant topic", func(l map[string]any) { l["topics"].([]string)[2] = "0x01" }, "invalid registrant topic"},
+		{"dirty registrant", func(l map[string]any) { l["topics"].([]string)[2] = "0x" + strings.Repeat("f", 64) }, "invalid address"},
+		{"data hex", func(l map[string]any) { l["data"] = "0xzz" }, "decode hex"},
+		{"short data", func(l map[string]any) { l["data"] = "0x01" }, "too short"},
+		{"huge timestamp", func(l map[string]any) { l["data"] = "0x" + strings.Repeat("f", 64) }, "timestamp out of range"},
+		{"block number", func(l map[string]any) { l["blockNumber"] = "zz" }, "block number"},
+		{"log index", func(l map[string]any) { l["logIndex"] = "zz" }, "log index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := minedReceipt("0xtx", "0x1", "0x1")
+			log := contentRegisteredLog(validAddr2, validHash, validAddr1, 1)
+			tt.mutate(log)
+			receipt["logs"] = []any{log}
+			svc, _ := newReceiptService(t, map[string]rpcHandler{"eth_getTransactionReceipt": result(receipt)})
+
+			_, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRegisterHash_SigningModeEstimatesRegisterCall(t *testing.T) {
+	stub := newRPCStub(t, signingHandlers())
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 0)
+
+	if _, err := svc.RegisterHash(context.Background(), validHash, "org"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var call map[string]string
+	json.Unmarshal(stub.callsTo("eth_estimateGas")[0][0], &call)
+	if !strings.HasPrefix(call["data"], registerSelector+validHash) {
+		t.Fatalf("estimated calldata = %s, want register call", call["data"])
+	}
+}
@@ -2,6 +2,7 @@ package repository_test
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -78,29 +79,31 @@ func TestNewEVMBlockchainService_AddressValidation(t *testing.T) {
 // --- RegisterHash ---
 
 func TestRegisterHash_Success(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0xtxhash123"}`))
+	stub := newRPCStub(t, withFees(map[string]rpcHandler{
+		"eth_sendTransaction":       result("0xtxhash123"),
+		"eth_getTransactionReceipt": result(minedReceipt("0xtxhash123", "0x2a", "0x1")),
 	}))
-	defer server.Close()
 
-	svc, _ := repository.NewEVMBlockchainService(server.URL, validAddr1, validAddr2)
-	tx, block, err := svc.RegisterHash(context.Background(), validHash)
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+	receipt, err := svc.RegisterHash(context.Background(), validHash, "tester")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if tx != "0xtxhash123" {
-		t.Errorf("tx = %q, want 0xtxhash123", tx)
+	if receipt.TxHash != "0xtxhash123" {
+		t.Errorf("tx = %q, want 0xtxhash123", receipt.TxHash)
 	}
-	if block != 0 {
-		t.Errorf("block = %d, want 0", block)
+	if receipt.BlockNumber != 42 {
+		t.Errorf("block = %d, want 42", receipt.BlockNumber)
+	}
+	if receipt.BlockHash != "0xblockhash" || receipt.GasUsed != 21000 {
+		t.Errorf("receipt = %+v, want block hash and gas used", receipt)
 	}
 }
 
 func TestRegisterHash_InvalidHash(t *testing.T) {
 	svc, _ := repository.NewEVMBlockchainService("http://localhost:8545", validAddr1, validAddr2)
 
-	_, _, err := svc.RegisterHash(context.Background(), "tooshort")
+	_, err := svc.RegisterHash(context.Background(), "tooshort", "tester")
 	if err == nil {
 		t.Fatal("expected error for invalid hash")
 	}
@@ -110,20 +113,20 @@ func TestRegisterHash_InvalidHexInHash(t *testing.T) {
 	svc, _ := repository.NewEVMBlockchainService("http://localhost:8545", validAddr1, validAddr2)
 
 	badHash := strings.Repeat("zz", 32)
-	_, _, err := svc.RegisterHash(context.Background(), badHash)
+	_, err := svc.RegisterHash(context.Background(), badHash, "tester")
 	if err == nil {
 		t.Fatal("expected error for non-hex hash")
 	}
 }
 
 func TestRegisterHash_WithOxPrefix(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0xtx"}`))
+	stub := newRPCStub(t, withFees(map[string]rpcHandler{
+		"eth_sendTransaction":       result("0xtx"),
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x1", "0x1")),
 	}))
-	defer server.Close()
 
-	svc, _ := repository.NewEVMBlockchainService(server.URL, validAddr1, validAddr2)
-	_, _, err := svc.RegisterHash(context.Background(), "0x"+validHash)
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+	_, err := svc.RegisterHash(context.Background(), "0x"+validHash, "tester")
 	if err != nil {
 		t.Fatalf("unexpected error with 0x prefix: %v", err)
 	}
@@ -136,20 +139,17 @@ func TestRegisterHash_RPCError(t *testing.T) {
 	defer server.Close()
 
 	svc, _ := repository.NewEVMBlockchainService(server.URL, validAddr1, validAddr2)
-	_, _, err := svc.RegisterHash(context.Background(), validHash)
+	_, err := svc.RegisterHash(context.Background(), validHash, "tester")
 	if err == nil || !strings.Contains(err.Error(), "insufficient funds") {
 		t.Fatalf("expected rpc error, got: %v", err)
 	}
 }
 
 func TestRegisterHash_EmptyResult(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":""}`))
-	}))
-	defer server.Close()
+	stub := newRPCStub(t, withFees(map[string]rpcHandler{"eth_sendTransaction": result("")}))
 
-	svc, _ := repository.NewEVMBlockchainService(server.URL, validAddr1, validAddr2)
-	_, _, err := svc.RegisterHash(context.Background(), validHash)
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+	_, err := svc.RegisterHash(context.Background(), validHash, "tester")
 	if err == nil || !strings.Contains(err.Error(), "empty transaction hash") {
 		t.Fatalf("expected empty tx error, got: %v", err)
 	}
@@ -162,7 +162,7 @@ func TestRegisterHash_InvalidJSON(t *testing.T) {
 	defer server.Close()
 
 	svc, _ := repository.NewEVMBlockchainService(server.URL, validAddr1, validAddr2)
-	_, _, err := svc.RegisterHash(context.Background(), validHash)
+	_, err := svc.RegisterHash(context.Background(), validHash, "tester")
 	if err == nil || !strings.Contains(err.Error(), "decode rpc response") {
 		t.Fatalf("expected decode error, got: %v", err)
 	}
@@ -170,7 +170,7 @@ func TestRegisterHash_InvalidJSON(t *testing.T) {
 
 func TestRegisterHash_HTTPFailure(t *testing.T) {
 	svc, _ := repository.NewEVMBlockchainService("http://127.0.0.1:1", validAddr1, validAddr2)
-	_, _, err := svc.RegisterHash(context.Background(), validHash)
+	_, err := svc.RegisterHash(context.Background(), validHash, "tester")
 	if err == nil || !strings.Contains(err.Error(), "send rpc request") {
 		t.Fatalf("expected connection error, got: %v", err)
 	}
@@ -178,8 +178,42 @@ func TestRegisterHash_HTTPFailure(t *testing.T) {
 
 // --- IsHashRegistered ---
 
+func boolWord(v bool) string {
+	if v {
+		return "0x" + strings.Repeat("0", 63) + "1"
+	}
+	return "0x" + strings.Repeat("0", 64)
+}
+
+func TestIsHashRegistered_CallsAnchorContract(t *testing.T) {
+	stub := newRPCStub(t, map[string]rpcHandler{"eth_call": result(boolWord(true))})
+
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+	registered, err := svc.IsHashRegistered(context.Background(), validHash)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !registered {
+		t.Error("expected true, got false")
+	}
+
+	params := stub.callsTo("eth_call")[0]
+	var call map[string]string
+	var tag string
+	json.Unmarshal(params[0], &call)
+	json.Unmarshal(params[1], &tag)
+	if call["to"] != validAddr2 || tag != "latest" {
+		t.Fatalf("eth_call params = %v %q", call, tag)
+	}
+	if want := "0x27258b22" + validHash; call["data"] != want {
+		t.Fatalf("calldata = %q, want %q", call["data"], want)
+	}
+}
+
 func TestIsHashRegistered_ReturnsFalse(t *testing.T) {
-	svc, _ := repository.NewEVMBlockchainService("http://localhost:8545", validAddr1, validAddr2)
+	stub := newRPCStub(t, map[string]rpcHandler{"eth_call": result(boolWord(false))})
+
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
 	registered, err := svc.IsHashRegistered(context.Background(), validHash)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -189,6 +223,34 @@ func TestIsHashRegistered_ReturnsFalse(t *testing.T) {
 	}
 }
 
+func TestIsHashRegistered_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		handler rpcHandler
+		want    string
+	}{
+		{"invalid hash", "abc", result(boolWord(true)), "hash must be 32-byte hex"},
+		{"rpc error", validHash, rpcFailure("execution reverted"), "call isRegistered"},
+		{"empty return data", validHash, result("0x"), "return data too short"},
+		{"non hex return", validHash, result("0xzz"), "decode hex"},
+		{"dirty high bytes", validHash, result("0x" + strings.Repeat("1", 64)), "invalid bool"},
+		{"out of range bool", validHash, result("0x" + strings.Repeat("0", 62) + "02"), "invalid bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newRPCStub(t, map[string]rpcHandler{"eth_call": tt.handler})
+			svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+
+			_, err := svc.IsHashRegistered(context.Background(), tt.hash)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
 // --- Factory ---
 
 func TestNewBlockchainServiceFromEnv_ErrorsWhenEnvMissing(t *testing.T) {
@@ -196,7 +258,7 @@ func TestNewBlockchainServiceFromEnv_ErrorsWhenEnvMissing(t *testing.T) {
 	t.Setenv("FROM_ADDRESS", "")
 	t.Setenv("CONTRACT_ADDRESS", "")
 
-	_, err := repository.NewBlockchainServiceFromEnv()
+	_, err := repository.NewBlockchainServiceFromEnv(nil)
 	if err == nil {
 		t.Fatal("expected error when env vars are missing")
 	}
@@ -207,7 +269,7 @@ func TestNewBlockchainServiceFromEnv_InvalidConfig(t *testing.T) {
 	t.Setenv("FROM_ADDRESS", "0x1111111111111111111111111111111111111111")
 	t.Setenv("CONTRACT_ADDRESS", "invalid")
 
-	_, err := repository.NewBlockchainServiceFromEnv()
+	_, err := repository.NewBlockchainServiceFromEnv(nil)
 	if err == nil {
 		t.Fatal("expected error for invalid configuration")
 	}
@@ -218,7 +280,7 @@ func TestNewBlockchainServiceFromEnv_UsesRPCServiceWhenConfigured(t *testing.T)
 	t.Setenv("FROM_ADDRESS", "0x1111111111111111111111111111111111111111")
 	t.Setenv("CONTRACT_ADDRESS", "0x2222222222222222222222222222222222222222")
 
-	svc, err := repository.NewBlockchainServiceFromEnv()
+	svc, err := repository.NewBlockchainServiceFromEnv(nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -227,3 +289,58 @@ func TestNewBlockchainServiceFromEnv_UsesRPCServiceWhenConfigured(t *testing.T)
 		t.Fatalf("expected RPCBlockchainService, got %T", svc)
 	}
 }
+
+func TestNewBlockchainServiceFromEnv_UsesLocalSigningWithPrivateKey(t *testing.T) {
+	t.Setenv("RPC_URL", "http://localhost:8545")
+	t.Setenv("FROM_ADDRESS", "")
+	t.Setenv("PRIVATE_KEY", anvilKey)
+	t.Setenv("CHAIN_ID", "31337")
+	t.Setenv("CONTRACT_ADDRESS", validAddr2)
+
+	svc, err := repository.NewBlockchainServiceFromEnv(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rpcSvc, ok := svc.(*repository.RPCBlockchainService)
+	if !ok {
+		t.Fatalf("expected RPCBlockchainService, got %T", svc)
+	}
+	if rpcSvc.FromAddress() != anvilAddress {
+		t.Fatalf("from = %s, want %s", rpcSvc.FromAddress(), anvilAddress)
+	}
+}
+
+func TestNewBlockchainServiceFromEnv_PrivateKeyMatchesFromAddress(t *testing.T) {
+	t.Setenv("RPC_URL", "http://localhost:8545")
+	t.Setenv("PRIVATE_KEY", anvilKey)
+	t.Setenv("CHAIN_ID", "")
+	t.Setenv("CONTRACT_ADDRESS", validAddr2)
+
+	t.Setenv("FROM_ADDRESS", "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	if _, err := repository.NewBlockchainServiceFromEnv(nil); err != nil {
+		t.Fatalf("unexpected error for checksummed matching address: %v", err)
+	}
+
+	t.Setenv("FROM_ADDRESS", validAddr1)
+	if _, err := repository.NewBlockchainServiceFromEnv(nil); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("expected mismatch error, got %v", err)
+	}
+}
+
+func TestNewBlockchainServiceFromEnv_SigningConfigErrors(t *testing.T) {
+	t.Setenv("RPC_URL", "http://localhost:8545")
+	t.Setenv("FROM_ADDRESS", "")
+	t.Setenv("CONTRACT_ADDRESS", validAddr2)
+
+	t.Setenv("PRIVATE_KEY", anvilKey)
+	t.Setenv("CHAIN_ID", "abc")
+	if _, err := repository.NewBlockchainServiceFromEnv(nil); err == nil || !strings.Contains(err.Error(), "invalid CHAIN_ID") {
+		t.Fatalf("expected invalid CHAIN_ID error, got %v", err)
+	}
+
+	t.Setenv("CHAIN_ID", "")
+	t.Setenv("PRIVATE_KEY", "0x1234")
+	if _, err := repository.NewBlockchainServiceFromEnv(nil); err == nil || !strings.Contains(err.Error(), "invalid private key") {
+		t.Fatalf("expected invalid private key error, got %v", err)
+	}
+}
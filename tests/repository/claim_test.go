@@ -0,0 +1,53 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+type claimRepo interface {
+	Save(ctx context.Context, cert *domain.Certificate) error
+	ClaimUnfinished(ctx context.Context, limit int, staleAfter time.Duration) ([]*domain.Certificate, error)
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*domain.Certificate, error)
+}
+
+// The outbox worker and the batch anchorer both pick up stale pending
+// certificates; whichever claims one first leases it from the other.
+func TestClaims_OutboxAndBatcherShareLease(t *testing.T) {
+	const staleAfter = 50 * time.Millisecond
+	repos := map[string]func() claimRepo{
+		"memory": func() claimRepo { return repository.NewMemoryCertificateRepo() },
+		"sqlite": func() claimRepo { return openSQLiteRepo(t, ":memory:") },
+	}
+	for name, open := range repos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := open()
+			if err := repo.Save(ctx, memCert("stale", domain.StatusPending, time.Now())); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			time.Sleep(staleAfter + 10*time.Millisecond)
+
+			unfinished, _ := repo.ClaimUnfinished(ctx, 10, staleAfter)
+			if hashesOf(unfinished) != "stale" {
+				t.Fatalf("outbox claimed %q", hashesOf(unfinished))
+			}
+			if pending, _ := repo.ClaimPending(ctx, 10, time.Minute); len(pending) != 0 {
+				t.Fatalf("batcher claimed %q under the outbox lease", hashesOf(pending))
+			}
+
+			time.Sleep(staleAfter + 10*time.Millisecond)
+			if pending, _ := repo.ClaimPending(ctx, 10, time.Minute); hashesOf(pending) != "stale" {
+				t.Fatalf("batcher claimed %q after the outbox lease expired", hashesOf(pending))
+			}
+			time.Sleep(staleAfter + 10*time.Millisecond)
+			if again, _ := repo.ClaimUnfinished(ctx, 10, staleAfter); len(again) != 0 {
+				t.Fatalf("outbox claimed %q under the batcher lease", hashesOf(again))
+			}
+		})
+	}
+}
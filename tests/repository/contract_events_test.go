@@ -0,0 +1,193 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+func TestLatestBlock(t *testing.T) {
+	stub := newRPCStub(t, map[string]rpcHandler{"eth_blockNumber": result("0x2a")})
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+
+	head, err := svc.LatestBlock(context.Background())
+	if err != nil || head != 42 {
+		t.Fatalf("head = %d, err = %v", head, err)
+	}
+
+	stub = newRPCStub(t, map[string]rpcHandler{"eth_blockNumber": rpcFailure("down")})
+	svc, _ = repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+	if _, err := svc.LatestBlock(context.Background()); err == nil || !strings.Contains(err.Error(), "fetch block number") {
+		t.Fatalf("expected fetch error, got %v", err)
+	}
+}
+
+func TestBlockHash(t *testing.T) {
+	stub := newRPCStub(t, map[string]rpcHandler{
+		"eth_getBlockByNumber": func(params []json.RawMessage) (any, string) {
+			var number string
+			json.Unmarshal(params[0], &number)
+			if number != "0x2a" {
+				return nil, ""
+			}
+			return map[string]any{"number": "0x2a", "hash": "0xb42"}, ""
+		},
+	})
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+
+	if hash, err := svc.BlockHash(context.Background(), 42); err != nil || hash != "0xb42" {
+		t.Fatalf("hash = %q, err = %v", hash, err)
+	}
+	if hash, err := svc.BlockHash(context.Background(), 43); err != nil || hash != "" {
+		t.Fatalf("missing block: hash = %q, err = %v", hash, err)
+	}
+
+	stub = newRPCStub(t, map[string]rpcHandler{"eth_getBlockByNumber": rpcFailure("down")})
+	svc, _ = repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+	if _, err := svc.BlockHash(context.Background(), 1); err == nil || !strings.Contains(err.Error(), "fetch block 1") {
+		t.Fatalf("expected fetch error, got %v", err)
+	}
+}
+
+func TestRegistrationEvents_FiltersAndDecodesLogs(t *testing.T) {
+	first := contentRegisteredLog(validAddr2, validHash, validAddr1, 1700000000)
+	first["blockNumber"] = "0x10"
+	first["transactionHash"] = "0xaaa"
+	removed := contentRegisteredLog(validAddr2, strings.Repeat("cd", 32), validAddr1, 1)
+	removed["removed"] = true
+	foreign := contentRegisteredLog("0x4444444444444444444444444444444444444444", strings.Repeat("ef", 32), validAddr1, 1)
+
+	stub := newRPCStub(t, map[string]rpcHandler{"eth_getLogs": result([]any{first, removed, foreign})})
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+
+	events, err := svc.RegistrationEvents(context.Background(), 16, 31)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].ContentHash != validHash || events[0].BlockNumber != 16 || events[0].TxHash != "0xaaa" {
+		t.Fatalf("events = %+v", events)
+	}
+
+	var filter struct {
+		Address   string   `json:"address"`
+		FromBlock string   `json:"fromBlock"`
+		ToBlock   string   `json:"toBlock"`
+		Topics    []string `json:"topics"`
+	}
+	json.Unmarshal(stub.callsTo("eth_getLogs")[0][0], &filter)
+	if filter.Address != validAddr2 || filter.FromBlock != "0x10" || filter.ToBlock != "0x1f" ||
+		len(filter.Topics) != 1 || filter.Topics[0] != contentRegisteredTopic {
+		t.Fatalf("filter = %+v", filter)
+	}
+}
+
+func TestRegistrationEvents_Errors(t *testing.T) {
+	bad := contentRegisteredLog(validAddr2, validHash, validAddr1, 1)
+	bad["data"] = "0x"
+
+	tests := []struct {
+		name    string
+		handler rpcHandler
+		want    string
+	}{
+		{"rpc", rpcFailure("range too large"), "fetch logs"},
+		{"decode", result([]any{bad}), "decode ContentRegistered"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newRPCStub(t, map[string]rpcHandler{"eth_getLogs": tt.handler})
+			svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+
+			_, err := svc.RegistrationEvents(context.Background(), 0, 1)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// logsFilter is the filter object of an eth_getLogs call.
+type logsFilter struct {
+	FromBlock string   `json:"fromBlock"`
+	ToBlock   string   `json:"toBlock"`
+	Topics    []string `json:"topics"`
+}
+
+func TestFindRegistration(t *testing.T) {
+	ev := contentRegisteredLog(validAddr2, validHash, validAddr1, 1700000000)
+	ev["blockNumber"] = "0x20"
+	ev["transactionHash"] = "0xbbb"
+	stub := newRPCStub(t, map[string]rpcHandler{
+		"eth_blockNumber": result("0x9c4"),
+		"eth_getLogs": func(params []json.RawMessage) (any, string) {
+			var filter logsFilter
+			json.Unmarshal(params[0], &filter)
+			if filter.FromBlock == "0xa" {
+				return []any{ev}, ""
+			}
+			return []any{}, ""
+		},
+	})
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+	svc.WithLogRange(10, 1000)
+
+	got, err := svc.FindRegistration(context.Background(), validHash)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil || got.TxHash != "0xbbb" || got.BlockNumber != 32 {
+		t.Fatalf("event = %+v", got)
+	}
+
+	var ranges []string
+	for _, call := range stub.callsTo("eth_getLogs") {
+		var filter logsFilter
+		json.Unmarshal(call[0], &filter)
+		if len(filter.Topics) != 2 || filter.Topics[1] != "0x"+validHash {
+			t.Fatalf("topics = %v, want content hash filter", filter.Topics)
+		}
+		ranges = append(ranges, filter.FromBlock+"-"+filter.ToBlock)
+	}
+	if want := []string{"0x5dd-0x9c4", "0x1f5-0x5dc", "0xa-0x1f4"}; strings.Join(ranges, " ") != strings.Join(want, " ") {
+		t.Fatalf("ranges = %v, want %v from the head back to the start block", ranges, want)
+	}
+
+	stub = newRPCStub(t, map[string]rpcHandler{"eth_blockNumber": result("0x9c4"), "eth_getLogs": result([]any{})})
+	svc, _ = repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+	if got, err := svc.FindRegistration(context.Background(), validHash); err != nil || got != nil {
+		t.Fatalf("event = %+v, err = %v, want nil", got, err)
+	}
+	if n := len(stub.callsTo("eth_getLogs")); n != 3 {
+		t.Fatalf("eth_getLogs calls = %d, want 3 ranges of 1000 blocks down to block 0", n)
+	}
+
+	svc.WithLogRange(5000, 0)
+	if got, err := svc.FindRegistration(context.Background(), validHash); err != nil || got != nil {
+		t.Fatalf("head before start block: event = %+v, err = %v", got, err)
+	}
+	if n := len(stub.callsTo("eth_getLogs")); n != 3 {
+		t.Fatalf("eth_getLogs calls = %d, want none before the start block", n)
+	}
+}
+
+func TestChainIDAndContractAddress(t *testing.T) {
+	stub := newRPCStub(t, map[string]rpcHandler{"eth_chainId": result("0x7a69")})
+	svc, _ := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+
+	for i := 0; i < 2; i++ {
+		id, err := svc.ChainID(context.Background())
+		if err != nil || id != 31337 {
+			t.Fatalf("chain id = %d, err = %v", id, err)
+		}
+	}
+	if n := len(stub.callsTo("eth_chainId")); n != 1 {
+		t.Fatalf("eth_chainId calls = %d, want 1 (cached)", n)
+	}
+	if svc.ContractAddress() != validAddr2 {
+		t.Fatalf("contract = %s, want %s", svc.ContractAddress(), validAddr2)
+	}
+}
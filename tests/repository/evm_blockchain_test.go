@@ -46,17 +46,17 @@ func TestRPCBlockchainService_RegisterHash_ErrorPaths(t *testing.T) {
 		t.Fatalf("unexpected constructor error: %v", err)
 	}
 
-	_, _, err = svc.RegisterHash(context.Background(), "short")
+	_, err = svc.RegisterHash(context.Background(), "short", "tester")
 	if err == nil || !strings.Contains(err.Error(), "hash must be 32-byte hex") {
 		t.Fatalf("expected hash length error, got %v", err)
 	}
 
-	_, _, err = svc.RegisterHash(context.Background(), strings.Repeat("z", 64))
+	_, err = svc.RegisterHash(context.Background(), strings.Repeat("z", 64), "tester")
 	if err == nil || !strings.Contains(err.Error(), "decode hash") {
 		t.Fatalf("expected decode hash error, got %v", err)
 	}
 
-	_, _, err = svc.RegisterHash(context.Background(), strings.Repeat("a", 64))
+	_, err = svc.RegisterHash(context.Background(), strings.Repeat("a", 64), "tester")
 	if err == nil || !strings.Contains(err.Error(), "send rpc request") {
 		t.Fatalf("expected send rpc request error, got %v", err)
 	}
@@ -70,7 +70,7 @@ func TestRPCBlockchainService_RegisterHash_RPCResponseBranches(t *testing.T) {
 	}{
 		{name: "invalid json", response: "not-json", wantErrPart: "decode rpc response"},
 		{name: "rpc error", response: `{"error":{"message":"boom"}}`, wantErrPart: "rpc error: boom"},
-		{name: "empty result", response: `{"result":""}`, wantErrPart: "empty transaction hash"},
+		{name: "empty result", response: `{"result":""}`, wantErrPart: "estimate gas: invalid hex quantity"},
 	}
 
 	for _, tt := range tests {
@@ -89,7 +89,7 @@ func TestRPCBlockchainService_RegisterHash_RPCResponseBranches(t *testing.T) {
 				t.Fatalf("unexpected constructor error: %v", err)
 			}
 
-			_, _, err = svc.RegisterHash(context.Background(), strings.Repeat("a", 64))
+			_, err = svc.RegisterHash(context.Background(), strings.Repeat("a", 64), "tester")
 			if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
 				t.Fatalf("expected error containing %q, got %v", tt.wantErrPart, err)
 			}
@@ -99,7 +99,7 @@ func TestRPCBlockchainService_RegisterHash_RPCResponseBranches(t *testing.T) {
 
 func TestRPCBlockchainService_IsHashRegistered(t *testing.T) {
 	svc, err := repository.NewEVMBlockchainService(
-		"http://localhost",
+		"http://127.0.0.1:1",
 		"0x1111111111111111111111111111111111111111",
 		"0x2222222222222222222222222222222222222222",
 	)
@@ -107,11 +107,8 @@ func TestRPCBlockchainService_IsHashRegistered(t *testing.T) {
 		t.Fatalf("unexpected constructor error: %v", err)
 	}
 
-	ok, err := svc.IsHashRegistered(context.Background(), "abc")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if ok {
-		t.Fatal("expected false")
+	_, err = svc.IsHashRegistered(context.Background(), strings.Repeat("a", 64))
+	if err == nil || !strings.Contains(err.Error(), "send rpc request") {
+		t.Fatalf("expected send rpc request error, got %v", err)
 	}
 }
@@ -0,0 +1,201 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+func sentTransaction(t *testing.T, stub *rpcStub) map[string]string {
+	t.Helper()
+	calls := stub.callsTo("eth_sendTransaction")
+	if len(calls) != 1 {
+		t.Fatalf("eth_sendTransaction calls = %d, want 1", len(calls))
+	}
+	var tx map[string]string
+	json.Unmarshal(calls[0][0], &tx)
+	return tx
+}
+
+func feeService(t *testing.T, handlers map[string]rpcHandler, policy repository.FeePolicy) (*repository.RPCBlockchainService, *rpcStub) {
+	t.Helper()
+	svc, stub := newReceiptService(t, handlers)
+	return svc.WithFeePolicy(policy), stub
+}
+
+func TestRegisterHash_SendsEstimatedGasAndFeeHistoryFees(t *testing.T) {
+	svc, stub := feeService(t, map[string]rpcHandler{
+		"eth_estimateGas": result("0x64"),
+		"eth_feeHistory": result(map[string]any{
+			"baseFeePerGas": []string{"0x50", "0x5a", "0x64"},
+			"reward":        [][]string{{"0x5"}, {"0x0"}, {"0x3"}, {"0x9"}},
+		}),
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x1", "0x1")),
+	}, repository.DefaultFeePolicy())
+
+	if _, err := svc.RegisterHash(context.Background(), validHash, "tester"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tx := sentTransaction(t, stub)
+	// 100 gas + 20% buffer; median non-zero tip 5; 2 * next base fee 100 + tip.
+	if tx["gas"] != "0x78" || tx["maxPriorityFeePerGas"] != "0x5" || tx["maxFeePerGas"] != "0xcd" {
+		t.Fatalf("sent = %v", tx)
+	}
+
+	params := stub.callsTo("eth_feeHistory")[0]
+	var blocks, tag string
+	var percentiles []int
+	json.Unmarshal(params[0], &blocks)
+	json.Unmarshal(params[1], &tag)
+	json.Unmarshal(params[2], &percentiles)
+	if blocks != "0xa" || tag != "latest" || len(percentiles) != 1 || percentiles[0] != 50 {
+		t.Fatalf("eth_feeHistory params = (%s, %s, %v)", blocks, tag, percentiles)
+	}
+}
+
+func TestRegisterHash_CapsFees(t *testing.T) {
+	policy := repository.DefaultFeePolicy()
+	policy.MaxFeePerGas = big.NewInt(150)
+	policy.MaxPriorityFeePerGas = big.NewInt(2)
+	svc, stub := feeService(t, map[string]rpcHandler{
+		"eth_feeHistory":            result(map[string]any{"baseFeePerGas": []string{"0x64"}, "reward": [][]string{{"0x9"}}}),
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x1", "0x1")),
+	}, policy)
+
+	if _, err := svc.RegisterHash(context.Background(), validHash, "tester"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tx := sentTransaction(t, stub); tx["maxPriorityFeePerGas"] != "0x2" || tx["maxFeePerGas"] != "0x96" {
+		t.Fatalf("sent = %v, want capped fees", tx)
+	}
+}
+
+func TestRegisterHash_FallsBackToPriorityFeeForEmptyBlocks(t *testing.T) {
+	svc, stub := feeService(t, map[string]rpcHandler{
+		"eth_feeHistory":            result(map[string]any{"baseFeePerGas": []string{"0x64"}, "reward": [][]string{{"0x0"}, {}}}),
+		"eth_maxPriorityFeePerGas":  result("0x7"),
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x1", "0x1")),
+	}, repository.DefaultFeePolicy())
+
+	if _, err := svc.RegisterHash(context.Background(), validHash, "tester"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tx := sentTransaction(t, stub); tx["maxPriorityFeePerGas"] != "0x7" {
+		t.Fatalf("sent = %v, want node priority fee", tx)
+	}
+}
+
+func TestRegisterHash_LegacyGasPriceWhenNoBaseFee(t *testing.T) {
+	svc, stub := feeService(t, map[string]rpcHandler{
+		"eth_feeHistory":            result(map[string]any{"baseFeePerGas": []string{"0x0", "0x0"}}),
+		"eth_gasPrice":              result("0x4a817c800"),
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x1", "0x1")),
+	}, repository.DefaultFeePolicy())
+
+	if _, err := svc.RegisterHash(context.Background(), validHash, "tester"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tx := sentTransaction(t, stub)
+	if tx["gasPrice"] != "0x4a817c800" || tx["maxFeePerGas"] != "" {
+		t.Fatalf("sent = %v, want legacy gas price", tx)
+	}
+}
+
+func TestRegisterHash_SpendCapExceeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		handlers map[string]rpcHandler
+		policy   func(*repository.FeePolicy)
+		want     string
+	}{
+		{
+			name:   "tx cost",
+			policy: func(p *repository.FeePolicy) { p.MaxTxCost = big.NewInt(1_000_000) },
+			want:   "cost",
+		},
+		{
+			name:   "base fee above max fee",
+			policy: func(p *repository.FeePolicy) { p.MaxFeePerGas = big.NewInt(10) },
+			want:   "base fee",
+		},
+		{
+			name: "legacy gas price above max fee",
+			handlers: map[string]rpcHandler{
+				"eth_feeHistory": result(map[string]any{}),
+				"eth_gasPrice":   result("0x4a817c800"),
+			},
+			policy: func(p *repository.FeePolicy) { p.MaxFeePerGas = big.NewInt(10) },
+			want:   "gas price",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := map[string]rpcHandler{}
+			for k, v := range tt.handlers {
+				handlers[k] = v
+			}
+			policy := repository.DefaultFeePolicy()
+			tt.policy(&policy)
+			svc, stub := feeService(t, handlers, policy)
+
+			_, err := svc.SubmitHash(context.Background(), validHash, "tester")
+			if !errors.Is(err, domain.ErrSpendCapExceeded) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want ErrSpendCapExceeded mentioning %q", err, tt.want)
+			}
+			if n := len(stub.callsTo("eth_sendTransaction")); n != 0 {
+				t.Fatalf("eth_sendTransaction calls = %d, want none", n)
+			}
+		})
+	}
+}
+
+func TestWaitForReceipt_RecordsEffectiveGasPrice(t *testing.T) {
+	mined := minedReceipt("0xtx", "0x1", "0x1")
+	mined["effectiveGasPrice"] = "0x3b9aca00"
+	svc, _ := newReceiptService(t, map[string]rpcHandler{"eth_getTransactionReceipt": result(mined)})
+
+	receipt, err := svc.WaitForReceipt(context.Background(), validHash, "0xtx")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fee := receipt.Fee()
+	if fee == nil || fee.GasUsed != 21000 || fee.Total().String() != "21000000000000" {
+		t.Fatalf("fee = %+v", fee)
+	}
+}
+
+func TestSigningWaitForReceipt_SkipsReplacementAboveFeeCap(t *testing.T) {
+	var polls atomic.Int32
+	handlers := signingHandlers()
+	handlers["eth_getTransactionReceipt"] = func([]json.RawMessage) (any, string) {
+		if polls.Add(1) < 10 {
+			return nil, ""
+		}
+		return minedReceipt("0xsignedtx", "0x11", "0x1"), ""
+	}
+	stub := newRPCStub(t, handlers)
+	policy := repository.DefaultFeePolicy()
+	policy.MaxFeePerGas = big.NewInt(3_000_000_000)
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 31337)
+	svc.WithReceiptPolicy(rebroadcastPolicy()).WithFeePolicy(policy)
+
+	txHash, err := svc.SubmitHash(context.Background(), validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("SubmitHash: %v", err)
+	}
+	if _, err := svc.WaitForReceipt(context.Background(), validHash, txHash); err != nil {
+		t.Fatalf("WaitForReceipt: %v", err)
+	}
+	if n := len(stub.callsTo("eth_sendRawTransaction")); n != 1 {
+		t.Fatalf("eth_sendRawTransaction calls = %d, want no replacement above the cap", n)
+	}
+}
@@ -0,0 +1,271 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+var _ interface {
+	usecase.BatchRepository
+	usecase.ReorgRepository
+	usecase.ReconciliationRepository
+	usecase.CheckpointStore
+	usecase.AnchorRepository
+	usecase.ProofRepository
+} = (*repository.MemoryCertificateRepo)(nil)
+
+func memCert(hash string, status domain.CertificateStatus, created time.Time) *domain.Certificate {
+	return &domain.Certificate{ContentHash: hash, Status: status, CreatedAt: created}
+}
+
+func TestMemoryRepo_SaveEnforcesUniqueContentHash(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryCertificateRepo()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		saved     int
+		conflicts int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Save(ctx, memCert("aa", domain.StatusPending, time.Now()))
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				saved++
+			case errors.Is(err, domain.ErrAlreadyCertified):
+				conflicts++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if saved != 1 || conflicts != 19 {
+		t.Fatalf("saved = %d, conflicts = %d", saved, conflicts)
+	}
+
+	cert := memCert("bb", domain.StatusPending, time.Now())
+	if err := repo.Save(ctx, cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(cert.ID) {
+		t.Fatalf("id = %q, want a v4 UUID", cert.ID)
+	}
+	if found, _ := repo.FindByID(ctx, cert.ID); found == nil || found.ContentHash != "bb" {
+		t.Fatalf("FindByID = %+v", found)
+	}
+	if found, err := repo.FindByID(ctx, "not-a-uuid"); found != nil || err != nil {
+		t.Fatalf("FindByID(unknown) = %+v, %v", found, err)
+	}
+	if found, err := repo.FindByHash(ctx, "cc"); found != nil || err != nil {
+		t.Fatalf("FindByHash(unknown) = %+v, %v", found, err)
+	}
+}
+
+func TestMemoryRepo_ReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryCertificateRepo()
+	cert := memCert("aa", domain.StatusPending, time.Now())
+	repo.Save(ctx, cert)
+
+	cert.Status = domain.StatusConfirmed
+	cert.Fee = &domain.AnchorFee{GasUsed: 21000, EffectiveGasPrice: big.NewInt(7)}
+	if err := repo.UpdateAnchorState(ctx, cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert.Fee.EffectiveGasPrice.SetInt64(99)
+
+	found, _ := repo.FindByHash(ctx, "aa")
+	found.Status = domain.StatusFailed
+	again, _ := repo.FindByHash(ctx, "aa")
+	if again.Status != domain.StatusConfirmed || again.Fee.EffectiveGasPrice.Int64() != 7 {
+		t.Fatalf("stored certificate changed outside the repository: %+v", again)
+	}
+
+	if err := repo.UpdateAnchorState(ctx, &domain.Certificate{ID: "missing"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryRepo_FindByPerceptualHashRanksNearest(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryCertificateRepo()
+	for i, ph := range []uint64{0b1111, 0b0111, 0b0001} {
+		c := memCert(fmt.Sprintf("h%d", i), domain.StatusConfirmed, time.Now())
+		c.PerceptualHashes = domain.PerceptualHashes{domain.AverageHash: ph}
+		repo.Save(ctx, c)
+	}
+	ahash := func(h uint64) domain.PerceptualHashes { return domain.PerceptualHashes{domain.AverageHash: h} }
+	repo.Save(ctx, memCert("no-phash", domain.StatusConfirmed, time.Now()))
+
+	matches, err := repo.FindByPerceptualHash(ctx, ahash(0b0011), 5, 10)
+	if err != nil || len(matches) != 3 {
+		t.Fatalf("matches = %+v, %v", matches, err)
+	}
+	if got := hashesOf(similarCerts(matches)); got != "h1,h2,h0" || matches[0].Distance != 1 || matches[2].Distance != 2 || matches[0].Distances[domain.AverageHash] != 1 {
+		t.Fatalf("ranked = %s, distances %d..%d, want h1,h2,h0 from 1 to 2", got, matches[0].Distance, matches[2].Distance)
+	}
+	if top, _ := repo.FindByPerceptualHash(ctx, ahash(0b0011), 5, 1); len(top) != 1 || top[0].Certificate.ContentHash != "h1" {
+		t.Fatalf("top 1 = %+v", top)
+	}
+	if exact, _ := repo.FindByPerceptualHash(ctx, ahash(0b1111), 0, 10); len(exact) != 1 || exact[0].Certificate.ContentHash != "h0" {
+		t.Fatalf("exact = %+v", exact)
+	}
+	if none, _ := repo.FindByPerceptualHash(ctx, ahash(0xff00), 3, 10); len(none) != 0 {
+		t.Fatalf("expected no match, got %+v", none)
+	}
+}
+
+func TestMemoryRepo_Claims(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryCertificateRepo()
+	base := time.Now()
+	for i, status := range []domain.CertificateStatus{domain.StatusPending, domain.StatusConfirmed, domain.StatusPending, domain.StatusSubmitted} {
+		repo.Save(ctx, memCert(fmt.Sprintf("h%d", i), status, base.Add(time.Duration(-i)*time.Minute)))
+	}
+
+	claimed, _ := repo.ClaimPending(ctx, 10, time.Minute)
+	if len(claimed) != 2 || claimed[0].ContentHash != "h2" || claimed[1].ContentHash != "h0" {
+		t.Fatalf("claimed = %v", hashesOf(claimed))
+	}
+	if again, _ := repo.ClaimPending(ctx, 10, time.Minute); len(again) != 0 {
+		t.Fatalf("leased certificates claimed again: %v", hashesOf(again))
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	unfinished, _ := repo.ClaimUnfinished(ctx, 10, time.Millisecond)
+	if len(unfinished) != 1 || unfinished[0].ContentHash != "h3" {
+		t.Fatalf("unfinished = %v, want only the unleased submitted certificate", hashesOf(unfinished))
+	}
+	if again, _ := repo.ClaimUnfinished(ctx, 10, time.Hour); len(again) != 0 {
+		t.Fatalf("fresh certificates claimed: %v", hashesOf(again))
+	}
+}
+
+func TestMemoryRepo_AnchorsAndLookups(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryCertificateRepo()
+	const contract = "0xAbC0000000000000000000000000000000000001"
+
+	var certs []*domain.Certificate
+	for i := 0; i < 3; i++ {
+		c := memCert(fmt.Sprintf("h%d", i), domain.StatusPending, time.Now())
+		repo.Save(ctx, c)
+		c.Status = domain.StatusConfirmed
+		c.BlockNumber = uint64(10 - i)
+		c.Batch = &domain.MerkleBatch{Root: "root", LeafIndex: uint64(2 - i), TreeSize: 3}
+		c.RecordAnchor(domain.Anchor{ChainID: 1, ContractAddress: "0xprimary", TxHash: "0xtx"})
+		if err := repo.UpdateAnchorState(ctx, c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		certs = append(certs, c)
+	}
+
+	if err := repo.SaveAnchor(ctx, certs[1].ID, domain.Anchor{ChainID: 10, ContractAddress: contract, TxHash: "0xl2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.SaveAnchor(ctx, "missing", domain.Anchor{ChainID: 10}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	unanchored, _ := repo.FindUnanchored(ctx, 10, strings.ToLower(contract), 10)
+	if got := hashesOf(unanchored); got != "h2,h0" {
+		t.Fatalf("unanchored = %s, want h2,h0", got)
+	}
+	found, _ := repo.FindByID(ctx, certs[1].ID)
+	if len(found.Anchors) != 2 || found.Anchors[1].ContractAddress != strings.ToLower(contract) {
+		t.Fatalf("anchors = %+v", found.Anchors)
+	}
+
+	// Dropping an anchor from the certificate removes it from the store.
+	found.Anchors = found.Anchors[1:]
+	repo.UpdateAnchorState(ctx, found)
+	if again, _ := repo.FindByID(ctx, certs[1].ID); len(again.Anchors) != 1 || again.Anchors[0].ChainID != 10 {
+		t.Fatalf("anchors after sync = %+v", again.Anchors)
+	}
+
+	byRoot, _ := repo.FindByMerkleRoot(ctx, "root")
+	if got := hashesOf(byRoot); got != "h2,h1,h0" {
+		t.Fatalf("by root = %s, want leaf order h2,h1,h0", got)
+	}
+	since, _ := repo.FindConfirmedSince(ctx, 9)
+	if got := hashesOf(since); got != "h1,h0" {
+		t.Fatalf("confirmed since 9 = %s, want h1,h0", got)
+	}
+
+	if err := repo.FlagChainMismatch(ctx, certs[0].ID, "tx differs"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c, _ := repo.FindByHash(ctx, "h0"); c.ChainMismatch != "tx differs" {
+		t.Fatalf("chain mismatch = %q", c.ChainMismatch)
+	}
+
+	if _, ok, _ := repo.LoadCheckpoint(ctx, "indexer"); ok {
+		t.Fatal("unexpected checkpoint")
+	}
+	repo.SaveCheckpoint(ctx, "indexer", 42)
+	if block, ok, _ := repo.LoadCheckpoint(ctx, "indexer"); !ok || block != 42 {
+		t.Fatalf("checkpoint = %d, %v", block, ok)
+	}
+}
+
+func TestMemoryRepo_RunsCertificationEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryCertificateRepo()
+	chain := repository.NewSimulatedBlockchainService(31337, validAddr1)
+	certify := usecase.NewCertifyUseCase(repo, usecase.NewAnchorProcessor(repo, chain, 3))
+
+	out, err := certify.Execute(ctx, usecase.CertifyInput{Content: bytes.NewReader([]byte("hello")), Registrant: validAddr1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !out.Certificate.IsAnchored() || out.Certificate.BlockNumber != 1 {
+		t.Fatalf("certificate = %+v", out.Certificate)
+	}
+
+	verify, err := usecase.NewVerifyUseCase(repo, chain).Execute(ctx, usecase.VerifyInput{Hash: out.Certificate.ContentHash, ConfirmOnChain: true})
+	if err != nil || !verify.Certified || verify.OnChainConfirmed == nil || !*verify.OnChainConfirmed {
+		t.Fatalf("verify = %+v, %v", verify, err)
+	}
+	if len(verify.Certificate.Anchors) != 1 || verify.Certificate.Anchors[0].ChainID != 31337 {
+		t.Fatalf("anchors = %+v", verify.Certificate.Anchors)
+	}
+
+	if _, err := certify.Execute(ctx, usecase.CertifyInput{Content: bytes.NewReader([]byte("hello"))}); !errors.Is(err, domain.ErrAlreadyCertified) {
+		t.Fatalf("expected ErrAlreadyCertified, got %v", err)
+	}
+}
+
+func similarCerts(matches []domain.SimilarCertificate) []*domain.Certificate {
+	certs := make([]*domain.Certificate, len(matches))
+	for i, m := range matches {
+		certs[i] = m.Certificate
+	}
+	return certs
+}
+
+func hashesOf(certs []*domain.Certificate) string {
+	hashes := make([]string, len(certs))
+	for i, c := range certs {
+		hashes[i] = c.ContentHash
+	}
+	return strings.Join(hashes, ",")
+}
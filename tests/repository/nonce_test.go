@@ -0,0 +1,247 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+type sentTx struct {
+	nonce  uint64
+	tip    *big.Int
+	feeCap *big.Int
+}
+
+func decodeSentTx(t *testing.T, params []json.RawMessage) sentTx {
+	t.Helper()
+	var rawHex string
+	json.Unmarshal(params[0], &rawHex)
+	raw, err := hex.DecodeString(strings.TrimPrefix(rawHex, "0x"))
+	if err != nil || len(raw) == 0 || raw[0] != 0x02 {
+		t.Fatalf("not a dynamic fee transaction: %s", rawHex)
+	}
+	items := splitRLPList(t, raw[1:])
+	return sentTx{
+		nonce:  new(big.Int).SetBytes(rlpScalar(items[1])).Uint64(),
+		tip:    new(big.Int).SetBytes(rlpScalar(items[2])),
+		feeCap: new(big.Int).SetBytes(rlpScalar(items[3])),
+	}
+}
+
+func TestSigningSubmitHash_ConcurrentSubmissionsGetDistinctNonces(t *testing.T) {
+	handlers := signingHandlers()
+	handlers["eth_sendRawTransaction"] = func(params []json.RawMessage) (any, string) {
+		return fmt.Sprintf("0xtx%d", decodeSentTx(t, params).nonce), ""
+	}
+	stub := newRPCStub(t, handlers)
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 31337)
+
+	const n = 10
+	var wg sync.WaitGroup
+	hashes := make(chan string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			txHash, err := svc.SubmitHash(context.Background(), validHash, validAddr1)
+			if err != nil {
+				t.Errorf("SubmitHash: %v", err)
+			}
+			hashes <- txHash
+		}()
+	}
+	wg.Wait()
+	close(hashes)
+
+	seen := map[string]bool{}
+	for h := range hashes {
+		seen[h] = true
+	}
+	for nonce := 5; nonce < 5+n; nonce++ {
+		if !seen[fmt.Sprintf("0xtx%d", nonce)] {
+			t.Fatalf("nonce %d was not used; got %v", nonce, seen)
+		}
+	}
+	if calls := len(stub.callsTo("eth_getTransactionCount")); calls != 1 {
+		t.Fatalf("eth_getTransactionCount calls = %d, want 1", calls)
+	}
+}
+
+func TestSigningSubmitHash_ResyncsAfterNonceConflict(t *testing.T) {
+	var counts atomic.Int32
+	var sends atomic.Int32
+	handlers := signingHandlers()
+	handlers["eth_getTransactionCount"] = func([]json.RawMessage) (any, string) {
+		if counts.Add(1) == 1 {
+			return "0x5", ""
+		}
+		return "0x9", ""
+	}
+	handlers["eth_sendRawTransaction"] = func(params []json.RawMessage) (any, string) {
+		if sends.Add(1) == 1 {
+			return nil, "nonce too low: next nonce 9, tx nonce 5"
+		}
+		return fmt.Sprintf("0xtx%d", decodeSentTx(t, params).nonce), ""
+	}
+	stub := newRPCStub(t, handlers)
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 31337)
+
+	txHash, err := svc.SubmitHash(context.Background(), validHash, validAddr1)
+	if err != nil || txHash != "0xtx9" {
+		t.Fatalf("tx = %q, err = %v, want 0xtx9", txHash, err)
+	}
+	if next, _ := svc.SubmitHash(context.Background(), validHash, validAddr1); next != "0xtx10" {
+		t.Fatalf("next tx = %q, want 0xtx10", next)
+	}
+}
+
+func TestSigningSubmitHash_ResyncsAfterSendFailure(t *testing.T) {
+	var sends atomic.Int32
+	handlers := signingHandlers()
+	handlers["eth_sendRawTransaction"] = func([]json.RawMessage) (any, string) {
+		if sends.Add(1) == 1 {
+			return nil, "connection reset"
+		}
+		return "0xtx", ""
+	}
+	stub := newRPCStub(t, handlers)
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 31337)
+
+	if _, err := svc.SubmitHash(context.Background(), validHash, validAddr1); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("err = %v, want send failure", err)
+	}
+	if _, err := svc.SubmitHash(context.Background(), validHash, validAddr1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls := len(stub.callsTo("eth_getTransactionCount")); calls != 2 {
+		t.Fatalf("eth_getTransactionCount calls = %d, want 2", calls)
+	}
+}
+
+func rebroadcastPolicy() repository.ReceiptPolicy {
+	return repository.ReceiptPolicy{
+		Timeout:          time.Second,
+		PollInterval:     5 * time.Millisecond,
+		Confirmations:    1,
+		RebroadcastAfter: 20 * time.Millisecond,
+		FeeBumpPercent:   15,
+	}
+}
+
+func TestSigningWaitForReceipt_ReplacesStuckTransaction(t *testing.T) {
+	var sent []sentTx
+	var mu sync.Mutex
+	handlers := signingHandlers()
+	handlers["eth_sendRawTransaction"] = func(params []json.RawMessage) (any, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, decodeSentTx(t, params))
+		return fmt.Sprintf("0xtx-%d", len(sent)), ""
+	}
+	handlers["eth_getTransactionReceipt"] = func(params []json.RawMessage) (any, string) {
+		var h string
+		json.Unmarshal(params[0], &h)
+		if h != "0xtx-2" {
+			return nil, ""
+		}
+		return minedReceipt(h, "0x11", "0x1"), ""
+	}
+	stub := newRPCStub(t, handlers)
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 31337)
+	svc.WithReceiptPolicy(rebroadcastPolicy())
+
+	txHash, err := svc.SubmitHash(context.Background(), validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("SubmitHash: %v", err)
+	}
+	receipt, err := svc.WaitForReceipt(context.Background(), validHash, txHash)
+	if err != nil {
+		t.Fatalf("WaitForReceipt: %v", err)
+	}
+	if receipt.TxHash != "0xtx-2" {
+		t.Fatalf("receipt tx = %s, want replacement 0xtx-2", receipt.TxHash)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 2 || sent[0].nonce != sent[1].nonce {
+		t.Fatalf("sent = %+v, want one replacement with the same nonce", sent)
+	}
+	wantTip := new(big.Int).Div(new(big.Int).Mul(sent[0].tip, big.NewInt(115)), big.NewInt(100))
+	if sent[1].tip.Cmp(wantTip) != 0 || sent[1].feeCap.Cmp(sent[0].feeCap) <= 0 {
+		t.Fatalf("replacement fees tip=%s cap=%s, original tip=%s cap=%s", sent[1].tip, sent[1].feeCap, sent[0].tip, sent[0].feeCap)
+	}
+}
+
+func TestSigningWaitForReceipt_ReplacesTransactionFromBeforeRestart(t *testing.T) {
+	var replaced atomic.Value
+	handlers := signingHandlers()
+	handlers["eth_getTransactionByHash"] = result(map[string]any{
+		"type":                 "0x2",
+		"from":                 anvilAddress,
+		"to":                   validAddr2,
+		"nonce":                "0x3",
+		"gas":                  "0xea60",
+		"maxFeePerGas":         "0x77359400",
+		"maxPriorityFeePerGas": "0x3b9aca00",
+		"value":                "0x0",
+		"input":                "0xd22057a9",
+		"chainId":              "0x7a69",
+	})
+	handlers["eth_sendRawTransaction"] = func(params []json.RawMessage) (any, string) {
+		replaced.Store(decodeSentTx(t, params))
+		return "0xreplacement", ""
+	}
+	handlers["eth_getTransactionReceipt"] = func(params []json.RawMessage) (any, string) {
+		var h string
+		json.Unmarshal(params[0], &h)
+		if h != "0xreplacement" {
+			return nil, ""
+		}
+		return minedReceipt(h, "0x11", "0x1"), ""
+	}
+	stub := newRPCStub(t, handlers)
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 31337)
+	svc.WithReceiptPolicy(rebroadcastPolicy())
+
+	receipt, err := svc.WaitForReceipt(context.Background(), validHash, "0xold")
+	if err != nil {
+		t.Fatalf("WaitForReceipt: %v", err)
+	}
+	tx, _ := replaced.Load().(sentTx)
+	if receipt.TxHash != "0xreplacement" || tx.nonce != 3 || tx.feeCap.Cmp(big.NewInt(2300000000)) != 0 {
+		t.Fatalf("receipt = %s, replacement = %+v", receipt.TxHash, tx)
+	}
+}
+
+func TestSigningWaitForReceipt_ReplacementNonceTooLowKeepsPolling(t *testing.T) {
+	var polls atomic.Int32
+	handlers := signingHandlers()
+	handlers["eth_getTransactionReceipt"] = func([]json.RawMessage) (any, string) {
+		if polls.Add(1) < 10 {
+			return nil, ""
+		}
+		return minedReceipt("0xsignedtx", "0x11", "0x1"), ""
+	}
+	stub := newRPCStub(t, handlers)
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 31337)
+	svc.WithReceiptPolicy(rebroadcastPolicy())
+
+	txHash, _ := svc.SubmitHash(context.Background(), validHash, validAddr1)
+	stub.mu.Lock()
+	stub.handlers["eth_sendRawTransaction"] = rpcFailure("nonce too low")
+	stub.mu.Unlock()
+
+	if _, err := svc.WaitForReceipt(context.Background(), validHash, txHash); err != nil {
+		t.Fatalf("WaitForReceipt: %v", err)
+	}
+}
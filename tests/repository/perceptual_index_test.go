@@ -0,0 +1,178 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+func TestFindByPerceptualHash_IndexMatchesScan(t *testing.T) {
+	algorithms := []domain.HashAlgorithm{domain.DCTHash, domain.DifferenceHash}
+	for name, repo := range map[string]usecase.CertificateRepository{
+		"memory": repository.NewMemoryCertificateRepo(),
+		"sqlite": openSQLiteRepo(t, ":memory:"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			rng := rand.New(rand.NewSource(7))
+			perturb := func(h uint64, maxBits int) uint64 {
+				for _, bit := range rng.Perm(64)[:rng.Intn(maxBits+1)] {
+					h ^= 1 << bit
+				}
+				return h
+			}
+
+			var stored []domain.PerceptualHashes
+			for i := 0; i < 400; i++ {
+				hashes := domain.PerceptualHashes{}
+				for _, alg := range algorithms {
+					hashes[alg] = rng.Uint64()
+				}
+				if i%2 == 1 {
+					// Near-duplicates of an earlier image, each algorithm at
+					// every distance up to 12.
+					for alg, h := range stored[rng.Intn(len(stored))] {
+						hashes[alg] = perturb(h, 12)
+					}
+				}
+				c := memCert(fmt.Sprintf("h%d", i), domain.StatusConfirmed, time.Now())
+				c.PerceptualHashes = hashes
+				if err := repo.Save(ctx, c); err != nil {
+					t.Fatalf("save: %v", err)
+				}
+				stored = append(stored, hashes)
+			}
+
+			for _, maxDistance := range []int{0, 4, 8, 12, 20} {
+				for i := 0; i < 50; i++ {
+					query := domain.PerceptualHashes{}
+					for alg, h := range stored[rng.Intn(len(stored))] {
+						query[alg] = perturb(h, 3)
+					}
+					want := 65
+					for _, hashes := range stored {
+						if d, _ := domain.CombinedDistance(query.Distances(hashes)); d <= maxDistance && d < want {
+							want = d
+						}
+					}
+
+					matches, err := repo.FindByPerceptualHash(ctx, query, maxDistance, 3)
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					switch {
+					case want == 65 && len(matches) != 0:
+						t.Fatalf("distance %d: unexpected match %v for %v", maxDistance, matches[0].Certificate.PerceptualHashes, query)
+					case want < 65 && (len(matches) == 0 || matches[0].Distance != want):
+						t.Fatalf("distance %d: matches = %+v, want nearest at distance %d", maxDistance, matches, want)
+					}
+					for j := 1; j < len(matches); j++ {
+						if matches[j].Distance < matches[j-1].Distance || matches[j].Distance > maxDistance {
+							t.Fatalf("distance %d: matches out of order: %+v", maxDistance, matches)
+						}
+					}
+					for _, m := range matches {
+						if d, _ := domain.CombinedDistance(query.Distances(m.Certificate.PerceptualHashes)); d != m.Distance {
+							t.Fatalf("distance %d: reported %d, recomputed %d", maxDistance, m.Distance, d)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFindByVideoFingerprint_IndexMatchesScan(t *testing.T) {
+	algorithms := []domain.HashAlgorithm{domain.DCTHash, domain.DifferenceHash}
+	for name, repo := range map[string]usecase.CertificateRepository{
+		"memory": repository.NewMemoryCertificateRepo(),
+		"sqlite": openSQLiteRepo(t, ":memory:"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			rng := rand.New(rand.NewSource(11))
+			perturb := func(h uint64, maxBits int) uint64 {
+				for _, bit := range rng.Perm(64)[:rng.Intn(maxBits+1)] {
+					h ^= 1 << bit
+				}
+				return h
+			}
+
+			var stored []*domain.Certificate
+			for i := 0; i < 60; i++ {
+				fp := make(domain.VideoFingerprint, 10)
+				for j := range fp {
+					hashes := domain.PerceptualHashes{}
+					for _, alg := range algorithms {
+						hashes[alg] = rng.Uint64()
+					}
+					if i%3 == 2 {
+						// Re-encodes of a segment of an earlier video.
+						for alg, h := range stored[rng.Intn(len(stored))].Fingerprint[rng.Intn(10)].Hashes {
+							hashes[alg] = perturb(h, 10)
+						}
+					}
+					fp[j] = domain.VideoSegment{Start: time.Duration(j) * time.Second, End: time.Duration(j+1) * time.Second, Hashes: hashes}
+				}
+				c := memCert(fmt.Sprintf("v%d", i), domain.StatusConfirmed, time.Now())
+				c.Fingerprint = fp
+				if err := repo.Save(ctx, c); err != nil {
+					t.Fatalf("save: %v", err)
+				}
+				stored = append(stored, c)
+			}
+
+			loaded, err := repo.FindByHash(ctx, "v5")
+			if err != nil || len(loaded.Fingerprint) != 10 || loaded.Fingerprint[9].End != 10*time.Second ||
+				loaded.Fingerprint[4].Hashes[domain.DCTHash] != stored[5].Fingerprint[4].Hashes[domain.DCTHash] {
+				t.Fatalf("fingerprint did not round-trip: %+v, %v", loaded, err)
+			}
+
+			for _, maxDistance := range []int{0, 4, 8} {
+				for i := 0; i < 30; i++ {
+					// A trimmed clip of a stored video, or random frames.
+					clip := make(domain.VideoFingerprint, 4)
+					source, from := stored[rng.Intn(len(stored))], rng.Intn(7)
+					for j := range clip {
+						hashes := domain.PerceptualHashes{}
+						for alg, h := range source.Fingerprint[from+j].Hashes {
+							hashes[alg] = perturb(h, 3)
+							if i%5 == 4 {
+								hashes[alg] = rng.Uint64()
+							}
+						}
+						clip[j] = domain.VideoSegment{Start: time.Duration(j) * time.Second, End: time.Duration(j+1) * time.Second, Hashes: hashes}
+					}
+					want := -1
+					for _, c := range stored {
+						if m, ok := domain.MatchVideo(clip, c, maxDistance); ok && (want < 0 || m.Distance < want) {
+							want = m.Distance
+						}
+					}
+
+					matches, err := repo.FindByVideoFingerprint(ctx, clip, maxDistance, 3)
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					switch {
+					case want < 0 && len(matches) != 0:
+						t.Fatalf("distance %d: unexpected match %s", maxDistance, matches[0].Certificate.ContentHash)
+					case want >= 0 && (len(matches) == 0 || matches[0].Distance != want):
+						t.Fatalf("distance %d: matches = %+v, want nearest at distance %d", maxDistance, matches, want)
+					}
+					for _, m := range matches {
+						if again, ok := domain.MatchVideo(clip, m.Certificate, maxDistance); !ok || again.Distance != m.Distance {
+							t.Fatalf("distance %d: reported %d, recomputed %d", maxDistance, m.Distance, again.Distance)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+func TestFindByPixelHash_ReturnsOldest(t *testing.T) {
+	for name, repo := range map[string]usecase.CertificateRepository{
+		"memory": repository.NewMemoryCertificateRepo(),
+		"sqlite": openSQLiteRepo(t, ":memory:"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, hash := range []string{"first", "stripped", "other", "video"} {
+				c := memCert(hash, domain.StatusConfirmed, base.Add(time.Duration(i)*time.Hour))
+				switch hash {
+				case "first", "stripped":
+					c.PixelHash = "p1"
+				case "other":
+					c.PixelHash = "p2"
+				}
+				if err := repo.Save(ctx, c); err != nil {
+					t.Fatalf("save %s: %v", hash, err)
+				}
+			}
+
+			for pixelHash, want := range map[string]string{"p1": "first", "p2": "other", "p3": "", "": ""} {
+				got, err := repo.FindByPixelHash(ctx, pixelHash)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				switch {
+				case want == "" && got != nil:
+					t.Errorf("%q: found %s, want none", pixelHash, got.ContentHash)
+				case want != "" && (got == nil || got.ContentHash != want || got.PixelHash != pixelHash):
+					t.Errorf("%q: found %+v, want %s", pixelHash, got, want)
+				}
+			}
+		})
+	}
+}
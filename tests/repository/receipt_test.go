@@ -0,0 +1,266 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+const contentRegisteredTopic = "0xee13a0bc0a3edc04bc8cf9b57d7c4d7600bff7d16470ae978bfb827e3ae974ea"
+
+func contentRegisteredLog(contract, hash, registrant string, timestamp int64) map[string]any {
+	return map[string]any{
+		"address": contract,
+		"topics": []string{
+			contentRegisteredTopic,
+			"0x" + hash,
+			"0x" + strings.Repeat("0", 24) + strings.TrimPrefix(registrant, "0x"),
+		},
+		"data":     fmt.Sprintf("0x%064x", timestamp),
+		"logIndex": "0x0",
+	}
+}
+
+func minedReceipt(txHash, blockNumber, status string) map[string]any {
+	return map[string]any{
+		"transactionHash": txHash,
+		"blockNumber":     blockNumber,
+		"blockHash":       "0xblockhash",
+		"gasUsed":         "0x5208",
+		"status":          status,
+		"logs":            []any{contentRegisteredLog(validAddr2, validHash, validAddr1, 1700000000)},
+	}
+}
+
+func fastPolicy() repository.ReceiptPolicy {
+	return repository.ReceiptPolicy{Timeout: time.Second, PollInterval: 5 * time.Millisecond, Confirmations: 1}
+}
+
+func newReceiptService(t *testing.T, handlers map[string]rpcHandler) (*repository.RPCBlockchainService, *rpcStub) {
+	t.Helper()
+	if _, ok := handlers["eth_sendTransaction"]; !ok {
+		handlers["eth_sendTransaction"] = result("0xtx")
+	}
+	stub := newRPCStub(t, withFees(handlers))
+	svc, err := repository.NewEVMBlockchainService(stub.URL, validAddr1, validAddr2)
+	if err != nil {
+		t.Fatalf("unexpected constructor error: %v", err)
+	}
+	return svc.WithReceiptPolicy(fastPolicy()), stub
+}
+
+func TestRegisterHash_PollsUntilMined(t *testing.T) {
+	var polls atomic.Int32
+	svc, stub := newReceiptService(t, map[string]rpcHandler{
+		"eth_getTransactionReceipt": func([]json.RawMessage) (any, string) {
+			if polls.Add(1) < 3 {
+				return nil, ""
+			}
+			return minedReceipt("0xtx", "0x7", "0x1"), ""
+		},
+	})
+
+	receipt, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receipt.BlockNumber != 7 {
+		t.Fatalf("block = %d, want 7", receipt.BlockNumber)
+	}
+	if n := len(stub.callsTo("eth_getTransactionReceipt")); n != 3 {
+		t.Fatalf("receipt polls = %d, want 3", n)
+	}
+}
+
+func TestRegisterHash_WaitsForConfirmations(t *testing.T) {
+	var head atomic.Int64
+	head.Store(10)
+	svc, _ := newReceiptService(t, map[string]rpcHandler{
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0xa", "0x1")),
+		"eth_blockNumber": func([]json.RawMessage) (any, string) {
+			return fmt.Sprintf("0x%x", head.Add(1)), ""
+		},
+	})
+	svc.WithReceiptPolicy(repository.ReceiptPolicy{Timeout: time.Second, PollInterval: 5 * time.Millisecond, Confirmations: 3})
+
+	receipt, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receipt.BlockNumber != 10 {
+		t.Fatalf("block = %d, want 10", receipt.BlockNumber)
+	}
+	if head.Load() < 12 {
+		t.Fatalf("returned before 3 confirmations, head = %d", head.Load())
+	}
+}
+
+func TestRegisterHash_RevertedReceipt(t *testing.T) {
+	svc, _ := newReceiptService(t, map[string]rpcHandler{
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x7", "0x0")),
+	})
+
+	receipt, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+	if !errors.Is(err, domain.ErrTransactionReverted) {
+		t.Fatalf("expected ErrTransactionReverted, got %v", err)
+	}
+	if receipt == nil || receipt.Succeeded() {
+		t.Fatalf("expected reverted receipt, got %+v", receipt)
+	}
+}
+
+func TestRegisterHash_ReceiptTimeout(t *testing.T) {
+	svc, _ := newReceiptService(t, map[string]rpcHandler{
+		"eth_getTransactionReceipt": result(nil),
+	})
+	svc.WithReceiptPolicy(repository.ReceiptPolicy{Timeout: 30 * time.Millisecond, PollInterval: 5 * time.Millisecond, Confirmations: 1})
+
+	_, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+	if !errors.Is(err, domain.ErrReceiptTimeout) {
+		t.Fatalf("expected ErrReceiptTimeout, got %v", err)
+	}
+}
+
+func TestRegisterHash_ReceiptCanceledByCaller(t *testing.T) {
+	svc, _ := newReceiptService(t, map[string]rpcHandler{
+		"eth_getTransactionReceipt": result(nil),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	_, err := svc.RegisterHash(ctx, validHash, validAddr1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRegisterHash_ReceiptErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		handlers map[string]rpcHandler
+		want     string
+	}{
+		{"rpc failure", map[string]rpcHandler{"eth_getTransactionReceipt": rpcFailure("boom")}, "fetch receipt"},
+		{"bad block number", map[string]rpcHandler{"eth_getTransactionReceipt": result(minedReceipt("0xtx", "zz", "0x1"))}, "parse receipt block number"},
+		{"bad status", map[string]rpcHandler{"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x1", "zz"))}, "parse receipt status"},
+		{"bad gas used", map[string]rpcHandler{"eth_getTransactionReceipt": result(map[string]any{"blockNumber": "0x1", "gasUsed": "", "status": "0x1"})}, "parse receipt gas used"},
+		{"block number failure", map[string]rpcHandler{
+			"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x1", "0x1")),
+			"eth_blockNumber":           rpcFailure("down"),
+		}, "fetch block number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newReceiptService(t, tt.handlers)
+			svc.WithReceiptPolicy(repository.ReceiptPolicy{Timeout: time.Second, PollInterval: 5 * time.Millisecond, Confirmations: 2})
+
+			_, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNewBlockchainServiceFromEnv_ReceiptPolicyErrors(t *testing.T) {
+	t.Setenv("RPC_URL", "http://localhost:8545")
+	t.Setenv("FROM_ADDRESS", validAddr1)
+	t.Setenv("PRIVATE_KEY", "")
+	t.Setenv("CONTRACT_ADDRESS", validAddr2)
+
+	tests := []struct {
+		key, value, want string
+	}{
+		{"RECEIPT_TIMEOUT", "soon", "invalid RECEIPT_TIMEOUT"},
+		{"RECEIPT_POLL_INTERVAL", "-1s", "must be positive"},
+		{"CONFIRMATIONS", "-1", "invalid CONFIRMATIONS"},
+		{"REBROADCAST_AFTER", "-1s", "must not be negative"},
+		{"FEE_BUMP_PERCENT", "5", "invalid FEE_BUMP_PERCENT"},
+		{"FEE_HISTORY_BLOCKS", "0", "invalid FEE_HISTORY_BLOCKS"},
+		{"FEE_REWARD_PERCENTILE", "101", "invalid FEE_REWARD_PERCENTILE"},
+		{"BASE_FEE_MULTIPLIER", "0", "invalid BASE_FEE_MULTIPLIER"},
+		{"MAX_FEE_PER_GAS", "1.5gwei", "invalid MAX_FEE_PER_GAS"},
+		{"MAX_TX_COST", "-1", "invalid MAX_TX_COST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			_, err := repository.NewBlockchainServiceFromEnv(nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNewBlockchainServiceFromEnv_ReceiptPolicy(t *testing.T) {
+	t.Setenv("RPC_URL", "http://localhost:8545")
+	t.Setenv("FROM_ADDRESS", validAddr1)
+	t.Setenv("PRIVATE_KEY", "")
+	t.Setenv("CONTRACT_ADDRESS", validAddr2)
+	t.Setenv("RECEIPT_TIMEOUT", "30s")
+	t.Setenv("RECEIPT_POLL_INTERVAL", "500ms")
+	t.Setenv("CONFIRMATIONS", "3")
+	t.Setenv("REBROADCAST_AFTER", "0")
+
+	if _, err := repository.NewBlockchainServiceFromEnv(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSubmitHashAndWaitForReceipt(t *testing.T) {
+	svc, stub := newReceiptService(t, map[string]rpcHandler{
+		"eth_getTransactionReceipt": result(minedReceipt("0xtx", "0x7", "0x1")),
+	})
+
+	txHash, err := svc.SubmitHash(context.Background(), validHash, validAddr1)
+	if err != nil || txHash != "0xtx" {
+		t.Fatalf("tx = %q, err = %v", txHash, err)
+	}
+	if n := len(stub.callsTo("eth_getTransactionReceipt")); n != 0 {
+		t.Fatalf("SubmitHash polled receipts %d times, want 0", n)
+	}
+
+	receipt, err := svc.WaitForReceipt(context.Background(), validHash, txHash)
+	if err != nil || receipt.BlockNumber != 7 {
+		t.Fatalf("receipt = %+v, err = %v", receipt, err)
+	}
+
+	other := strings.Repeat("ef", 32)
+	if _, err := svc.WaitForReceipt(context.Background(), other, txHash); !errors.Is(err, domain.ErrAnchorEventMissing) {
+		t.Fatalf("err = %v, want ErrAnchorEventMissing for a different hash", err)
+	}
+}
+
+func TestNewAnchorChainFromEnv_ReadsPrefixedVariables(t *testing.T) {
+	t.Setenv("RPC_URL", "http://localhost:8545")
+	t.Setenv("CONTRACT_ADDRESS", validAddr2)
+	t.Setenv("FROM_ADDRESS", validAddr1)
+	t.Setenv("MAINNET_RPC_URL", "")
+
+	if _, err := repository.NewAnchorChainFromEnv("mainnet"); err == nil || !strings.Contains(err.Error(), "MAINNET_RPC_URL") {
+		t.Fatalf("expected missing MAINNET_RPC_URL error, got %v", err)
+	}
+
+	t.Setenv("MAINNET_RPC_URL", "http://localhost:8546")
+	t.Setenv("MAINNET_CONTRACT_ADDRESS", validAddr1)
+	t.Setenv("MAINNET_FROM_ADDRESS", validAddr2)
+	chain, err := repository.NewAnchorChainFromEnv("mainnet")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chain.ContractAddress() != validAddr1 {
+		t.Fatalf("contract = %s, want the MAINNET_ one", chain.ContractAddress())
+	}
+}
@@ -0,0 +1,277 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+// endpoint is a JSON-RPC stand-in whose response depends on the method and
+// how many requests it has served.
+type endpoint struct {
+	*httptest.Server
+	hits    atomic.Int32
+	methods chan string
+}
+
+func newEndpoint(t *testing.T, respond func(hit int32, method string) (int, string)) *endpoint {
+	t.Helper()
+	ep := &endpoint{methods: make(chan string, 16)}
+	ep.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		select {
+		case ep.methods <- req.Method:
+		default:
+		}
+		status, body := respond(ep.hits.Add(1), req.Method)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(ep.Close)
+	return ep
+}
+
+func rpcResult(v any) (int, string) {
+	raw, _ := json.Marshal(v)
+	return http.StatusOK, fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":%s}`, raw)
+}
+
+func rpcCodeError(code int, msg string) (int, string) {
+	return http.StatusOK, fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"error":{"code":%d,"message":%q}}`, code, msg)
+}
+
+func registered(int32, string) (int, string) { return rpcResult(boolWord(true)) }
+
+func unavailable(int32, string) (int, string) { return http.StatusServiceUnavailable, "upstream down" }
+
+func fastRetries() repository.RetryPolicy {
+	return repository.RetryPolicy{
+		MaxAttempts:    3,
+		BaseDelay:      time.Millisecond,
+		MaxDelay:       5 * time.Millisecond,
+		RequestTimeout: time.Second,
+		Cooldown:       time.Minute,
+	}
+}
+
+func failoverService(t *testing.T, policy repository.RetryPolicy, urls ...string) *repository.RPCBlockchainService {
+	t.Helper()
+	svc, err := repository.NewEVMBlockchainService(strings.Join(urls, ","), validAddr1, validAddr2)
+	if err != nil {
+		t.Fatalf("unexpected constructor error: %v", err)
+	}
+	return svc.WithRetryPolicy(policy)
+}
+
+func TestRPCFailover_SkipsUnhealthyEndpoint(t *testing.T) {
+	primary := newEndpoint(t, unavailable)
+	backup := newEndpoint(t, registered)
+	svc := failoverService(t, fastRetries(), primary.URL, backup.URL)
+
+	for i := 0; i < 2; i++ {
+		ok, err := svc.IsHashRegistered(context.Background(), validHash)
+		if err != nil || !ok {
+			t.Fatalf("call %d: registered = %v, err = %v", i, ok, err)
+		}
+	}
+	if primary.hits.Load() != 1 || backup.hits.Load() != 2 {
+		t.Fatalf("primary hits = %d, backup hits = %d; want the failed primary skipped while cooling down", primary.hits.Load(), backup.hits.Load())
+	}
+}
+
+func TestRPCFailover_ReturnsToPrimaryAfterCooldown(t *testing.T) {
+	primary := newEndpoint(t, func(hit int32, method string) (int, string) {
+		if hit == 1 {
+			return unavailable(hit, method)
+		}
+		return registered(hit, method)
+	})
+	backup := newEndpoint(t, registered)
+	policy := fastRetries()
+	policy.Cooldown = 20 * time.Millisecond
+	svc := failoverService(t, policy, primary.URL, backup.URL)
+
+	if _, err := svc.IsHashRegistered(context.Background(), validHash); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := svc.IsHashRegistered(context.Background(), validHash); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if primary.hits.Load() != 2 || backup.hits.Load() != 1 {
+		t.Fatalf("primary hits = %d, backup hits = %d", primary.hits.Load(), backup.hits.Load())
+	}
+}
+
+func TestRPCFailover_FailsOverOnTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := newEndpoint(t, func(hit int32, method string) (int, string) {
+		<-release
+		return registered(hit, method)
+	})
+	// Cleanups run in reverse, so the handler is released before Close waits
+	// for it.
+	t.Cleanup(func() { close(release) })
+	backup := newEndpoint(t, registered)
+	policy := fastRetries()
+	policy.RequestTimeout = 20 * time.Millisecond
+	svc := failoverService(t, policy, slow.URL, backup.URL)
+
+	if ok, err := svc.IsHashRegistered(context.Background(), validHash); err != nil || !ok {
+		t.Fatalf("registered = %v, err = %v", ok, err)
+	}
+}
+
+func TestRPCRetry_ClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		respond  func(int32, string) (int, string)
+		wantHits int32
+		wantErr  string
+	}{
+		{
+			name: "rate limited then ok",
+			respond: func(hit int32, m string) (int, string) {
+				if hit < 3 {
+					return http.StatusTooManyRequests, ""
+				}
+				return registered(hit, m)
+			},
+			wantHits: 3,
+		},
+		{
+			name: "limit exceeded code then ok",
+			respond: func(hit int32, m string) (int, string) {
+				if hit == 1 {
+					return rpcCodeError(-32005, "limit exceeded")
+				}
+				return registered(hit, m)
+			},
+			wantHits: 2,
+		},
+		{
+			name:     "internal error exhausts attempts",
+			respond:  func(int32, string) (int, string) { return rpcCodeError(-32603, "internal error") },
+			wantHits: 3,
+			wantErr:  "rpc error: internal error",
+		},
+		{
+			name:     "server error exhausts attempts",
+			respond:  unavailable,
+			wantHits: 3,
+			wantErr:  "rpc http status 503",
+		},
+		{
+			name:     "execution error is fatal",
+			respond:  func(int32, string) (int, string) { return rpcCodeError(-32000, "execution reverted") },
+			wantHits: 1,
+			wantErr:  "execution reverted",
+		},
+		{
+			name:     "invalid params is fatal",
+			respond:  func(int32, string) (int, string) { return rpcCodeError(-32602, "invalid params") },
+			wantHits: 1,
+			wantErr:  "invalid params",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := newEndpoint(t, tt.respond)
+			svc := failoverService(t, fastRetries(), ep.URL)
+
+			_, err := svc.IsHashRegistered(context.Background(), validHash)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if got := ep.hits.Load(); got != tt.wantHits {
+				t.Fatalf("hits = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func nodeSend(status int, body string) func(int32, string) (int, string) {
+	return func(_ int32, method string) (int, string) {
+		switch method {
+		case "eth_estimateGas":
+			return rpcResult("0xea60")
+		case "eth_feeHistory":
+			return rpcResult(map[string]any{"baseFeePerGas": []string{"0x1"}, "reward": [][]string{{"0x1"}}})
+		}
+		return status, body
+	}
+}
+
+func TestRPCRetry_DoesNotResendDeliveredTransaction(t *testing.T) {
+	primary := newEndpoint(t, nodeSend(unavailable(0, "")))
+	backup := newEndpoint(t, nodeSend(rpcResult("0xtx")))
+	svc := failoverService(t, fastRetries(), primary.URL, backup.URL)
+
+	if _, err := svc.SubmitHash(context.Background(), validHash, "tester"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("err = %v, want the primary failure", err)
+	}
+	close(backup.methods)
+	for m := range backup.methods {
+		if m == "eth_sendTransaction" {
+			t.Fatal("transaction was resent to the backup after the primary received it")
+		}
+	}
+}
+
+func TestRPCFailover_ResendsUndeliveredTransaction(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	backup := newEndpoint(t, nodeSend(rpcResult("0xtx")))
+	svc := failoverService(t, fastRetries(), down.URL, backup.URL)
+
+	txHash, err := svc.SubmitHash(context.Background(), validHash, "tester")
+	if err != nil || txHash != "0xtx" {
+		t.Fatalf("tx = %q, err = %v", txHash, err)
+	}
+}
+
+func TestNewEVMBlockchainService_RequiresEndpoint(t *testing.T) {
+	if _, err := repository.NewEVMBlockchainService(" , ", validAddr1, validAddr2); err == nil || !strings.Contains(err.Error(), "rpc url is required") {
+		t.Fatalf("err = %v, want missing rpc url", err)
+	}
+}
+
+func TestNewBlockchainServiceFromEnv_RetryPolicyErrors(t *testing.T) {
+	t.Setenv("RPC_URL", "http://localhost:8545,http://localhost:8546")
+	t.Setenv("FROM_ADDRESS", validAddr1)
+	t.Setenv("PRIVATE_KEY", "")
+	t.Setenv("CONTRACT_ADDRESS", validAddr2)
+
+	tests := []struct {
+		key, value, want string
+	}{
+		{"RPC_MAX_ATTEMPTS", "0", "invalid RPC_MAX_ATTEMPTS"},
+		{"RPC_BACKOFF_BASE", "fast", "invalid RPC_BACKOFF_BASE"},
+		{"RPC_BACKOFF_MAX", "0s", "must be positive"},
+		{"RPC_TIMEOUT", "-1s", "must be positive"},
+		{"RPC_COOLDOWN", "x", "invalid RPC_COOLDOWN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			if _, err := repository.NewBlockchainServiceFromEnv(nil); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type rpcHandler func(params []json.RawMessage) (any, string)
+
+type rpcStub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handlers map[string]rpcHandler
+	calls    map[string][][]json.RawMessage
+}
+
+func newRPCStub(t *testing.T, handlers map[string]rpcHandler) *rpcStub {
+	t.Helper()
+	stub := &rpcStub{handlers: handlers, calls: map[string][][]json.RawMessage{}}
+	stub.Server = httptest.NewServer(http.HandlerFunc(stub.serve))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (s *rpcStub) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.calls[req.Method] = append(s.calls[req.Method], req.Params)
+	h, ok := s.handlers[req.Method]
+	s.mu.Unlock()
+
+	resp := map[string]any{"jsonrpc": "2.0", "id": 1}
+	if !ok {
+		resp["error"] = map[string]any{"code": -32601, "message": "method not found: " + req.Method}
+	} else if result, errMsg := h(req.Params); errMsg != "" {
+		resp["error"] = map[string]any{"code": -32000, "message": errMsg}
+	} else {
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *rpcStub) callsTo(method string) [][]json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func result(v any) rpcHandler {
+	return func([]json.RawMessage) (any, string) { return v, "" }
+}
+
+func rpcFailure(msg string) rpcHandler {
+	return func([]json.RawMessage) (any, string) { return nil, msg }
+}
+
+// withFees adds the gas estimate and fee history every anchor transaction
+// needs, unless the test overrides them.
+func withFees(handlers map[string]rpcHandler) map[string]rpcHandler {
+	defaults := map[string]rpcHandler{
+		"eth_estimateGas": result("0xea60"),
+		"eth_feeHistory": result(map[string]any{
+			"baseFeePerGas": []string{"0x3b9aca00", "0x3b9aca00"},
+			"reward":        [][]string{{"0x3b9aca00"}},
+		}),
+	}
+	for method, h := range defaults {
+		if _, ok := handlers[method]; !ok {
+			handlers[method] = h
+		}
+	}
+	return handlers
+}
@@ -0,0 +1,145 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+func signingHandlers() map[string]rpcHandler {
+	return withFees(map[string]rpcHandler{
+		"eth_chainId":               result("0x7a69"),
+		"eth_getTransactionCount":   result("0x5"),
+		"eth_maxPriorityFeePerGas":  result("0x3b9aca00"),
+		"eth_gasPrice":              result("0x4a817c800"),
+		"eth_sendRawTransaction":    result("0xsignedtx"),
+		"eth_getTransactionReceipt": result(minedReceipt("0xsignedtx", "0x11", "0x1")),
+	})
+}
+
+func TestNewSigningEVMBlockchainService_DerivesSender(t *testing.T) {
+	svc, err := repository.NewSigningEVMBlockchainService("http://localhost:8545", anvilKey, validAddr2, 31337)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svc.FromAddress() != anvilAddress {
+		t.Fatalf("from = %s, want %s", svc.FromAddress(), anvilAddress)
+	}
+}
+
+func TestNewSigningEVMBlockchainService_Errors(t *testing.T) {
+	if _, err := repository.NewSigningEVMBlockchainService("http://localhost", "0x01", validAddr2, 1); err == nil || !strings.Contains(err.Error(), "invalid private key") {
+		t.Fatalf("expected invalid private key error, got %v", err)
+	}
+	if _, err := repository.NewSigningEVMBlockchainService("http://localhost", anvilKey, "bad", 1); err == nil || !strings.Contains(err.Error(), "invalid anchor address") {
+		t.Fatalf("expected invalid anchor address error, got %v", err)
+	}
+}
+
+func TestSigningRegisterHash_SendsDynamicFeeTransaction(t *testing.T) {
+	stub := newRPCStub(t, signingHandlers())
+
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 0)
+	receipt, err := svc.RegisterHash(context.Background(), validHash, "tester")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receipt.TxHash != "0xsignedtx" {
+		t.Fatalf("tx = %q, want 0xsignedtx", receipt.TxHash)
+	}
+
+	if n := len(stub.callsTo("eth_sendTransaction")); n != 0 {
+		t.Fatalf("eth_sendTransaction called %d times in signing mode", n)
+	}
+	sent := stub.callsTo("eth_sendRawTransaction")
+	if len(sent) != 1 {
+		t.Fatalf("eth_sendRawTransaction calls = %d, want 1", len(sent))
+	}
+	var rawHex string
+	json.Unmarshal(sent[0][0], &rawHex)
+	if !strings.HasPrefix(rawHex, "0x02") {
+		t.Fatalf("expected EIP-1559 envelope, got %s", rawHex[:6])
+	}
+
+	nonceParams := stub.callsTo("eth_getTransactionCount")[0]
+	var from, tag string
+	json.Unmarshal(nonceParams[0], &from)
+	json.Unmarshal(nonceParams[1], &tag)
+	if from != anvilAddress || tag != "pending" {
+		t.Fatalf("nonce lookup = (%s, %s), want (%s, pending)", from, tag, anvilAddress)
+	}
+}
+
+func TestSigningRegisterHash_CachesChainID(t *testing.T) {
+	stub := newRPCStub(t, signingHandlers())
+
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 0)
+	for i := 0; i < 2; i++ {
+		if _, err := svc.RegisterHash(context.Background(), validHash, "tester"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := len(stub.callsTo("eth_chainId")); n != 1 {
+		t.Fatalf("eth_chainId calls = %d, want 1", n)
+	}
+}
+
+func TestSigningRegisterHash_LegacyWhenNoBaseFee(t *testing.T) {
+	handlers := signingHandlers()
+	handlers["eth_feeHistory"] = result(map[string]any{"baseFeePerGas": []string{"0x0", "0x0"}})
+	stub := newRPCStub(t, handlers)
+
+	svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 1)
+	if _, err := svc.RegisterHash(context.Background(), validHash, "tester"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var rawHex string
+	json.Unmarshal(stub.callsTo("eth_sendRawTransaction")[0][0], &rawHex)
+	if strings.HasPrefix(rawHex, "0x02") {
+		t.Fatal("expected legacy transaction when the chain reports no base fee")
+	}
+	if n := len(stub.callsTo("eth_chainId")); n != 0 {
+		t.Fatalf("eth_chainId calls = %d, want 0 with configured chain id", n)
+	}
+}
+
+func TestSigningRegisterHash_ErrorPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		override map[string]rpcHandler
+		want     string
+	}{
+		{"chain id", map[string]rpcHandler{"eth_chainId": rpcFailure("down")}, "fetch chain id"},
+		{"nonce", map[string]rpcHandler{"eth_getTransactionCount": rpcFailure("down")}, "fetch nonce"},
+		{"estimate", map[string]rpcHandler{"eth_estimateGas": rpcFailure("execution reverted")}, "estimate gas"},
+		{"fee history", map[string]rpcHandler{"eth_feeHistory": rpcFailure("down")}, "fetch fee history"},
+		{"base fee", map[string]rpcHandler{"eth_feeHistory": result(map[string]any{"baseFeePerGas": []string{"zz"}})}, "parse base fee"},
+		{"reward", map[string]rpcHandler{"eth_feeHistory": result(map[string]any{"baseFeePerGas": []string{"0x1"}, "reward": [][]string{{"zz"}}})}, "parse fee history reward"},
+		{"priority fee", map[string]rpcHandler{"eth_feeHistory": result(map[string]any{"baseFeePerGas": []string{"0x1"}}), "eth_maxPriorityFeePerGas": rpcFailure("down")}, "fetch priority fee"},
+		{"gas price", map[string]rpcHandler{"eth_feeHistory": result(map[string]any{}), "eth_gasPrice": rpcFailure("down")}, "fetch gas price"},
+		{"send", map[string]rpcHandler{"eth_sendRawTransaction": rpcFailure("nonce too low")}, "nonce too low"},
+		{"empty hash", map[string]rpcHandler{"eth_sendRawTransaction": result("")}, "empty transaction hash"},
+		{"bad quantity", map[string]rpcHandler{"eth_getTransactionCount": result("5")}, "invalid hex quantity"},
+		{"bad result type", map[string]rpcHandler{"eth_getTransactionCount": result(5)}, "decode rpc result"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := signingHandlers()
+			for k, v := range tt.override {
+				handlers[k] = v
+			}
+			stub := newRPCStub(t, handlers)
+
+			svc, _ := repository.NewSigningEVMBlockchainService(stub.URL, anvilKey, validAddr2, 0)
+			_, err := svc.RegisterHash(context.Background(), validHash, "tester")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
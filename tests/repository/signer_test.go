@@ -0,0 +1,212 @@
+package repository_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+const (
+	anvilKey     = "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	anvilAddress = "0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266"
+)
+
+func TestNewLocalSigner_DerivesAddress(t *testing.T) {
+	signer, err := repository.NewLocalSigner(anvilKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if signer.Address() != anvilAddress {
+		t.Fatalf("address = %s, want %s", signer.Address(), anvilAddress)
+	}
+}
+
+func TestNewLocalSigner_InvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"non hex", "0xzz", "decode private key"},
+		{"wrong length", "0x0102", "must be 32 bytes"},
+		{"zero", "0x" + strings.Repeat("00", 32), "out of range"},
+		{"overflow", "0x" + strings.Repeat("ff", 32), "out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repository.NewLocalSigner(tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// Test vector from EIP-155.
+func TestLocalSigner_SignLegacyEIP155Vector(t *testing.T) {
+	signer, err := repository.NewLocalSigner(strings.Repeat("46", 32))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, _ := new(big.Int).SetString("1000000000000000000", 10)
+	raw, err := signer.SignTx(&repository.EVMTransaction{
+		Type:     repository.LegacyTxType,
+		ChainID:  big.NewInt(1),
+		Nonce:    9,
+		GasPrice: big.NewInt(20_000_000_000),
+		Gas:      21000,
+		To:       "0x3535353535353535353535353535353535353535",
+		Value:    value,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+	if got := hex.EncodeToString(raw); got != want {
+		t.Fatalf("raw tx mismatch\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestLocalSigner_SignDynamicFeeRecoversSender(t *testing.T) {
+	signer, _ := repository.NewLocalSigner(anvilKey)
+
+	raw, err := signer.SignTx(&repository.EVMTransaction{
+		Type:      repository.DynamicFeeTxType,
+		ChainID:   big.NewInt(31337),
+		Nonce:     3,
+		GasTipCap: big.NewInt(1_000_000_000),
+		GasFeeCap: big.NewInt(3_000_000_000),
+		Gas:       60000,
+		To:        validAddr2,
+		Value:     new(big.Int),
+		Data:      bytes.Repeat([]byte{0xab}, 32),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw[0] != repository.DynamicFeeTxType {
+		t.Fatalf("type byte = %#x, want 0x02", raw[0])
+	}
+
+	if got := recoverDynamicFeeSender(t, raw); got != anvilAddress {
+		t.Fatalf("recovered sender = %s, want %s", got, anvilAddress)
+	}
+}
+
+func TestLocalSigner_SignTxErrors(t *testing.T) {
+	signer, _ := repository.NewLocalSigner(anvilKey)
+
+	tests := []struct {
+		name string
+		tx   *repository.EVMTransaction
+		want string
+	}{
+		{"missing chain id", &repository.EVMTransaction{To: validAddr2}, "chain id is required"},
+		{"bad recipient", &repository.EVMTransaction{ChainID: big.NewInt(1), To: "bad"}, "invalid address"},
+		{"unknown type", &repository.EVMTransaction{Type: 7, ChainID: big.NewInt(1), To: validAddr2}, "unsupported transaction type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := signer.SignTx(tt.tx)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// recoverDynamicFeeSender decodes a signed type-2 envelope, rebuilds the
+// signing payload from its first nine fields and recovers the signer address.
+func recoverDynamicFeeSender(t *testing.T, raw []byte) string {
+	t.Helper()
+	items := splitRLPList(t, raw[1:])
+	if len(items) != 12 {
+		t.Fatalf("expected 12 fields, got %d", len(items))
+	}
+
+	var body []byte
+	for _, item := range items[:9] {
+		body = append(body, item...)
+	}
+	payload := append([]byte{0x02}, rlpListHeader(len(body))...)
+	payload = append(payload, body...)
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write(payload)
+	hash := h.Sum(nil)
+
+	sig := make([]byte, 65)
+	sig[0] = 27 + rlpScalar(items[9])[0]
+	copy(sig[33-len(rlpScalar(items[10])):33], rlpScalar(items[10]))
+	copy(sig[65-len(rlpScalar(items[11])):65], rlpScalar(items[11]))
+
+	pub, _, err := ecdsa.RecoverCompact(sig, hash)
+	if err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	uncompressed := pub.SerializeUncompressed()
+	h = sha3.NewLegacyKeccak256()
+	h.Write(uncompressed[1:])
+	return "0x" + hex.EncodeToString(h.Sum(nil)[12:])
+}
+
+func splitRLPList(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	offset, _ := rlpPrefix(data)
+	var items [][]byte
+	for rest := data[offset:]; len(rest) > 0; {
+		hdr, size := rlpPrefix(rest)
+		items = append(items, rest[:hdr+size])
+		rest = rest[hdr+size:]
+	}
+	return items
+}
+
+func rlpPrefix(data []byte) (header, size int) {
+	b := data[0]
+	switch {
+	case b < 0x80:
+		return 0, 1
+	case b < 0xb8:
+		return 1, int(b - 0x80)
+	case b < 0xc0:
+		n := int(b - 0xb7)
+		return 1 + n, int(new(big.Int).SetBytes(data[1 : 1+n]).Int64())
+	case b < 0xf8:
+		return 1, int(b - 0xc0)
+	default:
+		n := int(b - 0xf7)
+		return 1 + n, int(new(big.Int).SetBytes(data[1 : 1+n]).Int64())
+	}
+}
+
+func rlpScalar(item []byte) []byte {
+	hdr, size := rlpPrefix(item)
+	if hdr == 0 {
+		return item
+	}
+	v := item[hdr : hdr+size]
+	if len(v) == 0 {
+		return []byte{0}
+	}
+	return v
+}
+
+func rlpListHeader(size int) []byte {
+	if size < 56 {
+		return []byte{0xc0 + byte(size)}
+	}
+	n := big.NewInt(int64(size)).Bytes()
+	return append([]byte{0xf7 + byte(len(n))}, n...)
+}
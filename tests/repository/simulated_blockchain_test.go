@@ -0,0 +1,148 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+var (
+	_ usecase.AnchorTarget            = (*repository.SimulatedBlockchainService)(nil)
+	_ usecase.CanonicalChain          = (*repository.SimulatedBlockchainService)(nil)
+	_ usecase.RegistrationLookup      = (*repository.SimulatedBlockchainService)(nil)
+	_ usecase.RegistrationEventSource = (*repository.SimulatedBlockchainService)(nil)
+)
+
+func TestSimulatedBlockchain_MinesDeterministicBlocks(t *testing.T) {
+	ctx := context.Background()
+	other := strings.Repeat("cd", 32)
+
+	var first []*domain.Receipt
+	for run := 0; run < 2; run++ {
+		svc := repository.NewSimulatedBlockchainService(31337, validAddr1)
+		var receipts []*domain.Receipt
+		for _, hash := range []string{"0x" + validHash, other} {
+			receipt, err := svc.RegisterHash(ctx, hash, validAddr1)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			receipts = append(receipts, receipt)
+		}
+
+		if receipts[0].BlockNumber != 1 || receipts[1].BlockNumber != 2 || !receipts[1].Succeeded() {
+			t.Fatalf("receipts = %+v, %+v", receipts[0], receipts[1])
+		}
+		if receipts[0].Event.ContentHash != validHash || receipts[0].TxHash == receipts[1].TxHash {
+			t.Fatalf("events = %+v, %+v", receipts[0].Event, receipts[1].Event)
+		}
+		if run == 1 {
+			for i := range receipts {
+				if receipts[i].TxHash != first[i].TxHash || receipts[i].BlockHash != first[i].BlockHash {
+					t.Fatalf("receipt %d differs between runs: %s != %s", i, receipts[i].TxHash, first[i].TxHash)
+				}
+			}
+		}
+		first = receipts
+	}
+}
+
+func TestSimulatedBlockchain_TracksRegistrations(t *testing.T) {
+	ctx := context.Background()
+	svc := repository.NewSimulatedBlockchainService(1, validAddr1)
+
+	if registered, err := svc.IsHashRegistered(ctx, validHash); err != nil || registered {
+		t.Fatalf("registered = %v, err = %v", registered, err)
+	}
+	receipt, err := svc.RegisterHash(ctx, validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if registered, err := svc.IsHashRegistered(ctx, "0x"+validHash); err != nil || !registered {
+		t.Fatalf("registered = %v, err = %v", registered, err)
+	}
+	if _, err := svc.RegisterHash(ctx, validHash, validAddr1); !errors.Is(err, domain.ErrTransactionReverted) {
+		t.Fatalf("expected ErrTransactionReverted for a duplicate, got %v", err)
+	}
+
+	event, err := svc.FindRegistration(ctx, validHash)
+	if err != nil || event == nil || event.TxHash != receipt.TxHash {
+		t.Fatalf("event = %+v, err = %v", event, err)
+	}
+	events, err := svc.RegistrationEvents(ctx, 0, 10)
+	if err != nil || len(events) != 1 || events[0].BlockNumber != 1 {
+		t.Fatalf("events = %+v, err = %v", events, err)
+	}
+	if latest, _ := svc.LatestBlock(ctx); latest != 1 {
+		t.Fatalf("latest block = %d, want 1", latest)
+	}
+	if hash, _ := svc.BlockHash(ctx, 1); hash != receipt.BlockHash {
+		t.Fatalf("block hash = %s, want %s", hash, receipt.BlockHash)
+	}
+	if hash, _ := svc.BlockHash(ctx, 2); hash != "" {
+		t.Fatalf("future block hash = %q, want empty", hash)
+	}
+}
+
+func TestSimulatedBlockchain_InjectsFailuresAndLatency(t *testing.T) {
+	ctx := context.Background()
+	failing := repository.NewSimulatedBlockchainService(1, validAddr1).WithFailureRate(1)
+	if _, err := failing.RegisterHash(ctx, validHash, validAddr1); !errors.Is(err, repository.ErrSimulatedFailure) {
+		t.Fatalf("expected ErrSimulatedFailure, got %v", err)
+	}
+	if latest, _ := failing.WithFailureRate(0).LatestBlock(ctx); latest != 0 {
+		t.Fatalf("failed registration mined block %d", latest)
+	}
+
+	slow := repository.NewSimulatedBlockchainService(1, validAddr1).WithLatency(time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := slow.RegisterHash(ctx, validHash, validAddr1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context deadline, got %v", err)
+	}
+}
+
+func TestNewBlockchainServiceFromEnv_SimulatedMode(t *testing.T) {
+	t.Setenv("CHAIN_MODE", "simulated")
+	t.Setenv("RPC_URL", "")
+	t.Setenv("CONTRACT_ADDRESS", "")
+
+	svc, err := repository.NewBlockchainServiceFromEnv(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sim, ok := svc.(*repository.SimulatedBlockchainService)
+	if !ok {
+		t.Fatalf("service = %T, want *repository.SimulatedBlockchainService", svc)
+	}
+	if id, _ := sim.ChainID(context.Background()); id != 31337 {
+		t.Fatalf("chain id = %d, want 31337", id)
+	}
+
+	for key, value := range map[string]string{
+		"SIMULATED_FAILURE_RATE": "1.5",
+		"SIMULATED_LATENCY":      "soon",
+		"SIMULATED_CHAIN_ID":     "-1",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := repository.NewBlockchainServiceFromEnv(nil); err == nil || !strings.Contains(err.Error(), "invalid "+key) {
+				t.Fatalf("expected invalid %s error, got %v", key, err)
+			}
+		})
+	}
+
+	t.Setenv("SIMULATED_LATENCY", "0")
+	if _, err := repository.NewBlockchainServiceFromEnv(nil); err != nil {
+		t.Fatalf("zero latency: unexpected error: %v", err)
+	}
+	t.Setenv("SIMULATED_LATENCY", "-1s")
+	if _, err := repository.NewBlockchainServiceFromEnv(nil); err == nil || !strings.Contains(err.Error(), "invalid SIMULATED_LATENCY") {
+		t.Fatalf("expected invalid SIMULATED_LATENCY error, got %v", err)
+	}
+}
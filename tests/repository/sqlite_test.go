@@ -0,0 +1,173 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+var _ interface {
+	usecase.BatchRepository
+	usecase.ReorgRepository
+	usecase.ReconciliationRepository
+	usecase.CheckpointStore
+	usecase.AnchorRepository
+	usecase.ProofRepository
+} = (*repository.SQLiteCertificateRepo)(nil)
+
+func openSQLiteRepo(t *testing.T, path string) *repository.SQLiteCertificateRepo {
+	t.Helper()
+	db, err := repository.OpenSQLite(context.Background(), path)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return repository.NewSQLiteCertificateRepo(db)
+}
+
+func TestSQLiteRepo_SaveAndFind(t *testing.T) {
+	ctx := context.Background()
+	repo := openSQLiteRepo(t, ":memory:")
+
+	ph := ^uint64(0)
+	hashes := domain.PerceptualHashes{domain.DCTHash: ph, domain.AverageHash: 0}
+	created := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	cert := &domain.Certificate{ContentHash: "aa", PerceptualHashes: hashes, Registrant: "0xabc", Status: domain.StatusPending, CreatedAt: created}
+	if err := repo.Save(ctx, cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(cert.ID) {
+		t.Fatalf("id = %q, want a v4 UUID", cert.ID)
+	}
+	if err := repo.Save(ctx, &domain.Certificate{ContentHash: "aa", Status: domain.StatusPending}); !errors.Is(err, domain.ErrAlreadyCertified) {
+		t.Fatalf("expected ErrAlreadyCertified, got %v", err)
+	}
+	if err := repo.Save(ctx, &domain.Certificate{ContentHash: "bb", Status: "bogus"}); err == nil || errors.Is(err, domain.ErrAlreadyCertified) {
+		t.Fatalf("expected a status constraint error, got %v", err)
+	}
+
+	cert.Status = domain.StatusConfirmed
+	cert.TxHash, cert.BlockNumber, cert.BlockHash = "0xtx", 7, "0xblock"
+	cert.Batch = &domain.MerkleBatch{Root: "root", LeafIndex: 1, TreeSize: 2, Proof: []string{"p0"}}
+	cert.Fee = &domain.AnchorFee{GasUsed: 21000, EffectiveGasPrice: new(big.Int).Lsh(big.NewInt(1), 100)}
+	cert.TimestampToken = []byte{0x30, 0x00}
+	cert.RecordAnchor(domain.Anchor{ChainID: 1, ContractAddress: "0xPrimary", TxHash: "0xtx", BlockNumber: 7, Batch: cert.Batch})
+	if err := repo.UpdateAnchorState(ctx, cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.UpdateAnchorState(ctx, &domain.Certificate{ID: "missing"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	found, err := repo.FindByHash(ctx, "aa")
+	if err != nil || found == nil {
+		t.Fatalf("FindByHash = %+v, %v", found, err)
+	}
+	switch {
+	case found.ID != cert.ID || len(found.PerceptualHashes) != 2 || found.PerceptualHashes[domain.DCTHash] != ph || !found.CreatedAt.Equal(created):
+		t.Fatalf("identity columns = %+v", found)
+	case found.BlockNumber != 7 || found.Batch == nil || found.Batch.Proof[0] != "p0" || found.Batch.LeafIndex != 1:
+		t.Fatalf("anchor columns = %+v, batch = %+v", found, found.Batch)
+	case found.Fee == nil || found.Fee.EffectiveGasPrice.Cmp(cert.Fee.EffectiveGasPrice) != 0 || string(found.TimestampToken) != "\x30\x00":
+		t.Fatalf("fee = %+v, token = %x", found.Fee, found.TimestampToken)
+	case len(found.Anchors) != 1 || found.Anchors[0].ContractAddress != "0xprimary" || found.Anchors[0].Batch.Root != "root":
+		t.Fatalf("anchors = %+v", found.Anchors)
+	}
+
+	if found, err := repo.FindByID(ctx, "not-a-uuid"); found != nil || err != nil {
+		t.Fatalf("FindByID(unknown) = %+v, %v", found, err)
+	}
+	if found, err := repo.FindByHash(ctx, "cc"); found != nil || err != nil {
+		t.Fatalf("FindByHash(unknown) = %+v, %v", found, err)
+	}
+	matches, _ := repo.FindByPerceptualHash(ctx, domain.PerceptualHashes{domain.DCTHash: ph ^ 0b11, domain.AverageHash: 0}, 2, 5)
+	switch {
+	case len(matches) != 1 || matches[0].Certificate.ID != cert.ID || len(matches[0].Certificate.Anchors) != 1:
+		t.Fatalf("perceptual matches = %+v", matches)
+	case matches[0].Distance != 1 || matches[0].Distances[domain.DCTHash] != 2 || len(matches[0].Certificate.PerceptualHashes) != 2:
+		t.Fatalf("combined distance = %d, distances = %v, hashes = %v", matches[0].Distance, matches[0].Distances, matches[0].Certificate.PerceptualHashes)
+	}
+}
+
+func TestSQLiteRepo_ClaimsAnchorsAndCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	repo := openSQLiteRepo(t, ":memory:")
+	base := time.Now()
+	for i, status := range []domain.CertificateStatus{domain.StatusPending, domain.StatusConfirmed, domain.StatusPending, domain.StatusSubmitted} {
+		repo.Save(ctx, memCert(fmt.Sprintf("h%d", i), status, base.Add(time.Duration(-i)*time.Minute)))
+	}
+
+	claimed, _ := repo.ClaimPending(ctx, 10, time.Minute)
+	if got := hashesOf(claimed); got != "h2,h0" {
+		t.Fatalf("claimed = %s, want h2,h0", got)
+	}
+	if again, _ := repo.ClaimPending(ctx, 10, time.Minute); len(again) != 0 {
+		t.Fatalf("leased certificates claimed again: %v", hashesOf(again))
+	}
+	time.Sleep(5 * time.Millisecond)
+	if unfinished, _ := repo.ClaimUnfinished(ctx, 10, time.Millisecond); hashesOf(unfinished) != "h3" {
+		t.Fatalf("unfinished = %v, want only the unleased submitted certificate", hashesOf(unfinished))
+	}
+
+	confirmed, _ := repo.FindByHash(ctx, "h1")
+	if err := repo.SaveAnchor(ctx, confirmed.ID, domain.Anchor{ChainID: 10, ContractAddress: "0xL2", TxHash: "0xl2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.SaveAnchor(ctx, "missing", domain.Anchor{ChainID: 10}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if unanchored, _ := repo.FindUnanchored(ctx, 10, "0xl2", 10); len(unanchored) != 0 {
+		t.Fatalf("unanchored = %s", hashesOf(unanchored))
+	}
+	if unanchored, _ := repo.FindUnanchored(ctx, 11, "0xl2", 10); hashesOf(unanchored) != "h1" {
+		t.Fatalf("unanchored = %s, want h1", hashesOf(unanchored))
+	}
+	repo.UpdateAnchorState(ctx, confirmed)
+	if again, _ := repo.FindByID(ctx, confirmed.ID); len(again.Anchors) != 0 {
+		t.Fatalf("anchors after sync = %+v", again.Anchors)
+	}
+
+	if err := repo.FlagChainMismatch(ctx, "missing", "x"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, ok, _ := repo.LoadCheckpoint(ctx, "indexer"); ok {
+		t.Fatal("unexpected checkpoint")
+	}
+	repo.SaveCheckpoint(ctx, "indexer", 41)
+	repo.SaveCheckpoint(ctx, "indexer", 42)
+	if block, ok, _ := repo.LoadCheckpoint(ctx, "indexer"); !ok || block != 42 {
+		t.Fatalf("checkpoint = %d, %v", block, ok)
+	}
+}
+
+func TestSQLiteRepo_PersistsAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "aletheia.db")
+	chain := repository.NewSimulatedBlockchainService(31337, validAddr1)
+
+	repo := openSQLiteRepo(t, path)
+	certify := usecase.NewCertifyUseCase(repo, usecase.NewAnchorProcessor(repo, chain, 3))
+	out, err := certify.Execute(ctx, usecase.CertifyInput{Content: strings.NewReader("hello"), Registrant: validAddr1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reopened := openSQLiteRepo(t, path)
+	verify, err := usecase.NewVerifyUseCase(reopened, chain).Execute(ctx, usecase.VerifyInput{Hash: out.Certificate.ContentHash, ConfirmOnChain: true})
+	if err != nil || !verify.Certified || verify.OnChainConfirmed == nil || !*verify.OnChainConfirmed {
+		t.Fatalf("verify = %+v, %v", verify, err)
+	}
+	if len(verify.Certificate.Anchors) != 1 || verify.Certificate.Anchors[0].ChainID != 31337 {
+		t.Fatalf("anchors = %+v", verify.Certificate.Anchors)
+	}
+}
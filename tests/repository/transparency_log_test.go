@@ -0,0 +1,51 @@
+package repository_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+func TestNewBlockchainServiceFromEnv_LogMode(t *testing.T) {
+	db, err := sql.Open("postgres", "postgres://localhost/aletheia?sslmode=disable")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	t.Setenv("CHAIN_MODE", "log")
+	seed := strings.Repeat("11", 32)
+
+	tests := []struct {
+		name, key string
+		db        *sql.DB
+		want      string
+	}{
+		{"missing key", "", db, "LOG_SIGNING_KEY is required"},
+		{"short key", "0x1234", db, "invalid LOG_SIGNING_KEY"},
+		{"no database", seed, nil, "requires a database"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOG_SIGNING_KEY", tt.key)
+			if _, err := repository.NewBlockchainServiceFromEnv(tt.db); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Setenv("LOG_SIGNING_KEY", "0x"+seed)
+	svc, err := repository.NewBlockchainServiceFromEnv(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tlog, ok := svc.(*repository.PostgresTransparencyLog)
+	if !ok {
+		t.Fatalf("service = %T, want *repository.PostgresTransparencyLog", svc)
+	}
+	if len(tlog.PublicKey()) != 32 {
+		t.Fatalf("public key = %x", tlog.PublicKey())
+	}
+}
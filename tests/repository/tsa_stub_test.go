@@ -0,0 +1,234 @@
+package repository_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	stubOIDSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	stubOIDTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	stubOIDContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	stubOIDMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	stubOIDSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	stubOIDECDSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	stubTSAPolicy        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+	stubGenTime          = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+)
+
+type stubImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type stubTSReq struct {
+	Version int
+	Imprint stubImprint
+	Policy  asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce   *big.Int              `asn1:"optional"`
+	CertReq bool                  `asn1:"optional,default:false"`
+}
+
+type stubTSTInfo struct {
+	Version int
+	Policy  asn1.ObjectIdentifier
+	Imprint stubImprint
+	Serial  *big.Int
+	GenTime time.Time `asn1:"generalized"`
+	Nonce   *big.Int  `asn1:"optional"`
+}
+
+type stubAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type stubIssuerSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type stubSignerInfo struct {
+	Version     int
+	SID         stubIssuerSerial
+	DigestAlg   pkix.AlgorithmIdentifier
+	SignedAttrs asn1.RawValue
+	SigAlg      pkix.AlgorithmIdentifier
+	Signature   []byte
+}
+
+type stubEncap struct {
+	Type    asn1.ObjectIdentifier
+	Content []byte `asn1:"explicit,tag:0"`
+}
+
+type stubSignedData struct {
+	Version     int
+	DigestAlgs  []pkix.AlgorithmIdentifier `asn1:"set"`
+	Encap       stubEncap
+	Certs       asn1.RawValue
+	SignerInfos []stubSignerInfo `asn1:"set"`
+}
+
+// stubContentInfo spells out the explicit [0] of its content, since
+// encoding/asn1 ignores tags when marshaling a RawValue.
+type stubContentInfo struct {
+	Type    asn1.ObjectIdentifier
+	Content asn1.RawValue
+}
+
+type stubStatus struct {
+	Status       int
+	StatusString []string `asn1:"optional"`
+}
+
+type stubTSResp struct {
+	Status stubStatus
+	Token  asn1.RawValue `asn1:"optional"`
+}
+
+// tsaStub is a minimal RFC 3161 time-stamping authority that signs TSTInfo
+// structures with an ECDSA key and a self-signed time-stamping certificate.
+type tsaStub struct {
+	*httptest.Server
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+
+	mu       sync.Mutex
+	requests []stubTSReq
+	// status other than 0 rejects every request.
+	status int
+	// tamper edits the TSTInfo before it is signed.
+	tamper func(*stubTSTInfo)
+}
+
+func newTSAStub(t *testing.T) *tsaStub {
+	t.Helper()
+	key, cert := newTSACert(t, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping})
+	stub := &tsaStub{key: key, cert: cert}
+	stub.Server = httptest.NewServer(http.HandlerFunc(stub.serve))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func newTSACert(t *testing.T, usage []x509.ExtKeyUsage) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	return newTSACertUntil(t, usage, time.Now().Add(24*time.Hour))
+}
+
+func newTSACertUntil(t *testing.T, usage []x509.ExtKeyUsage, notAfter time.Time) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(42),
+		Subject:               pkix.Name{CommonName: "Test TSA"},
+		NotBefore:             stubGenTime.Add(-time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           usage,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return key, cert
+}
+
+func (s *tsaStub) requestsSeen() []stubTSReq {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]stubTSReq(nil), s.requests...)
+}
+
+func (s *tsaStub) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req stubTSReq
+	if _, err := asn1.Unmarshal(body, &req); err != nil || r.Header.Get("Content-Type") != "application/timestamp-query" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	status, tamper := s.status, s.tamper
+	s.mu.Unlock()
+
+	resp := stubTSResp{Status: stubStatus{Status: status}}
+	if status != 0 {
+		resp.Status.StatusString = []string{"request rejected by policy"}
+	} else {
+		info := stubTSTInfo{Version: 1, Policy: stubTSAPolicy, Imprint: req.Imprint, Serial: big.NewInt(7), GenTime: stubGenTime, Nonce: req.Nonce}
+		if tamper != nil {
+			tamper(&info)
+		}
+		resp.Token = asn1.RawValue{FullBytes: s.sign(info)}
+	}
+
+	der, _ := asn1.Marshal(resp)
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	w.Write(der)
+}
+
+func (s *tsaStub) sign(info stubTSTInfo) []byte {
+	eContent := mustMarshal(info)
+	digest := sha256.Sum256(eContent)
+
+	attrs := []stubAttribute{
+		{Type: stubOIDContentType, Values: []asn1.RawValue{{FullBytes: mustMarshal(stubOIDTSTInfo)}}},
+		{Type: stubOIDMessageDigest, Values: []asn1.RawValue{{FullBytes: mustMarshal(digest[:])}}},
+	}
+	attrsDER, err := asn1.MarshalWithParams(attrs, "set")
+	if err != nil {
+		panic(err)
+	}
+	attrsHash := sha256.Sum256(attrsDER)
+	sig, err := ecdsa.SignASN1(rand.Reader, s.key, attrsHash[:])
+	if err != nil {
+		panic(err)
+	}
+
+	sd := stubSignedData{
+		Version:    3,
+		DigestAlgs: []pkix.AlgorithmIdentifier{{Algorithm: stubOIDSHA256}},
+		Encap:      stubEncap{Type: stubOIDTSTInfo, Content: eContent},
+		Certs:      asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: s.cert.Raw},
+		SignerInfos: []stubSignerInfo{{
+			Version:     1,
+			SID:         stubIssuerSerial{Issuer: asn1.RawValue{FullBytes: s.cert.RawIssuer}, Serial: s.cert.SerialNumber},
+			DigestAlg:   pkix.AlgorithmIdentifier{Algorithm: stubOIDSHA256},
+			SignedAttrs: asn1.RawValue{FullBytes: append([]byte{0xa0}, attrsDER[1:]...)},
+			SigAlg:      pkix.AlgorithmIdentifier{Algorithm: stubOIDECDSASHA256},
+			Signature:   sig,
+		}},
+	}
+	return mustMarshal(stubContentInfo{Type: stubOIDSignedData, Content: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: mustMarshal(sd)}})
+}
+
+func mustMarshal(v any) []byte {
+	der, err := asn1.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
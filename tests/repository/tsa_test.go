@@ -0,0 +1,246 @@
+package repository_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+)
+
+// newTSAService trusts the stub's own certificate.
+func newTSAService(t *testing.T, stub *tsaStub) *repository.TSAService {
+	t.Helper()
+	roots := x509.NewCertPool()
+	roots.AddCert(stub.cert)
+	return newTSAServiceTrusting(t, stub, roots)
+}
+
+func newTSAServiceTrusting(t *testing.T, stub *tsaStub, roots *x509.CertPool) *repository.TSAService {
+	t.Helper()
+	svc, err := repository.NewTSAService(stub.URL, roots)
+	if err != nil {
+		t.Fatalf("unexpected constructor error: %v", err)
+	}
+	return svc
+}
+
+func TestTSAService_RegisterHashReturnsVerifiableToken(t *testing.T) {
+	stub := newTSAStub(t)
+	svc := newTSAService(t, stub)
+
+	receipt, err := svc.RegisterHash(context.Background(), "0x"+validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !receipt.Succeeded() || len(receipt.TimestampToken) == 0 {
+		t.Fatalf("receipt = %+v", receipt)
+	}
+	if receipt.Event == nil || receipt.Event.ContentHash != validHash || !receipt.Event.RegisteredAt.Equal(stubGenTime) {
+		t.Fatalf("event = %+v", receipt.Event)
+	}
+
+	reqs := stub.requestsSeen()
+	if len(reqs) != 1 || !reqs[0].CertReq || reqs[0].Nonce == nil || hex.EncodeToString(reqs[0].Imprint.HashedMessage) != validHash {
+		t.Fatalf("tsa requests = %+v", reqs)
+	}
+
+	at, err := svc.VerifyTimestamp(validHash, receipt.TimestampToken)
+	if err != nil || !at.Equal(stubGenTime) {
+		t.Fatalf("VerifyTimestamp = %v, %v", at, err)
+	}
+}
+
+func TestTSAService_VerifyTimestampRejectsInvalidTokens(t *testing.T) {
+	stub := newTSAStub(t)
+	svc := newTSAService(t, stub)
+	receipt, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token := receipt.TimestampToken
+
+	flipped := append([]byte(nil), token...)
+	idx := strings.Index(string(flipped), string(stubGenTime.Format("20060102150405")))
+	flipped[idx] ^= 0x01
+
+	tests := []struct {
+		name  string
+		hash  string
+		token []byte
+	}{
+		{"other hash", strings.Repeat("ef", 32), token},
+		{"tampered TSTInfo", validHash, flipped},
+		{"truncated", validHash, token[:len(token)/2]},
+		{"garbage", validHash, []byte("not a token")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.VerifyTimestamp(tt.hash, tt.token); !errors.Is(err, domain.ErrInvalidTimestamp) {
+				t.Fatalf("expected ErrInvalidTimestamp, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTSAService_RejectedRequest(t *testing.T) {
+	stub := newTSAStub(t)
+	stub.status = 2
+	svc := newTSAService(t, stub)
+
+	_, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+	if !errors.Is(err, domain.ErrTimestampRejected) || !strings.Contains(err.Error(), "rejected by policy") {
+		t.Fatalf("expected ErrTimestampRejected, got %v", err)
+	}
+}
+
+func TestTSAService_RejectsMismatchedReplies(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(*stubTSTInfo)
+	}{
+		{"nonce", func(info *stubTSTInfo) { info.Nonce = big.NewInt(1) }},
+		{"missing nonce", func(info *stubTSTInfo) { info.Nonce = nil }},
+		{"imprint", func(info *stubTSTInfo) { info.Imprint.HashedMessage = make([]byte, 32) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newTSAStub(t)
+			stub.tamper = tt.tamper
+			svc := newTSAService(t, stub)
+
+			if _, err := svc.RegisterHash(context.Background(), validHash, validAddr1); !errors.Is(err, domain.ErrInvalidTimestamp) {
+				t.Fatalf("expected ErrInvalidTimestamp, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTSAService_RequiresTimeStampingCertificate(t *testing.T) {
+	stub := newTSAStub(t)
+	stub.key, stub.cert = newTSACert(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	svc := newTSAService(t, stub)
+
+	_, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+	if !errors.Is(err, domain.ErrInvalidTimestamp) || !strings.Contains(err.Error(), "time stamping") {
+		t.Fatalf("expected time stamping usage error, got %v", err)
+	}
+}
+
+func TestTSAService_ChecksTrustedRoots(t *testing.T) {
+	stub := newTSAStub(t)
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(stub.cert)
+	if _, err := newTSAServiceTrusting(t, stub, trusted).RegisterHash(context.Background(), validHash, validAddr1); err != nil {
+		t.Fatalf("trusted TSA rejected: %v", err)
+	}
+
+	_, other := newTSACert(t, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping})
+	untrusted := x509.NewCertPool()
+	untrusted.AddCert(other)
+	_, err := newTSAServiceTrusting(t, stub, untrusted).RegisterHash(context.Background(), validHash, validAddr1)
+	if !errors.Is(err, domain.ErrInvalidTimestamp) || !strings.Contains(err.Error(), "untrusted") {
+		t.Fatalf("expected untrusted signer error, got %v", err)
+	}
+
+	if _, err := repository.NewTSAService(stub.URL, nil); err == nil {
+		t.Fatal("expected an error without trusted roots")
+	}
+}
+
+func TestTSAService_ChecksSignerAtGenTime(t *testing.T) {
+	stub := newTSAStub(t)
+	svc := newTSAService(t, stub)
+	receipt, err := svc.RegisterHash(context.Background(), validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A certificate that has expired since the token was issued still
+	// vouches for it.
+	stub.key, stub.cert = newTSACertUntil(t, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}, stubGenTime.Add(time.Hour))
+	expired, err := newTSAService(t, stub).RegisterHash(context.Background(), validHash, validAddr1)
+	if err != nil {
+		t.Fatalf("token from a since expired certificate rejected: %v", err)
+	}
+	if receipt.Event.RegisteredAt != expired.Event.RegisteredAt {
+		t.Fatalf("registered at %v, want %v", expired.Event.RegisteredAt, receipt.Event.RegisteredAt)
+	}
+
+	// One that had already expired does not.
+	stub.key, stub.cert = newTSACertUntil(t, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}, stubGenTime.Add(-time.Minute))
+	if _, err := newTSAService(t, stub).RegisterHash(context.Background(), validHash, validAddr1); !errors.Is(err, domain.ErrInvalidTimestamp) {
+		t.Fatalf("expected ErrInvalidTimestamp, got %v", err)
+	}
+}
+
+func TestTSAService_IsHashRegisteredKeepsNoRegistry(t *testing.T) {
+	svc := newTSAService(t, newTSAStub(t))
+
+	if registered, err := svc.IsHashRegistered(context.Background(), validHash); err != nil || registered {
+		t.Fatalf("registered = %v, err = %v", registered, err)
+	}
+	if _, err := svc.IsHashRegistered(context.Background(), "abc"); err == nil {
+		t.Fatal("expected error for malformed hash")
+	}
+}
+
+func TestNewBlockchainServiceFromEnv_TSAMode(t *testing.T) {
+	t.Setenv("CHAIN_MODE", "tsa")
+	t.Setenv("TSA_URL", "")
+	caFile := filepath.Join(t.TempDir(), "tsa-ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: newTSAStub(t).cert.Raw})
+	if err := os.WriteFile(caFile, pemBytes, 0o600); err != nil {
+		t.Fatalf("write ca file: %v", err)
+	}
+	t.Setenv("TSA_CA_FILE", caFile)
+
+	tests := []struct {
+		key, value, want string
+	}{
+		{"TSA_URL", "", "TSA_URL is required"},
+		{"TSA_POLICY", "1.x.3", "invalid TSA_POLICY"},
+		{"TSA_TIMEOUT", "0s", "invalid TSA_TIMEOUT"},
+		{"TSA_CA_FILE", "", "TSA_CA_FILE is required"},
+		{"TSA_CA_FILE", "/does/not/exist.pem", "invalid TSA_CA_FILE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if tt.key != "TSA_URL" {
+				t.Setenv("TSA_URL", "http://tsa.example")
+			}
+			t.Setenv(tt.key, tt.value)
+			_, err := repository.NewBlockchainServiceFromEnv(nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Setenv("TSA_URL", "http://tsa.example")
+	t.Setenv("TSA_POLICY", "1.3.6.1.4.1.99999.1")
+	svc, err := repository.NewBlockchainServiceFromEnv(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := svc.(*repository.TSAService); !ok {
+		t.Fatalf("service = %T, want *repository.TSAService", svc)
+	}
+
+	t.Setenv("CHAIN_MODE", "bitcoin")
+	if _, err := repository.NewBlockchainServiceFromEnv(nil); err == nil || !strings.Contains(err.Error(), "invalid CHAIN_MODE") {
+		t.Fatalf("expected invalid CHAIN_MODE error, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+func recordingRepo() (*mockRepo, *[]domain.CertificateStatus) {
+	var states []domain.CertificateStatus
+	return &mockRepo{
+		updateAnchorStateFn: func(_ context.Context, cert *domain.Certificate) error {
+			states = append(states, cert.Status)
+			return nil
+		},
+	}, &states
+}
+
+func TestAnchorProcessor_TwoPhasePersistsEachStep(t *testing.T) {
+	repo, states := recordingRepo()
+	chain := &mockSubmitter{
+		submitHashFn: func(_ context.Context, _, _ string) (string, error) { return "0xtx", nil },
+		waitForReceiptFn: func(_ context.Context, _, txHash string) (*domain.Receipt, error) {
+			return &domain.Receipt{TxHash: txHash, BlockNumber: 9, BlockHash: "0xblock", Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+	cert := &domain.Certificate{ContentHash: "h", Status: domain.StatusPending}
+
+	if err := usecase.NewAnchorProcessor(repo, chain, 0).Process(context.Background(), cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []domain.CertificateStatus{domain.StatusSubmitted, domain.StatusConfirmed}
+	if fmt.Sprint(*states) != fmt.Sprint(want) {
+		t.Fatalf("persisted states = %v, want %v", *states, want)
+	}
+	if cert.TxHash != "0xtx" || cert.BlockNumber != 9 || cert.Attempts != 1 {
+		t.Fatalf("cert = %+v", cert)
+	}
+}
+
+func TestAnchorProcessor_RecoversRegisteredHashWithoutResubmitting(t *testing.T) {
+	repo, _ := recordingRepo()
+	chain := &mockSubmitter{
+		mockBlockchain: mockBlockchain{
+			isHashRegisteredFn: func(_ context.Context, _ string) (bool, error) { return true, nil },
+		},
+		submitHashFn: func(_ context.Context, _, _ string) (string, error) {
+			t.Fatal("SubmitHash must not be called for a registered hash")
+			return "", nil
+		},
+		findRegistrationFn: func(_ context.Context, hash string) (*domain.RegistrationEvent, error) {
+			return &domain.RegistrationEvent{ContentHash: hash, TxHash: "0xonchain", BlockNumber: 3, BlockHash: "0xb3"}, nil
+		},
+	}
+	cert := &domain.Certificate{ContentHash: "h", Status: domain.StatusPending}
+
+	if err := usecase.NewAnchorProcessor(repo, chain, 0).Process(context.Background(), cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cert.Status != domain.StatusConfirmed || cert.TxHash != "0xonchain" || cert.BlockNumber != 3 {
+		t.Fatalf("cert = %+v, want confirmed from chain event", cert)
+	}
+}
+
+func TestAnchorProcessor_RegisteredHashWithoutLookupFails(t *testing.T) {
+	for _, status := range []domain.CertificateStatus{domain.StatusReorged, domain.StatusPending} {
+		t.Run(string(status), func(t *testing.T) {
+			repo, states := recordingRepo()
+			chain := &mockBlockchain{
+				registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+					t.Fatal("RegisterHash must not be called for a registered hash")
+					return nil, nil
+				},
+				isHashRegisteredFn: func(_ context.Context, _ string) (bool, error) { return true, nil },
+			}
+			cert := &domain.Certificate{ContentHash: "h", Status: status, TxHash: "0xorphaned", BlockNumber: 7, BlockHash: "0xstale"}
+
+			err := usecase.NewAnchorProcessor(repo, chain, 0).Process(context.Background(), cert)
+			if !errors.Is(err, domain.ErrRegistrationUnknown) {
+				t.Fatalf("err = %v, want ErrRegistrationUnknown", err)
+			}
+			if cert.Status != domain.StatusFailed || !strings.Contains(cert.LastError, "cannot be looked up") {
+				t.Fatalf("cert = %+v, want failed", cert)
+			}
+			if len(*states) != 1 || (*states)[0] != domain.StatusFailed {
+				t.Fatalf("persisted states = %v", *states)
+			}
+		})
+	}
+}
+
+func TestAnchorProcessor_ReceiptTimeoutRequeues(t *testing.T) {
+	repo, states := recordingRepo()
+	chain := &mockSubmitter{
+		waitForReceiptFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+			return nil, domain.ErrReceiptTimeout
+		},
+	}
+	cert := &domain.Certificate{ContentHash: "h", TxHash: "0xlost", Status: domain.StatusSubmitted}
+
+	err := usecase.NewAnchorProcessor(repo, chain, 0).Process(context.Background(), cert)
+	if !errors.Is(err, domain.ErrReceiptTimeout) {
+		t.Fatalf("err = %v, want ErrReceiptTimeout", err)
+	}
+	if cert.Status != domain.StatusPending || cert.TxHash != "" {
+		t.Fatalf("cert = %+v, want requeued", cert)
+	}
+	if len(*states) != 1 || (*states)[0] != domain.StatusPending {
+		t.Fatalf("persisted states = %v", *states)
+	}
+}
+
+func TestAnchorProcessor_Failures(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int
+		submitErr  error
+		wantStatus domain.CertificateStatus
+	}{
+		{"transient error stays pending", 0, errors.New("node down"), domain.StatusPending},
+		{"max attempts fails", 2, errors.New("node down"), domain.StatusFailed},
+		{"revert fails immediately", 0, domain.ErrTransactionReverted, domain.StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, states := recordingRepo()
+			chain := &mockSubmitter{
+				submitHashFn: func(_ context.Context, _, _ string) (string, error) { return "", tt.submitErr },
+			}
+			cert := &domain.Certificate{ContentHash: "h", Status: domain.StatusPending, Attempts: tt.attempts}
+
+			err := usecase.NewAnchorProcessor(repo, chain, 3).Process(context.Background(), cert)
+			if !errors.Is(err, tt.submitErr) {
+				t.Fatalf("err = %v, want %v", err, tt.submitErr)
+			}
+			if cert.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q", cert.Status, tt.wantStatus)
+			}
+			if cert.LastError != tt.submitErr.Error() || len(*states) != 1 {
+				t.Fatalf("last_error = %q, persisted = %v", cert.LastError, *states)
+			}
+		})
+	}
+}
+
+func TestAnchorProcessor_PersistError(t *testing.T) {
+	repo := &mockRepo{
+		updateAnchorStateFn: func(_ context.Context, _ *domain.Certificate) error { return errors.New("db down") },
+	}
+	chain := &mockSubmitter{
+		submitHashFn: func(_ context.Context, _, _ string) (string, error) { return "0xtx", nil },
+	}
+	cert := &domain.Certificate{ContentHash: "h", Status: domain.StatusPending}
+
+	err := usecase.NewAnchorProcessor(repo, chain, 0).Process(context.Background(), cert)
+	if err == nil || !strings.Contains(err.Error(), "persisting submitted state") {
+		t.Fatalf("err = %v, want persisting error", err)
+	}
+}
+
+// backfillRepo stores certificates and their anchors.
+type backfillRepo struct {
+	mockRepo
+	mockAnchorRepo
+}
+
+func TestAnchorProcessor_BackfillAnchors(t *testing.T) {
+	batch := &domain.MerkleBatch{Root: "root", LeafIndex: 1, TreeSize: 2}
+	certs := unanchoredCerts(2)
+	certs[0].TxHash, certs[0].BlockNumber, certs[0].BlockHash = "0xtx", 7, "0xblock"
+	certs[1].TxHash, certs[1].BlockNumber, certs[1].Batch = "0xroot", 8, batch
+	repo := &backfillRepo{mockAnchorRepo: mockAnchorRepo{unanchored: certs}}
+	chain := &mockAnchorChain{staticTarget: staticTarget{chainID: 11155111}}
+
+	n, err := usecase.NewAnchorProcessor(repo, chain, 0).BackfillAnchors(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("recorded %d, err = %v, want 2", n, err)
+	}
+	if repo.gotChain != 11155111 {
+		t.Fatalf("looked up chain %d, want the primary chain", repo.gotChain)
+	}
+	if a := repo.saved["a"]; a.ChainID != 11155111 || a.ContractAddress != chain.ContractAddress() || a.TxHash != "0xtx" || a.BlockNumber != 7 || a.BlockHash != "0xblock" {
+		t.Fatalf("anchor = %+v", a)
+	}
+	if a := repo.saved["b"]; a.TxHash != "0xroot" || a.Batch != batch {
+		t.Fatalf("batched anchor = %+v", a)
+	}
+
+	if n, err := usecase.NewAnchorProcessor(repo, &mockBlockchain{}, 0).BackfillAnchors(context.Background()); err != nil || n != 0 {
+		t.Fatalf("chain without an anchor target: recorded %d, err = %v", n, err)
+	}
+	if _, err := usecase.NewAnchorProcessor(repo, &mockAnchorChain{staticTarget: staticTarget{err: errors.New("down")}}, 0).BackfillAnchors(context.Background()); err == nil {
+		t.Fatal("expected the chain id error")
+	}
+}
+
+func TestOutboxWorker_RunOnce(t *testing.T) {
+	var gotLimit int
+	var gotStale time.Duration
+	repo := &mockRepo{
+		claimUnfinishedFn: func(_ context.Context, limit int, staleAfter time.Duration) ([]*domain.Certificate, error) {
+			gotLimit, gotStale = limit, staleAfter
+			return []*domain.Certificate{
+				{ID: "1", ContentHash: "ok", Status: domain.StatusPending},
+				{ID: "2", ContentHash: "bad", Status: domain.StatusPending},
+			}, nil
+		},
+	}
+	chain := &mockBlockchain{
+		registerHashFn: func(_ context.Context, hash, _ string) (*domain.Receipt, error) {
+			if hash == "bad" {
+				return nil, errors.New("node down")
+			}
+			return &domain.Receipt{TxHash: "0xtx", Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	worker := usecase.NewOutboxWorker(repo, usecase.NewAnchorProcessor(repo, chain, 0), usecase.OutboxConfig{BatchSize: 7, StaleAfter: time.Minute})
+	res, err := worker.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotLimit != 7 || gotStale != time.Minute {
+		t.Fatalf("claim(%d, %s), want claim(7, 1m0s)", gotLimit, gotStale)
+	}
+	if res.Claimed != 2 || res.Completed != 1 || res.Failed != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+}
+
+func TestOutboxWorker_RunOnceClaimError(t *testing.T) {
+	repo := &mockRepo{
+		claimUnfinishedFn: func(_ context.Context, _ int, _ time.Duration) ([]*domain.Certificate, error) {
+			return nil, errors.New("db down")
+		},
+	}
+	worker := usecase.NewOutboxWorker(repo, usecase.NewAnchorProcessor(repo, &mockBlockchain{}, 0), usecase.OutboxConfig{})
+
+	if _, err := worker.RunOnce(context.Background()); err == nil || !strings.Contains(err.Error(), "claiming unfinished") {
+		t.Fatalf("err = %v, want claim error", err)
+	}
+}
@@ -0,0 +1,201 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+func pendingCerts(n int) []*domain.Certificate {
+	certs := make([]*domain.Certificate, n)
+	for i := range certs {
+		sum := sha256.Sum256([]byte{byte(i)})
+		certs[i] = &domain.Certificate{ID: string(rune('a' + i)), ContentHash: hex.EncodeToString(sum[:]), Status: domain.StatusPending}
+	}
+	return certs
+}
+
+func TestBatchAnchorer_FlushAnchorsRootOnce(t *testing.T) {
+	certs := pendingCerts(3)
+	var gotLimit int
+	repo := &mockRepo{
+		claimPendingFn: func(_ context.Context, limit int, _ time.Duration) ([]*domain.Certificate, error) {
+			gotLimit = limit
+			return certs, nil
+		},
+	}
+	var anchored []string
+	chain := &mockBlockchain{
+		registerHashFn: func(_ context.Context, hash, registrant string) (*domain.Receipt, error) {
+			anchored = append(anchored, hash)
+			if registrant != "" {
+				t.Errorf("registrant = %q, want sending account for batches", registrant)
+			}
+			return &domain.Receipt{TxHash: "0xbatch", BlockNumber: 4, Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	batcher := usecase.NewBatchAnchorer(repo, usecase.NewAnchorProcessor(repo, chain, 0), usecase.BatchConfig{MaxSize: 10})
+	res, err := batcher.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotLimit != 10 || res.Size != 3 || !res.Anchored {
+		t.Fatalf("limit = %d, result = %+v", gotLimit, res)
+	}
+	if len(anchored) != 1 || anchored[0] != res.Root {
+		t.Fatalf("anchored = %v, want only root %s", anchored, res.Root)
+	}
+	for _, c := range certs {
+		if c.Status != domain.StatusConfirmed || c.TxHash != "0xbatch" || !c.VerifyInclusion() {
+			t.Fatalf("cert = %+v, want confirmed with valid inclusion", c)
+		}
+	}
+}
+
+func TestBatchAnchorer_TwoPhaseWaitsForOneReceipt(t *testing.T) {
+	certs := pendingCerts(4)
+	repo := &mockRepo{
+		claimPendingFn: func(_ context.Context, _ int, _ time.Duration) ([]*domain.Certificate, error) { return certs, nil },
+	}
+	var waits atomic.Int32
+	chain := &mockSubmitter{
+		submitHashFn: func(_ context.Context, _, _ string) (string, error) { return "0xtx", nil },
+		waitForReceiptFn: func(_ context.Context, hash, txHash string) (*domain.Receipt, error) {
+			waits.Add(1)
+			if hash != certs[0].Batch.Root {
+				t.Errorf("waited for %s, want batch root", hash)
+			}
+			return &domain.Receipt{TxHash: txHash, BlockNumber: 8, Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	batcher := usecase.NewBatchAnchorer(repo, usecase.NewAnchorProcessor(repo, chain, 0), usecase.BatchConfig{})
+	if _, err := batcher.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if waits.Load() != 1 {
+		t.Fatalf("receipt waits = %d, want 1", waits.Load())
+	}
+	for _, c := range certs {
+		if c.Status != domain.StatusConfirmed || c.BlockNumber != 8 {
+			t.Fatalf("cert = %+v", c)
+		}
+	}
+}
+
+func TestBatchAnchorer_TransientFailureLeavesCertificatesUnbatched(t *testing.T) {
+	certs := pendingCerts(2)
+	repo := &mockRepo{
+		claimPendingFn: func(_ context.Context, _ int, _ time.Duration) ([]*domain.Certificate, error) { return certs, nil },
+	}
+	chain := &mockBlockchain{
+		registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) { return nil, errors.New("node down") },
+	}
+
+	batcher := usecase.NewBatchAnchorer(repo, usecase.NewAnchorProcessor(repo, chain, 0), usecase.BatchConfig{})
+	res, err := batcher.Flush(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "node down") || res.Anchored {
+		t.Fatalf("res = %+v, err = %v", res, err)
+	}
+	for _, c := range certs {
+		if c.Status != domain.StatusPending || c.Batch != nil || c.LastError != "node down" {
+			t.Fatalf("cert = %+v, want pending without batch", c)
+		}
+	}
+}
+
+func TestBatchAnchorer_FlushesWhenFull(t *testing.T) {
+	flushed := make(chan struct{}, 1)
+	repo := &mockRepo{
+		claimPendingFn: func(_ context.Context, _ int, _ time.Duration) ([]*domain.Certificate, error) {
+			select {
+			case flushed <- struct{}{}:
+			default:
+			}
+			return nil, nil
+		},
+	}
+	batcher := usecase.NewBatchAnchorer(repo, usecase.NewAnchorProcessor(repo, &mockBlockchain{}, 0), usecase.BatchConfig{MaxSize: 2, Window: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- batcher.Run(ctx) }()
+
+	for i := 0; i < 2; i++ {
+		if err := batcher.Process(ctx, &domain.Certificate{}); err != nil {
+			t.Fatalf("Process: %v", err)
+		}
+	}
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("batch was not flushed after reaching MaxSize")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run returned %v, want context.Canceled", err)
+	}
+}
+
+func TestOutboxWorker_ConfirmsSubmittedBatchTogether(t *testing.T) {
+	certs := pendingCerts(3)
+	root, _ := domain.AssignMerkleBatch(certs)
+	for _, c := range certs {
+		c.Status, c.TxHash = domain.StatusSubmitted, "0xtx"
+	}
+	repo := &mockRepo{
+		claimUnfinishedFn: func(_ context.Context, _ int, _ time.Duration) ([]*domain.Certificate, error) { return certs, nil },
+	}
+	var waits atomic.Int32
+	chain := &mockSubmitter{
+		waitForReceiptFn: func(_ context.Context, hash, txHash string) (*domain.Receipt, error) {
+			waits.Add(1)
+			if hash != root {
+				t.Errorf("waited for %s, want %s", hash, root)
+			}
+			return &domain.Receipt{TxHash: txHash, BlockNumber: 2, Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	worker := usecase.NewOutboxWorker(repo, usecase.NewAnchorProcessor(repo, chain, 0), usecase.OutboxConfig{})
+	res, err := worker.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Completed != 3 || waits.Load() != 1 {
+		t.Fatalf("result = %+v, receipt waits = %d", res, waits.Load())
+	}
+}
+
+func TestCertifyUseCase_BatchModeQueuesCertificate(t *testing.T) {
+	repo := &mockRepo{
+		findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) { return nil, nil },
+		saveFn:       func(_ context.Context, _ *domain.Certificate) error { return nil },
+	}
+	chain := &mockBlockchain{
+		registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+			t.Fatal("certify must not anchor inline in batch mode")
+			return nil, nil
+		},
+	}
+	batcher := usecase.NewBatchAnchorer(repo, usecase.NewAnchorProcessor(repo, chain, 0), usecase.BatchConfig{})
+
+	out, err := usecase.NewCertifyUseCase(repo, batcher).Execute(context.Background(), usecase.CertifyInput{Content: strings.NewReader("x")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Certificate.Status != domain.StatusPending {
+		t.Fatalf("status = %q, want pending", out.Certificate.Status)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"image/jpeg"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/waizbart/aletheia-api/internal/domain"
 	"github.com/waizbart/aletheia-api/internal/usecase"
@@ -16,11 +17,12 @@ import (
 
 func TestCertifyUseCase_Execute(t *testing.T) {
 	tests := []struct {
-		name    string
-		repo    *mockRepo
-		chain   *mockBlockchain
-		input   usecase.CertifyInput
-		wantErr string
+		name       string
+		repo       *mockRepo
+		chain      *mockBlockchain
+		input      usecase.CertifyInput
+		wantErr    string
+		wantStatus domain.CertificateStatus
 	}{
 		{
 			name: "happy path",
@@ -33,31 +35,35 @@ func TestCertifyUseCase_Execute(t *testing.T) {
 				},
 			},
 			chain: &mockBlockchain{
-				registerHashFn: func(_ context.Context, _ string) (string, uint64, error) {
-					return "0xabc", 1, nil
+				registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+					return &domain.Receipt{TxHash: "0xabc", BlockNumber: 1, Status: domain.ReceiptStatusSuccess}, nil
 				},
 			},
 			input: usecase.CertifyInput{
 				Content:    strings.NewReader("test content"),
 				Registrant: "tester",
 			},
+			wantStatus: domain.StatusConfirmed,
 		},
 		{
-			name: "image sets perceptual hash",
+			name: "image sets pixel and perceptual hashes",
 			repo: &mockRepo{
 				findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) {
 					return nil, nil
 				},
 				saveFn: func(_ context.Context, cert *domain.Certificate) error {
-					if cert.PerceptualHash == nil {
-						t.Fatal("expected perceptual hash for image content")
+					if len(cert.PerceptualHashes) != 3 {
+						t.Fatalf("perceptual hashes = %v, want one per default algorithm", cert.PerceptualHashes)
+					}
+					if len(cert.PixelHash) != 64 || cert.PixelHash == cert.ContentHash {
+						t.Fatalf("pixel hash = %q", cert.PixelHash)
 					}
 					return nil
 				},
 			},
 			chain: &mockBlockchain{
-				registerHashFn: func(_ context.Context, _ string) (string, uint64, error) {
-					return "0xabc", 1, nil
+				registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+					return &domain.Receipt{TxHash: "0xabc", BlockNumber: 1, Status: domain.ReceiptStatusSuccess}, nil
 				},
 			},
 			input: usecase.CertifyInput{
@@ -104,15 +110,39 @@ func TestCertifyUseCase_Execute(t *testing.T) {
 			wantErr: "checking existing",
 		},
 		{
-			name: "blockchain register error",
+			name: "transient chain error leaves certificate pending",
+			repo: &mockRepo{
+				findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) {
+					return nil, nil
+				},
+				saveFn: func(_ context.Context, _ *domain.Certificate) error {
+					return nil
+				},
+			},
+			chain: &mockBlockchain{
+				registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+					return nil, errors.New("chain error")
+				},
+			},
+			input: usecase.CertifyInput{
+				Content:    strings.NewReader("test content"),
+				Registrant: "tester",
+			},
+			wantStatus: domain.StatusPending,
+		},
+		{
+			name: "reverted receipt",
 			repo: &mockRepo{
 				findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) {
 					return nil, nil
 				},
+				saveFn: func(_ context.Context, _ *domain.Certificate) error {
+					return nil
+				},
 			},
 			chain: &mockBlockchain{
-				registerHashFn: func(_ context.Context, _ string) (string, uint64, error) {
-					return "", 0, errors.New("chain error")
+				registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+					return &domain.Receipt{TxHash: "0xabc", Status: domain.ReceiptStatusReverted}, domain.ErrTransactionReverted
 				},
 			},
 			input: usecase.CertifyInput{
@@ -121,6 +151,49 @@ func TestCertifyUseCase_Execute(t *testing.T) {
 			},
 			wantErr: "registering on chain",
 		},
+		{
+			name: "failed certificate is retried",
+			repo: &mockRepo{
+				findByHashFn: func(_ context.Context, hash string) (*domain.Certificate, error) {
+					return &domain.Certificate{ContentHash: hash, Status: domain.StatusFailed, Attempts: 5, LastError: "reverted"}, nil
+				},
+			},
+			chain: &mockBlockchain{
+				registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+					return &domain.Receipt{TxHash: "0xabc", BlockNumber: 1, Status: domain.ReceiptStatusSuccess}, nil
+				},
+			},
+			input: usecase.CertifyInput{
+				Content:    strings.NewReader("test content"),
+				Registrant: "tester",
+			},
+			wantStatus: domain.StatusConfirmed,
+		},
+		{
+			name: "already registered on chain without a lookup fails",
+			repo: &mockRepo{
+				findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) {
+					return nil, nil
+				},
+				saveFn: func(_ context.Context, _ *domain.Certificate) error {
+					return nil
+				},
+			},
+			chain: &mockBlockchain{
+				registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+					t.Fatal("RegisterHash must not be called for a registered hash")
+					return nil, nil
+				},
+				isHashRegisteredFn: func(_ context.Context, _ string) (bool, error) {
+					return true, nil
+				},
+			},
+			input: usecase.CertifyInput{
+				Content:    strings.NewReader("test content"),
+				Registrant: "tester",
+			},
+			wantErr: "cannot be looked up",
+		},
 		{
 			name: "save error",
 			repo: &mockRepo{
@@ -132,8 +205,8 @@ func TestCertifyUseCase_Execute(t *testing.T) {
 				},
 			},
 			chain: &mockBlockchain{
-				registerHashFn: func(_ context.Context, _ string) (string, uint64, error) {
-					return "0xabc", 1, nil
+				registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+					return &domain.Receipt{TxHash: "0xabc", BlockNumber: 1, Status: domain.ReceiptStatusSuccess}, nil
 				},
 			},
 			input: usecase.CertifyInput{
@@ -146,7 +219,7 @@ func TestCertifyUseCase_Execute(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			uc := usecase.NewCertifyUseCase(tt.repo, tt.chain)
+			uc := newCertifyUseCase(tt.repo, tt.chain)
 			out, err := uc.Execute(context.Background(), tt.input)
 
 			if tt.wantErr != "" {
@@ -168,10 +241,17 @@ func TestCertifyUseCase_Execute(t *testing.T) {
 			if out.Certificate.Registrant != tt.input.Registrant {
 				t.Errorf("registrant = %q, want %q", out.Certificate.Registrant, tt.input.Registrant)
 			}
+			if tt.wantStatus != "" && out.Certificate.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", out.Certificate.Status, tt.wantStatus)
+			}
 		})
 	}
 }
 
+func newCertifyUseCase(repo *mockRepo, chain usecase.BlockchainService) *usecase.CertifyUseCase {
+	return usecase.NewCertifyUseCase(repo, usecase.NewAnchorProcessor(repo, chain, 0))
+}
+
 func sampleJPEGForCertify(t *testing.T) []byte {
 	t.Helper()
 	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
@@ -186,3 +266,84 @@ func sampleJPEGForCertify(t *testing.T) []byte {
 	}
 	return b.Bytes()
 }
+
+func TestCertifyUseCase_RecordsMinedReceipt(t *testing.T) {
+	var saved *domain.Certificate
+	repo := &mockRepo{
+		findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) { return nil, nil },
+		saveFn:       func(_ context.Context, _ *domain.Certificate) error { return nil },
+		updateAnchorStateFn: func(_ context.Context, cert *domain.Certificate) error {
+			saved = cert
+			return nil
+		},
+	}
+	chain := &mockBlockchain{
+		registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+			return &domain.Receipt{TxHash: "0xabc", BlockNumber: 42, BlockHash: "0xblock", GasUsed: 50000, Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	uc := newCertifyUseCase(repo, chain)
+	if _, err := uc.Execute(context.Background(), usecase.CertifyInput{Content: strings.NewReader("x")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved.BlockNumber != 42 || saved.BlockHash != "0xblock" || saved.TxHash != "0xabc" {
+		t.Fatalf("saved certificate = %+v, want receipt fields", saved)
+	}
+}
+
+func TestCertifyUseCase_StoresVideoFingerprint(t *testing.T) {
+	var saved *domain.Certificate
+	repo := &mockRepo{
+		findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) { return nil, nil },
+		saveFn: func(_ context.Context, cert *domain.Certificate) error {
+			saved = cert
+			return nil
+		},
+	}
+	fp := domain.VideoFingerprint{{Start: 0, End: time.Second, Hashes: domain.PerceptualHashes{domain.DCTHash: 1}}}
+	chain := &mockBlockchain{
+		registerHashFn: func(_ context.Context, _, _ string) (*domain.Receipt, error) {
+			return &domain.Receipt{TxHash: "0xabc", Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	uc := newCertifyUseCase(repo, chain).WithVideoFingerprinter(&mockFingerprinter{fp: fp})
+	if _, err := uc.Execute(context.Background(), usecase.CertifyInput{Content: strings.NewReader("video")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(saved.Fingerprint) != 1 || saved.PerceptualHashes != nil {
+		t.Fatalf("saved fingerprint = %+v", saved.Fingerprint)
+	}
+
+	uc = newCertifyUseCase(repo, chain).WithVideoFingerprinter(&mockFingerprinter{err: domain.ErrUnsupportedVideo})
+	if _, err := uc.Execute(context.Background(), usecase.CertifyInput{Content: strings.NewReader("h264")}); err != nil {
+		t.Fatalf("unreadable videos must still be certified: %v", err)
+	}
+	if saved.Fingerprint != nil {
+		t.Fatalf("saved fingerprint = %+v, want none", saved.Fingerprint)
+	}
+}
+
+func TestCertifyUseCase_PassesRegistrantToChain(t *testing.T) {
+	repo := &mockRepo{
+		findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) { return nil, nil },
+		saveFn:       func(_ context.Context, _ *domain.Certificate) error { return nil },
+	}
+	var gotRegistrant string
+	chain := &mockBlockchain{
+		registerHashFn: func(_ context.Context, _, registrant string) (*domain.Receipt, error) {
+			gotRegistrant = registrant
+			return &domain.Receipt{TxHash: "0xabc", Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	uc := newCertifyUseCase(repo, chain)
+	in := usecase.CertifyInput{Content: strings.NewReader("x"), Registrant: "0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18"}
+	if _, err := uc.Execute(context.Background(), in); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotRegistrant != in.Registrant {
+		t.Fatalf("registrant = %q, want %q", gotRegistrant, in.Registrant)
+	}
+}
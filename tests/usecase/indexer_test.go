@@ -0,0 +1,285 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+func chainEvent(hash string, block uint64) domain.RegistrationEvent {
+	return domain.RegistrationEvent{
+		ContentHash:  hash,
+		Registrant:   "0x1111111111111111111111111111111111111111",
+		RegisteredAt: time.Unix(1700000000, 0).UTC(),
+		TxHash:       "0xtx-" + hash,
+		BlockNumber:  block,
+		BlockHash:    "0xblock",
+	}
+}
+
+func TestIndexerUseCase_SyncOnce_InsertsMissingAndFlagsMismatches(t *testing.T) {
+	stored := map[string]*domain.Certificate{
+		"matching": {ID: "1", ContentHash: "matching", TxHash: "0xtx-matching", BlockNumber: 11, BlockHash: "0xblock", Status: domain.StatusConfirmed},
+		"diverged": {ID: "2", ContentHash: "diverged", TxHash: "0xother", BlockNumber: 12, Status: domain.StatusConfirmed},
+	}
+	var saved []*domain.Certificate
+	flags := map[string]string{}
+
+	repo := &mockReconcileRepo{
+		mockRepo: mockRepo{
+			findByHashFn: func(_ context.Context, hash string) (*domain.Certificate, error) { return stored[hash], nil },
+			saveFn: func(_ context.Context, cert *domain.Certificate) error {
+				saved = append(saved, cert)
+				return nil
+			},
+		},
+		flagFn: func(_ context.Context, id, reason string) error {
+			flags[id] = reason
+			return nil
+		},
+	}
+	source := &mockEventSource{
+		latestBlockFn: func(context.Context) (uint64, error) { return 20, nil },
+		eventsFn: func(_ context.Context, from, to uint64) ([]domain.RegistrationEvent, error) {
+			if from != 10 || to != 18 {
+				t.Fatalf("range = %d-%d, want 10-18", from, to)
+			}
+			return []domain.RegistrationEvent{
+				chainEvent("matching", 11),
+				chainEvent("diverged", 12),
+				chainEvent("missing", 13),
+			}, nil
+		},
+	}
+	checkpoints := &memCheckpoints{blocks: map[string]uint64{"content_registered": 9}}
+
+	uc := usecase.NewIndexerUseCase(source, repo, checkpoints, usecase.IndexerConfig{Confirmations: 3})
+	res, err := uc.SyncOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.Events != 3 || res.Inserted != 1 || res.Flagged != 1 || !res.CaughtUp {
+		t.Fatalf("result = %+v", res)
+	}
+	if len(saved) != 1 || saved[0].ContentHash != "missing" || saved[0].BlockNumber != 13 || saved[0].TxHash != "0xtx-missing" {
+		t.Fatalf("saved = %+v", saved)
+	}
+	if !strings.Contains(flags["2"], "tx_hash 0xother != chain 0xtx-diverged") {
+		t.Fatalf("flag reason = %q", flags["2"])
+	}
+	if _, ok := flags["1"]; ok {
+		t.Fatal("consistent certificate must not be flagged")
+	}
+	if checkpoints.blocks["content_registered"] != 18 {
+		t.Fatalf("checkpoint = %d, want 18", checkpoints.blocks["content_registered"])
+	}
+}
+
+func TestIndexerUseCase_SyncOnce_BatchesFromStartBlock(t *testing.T) {
+	source := &mockEventSource{
+		latestBlockFn: func(context.Context) (uint64, error) { return 1000, nil },
+		eventsFn: func(_ context.Context, from, to uint64) ([]domain.RegistrationEvent, error) {
+			if from != 100 || to != 149 {
+				t.Fatalf("range = %d-%d, want 100-149", from, to)
+			}
+			return nil, nil
+		},
+	}
+	checkpoints := &memCheckpoints{}
+
+	uc := usecase.NewIndexerUseCase(source, &mockReconcileRepo{}, checkpoints, usecase.IndexerConfig{Name: "idx", StartBlock: 100, BatchSize: 50})
+	res, err := uc.SyncOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.CaughtUp {
+		t.Fatal("expected more blocks to remain")
+	}
+	if checkpoints.blocks["idx"] != 149 {
+		t.Fatalf("checkpoint = %d, want 149", checkpoints.blocks["idx"])
+	}
+}
+
+func TestIndexerUseCase_SyncOnce_NothingToDo(t *testing.T) {
+	source := &mockEventSource{
+		latestBlockFn: func(context.Context) (uint64, error) { return 10, nil },
+		eventsFn: func(context.Context, uint64, uint64) ([]domain.RegistrationEvent, error) {
+			t.Fatal("events must not be fetched past the confirmed head")
+			return nil, nil
+		},
+	}
+	checkpoints := &memCheckpoints{blocks: map[string]uint64{"content_registered": 10}}
+
+	uc := usecase.NewIndexerUseCase(source, &mockReconcileRepo{}, checkpoints, usecase.IndexerConfig{})
+	res, err := uc.SyncOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.CaughtUp || res.Checkpoint != 10 {
+		t.Fatalf("result = %+v", res)
+	}
+
+	young := &mockEventSource{latestBlockFn: func(context.Context) (uint64, error) { return 1, nil }}
+	uc = usecase.NewIndexerUseCase(young, &mockReconcileRepo{}, &memCheckpoints{}, usecase.IndexerConfig{Confirmations: 5})
+	if res, err := uc.SyncOnce(context.Background()); err != nil || !res.CaughtUp {
+		t.Fatalf("young chain: res = %+v, err = %v", res, err)
+	}
+}
+
+func TestIndexerUseCase_SyncOnce_ClearsResolvedFlag(t *testing.T) {
+	var cleared bool
+	repo := &mockReconcileRepo{
+		mockRepo: mockRepo{findByHashFn: func(_ context.Context, hash string) (*domain.Certificate, error) {
+			return &domain.Certificate{ID: "1", ContentHash: hash, TxHash: "0xtx-" + hash, BlockNumber: 1, ChainMismatch: "stale", Status: domain.StatusConfirmed}, nil
+		}},
+		flagFn: func(_ context.Context, _, reason string) error {
+			cleared = reason == ""
+			return nil
+		},
+	}
+	source := &mockEventSource{
+		latestBlockFn: func(context.Context) (uint64, error) { return 5, nil },
+		eventsFn: func(context.Context, uint64, uint64) ([]domain.RegistrationEvent, error) {
+			return []domain.RegistrationEvent{chainEvent("h", 1)}, nil
+		},
+	}
+
+	uc := usecase.NewIndexerUseCase(source, repo, &memCheckpoints{}, usecase.IndexerConfig{})
+	res, err := uc.SyncOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cleared || res.Flagged != 0 {
+		t.Fatalf("cleared = %v, flagged = %d", cleared, res.Flagged)
+	}
+}
+
+func TestIndexerUseCase_SyncOnce_ReconcilesBatchRoot(t *testing.T) {
+	members := []*domain.Certificate{
+		{ID: "1", TxHash: "0xtx-root", BlockNumber: 3, Status: domain.StatusConfirmed},
+		{ID: "2", TxHash: "0xtx-root", BlockNumber: 2, Status: domain.StatusConfirmed},
+	}
+	flags := map[string]string{}
+	repo := &mockReconcileRepo{
+		mockRepo: mockRepo{
+			findByHashFn: func(context.Context, string) (*domain.Certificate, error) { return nil, nil },
+			saveFn: func(context.Context, *domain.Certificate) error {
+				t.Fatal("a batch root must not be inserted as a certificate")
+				return nil
+			},
+			findByMerkleRootFn: func(_ context.Context, root string) ([]*domain.Certificate, error) {
+				if root != "root" {
+					t.Fatalf("root = %q", root)
+				}
+				return members, nil
+			},
+		},
+		flagFn: func(_ context.Context, id, reason string) error {
+			flags[id] = reason
+			return nil
+		},
+	}
+	source := &mockEventSource{
+		latestBlockFn: func(context.Context) (uint64, error) { return 5, nil },
+		eventsFn: func(context.Context, uint64, uint64) ([]domain.RegistrationEvent, error) {
+			ev := chainEvent("root", 3)
+			ev.BlockHash = ""
+			return []domain.RegistrationEvent{ev}, nil
+		},
+	}
+
+	res, err := usecase.NewIndexerUseCase(source, repo, &memCheckpoints{}, usecase.IndexerConfig{}).SyncOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 0 || res.Flagged != 1 || len(flags) != 1 || !strings.Contains(flags["2"], "block_number 2 != chain 3") {
+		t.Fatalf("result = %+v, flags = %v", res, flags)
+	}
+}
+
+func TestIndexerUseCase_SyncOnce_Errors(t *testing.T) {
+	okSource := &mockEventSource{
+		latestBlockFn: func(context.Context) (uint64, error) { return 5, nil },
+		eventsFn: func(context.Context, uint64, uint64) ([]domain.RegistrationEvent, error) {
+			return []domain.RegistrationEvent{chainEvent("h", 1)}, nil
+		},
+	}
+	missing := mockRepo{
+		findByHashFn: func(context.Context, string) (*domain.Certificate, error) { return nil, nil },
+		saveFn:       func(context.Context, *domain.Certificate) error { return nil },
+	}
+	diverged := mockRepo{findByHashFn: func(context.Context, string) (*domain.Certificate, error) {
+		return &domain.Certificate{ID: "1", Status: domain.StatusConfirmed}, nil
+	}}
+
+	tests := []struct {
+		name        string
+		source      *mockEventSource
+		repo        *mockReconcileRepo
+		checkpoints *memCheckpoints
+		want        string
+	}{
+		{"load checkpoint", okSource, &mockReconcileRepo{mockRepo: missing}, &memCheckpoints{loadErr: errors.New("db")}, "loading checkpoint"},
+		{"head", &mockEventSource{latestBlockFn: func(context.Context) (uint64, error) { return 0, errors.New("rpc") }}, &mockReconcileRepo{mockRepo: missing}, &memCheckpoints{}, "fetching head"},
+		{"events", &mockEventSource{
+			latestBlockFn: func(context.Context) (uint64, error) { return 5, nil },
+			eventsFn: func(context.Context, uint64, uint64) ([]domain.RegistrationEvent, error) {
+				return nil, errors.New("rpc")
+			},
+		}, &mockReconcileRepo{mockRepo: missing}, &memCheckpoints{}, "fetching events"},
+		{"find", okSource, &mockReconcileRepo{mockRepo: mockRepo{findByHashFn: func(context.Context, string) (*domain.Certificate, error) {
+			return nil, errors.New("db")
+		}}}, &memCheckpoints{}, "indexer: finding"},
+		{"insert", okSource, &mockReconcileRepo{mockRepo: mockRepo{
+			findByHashFn: missing.findByHashFn,
+			saveFn:       func(context.Context, *domain.Certificate) error { return errors.New("unique violation") },
+		}}, &memCheckpoints{}, "indexer: inserting"},
+		{"flag", okSource, &mockReconcileRepo{mockRepo: diverged, flagFn: func(context.Context, string, string) error {
+			return errors.New("db")
+		}}, &memCheckpoints{}, "indexer: flagging"},
+		{"save checkpoint", okSource, &mockReconcileRepo{mockRepo: missing}, &memCheckpoints{saveErr: errors.New("db")}, "saving checkpoint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := usecase.NewIndexerUseCase(tt.source, tt.repo, tt.checkpoints, usecase.IndexerConfig{})
+			_, err := uc.SyncOnce(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestIndexerUseCase_Run_StopsOnCancel(t *testing.T) {
+	var calls int
+	source := &mockEventSource{
+		latestBlockFn: func(context.Context) (uint64, error) {
+			calls++
+			if calls == 1 {
+				return 0, errors.New("transient")
+			}
+			return 3000, nil
+		},
+		eventsFn: func(context.Context, uint64, uint64) ([]domain.RegistrationEvent, error) {
+			return []domain.RegistrationEvent{}, nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	uc := usecase.NewIndexerUseCase(source, &mockReconcileRepo{}, &memCheckpoints{}, usecase.IndexerConfig{PollInterval: 5 * time.Millisecond})
+	if err := uc.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if calls < 3 {
+		t.Fatalf("expected indexer to keep polling after errors, got %d calls", calls)
+	}
+}
@@ -3,6 +3,7 @@ package usecase_test
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/waizbart/aletheia-api/internal/domain"
 )
@@ -14,7 +15,14 @@ func (errReader) Read([]byte) (int, error) { return 0, errors.New("read error")
 type mockRepo struct {
 	saveFn                 func(ctx context.Context, cert *domain.Certificate) error
 	findByHashFn           func(ctx context.Context, hash string) (*domain.Certificate, error)
-	findByPerceptualHashFn func(ctx context.Context, hash uint64, maxDistance int) (*domain.Certificate, error)
+	findByPixelHashFn      func(ctx context.Context, pixelHash string) (*domain.Certificate, error)
+	findByPerceptualHashFn func(ctx context.Context, hashes domain.PerceptualHashes, maxDistance, limit int) ([]domain.SimilarCertificate, error)
+	findByVideoFn          func(ctx context.Context, clip domain.VideoFingerprint, maxDistance, limit int) ([]domain.SimilarCertificate, error)
+	updateAnchorStateFn    func(ctx context.Context, cert *domain.Certificate) error
+	claimUnfinishedFn      func(ctx context.Context, limit int, staleAfter time.Duration) ([]*domain.Certificate, error)
+	claimPendingFn         func(ctx context.Context, limit int, lease time.Duration) ([]*domain.Certificate, error)
+	findByMerkleRootFn     func(ctx context.Context, root string) ([]*domain.Certificate, error)
+	findConfirmedSinceFn   func(ctx context.Context, fromBlock uint64) ([]*domain.Certificate, error)
 }
 
 func (m *mockRepo) Save(ctx context.Context, cert *domain.Certificate) error {
@@ -25,22 +33,156 @@ func (m *mockRepo) FindByHash(ctx context.Context, hash string) (*domain.Certifi
 	return m.findByHashFn(ctx, hash)
 }
 
-func (m *mockRepo) FindByPerceptualHash(ctx context.Context, hash uint64, maxDistance int) (*domain.Certificate, error) {
+func (m *mockRepo) FindByPixelHash(ctx context.Context, pixelHash string) (*domain.Certificate, error) {
+	if m.findByPixelHashFn == nil {
+		return nil, nil
+	}
+	return m.findByPixelHashFn(ctx, pixelHash)
+}
+
+func (m *mockRepo) FindByPerceptualHash(ctx context.Context, hashes domain.PerceptualHashes, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
 	if m.findByPerceptualHashFn == nil {
 		return nil, nil
 	}
-	return m.findByPerceptualHashFn(ctx, hash, maxDistance)
+	return m.findByPerceptualHashFn(ctx, hashes, maxDistance, limit)
+}
+
+func (m *mockRepo) FindByVideoFingerprint(ctx context.Context, clip domain.VideoFingerprint, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+	if m.findByVideoFn == nil {
+		return nil, nil
+	}
+	return m.findByVideoFn(ctx, clip, maxDistance, limit)
+}
+
+func (m *mockRepo) UpdateAnchorState(ctx context.Context, cert *domain.Certificate) error {
+	if m.updateAnchorStateFn == nil {
+		return nil
+	}
+	return m.updateAnchorStateFn(ctx, cert)
+}
+
+func (m *mockRepo) ClaimUnfinished(ctx context.Context, limit int, staleAfter time.Duration) ([]*domain.Certificate, error) {
+	return m.claimUnfinishedFn(ctx, limit, staleAfter)
+}
+
+func (m *mockRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*domain.Certificate, error) {
+	return m.claimPendingFn(ctx, limit, lease)
+}
+
+func (m *mockRepo) FindByMerkleRoot(ctx context.Context, root string) ([]*domain.Certificate, error) {
+	if m.findByMerkleRootFn == nil {
+		return nil, nil
+	}
+	return m.findByMerkleRootFn(ctx, root)
+}
+
+func (m *mockRepo) FindConfirmedSince(ctx context.Context, fromBlock uint64) ([]*domain.Certificate, error) {
+	return m.findConfirmedSinceFn(ctx, fromBlock)
+}
+
+type mockFingerprinter struct {
+	fp    domain.VideoFingerprint
+	err   error
+	calls int
+}
+
+func (m *mockFingerprinter) Fingerprint([]byte) (domain.VideoFingerprint, error) {
+	m.calls++
+	return m.fp, m.err
 }
 
 type mockBlockchain struct {
-	registerHashFn     func(ctx context.Context, hash string) (string, uint64, error)
+	registerHashFn     func(ctx context.Context, hash, registrant string) (*domain.Receipt, error)
 	isHashRegisteredFn func(ctx context.Context, hash string) (bool, error)
 }
 
-func (m *mockBlockchain) RegisterHash(ctx context.Context, hash string) (string, uint64, error) {
-	return m.registerHashFn(ctx, hash)
+func (m *mockBlockchain) RegisterHash(ctx context.Context, hash, registrant string) (*domain.Receipt, error) {
+	return m.registerHashFn(ctx, hash, registrant)
 }
 
 func (m *mockBlockchain) IsHashRegistered(ctx context.Context, hash string) (bool, error) {
+	if m.isHashRegisteredFn == nil {
+		return false, nil
+	}
 	return m.isHashRegisteredFn(ctx, hash)
 }
+
+type mockSubmitter struct {
+	mockBlockchain
+	submitHashFn       func(ctx context.Context, hash, registrant string) (string, error)
+	waitForReceiptFn   func(ctx context.Context, hash, txHash string) (*domain.Receipt, error)
+	findRegistrationFn func(ctx context.Context, hash string) (*domain.RegistrationEvent, error)
+}
+
+func (m *mockSubmitter) SubmitHash(ctx context.Context, hash, registrant string) (string, error) {
+	return m.submitHashFn(ctx, hash, registrant)
+}
+
+func (m *mockSubmitter) WaitForReceipt(ctx context.Context, hash, txHash string) (*domain.Receipt, error) {
+	return m.waitForReceiptFn(ctx, hash, txHash)
+}
+
+func (m *mockSubmitter) FindRegistration(ctx context.Context, hash string) (*domain.RegistrationEvent, error) {
+	return m.findRegistrationFn(ctx, hash)
+}
+
+type mockReconcileRepo struct {
+	mockRepo
+	flagFn func(ctx context.Context, id, reason string) error
+}
+
+func (m *mockReconcileRepo) FlagChainMismatch(ctx context.Context, id, reason string) error {
+	return m.flagFn(ctx, id, reason)
+}
+
+type mockEventSource struct {
+	latestBlockFn func(ctx context.Context) (uint64, error)
+	eventsFn      func(ctx context.Context, from, to uint64) ([]domain.RegistrationEvent, error)
+}
+
+func (m *mockEventSource) LatestBlock(ctx context.Context) (uint64, error) {
+	return m.latestBlockFn(ctx)
+}
+
+func (m *mockEventSource) RegistrationEvents(ctx context.Context, from, to uint64) ([]domain.RegistrationEvent, error) {
+	return m.eventsFn(ctx, from, to)
+}
+
+type mockCanonicalChain struct {
+	head   uint64
+	hashes map[uint64]string
+	err    error
+}
+
+func (m *mockCanonicalChain) LatestBlock(context.Context) (uint64, error) {
+	return m.head, nil
+}
+
+func (m *mockCanonicalChain) BlockHash(_ context.Context, number uint64) (string, error) {
+	return m.hashes[number], m.err
+}
+
+type memCheckpoints struct {
+	blocks  map[string]uint64
+	loadErr error
+	saveErr error
+}
+
+func (m *memCheckpoints) LoadCheckpoint(_ context.Context, name string) (uint64, bool, error) {
+	if m.loadErr != nil {
+		return 0, false, m.loadErr
+	}
+	b, ok := m.blocks[name]
+	return b, ok, nil
+}
+
+func (m *memCheckpoints) SaveCheckpoint(_ context.Context, name string, block uint64) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	if m.blocks == nil {
+		m.blocks = map[string]uint64{}
+	}
+	m.blocks[name] = block
+	return nil
+}
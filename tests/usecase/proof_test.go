@@ -0,0 +1,71 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+type mockProofRepo struct {
+	findByIDFn func(ctx context.Context, id string) (*domain.Certificate, error)
+}
+
+func (m *mockProofRepo) FindByID(ctx context.Context, id string) (*domain.Certificate, error) {
+	return m.findByIDFn(ctx, id)
+}
+
+type staticTarget struct {
+	chainID uint64
+	err     error
+}
+
+func (s staticTarget) ChainID(context.Context) (uint64, error) { return s.chainID, s.err }
+func (s staticTarget) ContractAddress() string                 { return "0x5FbDB2315678afecb367f032d93F642f64180aa3" }
+
+func TestProofUseCase_Execute(t *testing.T) {
+	anchored := &domain.Certificate{ID: "1", ContentHash: strings.Repeat("ab", 32), TxHash: "0xtx", BlockNumber: 3, Status: domain.StatusConfirmed}
+	found := &mockProofRepo{findByIDFn: func(context.Context, string) (*domain.Certificate, error) { return anchored, nil }}
+
+	tests := []struct {
+		name    string
+		repo    *mockProofRepo
+		target  staticTarget
+		wantErr error
+		wantMsg string
+	}{
+		{name: "anchored", repo: found, target: staticTarget{chainID: 11155111}},
+		{name: "missing", repo: &mockProofRepo{findByIDFn: func(context.Context, string) (*domain.Certificate, error) { return nil, nil }}, wantErr: domain.ErrNotFound},
+		{name: "pending", repo: &mockProofRepo{findByIDFn: func(context.Context, string) (*domain.Certificate, error) {
+			return &domain.Certificate{ContentHash: anchored.ContentHash, Status: domain.StatusPending}, nil
+		}}, target: staticTarget{chainID: 1}, wantErr: domain.ErrNotAnchored},
+		{name: "repo error", repo: &mockProofRepo{findByIDFn: func(context.Context, string) (*domain.Certificate, error) { return nil, errors.New("db") }}, wantMsg: "db"},
+		{name: "chain id error", repo: found, target: staticTarget{err: errors.New("rpc down")}, wantMsg: "resolving chain id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, err := usecase.NewProofUseCase(tt.repo, tt.target).Execute(context.Background(), "1")
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Fatalf("err = %v, want %q", err, tt.wantMsg)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if bundle.ChainID != 11155111 || bundle.TxHash != "0xtx" || bundle.Verify() != nil {
+					t.Fatalf("bundle = %+v", bundle)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+func confirmedCert(id string, block uint64, blockHash string) *domain.Certificate {
+	return &domain.Certificate{
+		ID:          id,
+		ContentHash: strings.Repeat(id, 64)[:64],
+		TxHash:      "0xold" + id,
+		BlockNumber: block,
+		BlockHash:   blockHash,
+		Status:      domain.StatusConfirmed,
+	}
+}
+
+func TestReorgWatcher_LeavesCanonicalCertificatesAlone(t *testing.T) {
+	var from uint64
+	repo, states := recordingRepo()
+	repo.findConfirmedSinceFn = func(_ context.Context, fromBlock uint64) ([]*domain.Certificate, error) {
+		from = fromBlock
+		return []*domain.Certificate{confirmedCert("a", 95, "0xAB"), confirmedCert("b", 96, "")}, nil
+	}
+	chain := &mockCanonicalChain{head: 100, hashes: map[uint64]string{95: "0xab"}}
+
+	watcher := usecase.NewReorgWatcher(repo, chain, usecase.NewAnchorProcessor(repo, &mockBlockchain{}, 0), usecase.ReorgConfig{Depth: 10})
+	res, err := watcher.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if from != 90 || res.Checked != 2 || res.Reorged != 0 || len(*states) != 0 {
+		t.Fatalf("from = %d, result = %+v, states = %v", from, res, *states)
+	}
+}
+
+func TestReorgWatcher_MarksAndReanchorsOrphanedCertificate(t *testing.T) {
+	cert := confirmedCert("a", 95, "0xold")
+	repo, states := recordingRepo()
+	repo.findConfirmedSinceFn = func(context.Context, uint64) ([]*domain.Certificate, error) {
+		return []*domain.Certificate{cert}, nil
+	}
+	chain := &mockCanonicalChain{head: 100, hashes: map[uint64]string{95: "0xnew"}}
+	anchors := &mockBlockchain{
+		registerHashFn: func(_ context.Context, hash, _ string) (*domain.Receipt, error) {
+			if hash != cert.ContentHash {
+				t.Errorf("anchored %s, want %s", hash, cert.ContentHash)
+			}
+			return &domain.Receipt{TxHash: "0xretry", BlockNumber: 101, BlockHash: "0xb101", Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	watcher := usecase.NewReorgWatcher(repo, chain, usecase.NewAnchorProcessor(repo, anchors, 0), usecase.ReorgConfig{})
+	res, err := watcher.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Reorged != 1 || res.Reanchored != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+	want := []domain.CertificateStatus{domain.StatusReorged, domain.StatusConfirmed}
+	if fmt.Sprint(*states) != fmt.Sprint(want) {
+		t.Fatalf("persisted states = %v, want %v", *states, want)
+	}
+	if cert.TxHash != "0xretry" || cert.BlockNumber != 101 || cert.LastError != "" {
+		t.Fatalf("cert = %+v, want re-anchored", cert)
+	}
+}
+
+func TestReorgWatcher_ReanchorsBatchWithOneTransaction(t *testing.T) {
+	certs := pendingCerts(3)
+	root, _ := domain.AssignMerkleBatch(certs)
+	for _, c := range certs {
+		c.Status, c.TxHash, c.BlockNumber, c.BlockHash = domain.StatusConfirmed, "0xbatch", 50, "0xold"
+	}
+	repo, _ := recordingRepo()
+	repo.findConfirmedSinceFn = func(context.Context, uint64) ([]*domain.Certificate, error) { return certs, nil }
+	var anchored []string
+	anchors := &mockBlockchain{
+		registerHashFn: func(_ context.Context, hash, _ string) (*domain.Receipt, error) {
+			anchored = append(anchored, hash)
+			return &domain.Receipt{TxHash: "0xretry", BlockNumber: 60, Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	watcher := usecase.NewReorgWatcher(repo, &mockCanonicalChain{head: 60}, usecase.NewAnchorProcessor(repo, anchors, 0), usecase.ReorgConfig{})
+	res, err := watcher.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Reorged != 3 || res.Reanchored != 3 || len(anchored) != 1 || anchored[0] != root {
+		t.Fatalf("result = %+v, anchored = %v, want root %s once", res, anchored, root)
+	}
+	for _, c := range certs {
+		if c.Status != domain.StatusConfirmed || c.Batch == nil || !c.VerifyInclusion() {
+			t.Fatalf("cert = %+v, want confirmed in the same batch", c)
+		}
+	}
+}
+
+func TestReorgWatcher_FailedReanchorStaysReorged(t *testing.T) {
+	cert := confirmedCert("a", 95, "0xold")
+	repo, _ := recordingRepo()
+	repo.findConfirmedSinceFn = func(context.Context, uint64) ([]*domain.Certificate, error) {
+		return []*domain.Certificate{cert}, nil
+	}
+	anchors := &mockBlockchain{
+		registerHashFn: func(context.Context, string, string) (*domain.Receipt, error) { return nil, errors.New("node down") },
+	}
+
+	watcher := usecase.NewReorgWatcher(repo, &mockCanonicalChain{head: 100}, usecase.NewAnchorProcessor(repo, anchors, 0), usecase.ReorgConfig{})
+	res, err := watcher.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Reorged != 1 || res.Reanchored != 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	if cert.Status != domain.StatusReorged || cert.LastError != "node down" || cert.IsAnchored() {
+		t.Fatalf("cert = %+v, want reorged awaiting the outbox", cert)
+	}
+}
+
+func TestReorgWatcher_Errors(t *testing.T) {
+	repo, _ := recordingRepo()
+	repo.findConfirmedSinceFn = func(context.Context, uint64) ([]*domain.Certificate, error) {
+		return []*domain.Certificate{confirmedCert("a", 1, "0x1")}, nil
+	}
+	watcher := usecase.NewReorgWatcher(repo, &mockCanonicalChain{head: 5, err: errors.New("down")}, usecase.NewAnchorProcessor(repo, &mockBlockchain{}, 0), usecase.ReorgConfig{})
+	if _, err := watcher.RunOnce(context.Background()); err == nil || !strings.Contains(err.Error(), "fetching block 1") {
+		t.Fatalf("err = %v", err)
+	}
+
+	repo.findConfirmedSinceFn = func(context.Context, uint64) ([]*domain.Certificate, error) { return nil, errors.New("db down") }
+	if _, err := watcher.RunOnce(context.Background()); err == nil || !strings.Contains(err.Error(), "loading confirmed certificates") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestOutboxWorker_ResubmitsReorgedCertificate(t *testing.T) {
+	cert := confirmedCert("a", 10, "0xold")
+	cert.MarkReorged("block replaced")
+	repo, _ := recordingRepo()
+	repo.claimUnfinishedFn = func(context.Context, int, time.Duration) ([]*domain.Certificate, error) {
+		return []*domain.Certificate{cert}, nil
+	}
+	anchors := &mockBlockchain{
+		registerHashFn: func(context.Context, string, string) (*domain.Receipt, error) {
+			return &domain.Receipt{TxHash: "0xretry", BlockNumber: 12, Status: domain.ReceiptStatusSuccess}, nil
+		},
+	}
+
+	res, err := usecase.NewOutboxWorker(repo, usecase.NewAnchorProcessor(repo, anchors, 0), usecase.OutboxConfig{}).RunOnce(context.Background())
+	if err != nil || res.Completed != 1 || cert.Status != domain.StatusConfirmed {
+		t.Fatalf("result = %+v, err = %v, cert = %+v", res, err, cert)
+	}
+}
@@ -0,0 +1,173 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+type mockAnchorRepo struct {
+	unanchored []*domain.Certificate
+	saved      map[string]domain.Anchor
+	gotChain   uint64
+	gotLimit   int
+}
+
+func (m *mockAnchorRepo) FindUnanchored(_ context.Context, chainID uint64, _ string, limit int) ([]*domain.Certificate, error) {
+	m.gotChain, m.gotLimit = chainID, limit
+	return m.unanchored, nil
+}
+
+func (m *mockAnchorRepo) SaveAnchor(_ context.Context, certID string, anchor domain.Anchor) error {
+	if m.saved == nil {
+		m.saved = map[string]domain.Anchor{}
+	}
+	m.saved[certID] = anchor
+	return nil
+}
+
+type mockAnchorChain struct {
+	mockSubmitter
+	staticTarget
+}
+
+func unanchoredCerts(n int) []*domain.Certificate {
+	certs := make([]*domain.Certificate, n)
+	for i := range certs {
+		certs[i] = &domain.Certificate{
+			ID:          string(rune('a' + i)),
+			ContentHash: strings.Repeat(string(rune('1'+i)), 64),
+			Registrant:  "0xuser",
+			Status:      domain.StatusConfirmed,
+		}
+	}
+	return certs
+}
+
+func TestSecondaryAnchorer_AnchorsBatchRootWithPerCertificateProofs(t *testing.T) {
+	repo := &mockAnchorRepo{unanchored: unanchoredCerts(3)}
+	var registered, registrant string
+	chain := &mockAnchorChain{staticTarget: staticTarget{chainID: 1}}
+	chain.registerHashFn = func(_ context.Context, hash, from string) (*domain.Receipt, error) {
+		registered, registrant = hash, from
+		return &domain.Receipt{TxHash: "0xmainnet", BlockNumber: 42, BlockHash: "0xblock"}, nil
+	}
+
+	res, err := usecase.NewSecondaryAnchorer(repo, chain, usecase.SecondaryConfig{MaxSize: 8}).RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Size != 3 || res.ChainID != 1 || res.Hash != registered || registrant != "" {
+		t.Fatalf("result = %+v, registered %q by %q", res, registered, registrant)
+	}
+	if repo.gotChain != 1 || repo.gotLimit != 8 {
+		t.Fatalf("FindUnanchored(chain %d, limit %d)", repo.gotChain, repo.gotLimit)
+	}
+
+	for _, cert := range repo.unanchored {
+		anchor, ok := repo.saved[cert.ID]
+		if !ok || anchor.TxHash != "0xmainnet" || anchor.BlockNumber != 42 || anchor.Batch == nil || anchor.Batch.Root != registered {
+			t.Fatalf("cert %s anchor = %+v", cert.ID, anchor)
+		}
+		proven := &domain.Certificate{ContentHash: cert.ContentHash, Batch: anchor.Batch}
+		if !proven.VerifyInclusion() {
+			t.Fatalf("cert %s: anchor proof does not verify", cert.ID)
+		}
+		if cert.Batch != nil || !cert.AnchoredOn(1, chain.ContractAddress()) {
+			t.Fatalf("cert %s: batch %+v, anchors %+v", cert.ID, cert.Batch, cert.Anchors)
+		}
+	}
+}
+
+func TestSecondaryAnchorer_SingleCertificateAnchorsContentHash(t *testing.T) {
+	repo := &mockAnchorRepo{unanchored: unanchoredCerts(1)}
+	chain := &mockAnchorChain{staticTarget: staticTarget{chainID: 1}}
+	var registered, registrant string
+	chain.registerHashFn = func(_ context.Context, hash, from string) (*domain.Receipt, error) {
+		registered, registrant = hash, from
+		return &domain.Receipt{TxHash: "0xmainnet", BlockNumber: 42}, nil
+	}
+
+	if _, err := usecase.NewSecondaryAnchorer(repo, chain, usecase.SecondaryConfig{}).RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert := repo.unanchored[0]
+	if registered != cert.ContentHash || registrant != "0xuser" || repo.saved[cert.ID].Batch != nil {
+		t.Fatalf("registered %q by %q, anchor %+v", registered, registrant, repo.saved[cert.ID])
+	}
+}
+
+func TestSecondaryAnchorer_RecoversEarlierRegistration(t *testing.T) {
+	repo := &mockAnchorRepo{unanchored: unanchoredCerts(2)}
+	chain := &mockAnchorChain{staticTarget: staticTarget{chainID: 1}}
+	chain.isHashRegisteredFn = func(context.Context, string) (bool, error) { return true, nil }
+	chain.registerHashFn = func(context.Context, string, string) (*domain.Receipt, error) {
+		t.Fatal("registered twice")
+		return nil, nil
+	}
+	chain.findRegistrationFn = func(_ context.Context, hash string) (*domain.RegistrationEvent, error) {
+		return &domain.RegistrationEvent{ContentHash: hash, TxHash: "0xearlier", BlockNumber: 40}, nil
+	}
+
+	if _, err := usecase.NewSecondaryAnchorer(repo, chain, usecase.SecondaryConfig{}).RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for id, anchor := range repo.saved {
+		if anchor.TxHash != "0xearlier" || anchor.BlockNumber != 40 {
+			t.Fatalf("cert %s anchor = %+v", id, anchor)
+		}
+	}
+}
+
+func TestSecondaryAnchorer_NothingSavedWhenAnchoringFails(t *testing.T) {
+	repo := &mockAnchorRepo{unanchored: unanchoredCerts(2)}
+	chain := &mockAnchorChain{staticTarget: staticTarget{chainID: 1}}
+	chain.registerHashFn = func(context.Context, string, string) (*domain.Receipt, error) {
+		return nil, errors.New("rpc down")
+	}
+
+	_, err := usecase.NewSecondaryAnchorer(repo, chain, usecase.SecondaryConfig{}).RunOnce(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "rpc down") {
+		t.Fatalf("expected rpc error, got %v", err)
+	}
+	if len(repo.saved) != 0 {
+		t.Fatalf("saved anchors %+v after failure", repo.saved)
+	}
+}
+
+func TestAnchorProcessor_RecordsPrimaryAnchor(t *testing.T) {
+	repo, _ := recordingRepo()
+	chain := &mockAnchorChain{staticTarget: staticTarget{chainID: 10}}
+	chain.submitHashFn = func(context.Context, string, string) (string, error) { return "0xl2", nil }
+	chain.waitForReceiptFn = func(_ context.Context, _, txHash string) (*domain.Receipt, error) {
+		return &domain.Receipt{TxHash: txHash, BlockNumber: 9, BlockHash: "0xblock", Status: domain.ReceiptStatusSuccess}, nil
+	}
+	cert := &domain.Certificate{ContentHash: "h", Status: domain.StatusPending}
+
+	if err := usecase.NewAnchorProcessor(repo, chain, 0).Process(context.Background(), cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := domain.Anchor{ChainID: 10, ContractAddress: chain.ContractAddress(), TxHash: "0xl2", BlockNumber: 9, BlockHash: "0xblock"}
+	if len(cert.Anchors) != 1 || cert.Anchors[0] != want {
+		t.Fatalf("anchors = %+v, want %+v", cert.Anchors, want)
+	}
+}
+
+func TestAnchorProcessor_ChainIDFailureSubmitsNothing(t *testing.T) {
+	repo, _ := recordingRepo()
+	chain := &mockAnchorChain{staticTarget: staticTarget{err: errors.New("no chain id")}}
+	chain.submitHashFn = func(context.Context, string, string) (string, error) {
+		t.Fatal("submitted without a chain id")
+		return "", nil
+	}
+	cert := &domain.Certificate{ContentHash: "h", Status: domain.StatusPending}
+
+	err := usecase.NewAnchorProcessor(repo, chain, 0).Process(context.Background(), cert)
+	if err == nil || cert.Status != domain.StatusPending || !strings.Contains(cert.LastError, "no chain id") {
+		t.Fatalf("err = %v, cert = %+v", err, cert)
+	}
+}
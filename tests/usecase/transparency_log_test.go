@@ -0,0 +1,177 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+)
+
+// memLog is an in-memory transparency log built on the domain node reader.
+type memLog struct {
+	key     ed25519.PrivateKey
+	nodes   map[[2]uint64][]byte
+	leaves  map[string]uint64
+	heads   []*domain.SignedTreeHead
+	tamper  func(*domain.LogInclusionProof)
+	headKey ed25519.PrivateKey
+}
+
+func newMemLog(t *testing.T, hashes ...string) *memLog {
+	t.Helper()
+	_, key, _ := ed25519.GenerateKey(nil)
+	l := &memLog{key: key, headKey: key, nodes: map[[2]uint64][]byte{}, leaves: map[string]uint64{}}
+	for _, h := range hashes {
+		raw, _ := hex.DecodeString(h)
+		size := uint64(len(l.heads))
+		nodes, err := l.reader().Append(size, domain.MerkleLeafHash(raw))
+		if err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		for _, n := range nodes {
+			l.nodes[[2]uint64{uint64(n.Level), n.Index}] = n.Hash
+		}
+		root, _ := l.reader().Root(size + 1)
+		l.leaves[h] = size
+		l.heads = append(l.heads, domain.SignTreeHead(key, size+1, root, time.Now()))
+	}
+	return l
+}
+
+func (l *memLog) reader() domain.MerkleNodeReader {
+	return func(level uint, index uint64) ([]byte, error) {
+		if h, ok := l.nodes[[2]uint64{uint64(level), index}]; ok {
+			return h, nil
+		}
+		return nil, fmt.Errorf("missing node %d/%d", level, index)
+	}
+}
+
+func (l *memLog) PublicKey() ed25519.PublicKey { return l.headKey.Public().(ed25519.PublicKey) }
+
+func (l *memLog) LatestTreeHead(context.Context) (*domain.SignedTreeHead, error) {
+	if len(l.heads) == 0 {
+		return domain.SignTreeHead(l.key, 0, domain.MerkleRoot(nil), time.Now()), nil
+	}
+	return l.heads[len(l.heads)-1], nil
+}
+
+func (l *memLog) TreeHead(_ context.Context, size uint64) (*domain.SignedTreeHead, error) {
+	if size == 0 || size > uint64(len(l.heads)) {
+		return nil, fmt.Errorf("%w: %d", domain.ErrInvalidTreeSize, size)
+	}
+	return l.heads[size-1], nil
+}
+
+func (l *memLog) InclusionProof(_ context.Context, hash string, size uint64) (*domain.LogInclusionProof, error) {
+	index, ok := l.leaves[hash]
+	if !ok {
+		return nil, domain.ErrNotInLog
+	}
+	path, err := l.reader().InclusionProof(index, size)
+	if err != nil {
+		return nil, err
+	}
+	proof := &domain.LogInclusionProof{LeafIndex: index, TreeSize: size, AuditPath: domain.EncodeMerklePath(path)}
+	if l.tamper != nil {
+		l.tamper(proof)
+	}
+	return proof, nil
+}
+
+func (l *memLog) ConsistencyProof(_ context.Context, first, second uint64) (*domain.LogConsistencyProof, error) {
+	path, err := l.reader().ConsistencyProof(first, second)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.LogConsistencyProof{FirstSize: first, SecondSize: second, Path: domain.EncodeMerklePath(path)}, nil
+}
+
+func logHashes(n int) []string {
+	hashes := make([]string, n)
+	for i := range hashes {
+		hashes[i] = hex.EncodeToString(bytes.Repeat([]byte{byte(i + 1)}, 32))
+	}
+	return hashes
+}
+
+func TestTransparencyLogUseCase_InclusionProof(t *testing.T) {
+	hashes := logHashes(5)
+	uc := usecase.NewTransparencyLogUseCase(newMemLog(t, hashes...))
+
+	out, err := uc.InclusionProof(context.Background(), "0x"+hashes[1], 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.TreeHead.TreeSize != 5 || out.Proof.LeafIndex != 1 || out.Proof.TreeSize != 5 {
+		t.Fatalf("out = %+v, proof = %+v", out.TreeHead, out.Proof)
+	}
+
+	out, err = uc.InclusionProof(context.Background(), hashes[1], 3)
+	if err != nil || out.TreeHead.TreeSize != 3 {
+		t.Fatalf("proof against size 3: %+v, %v", out, err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		treeSize uint64
+		want     error
+	}{
+		{"not logged", logHashes(6)[5], 0, domain.ErrNotInLog},
+		{"unpublished size", hashes[1], 9, domain.ErrInvalidTreeSize},
+		{"logged after size", hashes[4], 2, domain.ErrInvalidTreeSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := uc.InclusionProof(context.Background(), tt.hash, tt.treeSize); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransparencyLogUseCase_RejectsBadProofsAndHeads(t *testing.T) {
+	hashes := logHashes(4)
+
+	forged := newMemLog(t, hashes...)
+	forged.tamper = func(p *domain.LogInclusionProof) { p.AuditPath[0] = hashes[3] }
+	if _, err := usecase.NewTransparencyLogUseCase(forged).InclusionProof(context.Background(), hashes[0], 0); !errors.Is(err, domain.ErrInvalidLogProof) {
+		t.Fatalf("expected ErrInvalidLogProof, got %v", err)
+	}
+
+	rekeyed := newMemLog(t, hashes...)
+	_, rekeyed.headKey, _ = ed25519.GenerateKey(nil)
+	if _, err := usecase.NewTransparencyLogUseCase(rekeyed).TreeHead(context.Background()); !errors.Is(err, domain.ErrInvalidTreeHead) {
+		t.Fatalf("expected ErrInvalidTreeHead, got %v", err)
+	}
+
+	if _, err := usecase.NewTransparencyLogUseCase(newMemLog(t)).InclusionProof(context.Background(), hashes[0], 0); !errors.Is(err, domain.ErrNotInLog) {
+		t.Fatalf("expected ErrNotInLog on an empty log, got %v", err)
+	}
+}
+
+func TestTransparencyLogUseCase_ConsistencyProof(t *testing.T) {
+	uc := usecase.NewTransparencyLogUseCase(newMemLog(t, logHashes(7)...))
+
+	out, err := uc.ConsistencyProof(context.Background(), 3, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.FirstTreeHead.TreeSize != 3 || out.SecondTreeHead.TreeSize != 7 || len(out.Proof.Path) == 0 {
+		t.Fatalf("out = %+v", out.Proof)
+	}
+
+	for _, sizes := range [][2]uint64{{0, 5}, {6, 5}, {8, 0}} {
+		if _, err := uc.ConsistencyProof(context.Background(), sizes[0], sizes[1]); !errors.Is(err, domain.ErrInvalidTreeSize) {
+			t.Fatalf("%v: expected ErrInvalidTreeSize, got %v", sizes, err)
+		}
+	}
+}
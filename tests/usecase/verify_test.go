@@ -9,6 +9,7 @@ import (
 	"image/jpeg"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/waizbart/aletheia-api/internal/domain"
 	"github.com/waizbart/aletheia-api/internal/usecase"
@@ -26,7 +27,7 @@ func TestVerifyUseCase_Execute(t *testing.T) {
 			name: "by hash found",
 			repo: &mockRepo{
 				findByHashFn: func(_ context.Context, hash string) (*domain.Certificate, error) {
-					return &domain.Certificate{ContentHash: hash}, nil
+					return &domain.Certificate{ContentHash: hash, Status: domain.StatusConfirmed}, nil
 				},
 			},
 			input:    usecase.VerifyInput{Hash: "abc123"},
@@ -46,7 +47,7 @@ func TestVerifyUseCase_Execute(t *testing.T) {
 			name: "by content found",
 			repo: &mockRepo{
 				findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) {
-					return &domain.Certificate{ContentHash: "computed"}, nil
+					return &domain.Certificate{ContentHash: "computed", Status: domain.StatusConfirmed}, nil
 				},
 			},
 			input:    usecase.VerifyInput{Content: strings.NewReader("test content")},
@@ -80,11 +81,11 @@ func TestVerifyUseCase_Execute(t *testing.T) {
 				findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) {
 					return nil, nil
 				},
-				findByPerceptualHashFn: func(_ context.Context, _ uint64, maxDistance int) (*domain.Certificate, error) {
-					if maxDistance != 8 {
-						t.Fatalf("maxDistance = %d, want 8", maxDistance)
+				findByPerceptualHashFn: func(_ context.Context, _ domain.PerceptualHashes, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+					if maxDistance != 8 || limit != 5 {
+						t.Fatalf("maxDistance, limit = %d, %d, want 8, 5", maxDistance, limit)
 					}
-					return &domain.Certificate{ContentHash: "perceptual"}, nil
+					return []domain.SimilarCertificate{{Certificate: &domain.Certificate{ContentHash: "perceptual", Status: domain.StatusConfirmed}, Distance: 3}}, nil
 				},
 			},
 			input:    usecase.VerifyInput{Content: bytes.NewReader(sampleJPEG(t))},
@@ -96,7 +97,7 @@ func TestVerifyUseCase_Execute(t *testing.T) {
 				findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) {
 					return nil, nil
 				},
-				findByPerceptualHashFn: func(_ context.Context, _ uint64, _ int) (*domain.Certificate, error) {
+				findByPerceptualHashFn: func(_ context.Context, _ domain.PerceptualHashes, _, _ int) ([]domain.SimilarCertificate, error) {
 					return nil, errors.New("perceptual db error")
 				},
 			},
@@ -107,7 +108,7 @@ func TestVerifyUseCase_Execute(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			uc := usecase.NewVerifyUseCase(tt.repo)
+			uc := usecase.NewVerifyUseCase(tt.repo, &mockBlockchain{})
 			out, err := uc.Execute(context.Background(), tt.input)
 
 			if tt.wantErr != "" {
@@ -130,6 +131,166 @@ func TestVerifyUseCase_Execute(t *testing.T) {
 	}
 }
 
+func TestVerifyUseCase_ReportsMatchType(t *testing.T) {
+	near := &domain.Certificate{ContentHash: "near", Status: domain.StatusConfirmed}
+	nearDistances := map[domain.HashAlgorithm]int{domain.DCTHash: 2, domain.DifferenceHash: 4, domain.AverageHash: 6}
+	far := &domain.Certificate{ContentHash: "far", Status: domain.StatusConfirmed}
+	repo := &mockRepo{
+		findByHashFn: func(_ context.Context, hash string) (*domain.Certificate, error) {
+			if hash == "exact" {
+				return &domain.Certificate{ContentHash: hash, Status: domain.StatusConfirmed}, nil
+			}
+			return nil, nil
+		},
+		findByPerceptualHashFn: func(_ context.Context, hashes domain.PerceptualHashes, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+			if _, legacy := hashes[domain.LegacyAverageHash]; len(hashes) != 4 || !legacy || maxDistance != 16 || limit != 2 {
+				t.Fatalf("hashes, maxDistance, limit = %v, %d, %d, want 3 hashes and the legacy one, 16, 2", hashes, maxDistance, limit)
+			}
+			return []domain.SimilarCertificate{{Certificate: near, Distance: 4, Distances: nearDistances}, {Certificate: far, Distance: 16}}, nil
+		},
+	}
+	uc := usecase.NewVerifyUseCase(repo, &mockBlockchain{})
+
+	out, err := uc.Execute(context.Background(), usecase.VerifyInput{Hash: "exact"})
+	if err != nil || out.MatchType != domain.MatchExact || out.Distance != 0 || out.Similarity != 1 || out.Similar != nil {
+		t.Fatalf("exact = %+v, %v", out, err)
+	}
+
+	maxDistance := 16
+	out, err = uc.Execute(context.Background(), usecase.VerifyInput{Content: bytes.NewReader(sampleJPEG(t)), MaxDistance: &maxDistance, Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.MatchType != domain.MatchPerceptual || out.Certificate != near || out.Distance != 4 || out.Similarity != 0.9375 || len(out.Similar) != 2 ||
+		out.Distances[domain.DCTHash] != 2 {
+		t.Fatalf("perceptual = %+v", out)
+	}
+
+	zero := 0
+	repo.findByPerceptualHashFn = func(_ context.Context, _ domain.PerceptualHashes, maxDistance, _ int) ([]domain.SimilarCertificate, error) {
+		if maxDistance != 0 {
+			t.Fatalf("maxDistance = %d, want an explicit 0 to be kept", maxDistance)
+		}
+		return nil, nil
+	}
+	if out, _ := uc.Execute(context.Background(), usecase.VerifyInput{Content: bytes.NewReader(sampleJPEG(t)), MaxDistance: &zero}); out.Certified || out.MatchType != "" {
+		t.Fatalf("no match = %+v", out)
+	}
+}
+
+func TestVerifyUseCase_PrefersNearestAnchoredCandidate(t *testing.T) {
+	pending := &domain.Certificate{ContentHash: "pending", Status: domain.StatusPending}
+	failed := &domain.Certificate{ContentHash: "failed", Status: domain.StatusFailed}
+	confirmed := &domain.Certificate{ContentHash: "confirmed", Status: domain.StatusConfirmed}
+	candidates := []domain.SimilarCertificate{
+		{Certificate: pending, Distance: 1},
+		{Certificate: failed, Distance: 2},
+		{Certificate: confirmed, Distance: 5},
+	}
+	repo := &mockRepo{
+		findByHashFn: func(context.Context, string) (*domain.Certificate, error) { return nil, nil },
+		findByPerceptualHashFn: func(context.Context, domain.PerceptualHashes, int, int) ([]domain.SimilarCertificate, error) {
+			return candidates, nil
+		},
+	}
+	uc := usecase.NewVerifyUseCase(repo, &mockBlockchain{})
+
+	out, err := uc.Execute(context.Background(), usecase.VerifyInput{Content: bytes.NewReader(sampleJPEG(t))})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !out.Certified || out.Certificate != confirmed || out.Distance != 5 || len(out.Similar) != 3 {
+		t.Fatalf("out = %+v, want the confirmed candidate behind the nearer pending and failed ones", out)
+	}
+
+	candidates = candidates[:2]
+	out, err = uc.Execute(context.Background(), usecase.VerifyInput{Content: bytes.NewReader(sampleJPEG(t))})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Certified || out.Certificate != pending || out.Distance != 1 {
+		t.Fatalf("out = %+v, want the nearest candidate reported as not certified", out)
+	}
+}
+
+func TestVerifyUseCase_MatchesPixelHash(t *testing.T) {
+	content := sampleJPEG(t)
+	original := &domain.Certificate{ContentHash: "original", Status: domain.StatusConfirmed}
+	var lookedUp string
+	repo := &mockRepo{
+		findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) { return nil, nil },
+		findByPixelHashFn: func(_ context.Context, pixelHash string) (*domain.Certificate, error) {
+			lookedUp = pixelHash
+			return original, nil
+		},
+		findByPerceptualHashFn: func(_ context.Context, _ domain.PerceptualHashes, _, _ int) ([]domain.SimilarCertificate, error) {
+			t.Fatal("pixel match fell back to perceptual search")
+			return nil, nil
+		},
+	}
+	uc := usecase.NewVerifyUseCase(repo, &mockBlockchain{})
+
+	out, err := uc.Execute(context.Background(), usecase.VerifyInput{Content: bytes.NewReader(content)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lookedUp != domain.PixelHashFromBytes(content) {
+		t.Fatalf("looked up pixel hash %q", lookedUp)
+	}
+	if out.MatchType != domain.MatchPixel || out.Certificate != original || out.Distance != 0 || out.Similarity != 1 || out.Similar != nil || !out.Certified {
+		t.Fatalf("pixel match = %+v", out)
+	}
+
+	repo.findByPixelHashFn = func(_ context.Context, _ string) (*domain.Certificate, error) {
+		return nil, errors.New("db down")
+	}
+	if _, err := uc.Execute(context.Background(), usecase.VerifyInput{Content: bytes.NewReader(content)}); err == nil || !strings.Contains(err.Error(), "db down") {
+		t.Fatalf("expected the lookup error, got %v", err)
+	}
+
+	repo.findByPixelHashFn = func(_ context.Context, _ string) (*domain.Certificate, error) {
+		t.Fatal("hash-only verify looked up a pixel hash")
+		return nil, nil
+	}
+	if _, err := uc.Execute(context.Background(), usecase.VerifyInput{Hash: "abc"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestVerifyUseCase_MatchesVideoFingerprint(t *testing.T) {
+	clip := domain.VideoFingerprint{{Start: 0, End: time.Second, Hashes: domain.PerceptualHashes{domain.DCTHash: 1}}}
+	original := &domain.Certificate{ContentHash: "original", Status: domain.StatusConfirmed}
+	repo := &mockRepo{
+		findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) { return nil, nil },
+		findByPerceptualHashFn: func(_ context.Context, _ domain.PerceptualHashes, _, _ int) ([]domain.SimilarCertificate, error) {
+			t.Fatal("video content searched by image hash")
+			return nil, nil
+		},
+		findByVideoFn: func(_ context.Context, got domain.VideoFingerprint, maxDistance, limit int) ([]domain.SimilarCertificate, error) {
+			if len(got) != 1 || maxDistance != usecase.DefaultMaxDistance || limit != usecase.DefaultSimilarLimit {
+				t.Fatalf("clip, maxDistance, limit = %v, %d, %d", got, maxDistance, limit)
+			}
+			return []domain.SimilarCertificate{{Certificate: original, Distance: 3, Video: &domain.VideoMatch{Start: 80 * time.Second, End: 90 * time.Second, Confidence: 0.9}}}, nil
+		},
+	}
+	fingerprinter := &mockFingerprinter{fp: clip}
+	uc := usecase.NewVerifyUseCase(repo, &mockBlockchain{}).WithVideoFingerprinter(fingerprinter)
+
+	out, err := uc.Execute(context.Background(), usecase.VerifyInput{Content: strings.NewReader("video")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.MatchType != domain.MatchPerceptual || out.Certificate != original || out.Distance != 3 || !out.Certified ||
+		out.Video == nil || out.Video.Start != 80*time.Second {
+		t.Fatalf("video match = %+v", out)
+	}
+
+	repo.findByPerceptualHashFn = nil
+	if _, err := uc.Execute(context.Background(), usecase.VerifyInput{Content: bytes.NewReader(sampleJPEG(t))}); err != nil || fingerprinter.calls != 1 {
+		t.Fatalf("images must not be fingerprinted: calls = %d, err = %v", fingerprinter.calls, err)
+	}
+}
+
 func sampleJPEG(t *testing.T) []byte {
 	t.Helper()
 	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
@@ -144,3 +305,182 @@ func sampleJPEG(t *testing.T) []byte {
 	}
 	return b.Bytes()
 }
+
+func TestVerifyUseCase_BatchInclusion(t *testing.T) {
+	certs := []*domain.Certificate{
+		{ContentHash: strings.Repeat("a", 64), Status: domain.StatusConfirmed},
+		{ContentHash: strings.Repeat("b", 64), Status: domain.StatusConfirmed},
+		{ContentHash: strings.Repeat("c", 64), Status: domain.StatusConfirmed},
+	}
+	root, err := domain.AssignMerkleBatch(certs)
+	if err != nil {
+		t.Fatalf("assign batch: %v", err)
+	}
+
+	var checked string
+	chain := &mockBlockchain{isHashRegisteredFn: func(_ context.Context, hash string) (bool, error) {
+		checked = hash
+		return true, nil
+	}}
+	repo := &mockRepo{findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) { return certs[1], nil }}
+
+	out, err := usecase.NewVerifyUseCase(repo, chain).Execute(context.Background(), usecase.VerifyInput{Hash: certs[1].ContentHash, ConfirmOnChain: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !out.Certified || out.InclusionVerified == nil || !*out.InclusionVerified {
+		t.Fatalf("output = %+v, want certified with verified inclusion", out)
+	}
+	if checked != root {
+		t.Fatalf("confirmed %q on chain, want batch root %q", checked, root)
+	}
+
+	certs[1].Batch.Proof[0] = strings.Repeat("0", 64)
+	out, err = usecase.NewVerifyUseCase(repo, chain).Execute(context.Background(), usecase.VerifyInput{Hash: certs[1].ContentHash})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Certified || *out.InclusionVerified {
+		t.Fatalf("output = %+v, want tampered proof rejected", out)
+	}
+}
+
+func TestVerifyUseCase_ConfirmOnChain(t *testing.T) {
+	found := &mockRepo{
+		findByHashFn: func(_ context.Context, hash string) (*domain.Certificate, error) {
+			return &domain.Certificate{ContentHash: hash, Status: domain.StatusConfirmed}, nil
+		},
+	}
+
+	tests := []struct {
+		name        string
+		repo        *mockRepo
+		chain       usecase.BlockchainService
+		confirm     bool
+		wantOnChain *bool
+		wantErr     string
+	}{
+		{
+			name:    "not requested",
+			repo:    found,
+			chain:   &mockBlockchain{},
+			confirm: false,
+		},
+		{
+			name: "backed by contract",
+			repo: found,
+			chain: &mockBlockchain{isHashRegisteredFn: func(_ context.Context, hash string) (bool, error) {
+				if hash != "abc123" {
+					t.Fatalf("checked hash %q, want abc123", hash)
+				}
+				return true, nil
+			}},
+			confirm:     true,
+			wantOnChain: boolPtr(true),
+		},
+		{
+			name: "missing on chain",
+			repo: found,
+			chain: &mockBlockchain{isHashRegisteredFn: func(_ context.Context, _ string) (bool, error) {
+				return false, nil
+			}},
+			confirm:     true,
+			wantOnChain: boolPtr(false),
+		},
+		{
+			name: "chain error",
+			repo: found,
+			chain: &mockBlockchain{isHashRegisteredFn: func(_ context.Context, _ string) (bool, error) {
+				return false, errors.New("rpc down")
+			}},
+			confirm: true,
+			wantErr: "confirming on chain",
+		},
+		{
+			name:    "no chain configured",
+			repo:    found,
+			chain:   nil,
+			confirm: true,
+			wantErr: "not available",
+		},
+		{
+			name: "not certified skips chain",
+			repo: &mockRepo{findByHashFn: func(_ context.Context, _ string) (*domain.Certificate, error) {
+				return nil, nil
+			}},
+			chain:   &mockBlockchain{},
+			confirm: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := usecase.NewVerifyUseCase(tt.repo, tt.chain)
+			out, err := uc.Execute(context.Background(), usecase.VerifyInput{Hash: "abc123", ConfirmOnChain: tt.confirm})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (out.OnChainConfirmed == nil) != (tt.wantOnChain == nil) {
+				t.Fatalf("on-chain = %v, want %v", out.OnChainConfirmed, tt.wantOnChain)
+			}
+			if tt.wantOnChain != nil && *out.OnChainConfirmed != *tt.wantOnChain {
+				t.Fatalf("on-chain = %v, want %v", *out.OnChainConfirmed, *tt.wantOnChain)
+			}
+		})
+	}
+}
+
+func boolPtr(v bool) *bool { return &v }
+
+type mockTimestampChain struct {
+	mockBlockchain
+	verifyFn func(hash string, token []byte) (time.Time, error)
+}
+
+func (m *mockTimestampChain) VerifyTimestamp(hash string, token []byte) (time.Time, error) {
+	return m.verifyFn(hash, token)
+}
+
+func TestVerifyUseCase_TimestampToken(t *testing.T) {
+	stampedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cert := &domain.Certificate{ContentHash: "abc123", Status: domain.StatusConfirmed, TimestampToken: []byte("token")}
+	repo := &mockRepo{findByHashFn: func(context.Context, string) (*domain.Certificate, error) { return cert, nil }}
+
+	tests := []struct {
+		name      string
+		verifyErr error
+		want      bool
+	}{
+		{"valid token", nil, true},
+		{"invalid token", domain.ErrInvalidTimestamp, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &mockTimestampChain{verifyFn: func(hash string, token []byte) (time.Time, error) {
+				if hash != "abc123" || string(token) != "token" {
+					t.Fatalf("verified %q with %q", hash, token)
+				}
+				return stampedAt, tt.verifyErr
+			}}
+
+			out, err := usecase.NewVerifyUseCase(repo, chain).Execute(context.Background(), usecase.VerifyInput{Hash: "abc123"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.Certified != tt.want || out.TimestampVerified == nil || *out.TimestampVerified != tt.want {
+				t.Fatalf("output = %+v, want certified and verified %v", out, tt.want)
+			}
+			if tt.want && (out.TimestampedAt == nil || !out.TimestampedAt.Equal(stampedAt)) {
+				t.Fatalf("timestamped at = %v, want %v", out.TimestampedAt, stampedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package video_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/video"
+)
+
+func TestDemuxBMFF_ReadsSampleTables(t *testing.T) {
+	frames := make([][]byte, 7)
+	for i := range frames {
+		frames[i] = []byte(fmt.Sprintf("frame-%d-%s", i, bytes.Repeat([]byte("x"), i)))
+	}
+	for _, wide := range []bool{false, true} {
+		t.Run(fmt.Sprintf("co64=%t", wide), func(t *testing.T) {
+			content := buildMP4(mp4Spec{
+				codec: "avc1", frames: frames, fps: 4, perChunk: 3, wide: wide, audio: true,
+				keyframe: func(i int) bool { return i%3 == 0 },
+			})
+
+			track, err := video.DemuxBMFF(content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if track.Codec != "avc1" || track.Duration != 1750*time.Millisecond || len(track.Samples) != len(frames) {
+				t.Fatalf("track = %s, %v, %d samples", track.Codec, track.Duration, len(track.Samples))
+			}
+			for i, s := range track.Samples {
+				if got := content[s.Offset : s.Offset+s.Size]; !bytes.Equal(got, frames[i]) {
+					t.Fatalf("sample %d = %q, want %q", i, got, frames[i])
+				}
+				if s.Time != time.Duration(i)*250*time.Millisecond || s.Sync != (i%3 == 0) {
+					t.Fatalf("sample %d at %v, sync %t", i, s.Time, s.Sync)
+				}
+			}
+		})
+	}
+}
+
+func TestDemuxBMFF_RejectsOtherContainers(t *testing.T) {
+	riff := append([]byte("RIFF\x24\x00\x00\x00AVI LIST"), make([]byte, 32)...)
+	for name, content := range map[string][]byte{
+		"avi":       riff,
+		"jpeg":      sceneFrame(t, 0, 90),
+		"truncated": buildMP4(mp4Spec{codec: "jpeg", frames: [][]byte{{1}}, fps: 1})[:40],
+	} {
+		if _, err := video.DemuxBMFF(content); !errors.Is(err, domain.ErrUnsupportedVideo) {
+			t.Errorf("%s: expected ErrUnsupportedVideo, got %v", name, err)
+		}
+	}
+}
+
+// patchBox overwrites the body of the first box of the given type from the
+// given byte on.
+func patchBox(content []byte, typ string, at int, b []byte) []byte {
+	out := append([]byte(nil), content...)
+	copy(out[bytes.Index(out, []byte(typ))+4+at:], b)
+	return out
+}
+
+func TestDemuxBMFF_RejectsForgedSampleTables(t *testing.T) {
+	spec := mp4Spec{codec: "jpeg", frames: sceneFrames(t, 0, 2, 2, 90), fps: 2, wide: true}
+	content := buildMP4(spec)
+	padded := append(buildMP4(spec), mp4Box("free", make([]byte, 3<<20))...)
+
+	for name, content := range map[string][]byte{
+		"offset overflow":          patchBox(content, "co64", 8, u64(math.MaxInt64-2)),
+		"uniform beyond chunks":    patchBox(content, "stsz", 4, append(u32(1), u32(uint32(len(content)))...)),
+		"uniform beyond the limit": patchBox(padded, "stsz", 4, append(u32(1), u32(2<<20)...)),
+	} {
+		if _, err := video.DemuxBMFF(content); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if _, err := video.NewFingerprinter().Fingerprint(content); err == nil {
+			t.Errorf("%s: fingerprint: expected an error", name)
+		}
+	}
+}
+
+func TestFingerprinter_SegmentsAtKeyframes(t *testing.T) {
+	content := buildMP4(mp4Spec{
+		codec: "jpeg", frames: sceneFrames(t, 0, 6, 4, 90), fps: 4, perChunk: 5,
+		keyframe: func(i int) bool { return i%2 == 0 },
+	})
+
+	fp, err := video.NewFingerprinter().Fingerprint(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fp) != 6 {
+		t.Fatalf("segments = %d, want one per second", len(fp))
+	}
+	for i, seg := range fp {
+		if seg.Start != time.Duration(i)*time.Second || seg.End != time.Duration(i+1)*time.Second || len(seg.Hashes) != 3 {
+			t.Fatalf("segment %d = %v-%v with %d hashes", i, seg.Start, seg.End, len(seg.Hashes))
+		}
+	}
+
+	halves, _ := video.NewFingerprinter().WithSegmentInterval(500 * time.Millisecond).Fingerprint(content)
+	if len(halves) != 12 {
+		t.Fatalf("segments = %d, want one per half second", len(halves))
+	}
+}
+
+func TestFingerprinter_SkipsOversizedKeyframes(t *testing.T) {
+	img, err := jpeg.Decode(bytes.NewReader(sceneFrame(t, 0, 90)))
+	if err != nil {
+		t.Fatalf("decode frame: %v", err)
+	}
+	var frame bytes.Buffer
+	if err := png.Encode(&frame, img); err != nil {
+		t.Fatalf("encode frame: %v", err)
+	}
+	bomb := append([]byte(nil), frame.Bytes()...)
+	binary.BigEndian.PutUint32(bomb[16:], 60000)
+	binary.BigEndian.PutUint32(bomb[20:], 60000)
+	binary.BigEndian.PutUint32(bomb[29:], crc32.ChecksumIEEE(bomb[12:29]))
+
+	content := buildMP4(mp4Spec{codec: "png ", frames: [][]byte{bomb, frame.Bytes()}, fps: 1})
+	fp, err := video.NewFingerprinter().Fingerprint(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fp) != 1 || fp[0].Start != time.Second {
+		t.Fatalf("fingerprint = %+v, want only the second keyframe", fp)
+	}
+
+	only := buildMP4(mp4Spec{codec: "png ", frames: [][]byte{bomb}, fps: 1})
+	if _, err := video.NewFingerprinter().Fingerprint(only); !errors.Is(err, domain.ErrUnsupportedVideo) {
+		t.Fatalf("expected ErrUnsupportedVideo, got %v", err)
+	}
+}
+
+func TestFingerprinter_ReportsUnsupportedCodecs(t *testing.T) {
+	for _, codec := range []string{"avc1", "hvc1", "hev1", "vp08", "vp09", "av01"} {
+		content := buildMP4(mp4Spec{codec: codec, frames: sceneFrames(t, 0, 2, 2, 90), fps: 2})
+		_, err := video.NewFingerprinter().Fingerprint(content)
+		if !errors.Is(err, domain.ErrUnsupportedVideo) {
+			t.Errorf("%s: expected ErrUnsupportedVideo, got %v", codec, err)
+		} else if !strings.Contains(err.Error(), "only Motion JPEG and PNG") {
+			t.Errorf("%s: error %q does not name the supported codecs", codec, err)
+		}
+	}
+}
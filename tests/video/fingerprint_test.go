@@ -0,0 +1,59 @@
+package video_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/waizbart/aletheia-api/internal/domain"
+	"github.com/waizbart/aletheia-api/internal/repository"
+	"github.com/waizbart/aletheia-api/internal/usecase"
+	"github.com/waizbart/aletheia-api/internal/video"
+)
+
+const registrant = "0x1111111111111111111111111111111111111111"
+
+func TestFingerprinter_MatchesTrimmedReencodedClip(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryCertificateRepo()
+	chain := repository.NewSimulatedBlockchainService(31337, registrant)
+	fingerprinter := video.NewFingerprinter()
+	certify := usecase.NewCertifyUseCase(repo, usecase.NewAnchorProcessor(repo, chain, 0)).WithVideoFingerprinter(fingerprinter)
+	verify := usecase.NewVerifyUseCase(repo, chain).WithVideoFingerprinter(fingerprinter)
+
+	var certified []*domain.Certificate
+	for _, seconds := range [][2]int{{0, 12}, {30, 42}} {
+		original := buildMP4(mp4Spec{
+			codec: "jpeg", frames: sceneFrames(t, seconds[0], seconds[1], 5, 90), fps: 5, perChunk: 5,
+			keyframe: func(i int) bool { return i%5 == 0 },
+		})
+		out, err := certify.Execute(ctx, usecase.CertifyInput{Content: bytes.NewReader(original), Registrant: registrant})
+		if err != nil {
+			t.Fatalf("certify: %v", err)
+		}
+		if len(out.Certificate.Fingerprint) != 12 {
+			t.Fatalf("fingerprint has %d segments, want 12", len(out.Certificate.Fingerprint))
+		}
+		certified = append(certified, out.Certificate)
+	}
+
+	// Seconds 4 to 9 of the first video, at a lower frame rate and quality,
+	// with a keyframe every frame.
+	clip := buildMP4(mp4Spec{codec: "jpeg", frames: sceneFrames(t, 4, 9, 2, 40), fps: 2, wide: true})
+	out, err := verify.Execute(ctx, usecase.VerifyInput{Content: bytes.NewReader(clip)})
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if out.MatchType != domain.MatchPerceptual || out.Certificate.ID != certified[0].ID || !out.Certified {
+		t.Fatalf("clip matched %+v", out)
+	}
+	if out.Video == nil || out.Video.Start != 4*time.Second || out.Video.End != 9*time.Second || out.Video.Confidence < 0.9 {
+		t.Fatalf("matched range = %+v, want 4s to 9s", out.Video)
+	}
+
+	unrelated := buildMP4(mp4Spec{codec: "jpeg", frames: sceneFrames(t, 60, 66, 2, 90), fps: 2})
+	if out, err := verify.Execute(ctx, usecase.VerifyInput{Content: bytes.NewReader(unrelated)}); err != nil || out.Certificate != nil {
+		t.Fatalf("unrelated video matched %+v, %v", out, err)
+	}
+}
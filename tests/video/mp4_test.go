@@ -0,0 +1,159 @@
+package video_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"testing"
+)
+
+const mp4Timescale = 600
+
+// mp4Spec describes a single-track ISO BMFF file built by buildMP4.
+type mp4Spec struct {
+	codec  string
+	frames [][]byte
+	fps    int
+	// keyframe reports the sync samples; nil omits the stss box, making
+	// every sample a sync sample.
+	keyframe func(i int) bool
+	// perChunk is how many samples each chunk holds; the last may hold fewer.
+	perChunk int
+	wide     bool
+	// audio adds a sound track ahead of the video track.
+	audio bool
+}
+
+func buildMP4(s mp4Spec) []byte {
+	ftyp := mp4Box("ftyp", []byte("isom"), u32(512), []byte("isommp41"))
+	mdatHeader := 8
+	if s.perChunk == 0 {
+		s.perChunk = 1
+	}
+
+	var mdat []byte
+	var offsets []uint64
+	var sizes []byte
+	for i, f := range s.frames {
+		if i%s.perChunk == 0 {
+			offsets = append(offsets, uint64(len(ftyp)+mdatHeader+len(mdat)))
+		}
+		mdat = append(mdat, f...)
+		sizes = append(sizes, u32(uint32(len(f)))...)
+	}
+
+	delta := mp4Timescale / s.fps
+	stsc := [][2]uint32{{1, uint32(s.perChunk)}}
+	if rem := len(s.frames) % s.perChunk; rem != 0 {
+		stsc = append(stsc, [2]uint32{uint32(len(offsets)), uint32(rem)})
+	}
+	var stscBody []byte
+	for _, run := range stsc {
+		stscBody = append(stscBody, u32(run[0])...)
+		stscBody = append(stscBody, u32(run[1])...)
+		stscBody = append(stscBody, u32(1)...)
+	}
+	var chunkTable []byte
+	if s.wide {
+		var body []byte
+		for _, o := range offsets {
+			body = append(body, u64(o)...)
+		}
+		chunkTable = fullBox("co64", u32(uint32(len(offsets))), body)
+	} else {
+		var body []byte
+		for _, o := range offsets {
+			body = append(body, u32(uint32(o))...)
+		}
+		chunkTable = fullBox("stco", u32(uint32(len(offsets))), body)
+	}
+
+	tables := [][]byte{
+		fullBox("stsd", u32(1), mp4Box(s.codec, make([]byte, 78))),
+		fullBox("stts", u32(1), u32(uint32(len(s.frames))), u32(uint32(delta))),
+		fullBox("stsc", u32(uint32(len(stsc))), stscBody),
+		fullBox("stsz", u32(0), u32(uint32(len(s.frames))), sizes),
+		chunkTable,
+	}
+	if s.keyframe != nil {
+		var sync []byte
+		n := 0
+		for i := range s.frames {
+			if s.keyframe(i) {
+				sync = append(sync, u32(uint32(i+1))...)
+				n++
+			}
+		}
+		tables = append(tables, fullBox("stss", u32(uint32(n)), sync))
+	}
+
+	duration := uint32(len(s.frames) * delta)
+	videoTrak := mp4Box("trak", mp4Box("mdia",
+		fullBox("mdhd", u32(0), u32(0), u32(mp4Timescale), u32(duration), make([]byte, 4)),
+		fullBox("hdlr", u32(0), []byte("vide"), make([]byte, 13)),
+		mp4Box("minf", mp4Box("stbl", tables...)),
+	))
+	moovBody := [][]byte{fullBox("mvhd", make([]byte, 96))}
+	if s.audio {
+		moovBody = append(moovBody, mp4Box("trak", mp4Box("mdia",
+			fullBox("hdlr", u32(0), []byte("soun"), make([]byte, 13)),
+		)))
+	}
+	moovBody = append(moovBody, videoTrak)
+
+	out := append([]byte(nil), ftyp...)
+	out = append(out, mp4Box("mdat", mdat)...)
+	return append(out, mp4Box("moov", moovBody...)...)
+}
+
+func mp4Box(typ string, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	return append(append(u32(uint32(8+len(body))), typ...), body...)
+}
+
+// fullBox prefixes the body with version 0 and no flags.
+func fullBox(typ string, parts ...[]byte) []byte {
+	return mp4Box(typ, append([][]byte{u32(0)}, parts...)...)
+}
+
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+func u64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+// sceneFrame draws a frame whose content changes every second, so each
+// segment of a fingerprint looks different.
+func sceneFrame(t *testing.T, second int, quality int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 96, 64))
+	cx, cy := float64(17*second%96), float64(29*second%64)
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 96; x++ {
+			v := 128 + 90*math.Sin(float64(x)/(6+float64(second%5))+float64(second))*math.Cos(float64(y)/(5+float64(second%3)))
+			if math.Hypot(float64(x)-cx, float64(y)-cy) < 14 {
+				v = 255 - v
+			}
+			c := uint8(math.Max(0, math.Min(255, v)))
+			img.Set(x, y, color.RGBA{R: c, G: 255 - c, B: uint8(second * 40), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatalf("encode frame: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// sceneFrames returns fps frames per second for seconds [from, to).
+func sceneFrames(t *testing.T, from, to, fps, quality int) [][]byte {
+	t.Helper()
+	var frames [][]byte
+	for second := from; second < to; second++ {
+		frame := sceneFrame(t, second, quality)
+		for i := 0; i < fps; i++ {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}